@@ -5,10 +5,18 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/vilasle/metrics/internal/logger"
+	"github.com/vilasle/metrics/internal/metric"
 	agent "github.com/vilasle/metrics/internal/service"
 )
 
+// spool keeps batches of metrics which are not sent to server until server is available
+type spool interface {
+	Push(key string, metrics []metric.Metric) error
+	Replay(send func(key string, metrics []metric.Metric) error) error
+}
+
 type collectorAgent struct {
 	agent.Collector
 	agent.Sender
@@ -16,6 +24,7 @@ type collectorAgent struct {
 	repeat       []time.Duration
 	reportDelay  time.Duration
 	collectDelay time.Duration
+	outbox       spool
 }
 
 type delay struct {
@@ -30,8 +39,17 @@ func newDelay(report, collect time.Duration) delay {
 	}
 }
 
-func newCollectorAgent(collector agent.Collector, sender agent.Sender, delaySetting delay) collectorAgent {
-	return collectorAgent{
+type agentOption func(*collectorAgent)
+
+// withOutbox sets the spool for metrics which are not sent, they are sent before the next report
+func withOutbox(outbox spool) agentOption {
+	return func(a *collectorAgent) {
+		a.outbox = outbox
+	}
+}
+
+func newCollectorAgent(collector agent.Collector, sender agent.Sender, delaySetting delay, opts ...agentOption) collectorAgent {
+	a := collectorAgent{
 		Collector: collector,
 		Sender:    sender,
 		mx:        &sync.Mutex{},
@@ -43,6 +61,10 @@ func newCollectorAgent(collector agent.Collector, sender agent.Sender, delaySett
 		reportDelay:  delaySetting.report,
 		collectDelay: delaySetting.collect,
 	}
+	for _, opt := range opts {
+		opt(&a)
+	}
+	return a
 }
 
 func (a collectorAgent) run(ctx context.Context, wg *sync.WaitGroup) {
@@ -89,16 +111,43 @@ func (a collectorAgent) report(ctx context.Context, wg *sync.WaitGroup) {
 func (a collectorAgent) handleReport() {
 	if err := a.sendReport(); err == nil {
 		a.ResetCounter("PollCount")
+		a.ResetSummary("PauseNs")
 	} else {
 		logger.Error("failed to report metrics", "err", err)
 	}
 }
 
-func (a collectorAgent) sendReport() (err error) {
+// sendReport sends collected metrics to server. If there is outbox, metrics which are not sent
+// are put to outbox and report is counted as sent, so counters are reset and their values are not sent twice.
+// Each report has own idempotency key which is kept for all attempts of sending,
+// so server does not apply report twice if response to the previous attempt was lost
+func (a collectorAgent) sendReport() error {
 	a.mx.Lock()
 	defer a.mx.Unlock()
+
+	key := uuid.NewString()
+	metrics := a.AllMetrics()
+	if a.outbox == nil {
+		return a.sendWithRepeat(key, metrics)
+	}
+
+	// metrics from outbox are sent first, so server gets them in order of collection.
+	// If server is still unavailable, there is no reason to repeat sending of current report
+	err := a.outbox.Replay(a.send)
+	if err == nil {
+		err = a.sendWithRepeat(key, metrics)
+	}
+	if err == nil {
+		return nil
+	}
+
+	logger.Warnw("metrics are not sent, they are put to outbox", "error", err)
+	return a.outbox.Push(key, metrics)
+}
+
+func (a collectorAgent) sendWithRepeat(key string, metrics []metric.Metric) (err error) {
 	for _, d := range a.repeat {
-		if err = a.Send(a.AllMetrics()...); err != nil {
+		if err = a.send(key, metrics); err != nil {
 			time.Sleep(d)
 		} else {
 			break
@@ -106,3 +155,7 @@ func (a collectorAgent) sendReport() (err error) {
 	}
 	return err
 }
+
+func (a collectorAgent) send(key string, metrics []metric.Metric) error {
+	return a.Send(agent.WithIdempotencyKey(context.Background(), key), metrics...)
+}
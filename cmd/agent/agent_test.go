@@ -8,13 +8,18 @@ import (
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"os"
 	"sync"
 	"testing"
 	"time"
 
 	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/service"
+	"github.com/vilasle/metrics/internal/service/agent/outbox"
 )
 
 func Test_collectorAgent(t *testing.T) {
@@ -25,9 +30,10 @@ func Test_collectorAgent(t *testing.T) {
 	collector.EXPECT().Collect().AnyTimes()
 	collector.EXPECT().AllMetrics().AnyTimes()
 	collector.EXPECT().ResetCounter("PollCount").AnyTimes()
+	collector.EXPECT().ResetSummary("PauseNs").AnyTimes()
 
 	sender := NewMockSender(ctrl)
-	sender.EXPECT().Send(gomock.Any()).AnyTimes()
+	sender.EXPECT().Send(gomock.Any(), gomock.Any()).AnyTimes()
 	sender.EXPECT().Close()
 
 	agent := newCollectorAgent(
@@ -46,6 +52,60 @@ func Test_collectorAgent(t *testing.T) {
 
 }
 
+func Test_collectorAgent_outbox(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	first := []metric.Metric{metric.NewCounterMetric("PollCount", 2)}
+	second := []metric.Metric{metric.NewCounterMetric("PollCount", 3)}
+
+	collector := NewMockCollector(ctrl)
+	collector.EXPECT().ResetCounter("PollCount").Times(2)
+	collector.EXPECT().ResetSummary("PauseNs").Times(2)
+
+	sender := NewMockSender(ctrl)
+	unavailable := errors.New("server is unavailable")
+
+	box, err := outbox.NewOutbox(t.TempDir())
+	require.NoError(t, err)
+
+	agent := newCollectorAgent(collector, sender, newDelay(time.Second, time.Second), withOutbox(box))
+	agent.repeat = []time.Duration{time.Millisecond, time.Millisecond}
+
+	// server is unavailable, metrics are put to outbox and counters are reset.
+	// All attempts of sending of report have the same idempotency key
+	keys := make([]string, 0)
+	collector.EXPECT().AllMetrics().Return(first)
+	sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ms ...metric.Metric) error {
+		keys = append(keys, service.IdempotencyKeyFromContext(ctx))
+		return unavailable
+	}).Times(2)
+	agent.handleReport()
+	assert.Equal(t, 1, box.Len())
+	require.Len(t, keys, 2)
+	require.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+
+	// metrics from outbox are sent before the current ones with their own key
+	collector.EXPECT().AllMetrics().Return(second)
+	gomock.InOrder(
+		sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ms ...metric.Metric) error {
+			require.Len(t, ms, 1)
+			assert.Equal(t, "2", ms[0].Value())
+			assert.Equal(t, keys[0], service.IdempotencyKeyFromContext(ctx))
+			return nil
+		}),
+		sender.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ms ...metric.Metric) error {
+			require.Len(t, ms, 1)
+			assert.Equal(t, "3", ms[0].Value())
+			assert.NotEqual(t, keys[0], service.IdempotencyKeyFromContext(ctx))
+			return nil
+		}),
+	)
+	agent.handleReport()
+	assert.Equal(t, 0, box.Len())
+}
+
 func Test_createSender(t *testing.T) {
 	hash := sha256.New().Sum([]byte("test"))
 
@@ -66,7 +126,26 @@ func Test_createSender(t *testing.T) {
 	err = os.WriteFile("hash.key", hash, 0644)
 	require.NoError(t, err)
 
-	_, err = createSender("hash.key", "cert.pub", "localhost:8080", 1)
+	_, err = createSender("hash.key", "cert.pub", "", "localhost:8080", "host/id", 1)
 	require.NoError(t, err)
 
 }
+
+func Test_reportURL(t *testing.T) {
+	testCases := []struct {
+		name string
+		conf runConfig
+		want string
+	}{
+		{name: "plain http", conf: runConfig{endpoint: "localhost:8080"}, want: "http://localhost:8080/update/"},
+		{name: "tls files", conf: runConfig{endpoint: "localhost:8080", tlsCA: "ca.pem"}, want: "https://localhost:8080/update/"},
+		{name: "client certificate", conf: runConfig{endpoint: "localhost:8080", tlsCert: "agent.crt"}, want: "https://localhost:8080/update/"},
+		{name: "scheme of endpoint", conf: runConfig{endpoint: "https://metrics.local"}, want: "https://metrics.local/update/"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, reportURL(tt.conf))
+		})
+	}
+}
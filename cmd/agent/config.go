@@ -13,12 +13,21 @@ import (
 )
 
 type runConfig struct {
-	report     time.Duration
-	poll       time.Duration
-	rateLimit  int
-	endpoint   string
-	hashSumKey string
-	cryptoKey  string
+	report      time.Duration
+	poll        time.Duration
+	rateLimit   int
+	endpoint    string
+	hashSumKey  string
+	cryptoKey   string
+	grpcAddress string
+	idFile      string
+	outboxDir   string
+	outboxSize  int
+	outboxAge   time.Duration
+	tlsCA       string
+	tlsCert     string
+	tlsKey      string
+	tokenFile   string
 }
 
 type jsonConfig struct {
@@ -26,6 +35,15 @@ type jsonConfig struct {
 	ReportInterval Duration `json:"report_interval"`
 	PollInterval   Duration `json:"poll_interval"`
 	CryptoKey      string   `json:"crypto_key"`
+	GRPCAddress    string   `json:"grpc_address"`
+	IDFile         string   `json:"id_file"`
+	OutboxDir      string   `json:"outbox_dir"`
+	OutboxSize     int      `json:"outbox_size"`
+	OutboxAge      Duration `json:"outbox_age"`
+	TLSCA          string   `json:"tls_ca"`
+	TLSCert        string   `json:"tls_cert"`
+	TLSKey         string   `json:"tls_key"`
+	TokenFile      string   `json:"token_file"`
 }
 
 // there are three sources of config:
@@ -46,9 +64,18 @@ func getConfig() runConfig {
 	endpoint := flag.String("a", "", "endpoint to send metrics")
 	reportSec := flag.Int("r", 0, "timeout(sec) for sending report to server")
 	pollSec := flag.Int("p", 0, "timeout(sec) for polling metrics")
-	hashSumKey := flag.String("k", "", "path to key for hash sum")
+	hashSumKey := flag.String("k", "", "path to key for hash sum or to json keyring, the newest valid key of keyring is used and its id is sent to server")
 	rateLimit := flag.Int("l", 0, "rate limit for sending metrics")
 	cryptoKey := flag.String("crypto-key", "", "path to public key")
+	grpcAddress := flag.String("g", "", "grpc endpoint to send metrics, if it is set metrics are sent by grpc instead of http")
+	idFile := flag.String("id-file", "", "path to file which keeps instance ID of agent, by default it is in user config directory")
+	outboxDir := flag.String("outbox-dir", "", "path to directory which keeps metrics which are not sent to server, by default it is in user config directory")
+	outboxSize := flag.Int("outbox-size", 0, "limit of size(MB) of metrics which are not sent to server, 10 MB by default")
+	outboxAge := flag.Int("outbox-age", 0, "limit of age(sec) of metrics which are not sent to server, older ones are dropped, 1 hour by default")
+	tlsCA := flag.String("tls-ca", "", "path to PEM certificate of authority which signs certificate of server, metrics are sent by HTTPS or by grpc over TLS if it is set")
+	tlsCert := flag.String("tls-cert", "", "path to PEM certificate of agent for servers which verify agents, metrics are sent by HTTPS or by grpc over TLS if it is set")
+	tlsKey := flag.String("tls-key", "", "path to PEM private key of agent")
+	tokenFile := flag.String("token-file", "", "path to file with API token with write scope for servers which require tokens")
 	
 	var configPath string
 	flag.StringVar(&configPath, "config", "", "path to json config file")
@@ -100,6 +127,60 @@ func getConfig() runConfig {
 		externalConfig.CryptoKey,
 	)
 
+	config.grpcAddress = cmp.Or(
+		os.Getenv("GRPC_ADDRESS"),
+		*grpcAddress,
+		externalConfig.GRPCAddress,
+	)
+
+	config.idFile = cmp.Or(
+		os.Getenv("ID_FILE"),
+		*idFile,
+		externalConfig.IDFile,
+	)
+
+	config.outboxDir = cmp.Or(
+		os.Getenv("OUTBOX_DIR"),
+		*outboxDir,
+		externalConfig.OutboxDir,
+	)
+
+	config.outboxSize = cmp.Or(
+		parseInt(os.Getenv("OUTBOX_SIZE"), 0),
+		*outboxSize,
+		externalConfig.OutboxSize,
+	)
+
+	config.outboxAge = cmp.Or(
+		parseDuration(os.Getenv("OUTBOX_AGE"), 0),
+		time.Duration(*outboxAge)*time.Second,
+		externalConfig.OutboxAge.Duration,
+	)
+
+	config.tlsCA = cmp.Or(
+		os.Getenv("TLS_CA"),
+		*tlsCA,
+		externalConfig.TLSCA,
+	)
+
+	config.tlsCert = cmp.Or(
+		os.Getenv("TLS_CERT"),
+		*tlsCert,
+		externalConfig.TLSCert,
+	)
+
+	config.tlsKey = cmp.Or(
+		os.Getenv("TLS_KEY"),
+		*tlsKey,
+		externalConfig.TLSKey,
+	)
+
+	config.tokenFile = cmp.Or(
+		os.Getenv("TOKEN_FILE"),
+		*tokenFile,
+		externalConfig.TokenFile,
+	)
+
 	return config
 }
 
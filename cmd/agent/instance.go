@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// instanceIDFileName is the name of file which keeps generated part of instance ID between restarts
+const instanceIDFileName = "agent.id"
+
+// outboxDirName is the name of directory which keeps metrics which are not sent to server
+const outboxDirName = "outbox"
+
+// defaultInstanceIDFile returns path to file with instance ID in user config directory
+// or in working directory if user config directory is not defined
+func defaultInstanceIDFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return instanceIDFileName
+	}
+	return filepath.Join(dir, "metrics", instanceIDFileName)
+}
+
+// defaultOutboxDir returns path to outbox in user config directory
+// or in working directory if user config directory is not defined
+func defaultOutboxDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return outboxDirName
+	}
+	return filepath.Join(dir, "metrics", outboxDirName)
+}
+
+// instanceID returns stable ID of agent in format <host name>/<uuid>.
+// UUID is read from file on path, if file does not exist UUID is generated and saved to the file.
+// If UUID can not be saved, the generated one is returned with error, so ID is changed after restart
+func instanceID(path string) (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	id, err := loadUUID(path)
+	if err == nil {
+		return host + "/" + id.String(), nil
+	}
+
+	id = uuid.New()
+	if !errors.Is(err, fs.ErrNotExist) {
+		return host + "/" + id.String(), err
+	}
+	return host + "/" + id.String(), saveUUID(path, id)
+}
+
+func loadUUID(path string) (uuid.UUID, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(strings.TrimSpace(string(content)))
+}
+
+func saveUUID(path string, id uuid.UUID) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(id.String()+"\n"), 0o644)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_instanceID(t *testing.T) {
+	host, err := os.Hostname()
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "metrics", instanceIDFileName)
+
+	first, err := instanceID(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(first, host+"/"))
+
+	_, err = uuid.Parse(strings.TrimPrefix(first, host+"/"))
+	require.NoError(t, err)
+
+	// id is the same after restart
+	second, err := instanceID(path)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+func Test_instanceID_brokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), instanceIDFileName)
+	require.NoError(t, os.WriteFile(path, []byte("not uuid"), 0o644))
+
+	id, err := instanceID(path)
+	require.Error(t, err)
+	assert.NotEmpty(t, id)
+
+	// broken file is not overwritten
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "not uuid", string(content))
+}
@@ -1,22 +1,27 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/vilasle/metrics/internal/encrypt"
+	"github.com/vilasle/metrics/internal/keyring"
 	"github.com/vilasle/metrics/internal/logger"
 	"github.com/vilasle/metrics/internal/service"
 	"github.com/vilasle/metrics/internal/service/agent/collector"
+	"github.com/vilasle/metrics/internal/service/agent/outbox"
+	"github.com/vilasle/metrics/internal/service/agent/sender/grpc"
 	"github.com/vilasle/metrics/internal/service/agent/sender/http"
+	"github.com/vilasle/metrics/internal/tlsutil"
 	"github.com/vilasle/metrics/internal/version"
 )
 
@@ -35,6 +40,10 @@ func main() {
 		logger.Fatal("can to register metric", "error", err)
 	}
 
+	if err := c.RegisterSummary("PauseNs"); err != nil {
+		logger.Fatalw("can not register summary", "error", err)
+	}
+
 	registerEvents(c, incrementPollCounter, collectExtraMetrics, collectRandomValue)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -42,23 +51,37 @@ func main() {
 	sigint := make(chan os.Signal, 1)
 	signal.Notify(sigint, os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM)
 
-	addr := fmt.Sprintf("http://%s/update/", conf.endpoint)
+	addr := reportURL(conf)
 
 	logger.Debug("starting agent",
 		"address", addr,
+		"grpcAddress", conf.grpcAddress,
 		"pollInterval", conf.poll/time.Second,
 		"reportInterval", conf.report/time.Second,
 	)
 
-	sender, err := createSender(conf.hashSumKey, conf.cryptoKey, addr, conf.rateLimit)
+	id, err := instanceID(cmp.Or(conf.idFile, defaultInstanceIDFile()))
+	if err != nil {
+		logger.Error("can not keep instance ID of agent, it will be changed after restart", "error", err)
+	}
+	logger.Info("instance ID of agent", "id", id)
+
+	wg := &sync.WaitGroup{}
+
+	sender, err := startSender(ctx, wg, conf, addr, id)
 	if err != nil {
 		logger.Fatal("can not create sender", "err", err)
 	}
 
-	agent := newCollectorAgent(c, sender, newDelay(conf.poll, conf.report))
+	opts := make([]agentOption, 0, 1)
+	if outbox, err := createOutbox(conf); err == nil {
+		opts = append(opts, withOutbox(outbox))
+	} else {
+		logger.Error("can not create outbox, metrics which are not sent will be lost", "error", err)
+	}
+
+	agent := newCollectorAgent(c, sender, newDelay(conf.poll, conf.report), opts...)
 
-	wg := &sync.WaitGroup{}
-	sender.Start(ctx, wg)
 	go agent.run(ctx, wg)
 
 	<-sigint
@@ -83,14 +106,111 @@ func defaultGaugeMetrics() []string {
 	}
 }
 
+func createOutbox(conf runConfig) (*outbox.Outbox, error) {
+	return outbox.NewOutbox(cmp.Or(conf.outboxDir, defaultOutboxDir()),
+		outbox.WithMaxSize(int64(conf.outboxSize)<<20),
+		outbox.WithMaxAge(conf.outboxAge),
+		// repeating does not help if server rejects metrics of batch
+		outbox.WithRejected(
+			http.ErrWrongMetricName, http.ErrWrongMetricTypeOrValue,
+			grpc.ErrWrongMetricName, grpc.ErrWrongMetricTypeOrValue,
+		),
+	)
+}
+
 func registerEvents(c *collector.RuntimeCollector, events ...func(service.Collector)) {
 	for _, event := range events {
 		c.RegisterEvent(event)
 	}
 }
 
-func createSender(hashPath, cryptoKeyPath, addr string, rateLimit int) (*http.HTTPSender, error) {
-	hashKey, err := getHashKeyFromFile(hashPath)
+// startSender creates grpc sender if grpc address is set, otherwise creates http sender and starts its workers
+func startSender(ctx context.Context, wg *sync.WaitGroup, conf runConfig, addr, id string) (service.Sender, error) {
+	var reloader *tlsutil.Reloader
+	if useTLS(conf) {
+		var err error
+		reloader, err = tlsutil.NewReloader(tlsutil.Files{
+			CertFile: conf.tlsCert,
+			KeyFile:  conf.tlsKey,
+			CAFile:   conf.tlsCA,
+		})
+		if err != nil {
+			return nil, errors.Join(err, errors.New("can not load tls files"))
+		}
+	}
+
+	if conf.grpcAddress != "" {
+		grpcOpts := make([]grpc.SenderOption, 0, 1)
+		if reloader != nil {
+			// grpc keeps config for all connections, so certificates are taken from reloader on each handshake
+			grpcOpts = append(grpcOpts, grpc.WithTLS(reloader.ClientConfig()))
+		}
+		return createGRPCSender(conf.hashSumKey, conf.cryptoKey, conf.tokenFile, conf.grpcAddress, id, grpcOpts...)
+	}
+
+	opts := make([]http.SenderOption, 0, 1)
+	if reloader != nil {
+		opts = append(opts, http.WithTLS(reloader.DialTLSContext))
+	}
+
+	sender, err := createSender(conf.hashSumKey, conf.cryptoKey, conf.tokenFile, addr, id, conf.rateLimit, opts...)
+	if err != nil {
+		return nil, err
+	}
+	sender.Start(ctx, wg)
+
+	return sender, nil
+}
+
+// useTLS reports whether metrics are sent by HTTPS
+func useTLS(conf runConfig) bool {
+	return conf.tlsCA != "" || conf.tlsCert != "" || strings.HasPrefix(conf.endpoint, "https://")
+}
+
+// reportURL returns address for sending of metrics, endpoint may contain scheme
+func reportURL(conf runConfig) string {
+	scheme, endpoint := "http", conf.endpoint
+	if useTLS(conf) {
+		scheme = "https"
+	}
+	if _, host, ok := strings.Cut(endpoint, "://"); ok {
+		endpoint = host
+	}
+	return fmt.Sprintf("%s://%s/update/", scheme, endpoint)
+}
+
+func createGRPCSender(hashPath, cryptoKeyPath, tokenPath, addr, id string, opts ...grpc.SenderOption) (*grpc.GRPCSender, error) {
+	hashKey, err := getSigningKey(hashPath)
+	if err != nil {
+		logger.Error("can not read key from file", "file", hashPath, "error", err)
+	}
+
+	publicKey, err := getPublicKeyFromFile(cryptoKeyPath)
+	if err != nil {
+		logger.Error("can not read public key from file", "file", cryptoKeyPath, "error", err)
+	}
+
+	token, err := getTokenFromFile(tokenPath)
+	if err != nil {
+		logger.Error("can not read token from file", "file", tokenPath, "error", err)
+	}
+
+	sender, err := grpc.NewGRPCSender(addr, append(opts,
+		grpc.WithToken(token),
+		grpc.WithCalculateHashSum([]byte(hashKey.Secret)),
+		grpc.WithHashKeyID(hashKey.ID),
+		grpc.WithEncryption(publicKey),
+		grpc.WithCompressing(),
+		grpc.WithInstanceID(id),
+	)...)
+	if err != nil {
+		return nil, errors.Join(err, errors.New("can not create grpc sender"))
+	}
+	return sender, nil
+}
+
+func createSender(hashPath, cryptoKeyPath, tokenPath, addr, id string, rateLimit int, opts ...http.SenderOption) (*http.HTTPSender, error) {
+	hashKey, err := getSigningKey(hashPath)
 	if err != nil {
 		logger.Error("can not read key from file", "file", hashPath, "error", err)
 	}
@@ -101,29 +221,49 @@ func createSender(hashPath, cryptoKeyPath, addr string, rateLimit int) (*http.HT
 	}
 
 	bodyWriter := http.NewJSONWriter(
-		http.WithCalculateHashSum(hashKey),
+		http.WithCalculateHashSum([]byte(hashKey.Secret)),
+		http.WithHashKeyID(hashKey.ID),
+		http.WithRequestStamp(),
 		http.WithEncryption(publicKey),
 		http.WithCompressing(),
 	)
 
-	maker, err := http.NewJSONRequestMaker(addr, bodyWriter)
+	token, err := getTokenFromFile(tokenPath)
+	if err != nil {
+		logger.Error("can not read token from file", "file", tokenPath, "error", err)
+	}
+
+	maker, err := http.NewJSONRequestMaker(addr, bodyWriter, http.WithInstanceID(id), http.WithToken(token))
 	if err != nil {
 		return nil, errors.Join(err, errors.New("can not create request maker"))
 	}
 
 	return http.NewHTTPSender(
 		maker,
-		http.WithRateLimit(rateLimit),
+		append(opts, http.WithRateLimit(rateLimit))...,
 	), nil
 
 }
 
-func getHashKeyFromFile(path string) ([]byte, error) {
-	if content, err := os.ReadFile(path); err == nil {
-		return content, err
-	} else {
-		return nil, err
+// getTokenFromFile returns API token from file, it returns empty token if path is empty
+func getTokenFromFile(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// getSigningKey returns key for hash sum from file, file may contain keyring, then the newest valid key is used
+func getSigningKey(path string) (keyring.Key, error) {
+	keys, err := keyring.Load(path)
+	if err != nil || keys == nil {
+		return keyring.Key{}, err
 	}
+	return keys.Signing()
 }
 
 func getPublicKeyFromFile(path string) (*rsa.PublicKey, error) {
@@ -136,6 +276,5 @@ func getPublicKeyFromFile(path string) (*rsa.PublicKey, error) {
 		return nil, err
 	}
 
-	publicBlock, _ := pem.Decode(content)
-	return x509.ParsePKCS1PublicKey(publicBlock.Bytes)
+	return encrypt.ParsePublicKey(content)
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	kindCA     = "ca"
+	kindServer = "server"
+	kindAgent  = "agent"
+)
+
+// defaultServerHosts are SANs of certificate of server if hosts are not set,
+// clients verify name of server by SANs, so certificate without them is not accepted by any client
+var defaultServerHosts = []string{"localhost", "127.0.0.1"}
+
+var errInvalidCertificate = errors.New("file does not contain certificate")
+
+// certRequest describes certificate which is created
+type certRequest struct {
+	kind       string
+	commonName string
+	// hosts are DNS names and IP addresses which are added to certificate as SANs
+	hosts []string
+	days  int
+}
+
+// template returns template of certificate by kind of request
+func (r certRequest) template() (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: r.commonName},
+		// clocks of hosts may be a bit behind
+		NotBefore: now.Add(-time.Hour),
+		NotAfter:  now.AddDate(0, 0, r.days),
+	}
+
+	hosts := r.hosts
+	if r.kind == kindServer && len(hosts) == 0 {
+		hosts = defaultServerHosts
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	switch r.kind {
+	case kindCA:
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.MaxPathLenZero = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+	case kindServer:
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	case kindAgent:
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	default:
+		return nil, errors.New("unknown kind of certificate " + r.kind)
+	}
+	return tmpl, nil
+}
+
+// createCertificate returns DER encoded certificate for key, certificate is signed by parent and its key,
+// certificate of authority is self-signed if parent is nil
+func createCertificate(r certRequest, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) ([]byte, error) {
+	tmpl, err := r.template()
+	if err != nil {
+		return nil, err
+	}
+
+	// rsa keys of TLS 1.2 key exchange are used for encryption
+	if _, ok := key.Public().(*rsa.PublicKey); ok && r.kind != kindCA {
+		tmpl.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	return x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+}
+
+// loadAuthority reads certificate of authority and its private key from PEM files
+func loadAuthority(certPath, keyPath string) (*x509.Certificate, crypto.Signer, error) {
+	content, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, nil, errors.Join(errInvalidCertificate, errors.New(certPath))
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !cert.IsCA {
+		return nil, nil, errors.Join(errInvalidCertificate, errors.New("certificate is not authority"))
+	}
+
+	content, err = os.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := parsePrivateKey(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// encodeCertificate returns PEM encoded certificate
+func encodeCertificate(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_createCertificate(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, algorithm := range []string{algorithmRSA, algorithmECDSA, algorithmEd25519} {
+		t.Run(algorithm, func(t *testing.T) {
+			caKey, err := generateKey(algorithm, 2048)
+			require.NoError(t, err)
+			caDer, err := createCertificate(certRequest{kind: kindCA, commonName: "ca", days: 1}, caKey, nil, nil)
+			require.NoError(t, err)
+
+			caKeyPEM, err := encodePrivateKey(caKey, true)
+			require.NoError(t, err)
+			certPath, keyPath := filepath.Join(dir, algorithm+".crt"), filepath.Join(dir, algorithm+".key")
+			require.NoError(t, os.WriteFile(certPath, encodeCertificate(caDer), 0600))
+			require.NoError(t, os.WriteFile(keyPath, caKeyPEM, 0600))
+
+			ca, signer, err := loadAuthority(certPath, keyPath)
+			require.NoError(t, err)
+
+			key, err := generateKey(algorithm, 2048)
+			require.NoError(t, err)
+			req := certRequest{kind: kindServer, commonName: "server", hosts: []string{"localhost", "127.0.0.1"}, days: 1}
+			der, err := createCertificate(req, key, ca, signer)
+			require.NoError(t, err)
+
+			cert, err := x509.ParseCertificate(der)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"localhost"}, cert.DNSNames)
+			require.Len(t, cert.IPAddresses, 1)
+			assert.Equal(t, "127.0.0.1", cert.IPAddresses[0].String())
+
+			roots := x509.NewCertPool()
+			roots.AddCert(ca)
+			_, err = cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "localhost", KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})
+			assert.NoError(t, err)
+
+			// certificate of server can not be used by agent
+			_, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_createCertificate_DefaultHosts(t *testing.T) {
+	caKey, err := generateKey(algorithmECDSA, 0)
+	require.NoError(t, err)
+	caDer, err := createCertificate(certRequest{kind: kindCA, commonName: "ca", days: 1}, caKey, nil, nil)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDer)
+	require.NoError(t, err)
+
+	key, err := generateKey(algorithmECDSA, 0)
+	require.NoError(t, err)
+
+	// certificate of server without hosts is valid for local host
+	der, err := createCertificate(certRequest{kind: kindServer, commonName: "server", days: 1}, key, ca, caKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	assert.NoError(t, cert.VerifyHostname("localhost"))
+	assert.NoError(t, cert.VerifyHostname("127.0.0.1"))
+	assert.Error(t, cert.VerifyHostname("metrics.example.com"))
+
+	// hosts which are set replace default ones
+	der, err = createCertificate(certRequest{kind: kindServer, commonName: "server", hosts: []string{"metrics.example.com"}, days: 1}, key, ca, caKey)
+	require.NoError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	assert.NoError(t, cert.VerifyHostname("metrics.example.com"))
+	assert.Error(t, cert.VerifyHostname("localhost"))
+
+	// certificate of agent is not bound to host
+	der, err = createCertificate(certRequest{kind: kindAgent, commonName: "agent", days: 1}, key, ca, caKey)
+	require.NoError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	assert.Empty(t, cert.DNSNames)
+	assert.Empty(t, cert.IPAddresses)
+}
+
+func Test_loadAuthority_NotCA(t *testing.T) {
+	dir := t.TempDir()
+
+	key, err := generateKey(algorithmECDSA, 0)
+	require.NoError(t, err)
+	der, err := createCertificate(certRequest{kind: kindAgent, commonName: "agent", days: 1}, key, nil, nil)
+	require.NoError(t, err)
+	keyPEM, err := encodePrivateKey(key, false)
+	require.NoError(t, err)
+
+	certPath, keyPath := filepath.Join(dir, "agent.crt"), filepath.Join(dir, "agent.key")
+	require.NoError(t, os.WriteFile(certPath, encodeCertificate(der), 0600))
+	require.NoError(t, os.WriteFile(keyPath, keyPEM, 0600))
+
+	_, _, err = loadAuthority(certPath, keyPath)
+	assert.ErrorIs(t, err, errInvalidCertificate)
+}
+
+func Test_fingerprints(t *testing.T) {
+	key, err := generateKey(algorithmRSA, 2048)
+	require.NoError(t, err)
+	der, err := createCertificate(certRequest{kind: kindCA, commonName: "ca", days: 1}, key, nil, nil)
+	require.NoError(t, err)
+
+	privatePEM, err := encodePrivateKey(key, false)
+	require.NoError(t, err)
+	publicPEM, err := encodePublicKey(key.Public(), false)
+	require.NoError(t, err)
+	pkixPEM, err := encodePublicKey(key.Public(), true)
+	require.NoError(t, err)
+
+	certPrints, err := fingerprints(encodeCertificate(der))
+	require.NoError(t, err)
+	require.Len(t, certPrints, 2)
+	assert.True(t, strings.HasPrefix(certPrints[0], "certificate ca SHA256="))
+
+	// all representations of the key have the same fingerprint
+	for _, content := range [][]byte{privatePEM, publicPEM, pkixPEM} {
+		prints, err := fingerprints(content)
+		require.NoError(t, err)
+		assert.Equal(t, []string{certPrints[1]}, prints)
+	}
+
+	_, err = fingerprints(pem.EncodeToMemory(&pem.Block{Type: "UNKNOWN", Bytes: []byte{1}}))
+	assert.Error(t, err)
+	_, err = fingerprints([]byte("not pem"))
+	assert.Error(t, err)
+}
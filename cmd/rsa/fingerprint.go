@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// fingerprint returns SHA-256 of content as colon separated hex like openssl prints it
+func fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = strings.ToUpper(hex.EncodeToString([]byte{b}))
+	}
+	return strings.Join(parts, ":")
+}
+
+// fingerprints returns fingerprints of PEM blocks of content.
+// Fingerprint of certificate is taken from the whole certificate, fingerprint of key from its public part in PKIX format,
+// so private key, public key and certificate which share the key can be matched by the key fingerprint
+func fingerprints(content []byte) ([]string, error) {
+	rs := make([]string, 0, 1)
+	for {
+		var block *pem.Block
+		if block, content = pem.Decode(content); block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			rs = append(rs,
+				fmt.Sprintf("certificate %s SHA256=%s", cert.Subject.CommonName, fingerprint(block.Bytes)),
+				fmt.Sprintf("key SHA256=%s", fingerprint(cert.RawSubjectPublicKeyInfo)))
+		case "RSA PUBLIC KEY":
+			key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			der, err := x509.MarshalPKIXPublicKey(key)
+			if err != nil {
+				return nil, err
+			}
+			rs = append(rs, fmt.Sprintf("key SHA256=%s", fingerprint(der)))
+		case "PUBLIC KEY":
+			rs = append(rs, fmt.Sprintf("key SHA256=%s", fingerprint(block.Bytes)))
+		default:
+			key, err := parsePrivateKey(pem.EncodeToMemory(block))
+			if err != nil {
+				return nil, err
+			}
+			der, err := x509.MarshalPKIXPublicKey(key.Public())
+			if err != nil {
+				return nil, err
+			}
+			rs = append(rs, fmt.Sprintf("key SHA256=%s", fingerprint(der)))
+		}
+	}
+	if len(rs) == 0 {
+		return nil, errInvalidKey
+	}
+	return rs, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+const (
+	algorithmRSA     = "rsa"
+	algorithmECDSA   = "ecdsa"
+	algorithmEd25519 = "ed25519"
+)
+
+var errUnknownAlgorithm = errors.New("unknown algorithm of key")
+var errInvalidKey = errors.New("file does not contain private key")
+
+// generateKey returns new private key of algorithm, bits are used only for rsa keys
+func generateKey(algorithm string, bits int) (crypto.Signer, error) {
+	switch algorithm {
+	case algorithmRSA:
+		return rsa.GenerateKey(rand.Reader, bits)
+	case algorithmECDSA:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case algorithmEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, errors.Join(errUnknownAlgorithm, errors.New(algorithm))
+	}
+}
+
+// encodePrivateKey returns PEM encoded private key.
+// RSA key is encoded by PKCS#1 unless pkcs8 is set, keys of other algorithms are always encoded by PKCS#8
+func encodePrivateKey(key crypto.Signer, pkcs8 bool) ([]byte, error) {
+	if k, ok := key.(*rsa.PrivateKey); ok && !pkcs8 {
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}), nil
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// encodePublicKey returns PEM encoded public key.
+// RSA key is encoded by PKCS#1 unless pkcs8 is set, keys of other algorithms are always encoded by PKIX
+func encodePublicKey(key crypto.PublicKey, pkcs8 bool) ([]byte, error) {
+	if k, ok := key.(*rsa.PublicKey); ok && !pkcs8 {
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(k)}), nil
+	}
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// parsePrivateKey parses PEM encoded private key in PKCS#1, PKCS#8 or SEC 1 formats
+func parsePrivateKey(content []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errInvalidKey
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, fmt.Errorf("%w: unsupported key %T", errInvalidKey, key)
+	default:
+		return nil, fmt.Errorf("%w: unexpected block %s", errInvalidKey, block.Type)
+	}
+}
@@ -1,22 +1,33 @@
 package main
 
 import (
-	"bytes"
-	"crypto/rand"
-	"crypto/rsa"
+	"crypto"
 	"crypto/x509"
-	"encoding/pem"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/spf13/pflag"
 )
 
+const (
+	modeKey         = "key"
+	modeFingerprint = "fingerprint"
+)
+
 type cliArgs struct {
-	name   string
-	length int32
-	help   bool
+	name      string
+	length    int32
+	help      bool
+	mode      string
+	algorithm string
+	pkcs8     bool
+	cn        string
+	hosts     []string
+	days      int
+	caCert    string
+	caKey     string
 }
 
 func parseCliArgs() cliArgs {
@@ -25,6 +36,16 @@ func parseCliArgs() cliArgs {
 	pflag.Int32VarP(&cli.length, "length", "l", 4096, "Length of the key")
 	pflag.StringVarP(&cli.name, "name", "n", "metric", "Name of keys")
 	pflag.BoolVarP(&cli.help, "help", "h", false, "Show help")
+	pflag.StringVarP(&cli.mode, "mode", "m", modeKey,
+		"What to create: key - key pair, ca - self-signed certificate of authority, server or agent - certificate signed by authority, "+
+			"fingerprint - print fingerprints of PEM files from arguments")
+	pflag.StringVarP(&cli.algorithm, "algorithm", "a", algorithmRSA, "Algorithm of key: rsa, ecdsa or ed25519")
+	pflag.BoolVar(&cli.pkcs8, "pkcs8", false, "Write rsa keys in PKCS#8 and PKIX formats instead of PKCS#1, keys of other algorithms are always in PKCS#8")
+	pflag.StringVar(&cli.cn, "cn", "", "Common name of certificate, name of keys by default")
+	pflag.StringSliceVar(&cli.hosts, "hosts", nil, "Comma separated DNS names and IP addresses which certificate is valid for, certificate of server is valid for localhost by default")
+	pflag.IntVar(&cli.days, "days", 365, "Validity of certificate in days")
+	pflag.StringVar(&cli.caCert, "ca-cert", "ca.crt", "Certificate of authority which signs server and agent certificates")
+	pflag.StringVar(&cli.caKey, "ca-key", "ca.key", "Private key of authority which signs server and agent certificates")
 	pflag.Parse()
 
 	return *cli
@@ -39,30 +60,113 @@ func main() {
 		return
 	}
 
-	privateKey, err := rsa.GenerateKey(rand.Reader, int(cli.length))
+	switch cli.mode {
+	case modeKey:
+		writeKeyPair(cli)
+	case kindCA, kindServer, kindAgent:
+		writeCertificate(cli)
+	case modeFingerprint:
+		printFingerprints(pflag.Args())
+	default:
+		log.Fatalf("unknown mode '%s'", cli.mode)
+	}
+}
+
+// writeKeyPair writes private key to file name and public key to file name.pub
+func writeKeyPair(cli cliArgs) {
+	key, err := generateKey(cli.algorithm, int(cli.length))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	var privateKeyPEM bytes.Buffer
-	pem.Encode(&privateKeyPEM, &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	})
+	privateKeyPEM, err := encodePrivateKey(key, cli.pkcs8)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	var publicKeyPEM bytes.Buffer
-	pem.Encode(&publicKeyPEM, &pem.Block{
-		Type:  "RSA PUBLIC KEY",
-		Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey),
-	})
+	publicKeyPEM, err := encodePublicKey(key.Public(), cli.pkcs8)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	privatePath, publicPath := cli.name, fmt.Sprintf("%s.pub", cli.name)
 
-	if err := os.WriteFile(privatePath, privateKeyPEM.Bytes(), 0644); err != nil {
+	if err := os.WriteFile(privatePath, privateKeyPEM, 0600); err != nil {
 		log.Fatalf("can not write private key to '%s' by reason %v", privatePath, err)
 	}
 
-	if err := os.WriteFile(publicPath, publicKeyPEM.Bytes(), 0644); err != nil {
+	if err := os.WriteFile(publicPath, publicKeyPEM, 0644); err != nil {
 		log.Fatalf("can not write public key to '%s' by reason %v", publicPath, err)
 	}
-}
\ No newline at end of file
+
+	printFingerprints([]string{publicPath})
+}
+
+// writeCertificate writes certificate to file name.crt and its private key to file name.key.
+// Certificate of authority is self-signed, other certificates are signed by authority from ca-cert and ca-key
+func writeCertificate(cli cliArgs) {
+	var (
+		parent    *x509.Certificate
+		parentKey crypto.Signer
+		err       error
+	)
+	if cli.mode != kindCA {
+		if parent, parentKey, err = loadAuthority(cli.caCert, cli.caKey); err != nil {
+			log.Fatalf("can not load certificate of authority by reason %v", err)
+		}
+	}
+
+	key, err := generateKey(cli.algorithm, int(cli.length))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cn := cli.cn
+	if cn == "" {
+		cn = cli.name
+	}
+
+	if cli.mode == kindServer && len(cli.hosts) == 0 {
+		log.Printf("warning: hosts are not set, certificate of server is valid only for %s", strings.Join(defaultServerHosts, ", "))
+	}
+
+	der, err := createCertificate(certRequest{kind: cli.mode, commonName: cn, hosts: cli.hosts, days: cli.days}, key, parent, parentKey)
+	if err != nil {
+		log.Fatalf("can not create certificate by reason %v", err)
+	}
+
+	privateKeyPEM, err := encodePrivateKey(key, cli.pkcs8)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	certPath, keyPath := fmt.Sprintf("%s.crt", cli.name), fmt.Sprintf("%s.key", cli.name)
+
+	if err := os.WriteFile(keyPath, privateKeyPEM, 0600); err != nil {
+		log.Fatalf("can not write private key to '%s' by reason %v", keyPath, err)
+	}
+
+	if err := os.WriteFile(certPath, encodeCertificate(der), 0644); err != nil {
+		log.Fatalf("can not write certificate to '%s' by reason %v", certPath, err)
+	}
+
+	printFingerprints([]string{certPath})
+}
+
+// printFingerprints prints SHA-256 fingerprints of certificates and keys from PEM files
+func printFingerprints(paths []string) {
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("can not read '%s' by reason %v", path, err)
+		}
+
+		prints, err := fingerprints(content)
+		if err != nil {
+			log.Fatalf("can not get fingerprint of '%s' by reason %v", path, err)
+		}
+		for _, p := range prints {
+			fmt.Printf("%s: %s\n", path, p)
+		}
+	}
+}
@@ -2,20 +2,28 @@ package main
 
 import (
 	"context"
-	"crypto/rsa"
-	"crypto/x509"
+	"crypto/tls"
 	"database/sql"
-	"encoding/pem"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/vilasle/metrics/internal/alert"
+	"github.com/vilasle/metrics/internal/auth"
+	"github.com/vilasle/metrics/internal/encrypt"
+	"github.com/vilasle/metrics/internal/keyring"
 	"github.com/vilasle/metrics/internal/logger"
+	"github.com/vilasle/metrics/internal/netutil"
+	"github.com/vilasle/metrics/internal/notify"
 	"github.com/vilasle/metrics/internal/repository"
 	"github.com/vilasle/metrics/internal/service"
+	"github.com/vilasle/metrics/internal/service/alerting"
 	srvSvc "github.com/vilasle/metrics/internal/service/server"
+	"github.com/vilasle/metrics/internal/tlsutil"
 	"github.com/vilasle/metrics/internal/version"
 
 	"github.com/vilasle/metrics/internal/repository/memory"
@@ -23,8 +31,13 @@ import (
 	"github.com/vilasle/metrics/internal/repository/postgresql"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/vilasle/metrics/internal/transport/grpc/interceptor"
+	"github.com/vilasle/metrics/internal/transport/grpc/pb"
+	grpcsrv "github.com/vilasle/metrics/internal/transport/grpc/server"
 	mdw "github.com/vilasle/metrics/internal/transport/rest/middleware"
 	rest "github.com/vilasle/metrics/internal/transport/rest/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var buildVersion, buildDate, buildCommit string
@@ -44,7 +57,23 @@ func main() {
 
 	conf := getConfig()
 
-	server, cancelDumper := createAndPreparingServer(conf)
+	svc, storage, cancelDumper := createRepositoryService(conf)
+
+	alerts, notifier := createAlertEngine(conf, svc, storage)
+
+	keys := getKeySet(conf.privateKeyPath)
+	reloadKeysOnSignal(keys)
+
+	tokens := getTokens(conf, storage)
+
+	// nonces are shared by http and grpc, so signed request can not be replayed by another protocol
+	replays := createReplayGuard(conf)
+
+	server := createAndPreparingServer(conf, svc, alerts, keys, tokens, replays)
+
+	adminServer := createAdminServer(conf, keys, getAdminTokens(conf, storage))
+
+	grpcServer := createGRPCServer(conf, svc, keys, tokens, replays)
 
 	stop := subscribeToStopSignals()
 	defer close(stop)
@@ -58,10 +87,45 @@ func main() {
 		stop <- os.Interrupt
 	}()
 
+	if adminServer != nil {
+		go func() {
+			// failed admin server does not stop the whole server, it is stopped after main server
+			if err := adminServer.Start(); err != nil {
+				logger.Errorw("admin server starting got error, profiler and admin handlers are not available", "error", err)
+			}
+		}()
+	}
+
+	if grpcServer != nil {
+		go func() {
+			if err := grpcServer.Start(); err != nil {
+				logger.Errorw("grpc server starting got error", "error", err)
+			}
+			stop <- os.Interrupt
+		}()
+	}
+
+	alertsCtx, stopAlerts := context.WithCancel(context.Background())
+	alertsStopped := make(chan struct{})
+	go func() {
+		defer close(alertsStopped)
+		alerts.Run(alertsCtx)
+	}()
+
 	<-stop
 
 	logger.Debug("got signal")
 
+	// evaluation in progress may notify, so notifier is closed after it
+	stopAlerts()
+	<-alertsStopped
+
+	if notifier != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		notifier.Close(ctx)
+		cancel()
+	}
+
 	if !server.IsRunning() {
 		logger.Fatal("server stopped unexpected")
 	}
@@ -72,6 +136,22 @@ func main() {
 
 	<-connectionClosed
 
+	if adminServer != nil {
+		adminClosed := make(chan struct{})
+
+		shutdown(adminServer, adminClosed)
+
+		<-adminClosed
+	}
+
+	if grpcServer != nil {
+		grpcClosed := make(chan struct{})
+
+		shutdownGRPC(grpcServer, grpcClosed)
+
+		<-grpcClosed
+	}
+
 	cancelDumper()
 
 	time.Sleep(time.Second * 2)
@@ -93,13 +173,29 @@ func shutdown(srv *rest.HTTPServer, closed chan struct{}) error {
 	return srv.ForceStop()
 }
 
+func shutdownGRPC(srv *grpcsrv.GRPCServer, closed chan struct{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+
+	defer close(closed)
+	defer cancel()
+
+	err := srv.Stop(ctx)
+
+	if err == nil {
+		return nil
+	}
+
+	logger.Error("grpc server stopping gracefully failed", "error", err)
+	return srv.ForceStop()
+}
+
 func subscribeToStopSignals() chan os.Signal {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM)
 	return stop
 }
 
-func createRepositoryService(config runConfig) (service.MetricService, context.CancelFunc) {
+func createRepositoryService(config runConfig) (service.MetricService, repository.MetricRepository, context.CancelFunc) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	storage, err := getStorage(ctx, config)
@@ -108,7 +204,55 @@ func createRepositoryService(config runConfig) (service.MetricService, context.C
 		os.Exit(1)
 	}
 
-	return srvSvc.NewMetricService(storage), cancel
+	svc := srvSvc.NewMetricService(storage,
+		srvSvc.WithIdempotencyWindow(time.Second*time.Duration(config.idempotencyWindow)),
+		srvSvc.WithMaxSampleAge(time.Second*time.Duration(config.maxSampleAge)))
+
+	return svc, storage, cancel
+}
+
+func createAlertEngine(config runConfig, svc service.MetricService, storage repository.MetricRepository) (*alerting.Engine, *notify.Dispatcher) {
+	var rules []alert.Rule
+	var routes []notify.Route
+	if config.rulesFile != "" {
+		var err error
+		if rules, err = alert.LoadRules(config.rulesFile); err != nil {
+			logger.Errorw("can not load alerting rules", "path", config.rulesFile, "error", err)
+			os.Exit(1)
+		}
+
+		var hashKey []byte
+		if keys := getHashKeyring(config.hashSumKey); keys != nil {
+			if key, err := keys.Signing(); err == nil {
+				hashKey = []byte(key.Secret)
+			} else {
+				logger.Error("can not get hash key for signing of notifications", "error", err)
+			}
+		}
+		if routes, err = notify.LoadRoutes(config.rulesFile, hashKey); err != nil {
+			logger.Errorw("can not load receivers of notifications", "path", config.rulesFile, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	store, ok := storage.(repository.AlertRepository)
+	if !ok {
+		logger.Warn("storage does not keep alerts, state of alerts will be lost after restart")
+	}
+
+	opts := []alerting.Option{alerting.WithInterval(time.Second * time.Duration(config.rulesInterval))}
+
+	var dispatcher *notify.Dispatcher
+	if len(routes) > 0 {
+		dispatcher = notify.NewDispatcher(routes)
+		opts = append(opts, alerting.WithNotifier(dispatcher))
+	}
+
+	engine := alerting.NewEngine(svc, store, rules, opts...)
+	if err := engine.Restore(context.Background()); err != nil {
+		logger.Errorw("can not restore state of alerts", "error", err)
+	}
+	return engine, dispatcher
 }
 
 func getStorage(ctx context.Context, config runConfig) (repository.MetricRepository, error) {
@@ -136,65 +280,241 @@ func postgresStorage(config runConfig) (repository.MetricRepository, error) {
 	if err != nil {
 		return nil, err
 	}
-	return postgresql.NewRepository(db)
-}
-
-func createAndPreparingServer(config runConfig) (*rest.HTTPServer, context.CancelFunc) {
-	hashKey, err := getHashKeyFromFile(config.hashSumKey)
-	if err != nil {
-		logger.Error("can not get hash key from file", "error", err)
+	opts := make([]postgresql.Option, 0, 1)
+	if config.gaugeHistory {
+		opts = append(opts, postgresql.WithGaugeHistory(time.Second*time.Duration(config.historyRetain)))
 	}
 
-	key, err := getPrivateKeyFromFile(config.privateKeyPath)
-	if err != nil {
-		logger.Error("can not get private key from file", "error", err)
-	}
+	return postgresql.NewRepository(db, opts...)
+}
+
+func createAndPreparingServer(config runConfig, svc service.MetricService, alerts service.AlertService, keys *encrypt.KeySet, tokens mdw.TokenGetter, replays *mdw.ReplayGuard) *rest.HTTPServer {
+	hashKeys := getHashKeyring(config.hashSumKey)
 
 	contentUnpackers := mdw.NewUnpackerChain(
-		mdw.CheckHashSum(hashKey),
-		mdw.DecryptContent(key, "update", "updates"),
+		mdw.CheckHashSumKeyring(hashKeys),
+		mdw.RejectReplays(replays),
+		mdw.DecryptContentKeySet(keys, "update", "updates"),
 		mdw.DecompressContent("gzip"),
 	)
 
-	middlewares := make([]func(http.Handler) http.Handler, 0, 4)
+	middlewares := make([]func(http.Handler) http.Handler, 0, 5)
 	middlewares = append(middlewares,
 		mdw.WithLogger(),
+		mdw.Authenticate(tokens, scopeRules()),
+		mdw.TrustedSubnet(getTrustedSubnets(config.trustedSubnet), "update", "updates", "api/v1/write"),
 		mdw.Compress("application/json", "text/html"),
 		mdw.WithUnpackBody(contentUnpackers),
 	)
 
 	server := rest.NewHTTPServer(config.address, middlewares...)
 
-	svc, cancel := createRepositoryService(config)
+	if config.tlsCert != "" {
+		tlsConfig, err := createTLSConfig(config)
+		if err != nil {
+			logger.Fatalw("can not load tls files", "error", err)
+		}
+		server.UseTLS(tlsConfig)
+	}
 
-	registerHandlers(server, svc)
-	return server, cancel
+	registerHandlers(server, svc, alerts)
+	return server
 }
 
-func registerHandlers(srv *rest.HTTPServer, svc service.MetricService) {
+// createAdminServer returns server for profiler and admin handlers, it is nil if admin address is not set
+func createAdminServer(config runConfig, keys *encrypt.KeySet, tokens mdw.TokenGetter) *rest.HTTPServer {
+	if config.adminAddress == "" {
+		return nil
+	}
+
+	server := rest.NewHTTPServer(config.adminAddress,
+		mdw.WithLogger(),
+		mdw.Authenticate(tokens, mdw.ScopeRules{"/": auth.ScopeAdmin}),
+	)
+
+	if config.tlsCert != "" && !isUnixSocket(config.adminAddress) {
+		tlsConfig, err := createTLSConfig(config)
+		if err != nil {
+			logger.Fatalw("can not load tls files", "error", err)
+		}
+		server.UseTLS(tlsConfig)
+	}
+
+	server.MountProfiler()
+	if keys != nil {
+		server.Register("/admin/keys/reload", rest.ReloadKeys(keys), http.MethodPost)
+	}
+	return server
+}
+
+func createReplayGuard(config runConfig) *mdw.ReplayGuard {
+	opts := []mdw.ReplayOption{mdw.WithClockSkew(time.Second * time.Duration(config.replayWindow))}
+	if config.requireStamp {
+		opts = append(opts, mdw.WithRequiredStamp())
+	}
+	return mdw.NewReplayGuard(opts...)
+}
+
+// getTokens returns storage of API tokens if authentication is required
+func getTokens(config runConfig, storage repository.MetricRepository) mdw.TokenGetter {
+	if !config.auth {
+		return nil
+	}
+	tokens, ok := storage.(repository.TokenRepository)
+	if !ok {
+		logger.Fatal("storage does not keep API tokens")
+	}
+	return tokens
+}
+
+// getAdminTokens returns storage of API tokens for admin server.
+// Admin server on tcp address always requires tokens, access to unix socket is limited by its permissions
+func getAdminTokens(config runConfig, storage repository.MetricRepository) mdw.TokenGetter {
+	if config.adminAddress == "" || (isUnixSocket(config.adminAddress) && !config.auth) {
+		return nil
+	}
+	config.auth = true
+	return getTokens(config, storage)
+}
+
+func isUnixSocket(addr string) bool {
+	return strings.HasPrefix(addr, rest.UnixSocketPrefix)
+}
+
+// scopeRules returns scopes which are required for paths, the longest matched path wins
+func scopeRules() mdw.ScopeRules {
+	return mdw.ScopeRules{
+		"/":             auth.ScopeRead,
+		"/ping":         "",
+		"/update":       auth.ScopeWrite,
+		"/updates":      auth.ScopeWrite,
+		"/api/v1/write": auth.ScopeWrite,
+	}
+}
+
+// methodScopes returns scopes which are required for grpc methods, they are the same as for REST API
+func methodScopes() interceptor.MethodScopes {
+	return interceptor.MethodScopes{
+		pb.Metrics_UpdateMetric_FullMethodName:  auth.ScopeWrite,
+		pb.Metrics_UpdateMetrics_FullMethodName: auth.ScopeWrite,
+		pb.Metrics_GetMetric_FullMethodName:     auth.ScopeRead,
+		pb.Metrics_ListMetrics_FullMethodName:   auth.ScopeRead,
+	}
+}
+
+// createTLSConfig returns config which reloads certificates when files are changed,
+// protos are application protocols which are negotiated on handshake
+func createTLSConfig(config runConfig, protos ...string) (*tls.Config, error) {
+	reloader, err := tlsutil.NewReloader(tlsutil.Files{
+		CertFile: config.tlsCert,
+		KeyFile:  config.tlsKey,
+		CAFile:   config.tlsClientCA,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reloader.ServerConfig(protos...)
+}
+
+func createGRPCServer(config runConfig, svc service.MetricService, keys *encrypt.KeySet, tokens mdw.TokenGetter, replays *mdw.ReplayGuard) *grpcsrv.GRPCServer {
+	if config.grpcAddress == "" {
+		return nil
+	}
+
+	hashKeys := getHashKeyring(config.hashSumKey)
+
+	subnets := getTrustedSubnets(config.trustedSubnet)
+
+	opts := make([]grpc.ServerOption, 0, 3)
+	if config.tlsCert != "" {
+		// grpc requires h2 protocol over TLS
+		tlsConfig, err := createTLSConfig(config, "h2")
+		if err != nil {
+			logger.Fatalw("can not load tls files", "error", err)
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(
+			interceptor.AuthenticateUnary(tokens, methodScopes()),
+			interceptor.TrustedSubnetUnary(subnets, pb.Metrics_UpdateMetric_FullMethodName),
+			interceptor.CheckHashSumKeyringUnary(hashKeys, replays),
+			interceptor.DecryptContentKeySetUnary(keys),
+		),
+		grpc.ChainStreamInterceptor(
+			interceptor.AuthenticateStream(tokens, methodScopes()),
+			interceptor.TrustedSubnetStream(subnets, pb.Metrics_UpdateMetrics_FullMethodName),
+			interceptor.CheckHashSumKeyringStream(hashKeys, replays),
+			interceptor.DecryptContentKeySetStream(keys),
+		),
+	)
+
+	return grpcsrv.NewGRPCServer(config.grpcAddress, svc, opts...)
+}
+
+func registerHandlers(srv *rest.HTTPServer, svc service.MetricService, alerts service.AlertService) {
 	srv.Register("/", rest.DisplayAllMetrics(svc), http.MethodGet)
 	srv.Register("/ping", rest.Ping(svc), http.MethodGet)
+	srv.Register("/metrics", rest.ExposeMetrics(svc), http.MethodGet)
 	srv.Register("/value/", rest.DisplayMetric(svc), http.MethodPost)
 	srv.Register("/update/", rest.UpdateMetric(svc), http.MethodPost)
 	srv.Register("/updates/", rest.BatchUpdate(svc), http.MethodPost)
+	srv.Register("/api/v1/write", rest.RemoteWrite(svc), http.MethodPost)
 	srv.Register("/value/{type}/{name}", rest.DisplayMetric(svc), http.MethodGet)
+	srv.Register("/history/{type}/{name}", rest.DisplayHistory(svc), http.MethodGet)
+	srv.Register("/source/{type}/{name}", rest.DisplaySource(svc), http.MethodGet)
+	srv.Register("/agents", rest.DisplayAgents(svc), http.MethodGet)
+	srv.Register("/alerts", rest.DisplayAlerts(alerts), http.MethodGet)
 	srv.Register("/update/{type}/{name}/{value}", rest.UpdateMetric(svc), http.MethodPost)
 }
 
-func getHashKeyFromFile(path string) ([]byte, error) {
-	return os.ReadFile(path)
+func getTrustedSubnets(cidrs string) []*net.IPNet {
+	subnets, err := netutil.ParseSubnets(cidrs)
+	if err != nil {
+		logger.Fatalw("can not parse trusted subnets", "subnets", cidrs, "error", err)
+	}
+	return subnets
+}
+
+// getHashKeyring returns keyring for checking hash sums, file may contain the only raw key or json keyring
+func getHashKeyring(path string) *keyring.Keyring {
+	keys, err := keyring.Load(path)
+	if err != nil {
+		logger.Error("can not get hash key from file", "error", err)
+	}
+	return keys
 }
 
-func getPrivateKeyFromFile(path string) (*rsa.PrivateKey, error) {
+// getKeySet returns private keys for decryption from file or directory, it returns nil if path is empty
+func getKeySet(path string) *encrypt.KeySet {
 	if path == "" {
-		return nil, nil
+		return nil
 	}
 
-	content, err := os.ReadFile(path)
+	keys, err := encrypt.LoadKeySet(path)
 	if err != nil {
-		return nil, err
+		logger.Error("can not get private keys", "path", path, "error", err)
+		return nil
+	}
+	return keys
+}
+
+// reloadKeysOnSignal reloads private keys when server gets SIGHUP
+func reloadKeysOnSignal(keys *encrypt.KeySet) {
+	if keys == nil {
+		return
 	}
 
-	privateBlock, _ := pem.Decode(content)
-	return x509.ParsePKCS1PrivateKey(privateBlock.Bytes)
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := keys.Reload(); err != nil {
+				logger.Errorw("can not reload private keys", "error", err)
+				continue
+			}
+			logger.Infow("private keys are reloaded", "keys", keys.IDs())
+		}
+	}()
 }
@@ -0,0 +1,179 @@
+// Command token issues, revokes and lists API tokens of server.
+//
+//	token issue --name dashboard --scopes read
+//	token revoke 3f2a9c0d1e4b5a6f
+//	token list
+//
+// Tokens are kept in the same storage as metrics: database from --dsn (DATABASE_DSN)
+// or file from --file (FILE_STORAGE_PATH). Server with file storage reads tokens on start
+// and overwrites the file by dumps, so tokens of file are changed only while server is stopped:
+// command refuses to work while lock file <file>.lock of running server exists
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/spf13/pflag"
+	"github.com/vilasle/metrics/internal/auth"
+	"github.com/vilasle/metrics/internal/repository"
+	"github.com/vilasle/metrics/internal/repository/memory"
+	"github.com/vilasle/metrics/internal/repository/memory/dumper"
+	"github.com/vilasle/metrics/internal/repository/postgresql"
+)
+
+const (
+	cmdIssue  = "issue"
+	cmdRevoke = "revoke"
+	cmdList   = "list"
+)
+
+type cliArgs struct {
+	command string
+	dsn     string
+	file    string
+	name    string
+	scopes  string
+	args    []string
+}
+
+func parseCliArgs() cliArgs {
+	cli := cliArgs{}
+	if len(os.Args) > 1 {
+		cli.command = os.Args[1]
+	}
+
+	flags := pflag.NewFlagSet(cli.command, pflag.ExitOnError)
+	flags.StringVarP(&cli.dsn, "dsn", "d", "", "database dsn of server, DATABASE_DSN by default")
+	flags.StringVarP(&cli.file, "file", "f", "", "file storage of server if it does not use database, FILE_STORAGE_PATH or a.metrics by default")
+	flags.StringVar(&cli.name, "name", "", "name of owner of token, e.g. name of dashboard")
+	flags.StringVar(&cli.scopes, "scopes", string(auth.ScopeRead), "comma separated scopes of token: read, write, admin")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s issue|revoke <id>|list [flags]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if cli.command == "" || strings.HasPrefix(cli.command, "-") {
+		flags.Usage()
+		os.Exit(2)
+	}
+	flags.Parse(os.Args[2:])
+
+	cli.dsn = cmp.Or(cli.dsn, os.Getenv("DATABASE_DSN"))
+	cli.file = cmp.Or(cli.file, os.Getenv("FILE_STORAGE_PATH"), "a.metrics")
+	cli.args = flags.Args()
+	return cli
+}
+
+func main() {
+	if err := run(parseCliArgs()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run executes command, storage is closed before returning, so lock of file storage does not stay after failures
+func run(cli cliArgs) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	tokens, closeStorage, err := openStorage(ctx, cli.dsn, cli.file)
+	if err != nil {
+		return fmt.Errorf("can not open storage by reason %w", err)
+	}
+	defer closeStorage()
+
+	switch cli.command {
+	case cmdIssue:
+		return issue(ctx, tokens, cli.name, cli.scopes)
+	case cmdRevoke:
+		if len(cli.args) != 1 {
+			return errors.New("id of token is required")
+		}
+		return tokens.DeleteToken(ctx, cli.args[0])
+	case cmdList:
+		return list(ctx, tokens)
+	default:
+		return fmt.Errorf("unknown command '%s'", cli.command)
+	}
+}
+
+// issue saves new token and prints its value, value is not kept anywhere, so it is shown once
+func issue(ctx context.Context, tokens repository.TokenRepository, name, rawScopes string) error {
+	scopes, err := auth.ParseScopes(rawScopes)
+	if err != nil {
+		return err
+	}
+
+	t, value, err := auth.Issue(name, scopes...)
+	if err != nil {
+		return err
+	}
+
+	if err := tokens.SaveToken(ctx, t); err != nil {
+		return err
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func list(ctx context.Context, tokens repository.TokenRepository) error {
+	all, err := tokens.Tokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSCOPES\tCREATED")
+	for _, t := range all {
+		scopes := make([]string, 0, len(t.Scopes))
+		for _, s := range t.Scopes {
+			scopes = append(scopes, string(s))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.ID, t.Name, strings.Join(scopes, ","), t.CreatedAt.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+// openStorage opens database if dsn is set, otherwise file storage which saves each change immediately
+func openStorage(ctx context.Context, dsn, file string) (repository.TokenRepository, func(), error) {
+	if dsn != "" {
+		db, err := sql.Open("pgx/v5", dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		storage, err := postgresql.NewRepository(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		return storage, storage.Close, nil
+	}
+
+	if dumper.InUse(file) {
+		return nil, nil, fmt.Errorf("file %s is used by running server, stop it or use database storage; "+
+			"if server is not running, delete %s.lock", file, file)
+	}
+
+	fs, err := dumper.NewFileStream(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	storage, err := dumper.NewFileDumper(ctx, dumper.Config{
+		Restore:      true,
+		Storage:      memory.NewMetricRepository(),
+		SerialWriter: fs,
+	})
+	if err != nil {
+		fs.Close()
+		return nil, nil, err
+	}
+	return storage, func() { fs.Close() }, nil
+}
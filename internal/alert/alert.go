@@ -0,0 +1,36 @@
+package alert
+
+import "time"
+
+// State is the state of alert
+type State string
+
+// States of alert. Alert becomes pending when condition of rule is met,
+// pending alert becomes firing when condition is met for duration For of rule,
+// firing alert becomes resolved when condition is not met anymore.
+// Pending alert is dropped if condition is not met before it fires
+const (
+	StatePending  State = "pending"
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// Alert is the state of alerting rule.
+// Value is the value of metric for threshold rules and count of seconds since the last update for absence rules
+type Alert struct {
+	Rule        string    `json:"rule"`
+	State       State     `json:"state"`
+	Summary     string    `json:"summary"`
+	Value       float64   `json:"value"`
+	ActiveSince time.Time `json:"active_since"`
+	FiredAt     time.Time `json:"fired_at"`
+	ResolvedAt  time.Time `json:"resolved_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Event is the change of state of alert which is delivered to receivers of notifications.
+// Previous is empty if alert did not exist before
+type Event struct {
+	Alert    Alert `json:"alert"`
+	Previous State `json:"previous_state"`
+}
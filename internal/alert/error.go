@@ -0,0 +1,6 @@
+package alert
+
+import "errors"
+
+var ErrInvalidRule = errors.New("invalid alerting rule")
+var ErrDuplicateRule = errors.New("alerting rule with the same name is already defined")
@@ -0,0 +1,194 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/vilasle/metrics/internal/metric"
+)
+
+// Operators of threshold rules
+const (
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+	OpLess         = "<"
+	OpLessEqual    = "<="
+	OpEqual        = "=="
+	OpNotEqual     = "!="
+)
+
+// Rule describes condition of alert.
+// Threshold rule compares value of metric with Threshold by Op, e.g. HeapAlloc > 1e9.
+// Absence rule is met when series of metric was not updated for Absent, e.g. no PollCount update for 2m.
+// Series of metric is chosen by labels like in MetricService.Get.
+// Alert fires when condition is met for For, alert fires immediately if For is 0
+type Rule struct {
+	Name      string
+	Type      string
+	Metric    string
+	Labels    metric.Labels
+	Op        string
+	Threshold float64
+	Absent    time.Duration
+	For       time.Duration
+}
+
+// IsAbsence returns true if rule checks absence of updates
+func (r Rule) IsAbsence() bool {
+	return r.Absent > 0
+}
+
+// Match returns true if value meets condition of threshold rule
+func (r Rule) Match(v float64) bool {
+	switch r.Op {
+	case OpGreater:
+		return v > r.Threshold
+	case OpGreaterEqual:
+		return v >= r.Threshold
+	case OpLess:
+		return v < r.Threshold
+	case OpLessEqual:
+		return v <= r.Threshold
+	case OpEqual:
+		return v == r.Threshold
+	case OpNotEqual:
+		return v != r.Threshold
+	default:
+		return false
+	}
+}
+
+// String returns description of rule like HeapAlloc > 1e+09 for 5m0s or no PollCount update for 2m0s
+func (r Rule) String() string {
+	name := metric.SeriesID(r.Metric, r.Labels)
+
+	var s string
+	if r.IsAbsence() {
+		s = fmt.Sprintf("no %s update for %s", name, r.Absent)
+	} else {
+		s = fmt.Sprintf("%s %s %s", name, r.Op, strconv.FormatFloat(r.Threshold, 'g', -1, 64))
+	}
+
+	if r.For > 0 {
+		s += fmt.Sprintf(" for %s", r.For)
+	}
+	return s
+}
+
+func (r Rule) validate() error {
+	if r.Name == "" {
+		return errors.Join(ErrInvalidRule, errors.New("name is empty"))
+	}
+	if r.Metric == "" {
+		return errors.Join(ErrInvalidRule, fmt.Errorf("rule %s: metric is empty", r.Name))
+	}
+	if _, ok := metric.LookupType(r.Type); !ok {
+		return errors.Join(ErrInvalidRule, metric.ErrUnknownMetricType, fmt.Errorf("rule %s: type %q", r.Name, r.Type))
+	}
+	if r.For < 0 || r.Absent < 0 {
+		return errors.Join(ErrInvalidRule, fmt.Errorf("rule %s: duration is negative", r.Name))
+	}
+	if r.IsAbsence() {
+		if r.Op != "" {
+			return errors.Join(ErrInvalidRule, fmt.Errorf("rule %s: absence rule does not have operator", r.Name))
+		}
+		return nil
+	}
+
+	switch r.Op {
+	case OpGreater, OpGreaterEqual, OpLess, OpLessEqual, OpEqual, OpNotEqual:
+		return nil
+	default:
+		return errors.Join(ErrInvalidRule, fmt.Errorf("rule %s: unknown operator %q", r.Name, r.Op))
+	}
+}
+
+// jsonRule is the representation of rule in rules file, durations are in format of time.ParseDuration
+type jsonRule struct {
+	Name      string        `json:"name"`
+	Type      string        `json:"type"`
+	Metric    string        `json:"metric"`
+	Labels    metric.Labels `json:"labels,omitempty"`
+	Op        string        `json:"op,omitempty"`
+	Threshold float64       `json:"threshold,omitempty"`
+	Absent    string        `json:"absent,omitempty"`
+	For       string        `json:"for,omitempty"`
+}
+
+// LoadRules reads rules from json file, see ParseRules
+func LoadRules(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseRules(file)
+}
+
+// ParseRules reads rules in json format like
+//
+//	{"rules": [
+//		{"name": "heap", "type": "gauge", "metric": "HeapAlloc", "op": ">", "threshold": 1e9, "for": "5m"},
+//		{"name": "poll", "type": "counter", "metric": "PollCount", "absent": "2m"}
+//	]}
+//
+// Names of rules must be unique
+func ParseRules(r io.Reader) ([]Rule, error) {
+	content := struct {
+		Rules []jsonRule `json:"rules"`
+	}{}
+	if err := json.NewDecoder(r).Decode(&content); err != nil {
+		return nil, errors.Join(ErrInvalidRule, err)
+	}
+
+	rules := make([]Rule, 0, len(content.Rules))
+	names := make(map[string]struct{}, len(content.Rules))
+	for _, raw := range content.Rules {
+		rule, err := raw.rule()
+		if err != nil {
+			return nil, err
+		}
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := names[rule.Name]; ok {
+			return nil, errors.Join(ErrDuplicateRule, fmt.Errorf("rule %s", rule.Name))
+		}
+		names[rule.Name] = struct{}{}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (r jsonRule) rule() (Rule, error) {
+	rule := Rule{
+		Name:      r.Name,
+		Type:      r.Type,
+		Metric:    r.Metric,
+		Labels:    r.Labels.Clone(),
+		Op:        r.Op,
+		Threshold: r.Threshold,
+	}
+
+	var err error
+	if rule.Absent, err = parseDuration(r.Absent); err != nil {
+		return rule, errors.Join(ErrInvalidRule, fmt.Errorf("rule %s: absent", r.Name), err)
+	}
+	if rule.For, err = parseDuration(r.For); err != nil {
+		return rule, errors.Join(ErrInvalidRule, fmt.Errorf("rule %s: for", r.Name), err)
+	}
+	return rule, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,108 @@
+package alert
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+)
+
+func TestParseRules(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    []Rule
+		wantErr error
+	}{
+		{
+			name: "threshold and absence",
+			content: `{"rules": [
+				{"name": "heap", "type": "gauge", "metric": "HeapAlloc", "op": ">", "threshold": 1e9, "for": "5m"},
+				{"name": "poll", "type": "counter", "metric": "PollCount", "labels": {"host": "a"}, "absent": "2m"}
+			]}`,
+			want: []Rule{
+				{Name: "heap", Type: metric.TypeGauge, Metric: "HeapAlloc", Op: OpGreater, Threshold: 1e9, For: 5 * time.Minute},
+				{Name: "poll", Type: metric.TypeCounter, Metric: "PollCount", Labels: metric.Labels{"host": "a"}, Absent: 2 * time.Minute},
+			},
+		},
+		{
+			name:    "duplicate name",
+			content: `{"rules": [{"name": "heap", "type": "gauge", "metric": "HeapAlloc", "op": ">"}, {"name": "heap", "type": "gauge", "metric": "HeapAlloc", "op": "<"}]}`,
+			wantErr: ErrDuplicateRule,
+		},
+		{
+			name:    "unknown operator",
+			content: `{"rules": [{"name": "heap", "type": "gauge", "metric": "HeapAlloc", "op": "=>"}]}`,
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name:    "operator of absence rule",
+			content: `{"rules": [{"name": "poll", "type": "counter", "metric": "PollCount", "op": ">", "absent": "2m"}]}`,
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name:    "unknown type",
+			content: `{"rules": [{"name": "heap", "type": "meter", "metric": "HeapAlloc", "op": ">"}]}`,
+			wantErr: metric.ErrUnknownMetricType,
+		},
+		{
+			name:    "invalid duration",
+			content: `{"rules": [{"name": "heap", "type": "gauge", "metric": "HeapAlloc", "op": ">", "for": "5 minutes"}]}`,
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name:    "empty metric",
+			content: `{"rules": [{"name": "heap", "type": "gauge", "op": ">"}]}`,
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name:    "invalid json",
+			content: `{"rules": [`,
+			wantErr: ErrInvalidRule,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRules(strings.NewReader(tt.content))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRule_Match(t *testing.T) {
+	testCases := []struct {
+		op    string
+		value float64
+		want  bool
+	}{
+		{op: OpGreater, value: 11, want: true},
+		{op: OpGreater, value: 10, want: false},
+		{op: OpGreaterEqual, value: 10, want: true},
+		{op: OpLess, value: 9, want: true},
+		{op: OpLessEqual, value: 11, want: false},
+		{op: OpEqual, value: 10, want: true},
+		{op: OpNotEqual, value: 10, want: false},
+	}
+
+	for _, tt := range testCases {
+		rule := Rule{Op: tt.op, Threshold: 10}
+		assert.Equal(t, tt.want, rule.Match(tt.value), "%v %s 10", tt.value, tt.op)
+	}
+}
+
+func TestRule_String(t *testing.T) {
+	heap := Rule{Metric: "HeapAlloc", Op: OpGreater, Threshold: 1e9, For: 5 * time.Minute}
+	assert.Equal(t, "HeapAlloc > 1e+09 for 5m0s", heap.String())
+
+	poll := Rule{Metric: "PollCount", Labels: metric.Labels{"host": "a"}, Absent: 2 * time.Minute}
+	assert.Equal(t, `no PollCount{host="a"} update for 2m0s`, poll.String())
+}
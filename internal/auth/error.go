@@ -0,0 +1,7 @@
+package auth
+
+import "errors"
+
+var ErrUnknownScope = errors.New("unknown scope of token")
+var ErrEmptyScopes = errors.New("token does not have scopes")
+var ErrInvalidToken = errors.New("invalid token")
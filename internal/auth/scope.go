@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+)
+
+// Scope defines which part of API is accessible by token
+type Scope string
+
+const (
+	// ScopeRead allows reading of metrics
+	ScopeRead Scope = "read"
+	// ScopeWrite allows sending of metrics
+	ScopeWrite Scope = "write"
+	// ScopeAdmin allows administration of server, e.g. profiling and reloading of keys
+	ScopeAdmin Scope = "admin"
+)
+
+// ParseScopes parses comma separated list of scopes like "read,write"
+func ParseScopes(s string) ([]Scope, error) {
+	rs := make([]Scope, 0, 3)
+	for _, raw := range strings.Split(s, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		scope := Scope(raw)
+		switch scope {
+		case ScopeRead, ScopeWrite, ScopeAdmin:
+		default:
+			return nil, errors.Join(ErrUnknownScope, errors.New(raw))
+		}
+		if !hasScope(rs, scope) {
+			rs = append(rs, scope)
+		}
+	}
+	if len(rs) == 0 {
+		return nil, ErrEmptyScopes
+	}
+	return rs, nil
+}
+
+func hasScope(scopes []Scope, scope Scope) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
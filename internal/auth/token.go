@@ -0,0 +1,82 @@
+// Package auth contains API tokens of server. Token is shown once when it is issued,
+// storage keeps only its id and hash of secret
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+const (
+	idSize     = 8
+	secretSize = 32
+	// separator divides id and secret of token like 3f2a9c0d1e4b5a6f.secret
+	separator = "."
+)
+
+// Token is the stored API token
+type Token struct {
+	ID string `json:"id"`
+	// Name describes owner of token, e.g. name of dashboard
+	Name string `json:"name"`
+	// Hash is hex encoded SHA-256 of secret of token
+	Hash      string    `json:"hash"`
+	Scopes    []Scope   `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Issue returns new token with scopes and its value which is passed by clients in header Authorization: Bearer <value>
+func Issue(name string, scopes ...Scope) (Token, string, error) {
+	if len(scopes) == 0 {
+		return Token{}, "", ErrEmptyScopes
+	}
+
+	id, secret := make([]byte, idSize), make([]byte, secretSize)
+	if _, err := rand.Read(id); err != nil {
+		return Token{}, "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return Token{}, "", err
+	}
+
+	t := Token{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+	value := base64.RawURLEncoding.EncodeToString(secret)
+	t.Hash = hashSecret(value)
+
+	return t, t.ID + separator + value, nil
+}
+
+// Split returns id and secret of value of token
+func Split(value string) (string, string, error) {
+	id, secret, ok := strings.Cut(value, separator)
+	if !ok || id == "" || secret == "" {
+		return "", "", ErrInvalidToken
+	}
+	return id, secret, nil
+}
+
+// Match reports whether secret belongs token
+func (t Token) Match(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hashSecret(secret))) == 1
+}
+
+// HasScope reports whether token has scope
+func (t Token) HasScope(scope Scope) bool {
+	return hasScope(t.Scopes, scope)
+}
+
+// secret of token is random, so it does not need slow hash like bcrypt
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
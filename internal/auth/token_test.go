@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssue(t *testing.T) {
+	token, value, err := Issue("dashboard", ScopeRead)
+	require.NoError(t, err)
+
+	id, secret, err := Split(value)
+	require.NoError(t, err)
+	assert.Equal(t, token.ID, id)
+	assert.Equal(t, "dashboard", token.Name)
+	assert.True(t, token.HasScope(ScopeRead))
+	assert.False(t, token.HasScope(ScopeWrite))
+
+	// secret is not kept by token
+	assert.NotContains(t, token.Hash, secret)
+	assert.True(t, token.Match(secret))
+	assert.False(t, token.Match(secret+"x"))
+
+	_, _, err = Issue("without scopes")
+	require.ErrorIs(t, err, ErrEmptyScopes)
+}
+
+func TestSplit(t *testing.T) {
+	for _, value := range []string{"", "id", "id.", ".secret"} {
+		_, _, err := Split(value)
+		require.ErrorIs(t, err, ErrInvalidToken, value)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes("read, write,read")
+	require.NoError(t, err)
+	assert.Equal(t, []Scope{ScopeRead, ScopeWrite}, scopes)
+
+	_, err = ParseScopes("read,root")
+	require.ErrorIs(t, err, ErrUnknownScope)
+
+	_, err = ParseScopes(strings.Repeat(",", 3))
+	require.ErrorIs(t, err, ErrEmptyScopes)
+}
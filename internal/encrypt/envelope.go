@@ -0,0 +1,108 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// ModeHeader is the header (metadata key for grpc) which defines the way of encryption of content
+	ModeHeader = "Encryption-Mode"
+	// ModeEnvelope is the value of ModeHeader for envelope encryption, see Encrypt.
+	// If header is empty, content is encrypted by RSA-OAEP with public key as is
+	ModeEnvelope = "rsa-aes-gcm"
+
+	aesKeySize   = 32
+	keyLenPrefix = 2
+)
+
+var ErrInvalidEnvelope = errors.New("invalid encrypted envelope")
+
+// Encrypt encrypts content by envelope scheme.
+// For each call it generates random AES-256 key, seals content with AES-GCM and wraps the key by RSA-OAEP with public key.
+// So size of content does not depend on size of RSA key.
+// Result layout: | length of wrapped key (2 bytes, big endian) | wrapped key | nonce | sealed content |
+func Encrypt(key *rsa.PublicKey, content []byte) ([]byte, error) {
+	aesKey := make([]byte, aesKeySize)
+	if _, err := rand.Read(aesKey); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(aesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key, aesKey, []byte{})
+	if err != nil {
+		return nil, err
+	}
+
+	rs := make([]byte, keyLenPrefix, keyLenPrefix+len(wrappedKey)+len(nonce)+len(content)+gcm.Overhead())
+	binary.BigEndian.PutUint16(rs, uint16(len(wrappedKey)))
+	rs = append(rs, wrappedKey...)
+	rs = append(rs, nonce...)
+
+	return gcm.Seal(rs, nonce, content, nil), nil
+}
+
+// Decrypt decrypts content which was encrypted by Encrypt
+func Decrypt(key *rsa.PrivateKey, content []byte) ([]byte, error) {
+	if len(content) < keyLenPrefix {
+		return nil, ErrInvalidEnvelope
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(content))
+	content = content[keyLenPrefix:]
+	if len(content) < keyLen {
+		return nil, ErrInvalidEnvelope
+	}
+
+	aesKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, content[:keyLen], []byte{})
+	if err != nil {
+		return nil, errors.Join(ErrInvalidEnvelope, err)
+	}
+	content = content[keyLen:]
+
+	gcm, err := newGCM(aesKey)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidEnvelope, err)
+	}
+
+	if len(content) < gcm.NonceSize() {
+		return nil, ErrInvalidEnvelope
+	}
+
+	nonce, sealed := content[:gcm.NonceSize()], content[gcm.NonceSize():]
+	rs, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidEnvelope, err)
+	}
+	return rs, nil
+}
+
+// DecryptByMode decrypts content according to mode, see ModeHeader
+func DecryptByMode(key *rsa.PrivateKey, mode string, content []byte) ([]byte, error) {
+	if mode == ModeEnvelope {
+		return Decrypt(key, content)
+	}
+	return rsa.DecryptOAEP(sha256.New(), rand.Reader, key, content, []byte{})
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
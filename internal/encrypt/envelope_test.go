@@ -0,0 +1,91 @@
+package encrypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{
+			name:    "small content",
+			content: []byte(`{"id":"test","type":"gauge","value":1.123}`),
+		},
+		{
+			name:    "content bigger than rsa key allows",
+			content: bytes.Repeat([]byte(`{"id":"test","type":"gauge","value":1.123},`), 1000),
+		},
+		{
+			name:    "empty content",
+			content: []byte{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := Encrypt(&privateKey.PublicKey, tt.content)
+			require.NoError(t, err)
+
+			got, err := Decrypt(privateKey, encrypted)
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.content), len(got))
+			assert.True(t, bytes.Equal(tt.content, got))
+		})
+	}
+}
+
+func TestDecrypt_InvalidEnvelope(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	encrypted, err := Encrypt(&privateKey.PublicKey, []byte("some data"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "empty", content: []byte{}},
+		{name: "truncated key", content: encrypted[:10]},
+		{name: "truncated content", content: encrypted[:len(encrypted)-1]},
+		{name: "changed content", content: append(append([]byte{}, encrypted[:len(encrypted)-1]...), encrypted[len(encrypted)-1]^0xff)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decrypt(privateKey, tt.content)
+			assert.ErrorIs(t, err, ErrInvalidEnvelope)
+		})
+	}
+}
+
+func TestDecryptByMode(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	src := []byte("some data")
+
+	legacy, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &privateKey.PublicKey, src, []byte{})
+	require.NoError(t, err)
+
+	got, err := DecryptByMode(privateKey, "", legacy)
+	require.NoError(t, err)
+	assert.Equal(t, src, got)
+
+	envelope, err := Encrypt(&privateKey.PublicKey, src)
+	require.NoError(t, err)
+
+	got, err = DecryptByMode(privateKey, ModeEnvelope, envelope)
+	require.NoError(t, err)
+	assert.Equal(t, src, got)
+}
@@ -0,0 +1,76 @@
+package encrypt
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidKey = errors.New("invalid rsa key")
+
+// ParsePrivateKey parses PEM encoded rsa private key in PKCS#1 or PKCS#8 format
+func ParsePrivateKey(content []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.Join(ErrInvalidKey, errors.New("content is not PEM encoded"))
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidKey, err)
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidKey, err)
+		}
+		if k, ok := key.(*rsa.PrivateKey); ok {
+			return k, nil
+		}
+		return nil, errors.Join(ErrInvalidKey, fmt.Errorf("key is %T", key))
+	default:
+		return nil, errors.Join(ErrInvalidKey, fmt.Errorf("unexpected block %s", block.Type))
+	}
+}
+
+// ParsePublicKey parses PEM encoded rsa public key in PKCS#1 or PKIX format or takes it from certificate
+func ParsePublicKey(content []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.Join(ErrInvalidKey, errors.New("content is not PEM encoded"))
+	}
+
+	var key any
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		k, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidKey, err)
+		}
+		return k, nil
+	case "PUBLIC KEY":
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidKey, err)
+		}
+		key = k
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidKey, err)
+		}
+		key = cert.PublicKey
+	default:
+		return nil, errors.Join(ErrInvalidKey, fmt.Errorf("unexpected block %s", block.Type))
+	}
+
+	if k, ok := key.(*rsa.PublicKey); ok {
+		return k, nil
+	}
+	return nil, errors.Join(ErrInvalidKey, fmt.Errorf("key is %T", key))
+}
@@ -0,0 +1,83 @@
+package encrypt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	for _, block := range []*pem.Block{
+		{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		{Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		got, err := ParsePrivateKey(pem.EncodeToMemory(block))
+		require.NoError(t, err, block.Type)
+		assert.True(t, key.Equal(got), block.Type)
+	}
+
+	spki, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	cert := selfSigned(t, key)
+	for _, block := range []*pem.Block{
+		{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey)},
+		{Type: "PUBLIC KEY", Bytes: spki},
+		{Type: "CERTIFICATE", Bytes: cert},
+	} {
+		got, err := ParsePublicKey(pem.EncodeToMemory(block))
+		require.NoError(t, err, block.Type)
+		assert.True(t, key.PublicKey.Equal(got), block.Type)
+	}
+}
+
+func TestParseKeys_Invalid(t *testing.T) {
+	_, err := ParsePrivateKey([]byte("not pem"))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = ParsePublicKey([]byte("not pem"))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("broken")}))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: []byte("broken")}))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	// only rsa keys are used for encryption
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	require.NoError(t, err)
+	_, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func selfSigned(t *testing.T, key *rsa.PrivateKey) []byte {
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "server"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return der
+}
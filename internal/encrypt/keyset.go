@@ -0,0 +1,178 @@
+package encrypt
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// KeyIDHeader is the header (metadata key for grpc) which contains id of public key which content is encrypted by, see KeyID
+const KeyIDHeader = "Encryption-Key-ID"
+
+var ErrUnknownKey = errors.New("unknown encryption key")
+var ErrNoKeys = errors.New("there are not private keys")
+
+// KeyID returns id of key, it is hex encoded SHA-256 of public key in PKIX format,
+// so agent and server get the same id without sharing anything except public key
+func KeyID(key *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// KeySet is the set of private keys for decryption, keys are chosen by KeyID.
+// It keeps keys from file or directory and can reload them, so keys are rotated without restart
+type KeySet struct {
+	mx   sync.RWMutex
+	path string
+	keys map[string]*rsa.PrivateKey
+}
+
+// NewKeySet returns set of passed keys, it can not be reloaded
+func NewKeySet(keys ...*rsa.PrivateKey) (*KeySet, error) {
+	s := &KeySet{}
+	return s, s.set(keys)
+}
+
+// LoadKeySet returns set of keys from path. Path is PEM file with private key
+// or directory with such files, files of directory which do not contain private keys (e.g. public keys) are skipped
+func LoadKeySet(path string) (*KeySet, error) {
+	s := &KeySet{path: path}
+	return s, s.Reload()
+}
+
+// Reload reads keys from path again, if reading failed, previous keys are kept
+func (s *KeySet) Reload() error {
+	if s.path == "" {
+		return nil
+	}
+
+	keys, err := readPrivateKeys(s.path)
+	if err != nil {
+		return err
+	}
+	return s.set(keys)
+}
+
+// IDs returns sorted ids of keys
+func (s *KeySet) IDs() []string {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Decrypt decrypts content according to mode by key with id, see DecryptByMode.
+// Content without id is decrypted by each key until success, as agents which do not send id encrypt it
+func (s *KeySet) Decrypt(id, mode string, content []byte) ([]byte, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	if id != "" {
+		key, ok := s.keys[id]
+		if !ok {
+			return nil, errors.Join(ErrUnknownKey, errors.New(id))
+		}
+		return DecryptByMode(key, mode, content)
+	}
+
+	var errs error
+	for _, key := range s.keys {
+		rs, err := DecryptByMode(key, mode, content)
+		if err == nil {
+			return rs, nil
+		}
+		errs = errors.Join(errs, err)
+	}
+	return nil, errs
+}
+
+func (s *KeySet) set(keys []*rsa.PrivateKey) error {
+	if len(keys) == 0 {
+		return ErrNoKeys
+	}
+
+	set := make(map[string]*rsa.PrivateKey, len(keys))
+	for _, key := range keys {
+		id, err := KeyID(&key.PublicKey)
+		if err != nil {
+			return err
+		}
+		set[id] = key
+	}
+
+	s.mx.Lock()
+	s.keys = set
+	s.mx.Unlock()
+	return nil
+}
+
+func readPrivateKeys(path string) ([]*rsa.PrivateKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		key, err := readPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return []*rsa.PrivateKey{key}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*rsa.PrivateKey, 0, len(entries))
+	for _, entry := range entries {
+		// symlinks are followed, e.g. keys of mounted secrets are links to files
+		name := filepath.Join(path, entry.Name())
+		info, err := os.Stat(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		key, err := readPrivateKey(name)
+		if errors.Is(err, ErrInvalidKey) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func readPrivateKey(path string) (*rsa.PrivateKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePrivateKey(content)
+	if err != nil {
+		return nil, errors.Join(err, fmt.Errorf("file %s", path))
+	}
+	return key, nil
+}
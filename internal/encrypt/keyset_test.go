@@ -0,0 +1,137 @@
+package encrypt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKey(t *testing.T, path string, key *rsa.PrivateKey) {
+	t.Helper()
+	content := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	require.NoError(t, os.WriteFile(path, content, 0600))
+}
+
+func TestKeySet(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	oldID, err := KeyID(&oldKey.PublicKey)
+	require.NoError(t, err)
+	newID, err := KeyID(&newKey.PublicKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writeKey(t, filepath.Join(dir, "old.pem"), oldKey)
+	// public keys are skipped
+	public, err := x509.MarshalPKIXPublicKey(&oldKey.PublicKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.pub"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}), 0644))
+
+	keys, err := LoadKeySet(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{oldID}, keys.IDs())
+
+	content := []byte(`{"id":"test","type":"gauge","value":1.123}`)
+	encrypted, err := Encrypt(&newKey.PublicKey, content)
+	require.NoError(t, err)
+
+	_, err = keys.Decrypt(newID, ModeEnvelope, encrypted)
+	require.ErrorIs(t, err, ErrUnknownKey)
+
+	t.Run("reload", func(t *testing.T) {
+		writeKey(t, filepath.Join(dir, "new.pem"), newKey)
+		require.NoError(t, keys.Reload())
+		assert.ElementsMatch(t, []string{oldID, newID}, keys.IDs())
+
+		got, err := keys.Decrypt(newID, ModeEnvelope, encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, content, got)
+
+		_, err = keys.Decrypt(oldID, ModeEnvelope, encrypted)
+		require.Error(t, err)
+	})
+
+	t.Run("without key id each key is tried", func(t *testing.T) {
+		got, err := keys.Decrypt("", ModeEnvelope, encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, content, got)
+	})
+
+	t.Run("broken key is skipped", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.pem"), pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("broken")}), 0600))
+		require.NoError(t, keys.Reload())
+		assert.ElementsMatch(t, []string{oldID, newID}, keys.IDs())
+	})
+
+	t.Run("failed reload keeps keys", func(t *testing.T) {
+		require.NoError(t, os.Rename(dir, dir+".moved"))
+		require.Error(t, keys.Reload())
+		assert.ElementsMatch(t, []string{oldID, newID}, keys.IDs())
+	})
+}
+
+func TestLoadKeySet_File(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "private.pem")
+	writeKey(t, path, key)
+
+	keys, err := LoadKeySet(path)
+	require.NoError(t, err)
+	assert.Len(t, keys.IDs(), 1)
+
+	_, err = LoadKeySet(t.TempDir())
+	require.ErrorIs(t, err, ErrNoKeys)
+}
+
+func TestLoadKeySet_BrokenPKCS1(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	id, err := KeyID(&key.PublicKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writeKey(t, filepath.Join(dir, "good.pem"), key)
+	broken := filepath.Join(dir, "broken.pem")
+	require.NoError(t, os.WriteFile(broken, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("broken")}), 0600))
+
+	keys, err := LoadKeySet(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{id}, keys.IDs())
+
+	// the broken file itself is not a key
+	_, err = LoadKeySet(broken)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestLoadKeySet_Symlinks(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	id, err := KeyID(&key.PublicKey)
+	require.NoError(t, err)
+
+	// layout of mounted secret, keys are links to files in hidden directory
+	dir := t.TempDir()
+	data := filepath.Join(dir, "..data")
+	require.NoError(t, os.Mkdir(data, 0700))
+	writeKey(t, filepath.Join(data, "private.pem"), key)
+	require.NoError(t, os.Symlink(filepath.Join(data, "private.pem"), filepath.Join(dir, "private.pem")))
+	// links to directories and broken links are skipped
+	require.NoError(t, os.Symlink(data, filepath.Join(dir, "current")))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "none.pem"), filepath.Join(dir, "broken.pem")))
+
+	keys, err := LoadKeySet(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{id}, keys.IDs())
+}
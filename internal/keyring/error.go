@@ -0,0 +1,9 @@
+package keyring
+
+import "errors"
+
+var ErrEmptySecret = errors.New("secret of key is empty")
+var ErrDuplicateID = errors.New("keyring has several keys with the same id")
+var ErrUnknownKey = errors.New("unknown key")
+var ErrKeyExpired = errors.New("key is expired")
+var ErrNoValidKeys = errors.New("keyring does not have valid keys")
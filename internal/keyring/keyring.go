@@ -0,0 +1,157 @@
+// Package keyring keeps secrets for HMAC signatures of requests.
+// Each key has ID which is sent by agent next to signature, so server can rotate keys without a flag day:
+// new key is added to keyring of server, agents are switched to it one by one
+// and old key is accepted until its expiration.
+package keyring
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// IDHeader is the header of request which contains ID of key of signature in header HashSHA256
+const IDHeader = "HashKeyID"
+
+// Key is the secret for HMAC-SHA256 signatures
+type Key struct {
+	// ID is the identifier of key, key which is read from raw file has empty ID
+	ID     string `json:"id"`
+	Secret string `json:"secret"`
+	// Expire is the time after which key is not accepted, key without it never expires
+	Expire time.Time `json:"expire"`
+}
+
+// Sign returns HMAC-SHA256 of content
+func (k Key) Sign(content []byte) []byte {
+	h := hmac.New(sha256.New, []byte(k.Secret))
+	h.Write(content)
+	return h.Sum(nil)
+}
+
+func (k Key) expired(now time.Time) bool {
+	return !k.Expire.IsZero() && !now.Before(k.Expire)
+}
+
+// Keyring is the set of keys, keys are kept in order from the newest to the oldest
+type Keyring struct {
+	keys []Key
+	now  func() time.Time
+}
+
+// New returns keyring with keys, keys have to be ordered from the newest to the oldest
+func New(keys ...Key) (*Keyring, error) {
+	ids := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		if k.Secret == "" {
+			return nil, errors.Join(ErrEmptySecret, errors.New(k.ID))
+		}
+		if _, ok := ids[k.ID]; ok {
+			return nil, errors.Join(ErrDuplicateID, errors.New(k.ID))
+		}
+		ids[k.ID] = struct{}{}
+	}
+	return &Keyring{keys: keys, now: time.Now}, nil
+}
+
+// FromSecret returns keyring with the only key without ID, it returns nil if secret is empty
+func FromSecret(secret []byte) *Keyring {
+	if len(secret) == 0 {
+		return nil
+	}
+	return &Keyring{keys: []Key{{Secret: string(secret)}}, now: time.Now}
+}
+
+// Parse returns keyring from content of file. Content is json like
+//
+//	{"keys": [{"id": "2024-06", "secret": "..."}, {"id": "2024-01", "secret": "...", "expire": "2024-07-01T00:00:00Z"}]}
+//
+// otherwise the whole content is the secret of the only key without ID, as keys were kept before keyrings.
+// It returns nil if content is empty
+func Parse(content []byte) (*Keyring, error) {
+	if len(content) == 0 {
+		return nil, nil
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) {
+		return FromSecret(content), nil
+	}
+
+	file := struct {
+		Keys []Key `json:"keys"`
+	}{}
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+	return New(file.Keys...)
+}
+
+// Load reads keyring from file, see Parse. It returns nil if path is empty
+func Load(path string) (*Keyring, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(content)
+}
+
+// Signing returns key for signing of requests, it is the newest key which is not expired
+func (k *Keyring) Signing() (Key, error) {
+	now := k.now()
+	for _, key := range k.keys {
+		if !key.expired(now) {
+			return key, nil
+		}
+	}
+	return Key{}, ErrNoValidKeys
+}
+
+// Candidates returns keys which can be used for checking of signature with key id.
+// If id is empty, signature may be made by any key which is not expired,
+// so agents which do not send ID keep working while their key is in keyring
+func (k *Keyring) Candidates(id string) ([]Key, error) {
+	now := k.now()
+	if id != "" {
+		for _, key := range k.keys {
+			if key.ID != id {
+				continue
+			}
+			if key.expired(now) {
+				return nil, errors.Join(ErrKeyExpired, errors.New(id))
+			}
+			return []Key{key}, nil
+		}
+		return nil, errors.Join(ErrUnknownKey, errors.New(id))
+	}
+
+	rs := make([]Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		if !key.expired(now) {
+			rs = append(rs, key)
+		}
+	}
+	if len(rs) == 0 {
+		return nil, ErrNoValidKeys
+	}
+	return rs, nil
+}
+
+// Verify reports whether sum is the signature of content by key with id or by any valid key if id is empty
+func (k *Keyring) Verify(id string, content, sum []byte) (bool, error) {
+	keys, err := k.Candidates(id)
+	if err != nil {
+		return false, err
+	}
+	for _, key := range keys {
+		if hmac.Equal(key.Sign(content), sum) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
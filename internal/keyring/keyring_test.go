@@ -0,0 +1,124 @@
+package keyring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    []Key
+		wantErr error
+	}{
+		{name: "empty content", content: ""},
+		{name: "raw key", content: "secret", want: []Key{{Secret: "secret"}}},
+		{
+			name:    "keyring",
+			content: `{"keys": [{"id": "k2", "secret": "new"}, {"id": "k1", "secret": "old", "expire": "2024-07-01T00:00:00Z"}]}`,
+			want: []Key{
+				{ID: "k2", Secret: "new"},
+				{ID: "k1", Secret: "old", Expire: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+		{name: "empty secret", content: `{"keys": [{"id": "k1"}]}`, wantErr: ErrEmptySecret},
+		{name: "duplicate id", content: `{"keys": [{"id": "k1", "secret": "a"}, {"id": "k1", "secret": "b"}]}`, wantErr: ErrDuplicateID},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := Parse([]byte(tt.content))
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.want == nil {
+				assert.Nil(t, keys)
+				return
+			}
+			assert.Equal(t, tt.want, keys.keys)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	keys, err := Load("")
+	require.NoError(t, err)
+	assert.Nil(t, keys)
+
+	path := filepath.Join(t.TempDir(), "hash.key")
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys": [{"id": "k1", "secret": "a"}]}`), 0600))
+
+	keys, err = Load(path)
+	require.NoError(t, err)
+	key, err := keys.Signing()
+	require.NoError(t, err)
+	assert.Equal(t, "k1", key.ID)
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	keys, err := New(
+		Key{ID: "k3", Secret: "newest"},
+		Key{ID: "k2", Secret: "current", Expire: now.Add(time.Hour)},
+		Key{ID: "k1", Secret: "old", Expire: now},
+	)
+	require.NoError(t, err)
+	keys.now = func() time.Time { return now }
+
+	content := []byte("content")
+
+	t.Run("signing key is the newest", func(t *testing.T) {
+		key, err := keys.Signing()
+		require.NoError(t, err)
+		assert.Equal(t, "k3", key.ID)
+	})
+
+	t.Run("key by id", func(t *testing.T) {
+		ok, err := keys.Verify("k2", content, Key{Secret: "current"}.Sign(content))
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = keys.Verify("k3", content, Key{Secret: "current"}.Sign(content))
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("expired key", func(t *testing.T) {
+		_, err := keys.Verify("k1", content, Key{Secret: "old"}.Sign(content))
+		require.ErrorIs(t, err, ErrKeyExpired)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		_, err := keys.Verify("k0", content, Key{Secret: "old"}.Sign(content))
+		require.ErrorIs(t, err, ErrUnknownKey)
+	})
+
+	t.Run("without id any valid key is accepted", func(t *testing.T) {
+		ok, err := keys.Verify("", content, Key{Secret: "current"}.Sign(content))
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = keys.Verify("", content, Key{Secret: "old"}.Sign(content))
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("all keys are expired", func(t *testing.T) {
+		keys, err := New(Key{ID: "k1", Secret: "old", Expire: now})
+		require.NoError(t, err)
+		keys.now = func() time.Time { return now.Add(time.Hour) }
+
+		_, err = keys.Signing()
+		require.ErrorIs(t, err, ErrNoValidKeys)
+		_, err = keys.Candidates("")
+		require.ErrorIs(t, err, ErrNoValidKeys)
+	})
+}
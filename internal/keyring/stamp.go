@@ -0,0 +1,39 @@
+package keyring
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+const (
+	// TimestampHeader is the header of request which contains time of signing in unix seconds
+	TimestampHeader = "X-Request-Timestamp"
+	// NonceHeader is the header of request which contains random nonce of signed request
+	NonceHeader = "X-Request-Nonce"
+)
+
+// SignedContent returns content which is signed by HMAC. Timestamp and nonce are signed together with body,
+// so captured request can not be replayed with other ones. Request without them is signed by body only
+func SignedContent(body []byte, timestamp, nonce string) []byte {
+	if timestamp == "" && nonce == "" {
+		return body
+	}
+
+	rs := make([]byte, 0, len(timestamp)+len(nonce)+len(body)+2)
+	rs = append(rs, timestamp...)
+	rs = append(rs, '.')
+	rs = append(rs, nonce...)
+	rs = append(rs, '.')
+	return append(rs, body...)
+}
+
+// NewStamp returns current time in unix seconds and random nonce for signing of request
+func NewStamp() (string, string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", "", err
+	}
+	return strconv.FormatInt(time.Now().Unix(), 10), hex.EncodeToString(nonce), nil
+}
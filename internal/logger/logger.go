@@ -119,3 +119,8 @@ func Warnw(msg string, keysAndValues ...interface{}) {
 func Errorw(msg string, keysAndValues ...interface{}) {
 	logger.Errorw(msg, keysAndValues...)
 }
+
+// Fatalw logs a message with some additional context and calls os.Exit. Context is key-value pairs
+func Fatalw(msg string, keysAndValues ...interface{}) {
+	logger.Fatalw(msg, keysAndValues...)
+}
@@ -5,11 +5,24 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 )
 
+// counterType describes counter for registry of types
+var counterType = TypeInfo{
+	Name:        TypeCounter,
+	Code:        "1",
+	Aggregation: AggregateSum,
+	Parse:       parseCounter,
+	DecodeJSON:  decodeCounter,
+	Merge:       CreateSummedCounter,
+}
+
 type counter struct {
-	name  string
-	value int64
+	name   string
+	value  int64
+	labels Labels
+	stamp
 }
 
 var _ Metric = (*counter)(nil)
@@ -24,6 +37,16 @@ func (c counter) Value() string {
 	return strconv.FormatInt(c.value, 10)
 }
 
+// Labels returns labels of metric
+func (c counter) Labels() Labels {
+	return c.labels
+}
+
+// SetLabels sets copy of labels to metric
+func (c *counter) SetLabels(labels Labels) {
+	c.labels = labels.Clone()
+}
+
 // Type returns type of metric
 func (c counter) Type() string {
 	return TypeCounter
@@ -54,7 +77,11 @@ func (c *counter) SetValue(val any) error {
 
 // String returns string representation of metric
 // representation string likes {type: metric_type; name: metric_name; value: metric_value}
+// labels are added to representation if metric has them
 func (c counter) String() string {
+	if len(c.labels) > 0 {
+		return fmt.Sprintf("{type: %s; name: %s; value: %d; labels: %s}", c.Type(), c.name, c.value, c.labels)
+	}
 	return fmt.Sprintf("{type: %s; name: %s; value: %d}", c.Type(), c.name, c.value)
 }
 
@@ -68,7 +95,7 @@ func (c counter) Int64() int64 {
 	return c.value
 }
 
-func parseCounter(name string, value string) (*counter, error) {
+func parseCounter(name string, value string) (Metric, error) {
 	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
 		return &counter{name: name, value: v}, nil
 	} else {
@@ -76,16 +103,45 @@ func parseCounter(name string, value string) (*counter, error) {
 	}
 }
 
+func decodeCounter(content []byte) (Metric, error) {
+	object := struct {
+		ID     string `json:"id"`
+		Delta  *int64 `json:"delta,omitempty"`
+		Labels Labels `json:"labels,omitempty"`
+	}{}
+	if err := json.Unmarshal(content, &object); err != nil {
+		return nil, errors.Join(ErrInvalidMetric, err)
+	}
+	return createCounterMetric(object.ID, object.Delta, object.Labels)
+}
+
+func createCounterMetric(name string, value *int64, labels Labels) (Metric, error) {
+	var (
+		v   int64
+		err error
+	)
+	if value != nil {
+		v = *value
+	} else {
+		err = ErrEmptyValue
+	}
+	return &counter{name: name, value: v, labels: labels.Clone()}, err
+}
+
 // MarshalJSON returns json representation of metric
 func (c counter) MarshalJSON() ([]byte, error) {
 	metric := struct {
-		ID    string `json:"id"`
-		MType string `json:"type"`
-		Value int64  `json:"delta"`
+		ID        string     `json:"id"`
+		MType     string     `json:"type"`
+		Value     int64      `json:"delta"`
+		Labels    Labels     `json:"labels,omitempty"`
+		Timestamp *time.Time `json:"timestamp,omitempty"`
 	}{
-		ID:    c.name,
-		MType: c.Type(),
-		Value: c.value,
+		ID:        c.name,
+		MType:     c.Type(),
+		Value:     c.value,
+		Labels:    c.labels,
+		Timestamp: c.jsonTimestamp(),
 	}
 	return json.Marshal(metric)
 }
@@ -7,3 +7,8 @@ var ErrUnknownMetricType = errors.New("unknown metric type")
 var ErrEmptyName = errors.New("name of metric is empty")
 var ErrEmptyValue = errors.New("value of metric is empty")
 var ErrInvalidMetric = errors.New("invalid metric data")
+var ErrInvalidHistogram = errors.New("invalid buckets of histogram")
+var ErrHistogramBoundsMismatch = errors.New("histograms have different bounds of buckets")
+var ErrInvalidSummary = errors.New("invalid sketch of summary")
+var ErrInvalidType = errors.New("invalid description of metric type")
+var ErrTypeIsRegistered = errors.New("metric type is already registered")
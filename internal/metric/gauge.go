@@ -5,11 +5,23 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 )
 
+// gaugeType describes gauge for registry of types
+var gaugeType = TypeInfo{
+	Name:        TypeGauge,
+	Code:        "0",
+	Aggregation: AggregateLast,
+	Parse:       parseGauge,
+	DecodeJSON:  decodeGauge,
+}
+
 type gauge struct {
-	name  string
-	value float64
+	name   string
+	value  float64
+	labels Labels
+	stamp
 }
 
 // Value returns name of metric
@@ -22,6 +34,16 @@ func (c gauge) Value() string {
 	return strconv.FormatFloat(c.value, 'f', -1, 64)
 }
 
+// Labels returns labels of metric
+func (c gauge) Labels() Labels {
+	return c.labels
+}
+
+// SetLabels sets copy of labels to metric
+func (c *gauge) SetLabels(labels Labels) {
+	c.labels = labels.Clone()
+}
+
 // Type returns type of metric
 func (c gauge) Type() string {
 	return TypeGauge
@@ -50,20 +72,28 @@ func (c *gauge) SetValue(val any) error {
 // MarshalJSON returns json representation of metric
 func (c gauge) MarshalJSON() ([]byte, error) {
 	metric := struct {
-		ID    string  `json:"id"`
-		MType string  `json:"type"`
-		Value float64 `json:"value"`
+		ID        string     `json:"id"`
+		MType     string     `json:"type"`
+		Value     float64    `json:"value"`
+		Labels    Labels     `json:"labels,omitempty"`
+		Timestamp *time.Time `json:"timestamp,omitempty"`
 	}{
-		ID:    c.name,
-		MType: c.Type(),
-		Value: c.value,
+		ID:        c.name,
+		MType:     c.Type(),
+		Value:     c.value,
+		Labels:    c.labels,
+		Timestamp: c.jsonTimestamp(),
 	}
 	return json.Marshal(metric)
 }
 
 // String returns string representation of metric
 // representation string likes {type: metric_type; name: metric_name; value: metric_value}
+// labels are added to representation if metric has them
 func (c gauge) String() string {
+	if len(c.labels) > 0 {
+		return fmt.Sprintf("{type: %s; name: %s; value: %f; labels: %s}", c.Type(), c.name, c.value, c.labels)
+	}
 	return fmt.Sprintf("{type: %s; name: %s; value: %f}", c.Type(), c.name, c.value)
 }
 
@@ -77,10 +107,35 @@ func (c gauge) Int64() int64 {
 	return int64(c.value)
 }
 
-func parseGauge(name string, value string) (*gauge, error) {
+func parseGauge(name string, value string) (Metric, error) {
 	if v, err := strconv.ParseFloat(value, 64); err == nil {
 		return &gauge{name: name, value: v}, nil
 	} else {
 		return nil, errors.Join(err, ErrConvertingRawValue)
 	}
 }
+
+func decodeGauge(content []byte) (Metric, error) {
+	object := struct {
+		ID     string   `json:"id"`
+		Value  *float64 `json:"value,omitempty"`
+		Labels Labels   `json:"labels,omitempty"`
+	}{}
+	if err := json.Unmarshal(content, &object); err != nil {
+		return nil, errors.Join(ErrInvalidMetric, err)
+	}
+	return createGaugeMetric(object.ID, object.Value, object.Labels)
+}
+
+func createGaugeMetric(name string, value *float64, labels Labels) (Metric, error) {
+	var (
+		v   float64
+		err error
+	)
+	if value != nil {
+		v = *value
+	} else {
+		err = ErrEmptyValue
+	}
+	return &gauge{name: name, value: v, labels: labels.Clone()}, err
+}
@@ -0,0 +1,375 @@
+package metric
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// infBound is the representation of upper bound of the last bucket
+const infBound = "+Inf"
+
+// Histogram is the metric which keeps distribution of observations by buckets
+type Histogram interface {
+	Metric
+
+	// Data returns copy of buckets, sum and count of observations
+	Data() HistogramData
+
+	// Observe adds observation to the bucket which it belongs to
+	Observe(float64)
+
+	// Merge adds observations of other histogram with the same bounds of buckets
+	Merge(HistogramData) error
+}
+
+// HistogramData is the distribution of observations by buckets.
+// Bounds are upper inclusive bounds of buckets in increasing order, the last bucket is up to +Inf,
+// that's why Counts has one element more than Bounds.
+// Counts are not cumulative, each of them is the count of observations in its bucket only
+type HistogramData struct {
+	Bounds []float64
+	Counts []int64
+	Sum    float64
+}
+
+// Count returns count of all observations
+func (d HistogramData) Count() int64 {
+	var count int64
+	for _, c := range d.Counts {
+		count += c
+	}
+	return count
+}
+
+// SameBounds returns true if histograms have the same buckets
+func (d HistogramData) SameBounds(other HistogramData) bool {
+	return slices.Equal(d.Bounds, other.Bounds)
+}
+
+// Clone returns deep copy of data
+func (d HistogramData) Clone() HistogramData {
+	return HistogramData{
+		Bounds: slices.Clone(d.Bounds),
+		Counts: slices.Clone(d.Counts),
+		Sum:    d.Sum,
+	}
+}
+
+func (d HistogramData) validate() error {
+	if len(d.Counts) != len(d.Bounds)+1 {
+		return errors.Join(ErrInvalidHistogram, fmt.Errorf("%d buckets for %d bounds", len(d.Counts), len(d.Bounds)))
+	}
+	for i, b := range d.Bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			return errors.Join(ErrInvalidHistogram, fmt.Errorf("bound %v is not finite", b))
+		}
+		if i > 0 && b <= d.Bounds[i-1] {
+			return errors.Join(ErrInvalidHistogram, errors.New("bounds are not in increasing order"))
+		}
+	}
+	for _, c := range d.Counts {
+		if c < 0 {
+			return errors.Join(ErrInvalidHistogram, errors.New("count of bucket is negative"))
+		}
+	}
+	if math.IsNaN(d.Sum) {
+		return errors.Join(ErrInvalidHistogram, errors.New("sum is NaN"))
+	}
+	return nil
+}
+
+// histogramType describes histogram for registry of types
+var histogramType = TypeInfo{
+	Name:        TypeHistogram,
+	Code:        "2",
+	Aggregation: AggregateMerge,
+	Parse:       parseHistogram,
+	DecodeJSON:  decodeHistogram,
+	Merge:       CreateMergedHistogram,
+}
+
+type histogram struct {
+	name   string
+	data   HistogramData
+	labels Labels
+	stamp
+}
+
+var _ Histogram = (*histogram)(nil)
+
+// NewHistogramMetric returns new histogram metric without observations with buckets bounded by bounds
+func NewHistogramMetric(name string, bounds ...float64) (Histogram, error) {
+	bounds = slices.Clone(bounds)
+	sort.Float64s(bounds)
+	return newHistogram(name, HistogramData{Bounds: bounds, Counts: make([]int64, len(bounds)+1)})
+}
+
+// NewHistogramMetricFromData returns new histogram metric with copy of data
+func NewHistogramMetricFromData(name string, data HistogramData) (Histogram, error) {
+	return newHistogram(name, data.Clone())
+}
+
+func newHistogram(name string, data HistogramData) (*histogram, error) {
+	if err := data.validate(); err != nil {
+		return nil, err
+	}
+	return &histogram{name: name, data: data}, nil
+}
+
+// Name returns name of metric
+func (h histogram) Name() string {
+	return h.name
+}
+
+// Value returns buckets and sum as string like 0.1:3,0.5:2,+Inf:1,sum:1.45
+// where each bucket is presented by upper bound and count of observations
+func (h histogram) Value() string {
+	sb := strings.Builder{}
+	for i, c := range h.data.Counts {
+		if i < len(h.data.Bounds) {
+			sb.WriteString(strconv.FormatFloat(h.data.Bounds[i], 'f', -1, 64))
+		} else {
+			sb.WriteString(infBound)
+		}
+		sb.WriteByte(':')
+		sb.WriteString(strconv.FormatInt(c, 10))
+		sb.WriteByte(',')
+	}
+	sb.WriteString("sum:")
+	sb.WriteString(strconv.FormatFloat(h.data.Sum, 'f', -1, 64))
+	return sb.String()
+}
+
+// Labels returns labels of metric
+func (h histogram) Labels() Labels {
+	return h.labels
+}
+
+// SetLabels sets copy of labels to metric
+func (h *histogram) SetLabels(labels Labels) {
+	h.labels = labels.Clone()
+}
+
+// Type returns type of metric
+func (h histogram) Type() string {
+	return TypeHistogram
+}
+
+// Data returns copy of buckets, sum and count of observations
+func (h histogram) Data() HistogramData {
+	return h.data.Clone()
+}
+
+// Observe adds observation to the bucket which it belongs to
+func (h *histogram) Observe(v float64) {
+	h.data.Counts[sort.SearchFloat64s(h.data.Bounds, v)]++
+	h.data.Sum += v
+}
+
+// Merge adds observations of other histogram, bounds of buckets must be the same
+func (h *histogram) Merge(other HistogramData) error {
+	if !h.data.SameBounds(other) || len(other.Counts) != len(h.data.Counts) {
+		return ErrHistogramBoundsMismatch
+	}
+	for i, c := range other.Counts {
+		h.data.Counts[i] += c
+	}
+	h.data.Sum += other.Sum
+	return nil
+}
+
+// AddValue adds observation to metric, accept float64, int64 or int.
+// HistogramData is merged with observations of metric
+func (h *histogram) AddValue(val any) error {
+	switch v := val.(type) {
+	case float64:
+		h.Observe(v)
+	case int64:
+		h.Observe(float64(v))
+	case int:
+		h.Observe(float64(v))
+	case HistogramData:
+		return h.Merge(v)
+	default:
+		return fmt.Errorf("value is %T, expect float64, int64 or HistogramData", val)
+	}
+	return nil
+}
+
+// SetValue replaces observations of metric, accept HistogramData
+func (h *histogram) SetValue(val any) error {
+	v, ok := val.(HistogramData)
+	if !ok {
+		return fmt.Errorf("value is %T, expect HistogramData", val)
+	}
+	if err := v.validate(); err != nil {
+		return err
+	}
+	h.data = v.Clone()
+	return nil
+}
+
+// Float64 returns sum of observations
+func (h histogram) Float64() float64 {
+	return h.data.Sum
+}
+
+// Int64 returns count of observations
+func (h histogram) Int64() int64 {
+	return h.data.Count()
+}
+
+// String returns string representation of metric
+// representation string likes {type: metric_type; name: metric_name; value: metric_value}
+// labels are added to representation if metric has them
+func (h histogram) String() string {
+	if len(h.labels) > 0 {
+		return fmt.Sprintf("{type: %s; name: %s; value: %s; labels: %s}", h.Type(), h.name, h.Value(), h.labels)
+	}
+	return fmt.Sprintf("{type: %s; name: %s; value: %s}", h.Type(), h.name, h.Value())
+}
+
+// MarshalJSON returns json representation of metric
+func (h histogram) MarshalJSON() ([]byte, error) {
+	metric := struct {
+		ID        string     `json:"id"`
+		MType     string     `json:"type"`
+		Bounds    []float64  `json:"bounds"`
+		Counts    []int64    `json:"counts"`
+		Sum       float64    `json:"sum"`
+		Count     int64      `json:"count"`
+		Labels    Labels     `json:"labels,omitempty"`
+		Timestamp *time.Time `json:"timestamp,omitempty"`
+	}{
+		ID:        h.name,
+		MType:     h.Type(),
+		Bounds:    h.data.Bounds,
+		Counts:    h.data.Counts,
+		Sum:       h.data.Sum,
+		Count:     h.data.Count(),
+		Labels:    h.labels,
+		Timestamp: h.jsonTimestamp(),
+	}
+	if metric.Bounds == nil {
+		metric.Bounds = []float64{}
+	}
+	return json.Marshal(metric)
+}
+
+// parseHistogram parses value in format of histogram.Value
+func parseHistogram(name string, value string) (Metric, error) {
+	parts := strings.Split(value, ",")
+	last := parts[len(parts)-1]
+	if !strings.HasPrefix(last, "sum:") {
+		return nil, ErrConvertingRawValue
+	}
+
+	sum, err := strconv.ParseFloat(strings.TrimPrefix(last, "sum:"), 64)
+	if err != nil {
+		return nil, errors.Join(err, ErrConvertingRawValue)
+	}
+
+	data := HistogramData{Sum: sum}
+	buckets := parts[:len(parts)-1]
+	for i, bucket := range buckets {
+		rawBound, rawCount, ok := strings.Cut(bucket, ":")
+		if !ok {
+			return nil, ErrConvertingRawValue
+		}
+
+		count, err := strconv.ParseInt(rawCount, 10, 64)
+		if err != nil {
+			return nil, errors.Join(err, ErrConvertingRawValue)
+		}
+		data.Counts = append(data.Counts, count)
+
+		if i == len(buckets)-1 {
+			if rawBound != infBound {
+				return nil, errors.Join(ErrConvertingRawValue, errors.New("the last bucket must be +Inf"))
+			}
+			break
+		}
+
+		bound, err := strconv.ParseFloat(rawBound, 64)
+		if err != nil {
+			return nil, errors.Join(err, ErrConvertingRawValue)
+		}
+		data.Bounds = append(data.Bounds, bound)
+	}
+
+	h, err := newHistogram(name, data)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func decodeHistogram(content []byte) (Metric, error) {
+	object := struct {
+		ID     string    `json:"id"`
+		Bounds []float64 `json:"bounds,omitempty"`
+		Counts []int64   `json:"counts,omitempty"`
+		Sum    *float64  `json:"sum,omitempty"`
+		Count  *int64    `json:"count,omitempty"`
+		Labels Labels    `json:"labels,omitempty"`
+	}{}
+	if err := json.Unmarshal(content, &object); err != nil {
+		return nil, errors.Join(ErrInvalidMetric, err)
+	}
+	return createHistogramMetric(object.ID, object.Bounds, object.Counts, object.Sum, object.Count, object.Labels)
+}
+
+func createHistogramMetric(name string, bounds []float64, counts []int64, sum *float64, count *int64, labels Labels) (Metric, error) {
+	if counts == nil || sum == nil {
+		return &histogram{name: name, labels: labels.Clone()}, ErrEmptyValue
+	}
+
+	h, err := newHistogram(name, HistogramData{Bounds: slices.Clone(bounds), Counts: slices.Clone(counts), Sum: *sum})
+	if err != nil {
+		return &histogram{name: name, labels: labels.Clone()}, err
+	}
+	if count != nil && *count != h.data.Count() {
+		return h, errors.Join(ErrInvalidHistogram, errors.New("count does not match counts of buckets"))
+	}
+	h.labels = labels.Clone()
+	return h, nil
+}
+
+// CreateMergedHistogram joins histograms to one and return this.
+// All histograms must have the same bounds of buckets, labels are taken from the first metric
+func CreateMergedHistogram(name string, metrics []Metric) (Metric, error) {
+	if len(metrics) == 0 {
+		return nil, ErrEmptyValue
+	}
+
+	var rs *histogram
+	for _, m := range metrics {
+		h, ok := m.(Histogram)
+		if !ok {
+			return nil, fmt.Errorf("metric { name: %s; type: %s; value: %s} is not a histogram", m.Name(), m.Type(), m.Value())
+		}
+
+		if rs == nil {
+			var err error
+			if rs, err = newHistogram(name, h.Data()); err != nil {
+				return nil, err
+			}
+			rs.labels = h.Labels().Clone()
+			rs.timestamp = h.Timestamp()
+			continue
+		}
+
+		if err := rs.Merge(h.Data()); err != nil {
+			return nil, err
+		}
+		rs.timestamp = latest(rs.timestamp, h.Timestamp())
+	}
+	return rs, nil
+}
@@ -0,0 +1,125 @@
+package metric
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogram_Observe(t *testing.T) {
+	h, err := NewHistogramMetric("latency", 0.5, 0.1)
+	require.NoError(t, err)
+
+	for _, v := range []float64{0.05, 0.1, 0.3, 1, 2} {
+		h.Observe(v)
+	}
+
+	data := h.Data()
+	assert.Equal(t, []float64{0.1, 0.5}, data.Bounds)
+	assert.Equal(t, []int64{2, 1, 2}, data.Counts)
+	assert.InDelta(t, 3.45, data.Sum, 1e-9)
+	assert.Equal(t, int64(5), h.Int64())
+	assert.Equal(t, "0.1:2,0.5:1,+Inf:2,sum:3.45", h.Value())
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	h, err := NewHistogramMetricFromData("latency", HistogramData{Bounds: []float64{1}, Counts: []int64{1, 2}, Sum: 4})
+	require.NoError(t, err)
+
+	require.NoError(t, h.AddValue(HistogramData{Bounds: []float64{1}, Counts: []int64{3, 0}, Sum: 1.5}))
+	assert.Equal(t, HistogramData{Bounds: []float64{1}, Counts: []int64{4, 2}, Sum: 5.5}, h.Data())
+
+	err = h.Merge(HistogramData{Bounds: []float64{2}, Counts: []int64{1, 1}, Sum: 3})
+	assert.ErrorIs(t, err, ErrHistogramBoundsMismatch)
+}
+
+func TestNewHistogramMetricFromData(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    HistogramData
+		wantErr bool
+	}{
+		{name: "valid", data: HistogramData{Bounds: []float64{0.1, 1}, Counts: []int64{1, 0, 2}, Sum: 10}},
+		{name: "only +Inf bucket", data: HistogramData{Counts: []int64{3}, Sum: 1}},
+		{name: "wrong count of buckets", data: HistogramData{Bounds: []float64{0.1}, Counts: []int64{1}}, wantErr: true},
+		{name: "bounds are not sorted", data: HistogramData{Bounds: []float64{1, 0.1}, Counts: []int64{1, 1, 1}}, wantErr: true},
+		{name: "infinite bound", data: HistogramData{Bounds: []float64{math.Inf(1)}, Counts: []int64{1, 1}}, wantErr: true},
+		{name: "negative count", data: HistogramData{Bounds: []float64{1}, Counts: []int64{-1, 1}}, wantErr: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHistogramMetricFromData("latency", tt.data)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidHistogram)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseMetric_Histogram(t *testing.T) {
+	m, err := ParseMetric("latency", "0.1:2,0.5:1,+Inf:2,sum:3.45", TypeHistogram)
+	require.NoError(t, err)
+	assert.Equal(t, HistogramData{Bounds: []float64{0.1, 0.5}, Counts: []int64{2, 1, 2}, Sum: 3.45}, m.(Histogram).Data())
+
+	m, err = ParseMetric("latency", "+Inf:2,sum:3", TypeHistogram)
+	require.NoError(t, err)
+	assert.Equal(t, "+Inf:2,sum:3", m.Value())
+
+	for _, value := range []string{"sum:1", "0.1:2,+Inf:1", "0.1:2,0.5:1,sum:1", "a:1,+Inf:1,sum:1", "0.1:b,+Inf:1,sum:1"} {
+		_, err := ParseMetric("latency", value, TypeHistogram)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestFromJSON_Histogram(t *testing.T) {
+	m, err := FromJSON([]byte(`{"id":"latency","type":"histogram","bounds":[0.1,0.5],"counts":[3,2,1],"sum":1.45,"labels":{"host":"a"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, TypeHistogram, m.Type())
+	assert.Equal(t, Labels{"host": "a"}, m.Labels())
+	assert.Equal(t, int64(6), m.Int64())
+
+	b, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"latency","type":"histogram","bounds":[0.1,0.5],"counts":[3,2,1],"sum":1.45,"count":6,"labels":{"host":"a"}}`, string(b))
+
+	_, err = FromJSON([]byte(`{"id":"latency","type":"histogram","bounds":[0.1],"counts":[3,2],"sum":1,"count":4}`))
+	assert.ErrorIs(t, err, ErrInvalidHistogram)
+
+	_, err = FromJSON([]byte(`{"id":"latency","type":"histogram","bounds":[0.1]}`))
+	assert.ErrorIs(t, err, ErrEmptyValue)
+
+	ms, err := FromJSONArray([]byte(`[{"id":"latency","type":"histogram","counts":[1],"sum":0.5},{"id":"c","type":"counter","delta":1}]`))
+	require.NoError(t, err)
+	assert.Len(t, ms, 2)
+}
+
+func TestCreateMergedHistogram(t *testing.T) {
+	a, err := NewHistogramMetricFromData("latency", HistogramData{Bounds: []float64{1}, Counts: []int64{1, 0}, Sum: 0.5})
+	require.NoError(t, err)
+	a.SetLabels(Labels{"host": "a"})
+
+	b, err := NewHistogramMetricFromData("latency", HistogramData{Bounds: []float64{1}, Counts: []int64{0, 2}, Sum: 4})
+	require.NoError(t, err)
+
+	m, err := CreateMergedHistogram("latency", []Metric{a, b})
+	require.NoError(t, err)
+	assert.Equal(t, HistogramData{Bounds: []float64{1}, Counts: []int64{1, 2}, Sum: 4.5}, m.(Histogram).Data())
+	assert.Equal(t, Labels{"host": "a"}, m.Labels())
+
+	// sources are not changed
+	assert.Equal(t, int64(1), a.Int64())
+
+	c, err := NewHistogramMetric("latency", 2)
+	require.NoError(t, err)
+	_, err = CreateMergedHistogram("latency", []Metric{a, c})
+	assert.ErrorIs(t, err, ErrHistogramBoundsMismatch)
+
+	_, err = CreateMergedHistogram("latency", []Metric{a, NewCounterMetric("latency", 1)})
+	assert.Error(t, err)
+}
@@ -0,0 +1,74 @@
+package metric
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Labels is the set of pairs name-value, metrics with the same name and type
+// but with different labels are different series, e.g. the same metric from different hosts
+type Labels map[string]string
+
+// String returns canonical representation of labels sorted by name like host="a",region="b".
+// Empty labels are represented by empty string
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	sb := strings.Builder{}
+	for i, name := range l.Names() {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(l[name]))
+	}
+	return sb.String()
+}
+
+// Names returns sorted names of labels
+func (l Labels) Names() []string {
+	return slices.Sorted(maps.Keys(l))
+}
+
+// Match returns true if labels contain all pairs of filter, empty filter matches any labels
+func (l Labels) Match(filter Labels) bool {
+	for name, value := range filter {
+		if v, ok := l[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal returns true if labels have the same pairs, nil and empty labels are equal
+func (l Labels) Equal(other Labels) bool {
+	return maps.Equal(l, other)
+}
+
+// Clone returns copy of labels, copy of empty labels is nil
+func (l Labels) Clone() Labels {
+	if len(l) == 0 {
+		return nil
+	}
+	return maps.Clone(l)
+}
+
+// SeriesID returns identifier of series which consists of name and labels like name{host="a"}.
+// Identifier of series without labels is its name
+func SeriesID(name string, labels Labels) string {
+	if len(labels) == 0 {
+		return name
+	}
+	return name + "{" + labels.String() + "}"
+}
+
+// WithLabels sets labels to metric and returns it
+func WithLabels(m Metric, labels Labels) Metric {
+	m.SetLabels(labels)
+	return m
+}
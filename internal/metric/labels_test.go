@@ -0,0 +1,62 @@
+package metric
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabels_String(t *testing.T) {
+	assert.Equal(t, "", Labels(nil).String())
+	assert.Equal(t, `host="a",region="eu \"west\""`, Labels{"region": `eu "west"`, "host": "a"}.String())
+}
+
+func TestLabels_Match(t *testing.T) {
+	labels := Labels{"host": "a", "region": "b"}
+
+	assert.True(t, labels.Match(nil))
+	assert.True(t, labels.Match(Labels{"host": "a"}))
+	assert.True(t, labels.Match(Labels{"host": "a", "region": "b"}))
+	assert.False(t, labels.Match(Labels{"host": "b"}))
+	assert.False(t, labels.Match(Labels{"host": "a", "dc": "c"}))
+	assert.False(t, Labels(nil).Match(Labels{"host": "a"}))
+}
+
+func TestLabels_Equal(t *testing.T) {
+	assert.True(t, Labels(nil).Equal(Labels{}))
+	assert.True(t, Labels{"host": "a"}.Equal(Labels{"host": "a"}))
+	assert.False(t, Labels{"host": "a"}.Equal(Labels{"host": "a", "region": "b"}))
+}
+
+func TestSeriesID(t *testing.T) {
+	assert.Equal(t, "cpu", SeriesID("cpu", nil))
+	assert.Equal(t, `cpu{core="1",host="a"}`, SeriesID("cpu", Labels{"host": "a", "core": "1"}))
+}
+
+func TestWithLabels(t *testing.T) {
+	labels := Labels{"host": "a"}
+	m := WithLabels(NewCounterMetric("counter", 1), labels)
+
+	// metric keeps copy of labels
+	labels["host"] = "b"
+	assert.Equal(t, Labels{"host": "a"}, m.Labels())
+
+	assert.Nil(t, WithLabels(NewGaugeMetric("gauge", 1), Labels{}).Labels())
+}
+
+func TestMarshalJSONWithLabels(t *testing.T) {
+	b, err := json.Marshal(WithLabels(NewGaugeMetric("gauge", 1.5), Labels{"host": "a"}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"gauge","type":"gauge","value":1.5,"labels":{"host":"a"}}`, string(b))
+
+	b, err = json.Marshal(NewCounterMetric("counter", 2))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"counter","type":"counter","delta":2}`, string(b))
+
+	ms, err := FromJSONArray([]byte(`[{"id":"counter","type":"counter","delta":2,"labels":{"host":"a"}}]`))
+	require.NoError(t, err)
+	require.Len(t, ms, 1)
+	assert.Equal(t, Labels{"host": "a"}, ms[0].Labels())
+}
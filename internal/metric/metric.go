@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 )
 
 const (
-	TypeGauge   = "gauge"
-	TypeCounter = "counter"
+	TypeGauge     = "gauge"
+	TypeCounter   = "counter"
+	TypeHistogram = "histogram"
+	TypeSummary   = "summary"
 )
 
 // Metric provided way for getting information about metric and change value
@@ -38,6 +41,18 @@ type Metric interface {
 	// representation string likes {type: metric_type; name: metric_name; value: metric_value}
 	String() string
 
+	// Labels returns labels of metric, metric without labels returns nil
+	Labels() Labels
+
+	// SetLabels sets labels to metric
+	SetLabels(Labels)
+
+	// Timestamp returns time when value of metric was observed, zero time means that time is unknown
+	Timestamp() time.Time
+
+	// SetTimestamp sets time when value of metric was observed
+	SetTimestamp(time.Time)
+
 	// MarshalJSON returns json representation of metric
 	MarshalJSON() ([]byte, error)
 }
@@ -48,14 +63,11 @@ func ParseMetric(name, value, metricType string) (Metric, error) {
 	if err := isNotEmpty(name, value); err != nil {
 		return nil, err
 	}
-	switch metricType {
-	case TypeGauge:
-		return parseGauge(name, value)
-	case TypeCounter:
-		return parseCounter(name, value)
-	default:
+	t, ok := LookupType(metricType)
+	if !ok {
 		return nil, ErrUnknownMetricType
 	}
+	return t.Parse(name, value)
 }
 
 // NewGaugeMetric returns new gauge metric
@@ -69,10 +81,11 @@ func NewCounterMetric(name string, value int64) Metric {
 }
 
 // CreateSummedCounter join metrics to one and return this. 
-// New value for metric is sum of value of metrics from slice   
+// New value for metric is sum of value of metrics from slice, labels are taken from the first metric
 func CreateSummedCounter(name string, metrics []Metric) (Metric, error) {
 	var (
 		sum  int64
+		ts   time.Time
 		errs = make([]error, 0)
 	)
 
@@ -80,6 +93,7 @@ func CreateSummedCounter(name string, metrics []Metric) (Metric, error) {
 	for _, c := range metrics {
 		if v, ok := c.(*counter); ok {
 			sum += v.value
+			ts = latest(ts, v.timestamp)
 		} else {
 			errs = append(errs, fmt.Errorf(errFormat, c.Name(), c.Type(), c.Value()))
 		}
@@ -88,97 +102,113 @@ func CreateSummedCounter(name string, metrics []Metric) (Metric, error) {
 	if len(errs) > 0 {
 		return nil, errors.Join(errs...)
 	}
-	return &counter{name: name, value: sum}, nil
+	var labels Labels
+	if len(metrics) > 0 {
+		labels = metrics[0].Labels().Clone()
+	}
+	return &counter{name: name, value: sum, labels: labels, stamp: stamp{ts}}, nil
 }
 
 // FromJSON parse metric from json string and return Metric or error
 func FromJSON(content []byte) (Metric, error) {
-	object := struct {
-		ID    string   `json:"id"`
-		MType string   `json:"type"`
-		Delta *int64   `json:"delta,omitempty"`
-		Value *float64 `json:"value,omitempty"`
-	}{}
-
+	object := jsonHeader{}
 	if err := json.Unmarshal(content, &object); err != nil {
 		return nil, errors.Join(ErrInvalidMetric, err)
 	} else if object.ID == "" {
 		return nil, ErrInvalidMetric
 	}
 
-	if object.MType == TypeGauge {
-		return createGaugeMetric(object.ID, object.Value)
-	} else if object.MType == TypeCounter {
-		return createCounterMetric(object.ID, object.Delta)
-	} else {
+	t, ok := LookupType(object.MType)
+	if !ok {
 		return nil, ErrUnknownMetricType
 	}
+	return object.decode(t, content)
 }
 
 // FromJSONArray parse metrics from json array and return slice of Metric or error
 func FromJSONArray(content []byte) ([]Metric, error) {
 	rs := make([]Metric, 0)
 	errs := make([]error, 0)
-	objects := []struct {
-		ID    string   `json:"id"`
-		MType string   `json:"type"`
-		Delta *int64   `json:"delta,omitempty"`
-		Value *float64 `json:"value,omitempty"`
-	}{}
+	objects := []json.RawMessage{}
 
 	if err := json.Unmarshal(content, &objects); err != nil {
 		return nil, errors.Join(ErrInvalidMetric, err)
 	}
 
-	for _, object := range objects {
+	for _, raw := range objects {
+		object := jsonHeader{}
+		if err := json.Unmarshal(raw, &object); err != nil {
+			errs = append(errs, errors.Join(ErrInvalidMetric, err))
+			continue
+		}
+
 		if object.ID == "" {
-			errs = append(errs, errors.Join(ErrInvalidMetric, fmt.Errorf("%v", object)))
+			errs = append(errs, errors.Join(ErrInvalidMetric, fmt.Errorf("%s", raw)))
+			continue
+		}
+
+		t, ok := LookupType(object.MType)
+		if !ok {
+			errs = append(errs, errors.Join(ErrUnknownMetricType, fmt.Errorf("%s", raw)))
+			continue
 		}
 
-		if object.MType == TypeGauge {
-			if m, err := createGaugeMetric(object.ID, object.Value); err == nil {
-				rs = append(rs, m)
-			} else {
-				errs = append(errs, errors.Join(err, fmt.Errorf("%v", object)))
-			}
-		} else if object.MType == TypeCounter {
-			if m, err := createCounterMetric(object.ID, object.Delta); err == nil {
-				rs = append(rs, m)
-			} else {
-				errs = append(errs, errors.Join(err, fmt.Errorf("%v", object)))
-			}
+		if m, err := object.decode(t, raw); err == nil {
+			rs = append(rs, m)
 		} else {
-			errs = append(errs, errors.Join(ErrUnknownMetricType, fmt.Errorf("%v", object)))
+			errs = append(errs, errors.Join(err, fmt.Errorf("%s", raw)))
 		}
 	}
 
 	return rs, errors.Join(errs...)
 }
 
-func createGaugeMetric(name string, value *float64) (Metric, error) {
-	var (
-		v   float64
-		err error
-	)
-	if value != nil {
-		v = *value
-	} else {
-		err = ErrEmptyValue
+// jsonHeader is the common part of json representation of metrics which defines type of metric
+type jsonHeader struct {
+	ID        string     `json:"id"`
+	MType     string     `json:"type"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+// decode decodes metric by its type and sets timestamp which is common for all types
+func (h jsonHeader) decode(t TypeInfo, content []byte) (Metric, error) {
+	m, err := t.DecodeJSON(content)
+	if m != nil && h.Timestamp != nil {
+		m.SetTimestamp(*h.Timestamp)
 	}
-	return NewGaugeMetric(name, v), err
+	return m, err
 }
 
-func createCounterMetric(name string, value *int64) (Metric, error) {
-	var (
-		v   int64
-		err error
-	)
-	if value != nil {
-		v = *value
-	} else {
-		err = ErrEmptyValue
+// stamp keeps time when value of metric was observed, it is embedded to all types of metrics
+type stamp struct {
+	timestamp time.Time
+}
+
+// Timestamp returns time when value of metric was observed, zero time means that time is unknown
+func (s stamp) Timestamp() time.Time {
+	return s.timestamp
+}
+
+// SetTimestamp sets time when value of metric was observed
+func (s *stamp) SetTimestamp(t time.Time) {
+	s.timestamp = t
+}
+
+// jsonTimestamp returns timestamp for json representation, unknown timestamp is omitted
+func (s stamp) jsonTimestamp() *time.Time {
+	if s.timestamp.IsZero() {
+		return nil
+	}
+	t := s.timestamp
+	return &t
+}
+
+// latest returns the latest of two timestamps
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
 	}
-	return NewCounterMetric(name, v), err
+	return a
 }
 
 func isNotEmpty(name, value string) error {
@@ -5,6 +5,7 @@ import (
 	"math/rand/v2"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -395,6 +396,18 @@ func TestFromJSON(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "correct gauge metric with labels",
+			args: args{
+				content: []byte(`{"id":"test1","type":"gauge","value": 1.5,"labels":{"host":"a"}}`),
+			},
+			want: &gauge{
+				name:   "test1",
+				value:  1.5,
+				labels: Labels{"host": "a"},
+			},
+			wantErr: false,
+		},
 		{
 			name: "uncorrected gauge metric",
 			args: args{
@@ -644,3 +657,38 @@ func Benchmark_ParseMetric(b *testing.B) {
 		}
 	}
 }
+
+func TestFromJSON_Timestamp(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, m := range []Metric{
+		NewGaugeMetric("gauge1", 1.5),
+		NewCounterMetric("counter1", 2),
+		NewSummaryMetric("summary1", 1, 2),
+	} {
+		m.SetTimestamp(ts)
+		content, err := m.MarshalJSON()
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), `"timestamp":"2024-01-01T00:00:00Z"`)
+
+		got, err := FromJSON(content)
+		assert.NoError(t, err)
+		assert.True(t, ts.Equal(got.Timestamp()), m.Type())
+	}
+
+	// metric without timestamp does not have it in json
+	content, err := NewGaugeMetric("gauge1", 1.5).MarshalJSON()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "timestamp")
+
+	metrics, err := FromJSONArray([]byte(`[{"id":"c","type":"counter","delta":1,"timestamp":"2024-01-01T00:00:00Z"},{"id":"c","type":"counter","delta":2}]`))
+	assert.NoError(t, err)
+	assert.True(t, ts.Equal(metrics[0].Timestamp()))
+	assert.True(t, metrics[1].Timestamp().IsZero())
+
+	// summed counter takes the latest timestamp
+	metrics[1].SetTimestamp(ts.Add(time.Minute))
+	sum, err := CreateSummedCounter("c", metrics)
+	assert.NoError(t, err)
+	assert.True(t, ts.Add(time.Minute).Equal(sum.Timestamp()))
+}
@@ -0,0 +1,9 @@
+package metric
+
+import "time"
+
+// Point is the value of metric at the moment of time
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
@@ -0,0 +1,135 @@
+package metric
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Aggregation defines how values of the same series are combined
+type Aggregation int
+
+const (
+	// AggregateLast keeps only the last value of series, e.g. gauge
+	AggregateLast Aggregation = iota
+	// AggregateSum keeps all values of series, value of series is their sum, e.g. counter
+	AggregateSum
+	// AggregateMerge merges new value into the stored one, e.g. histogram
+	AggregateMerge
+)
+
+// TypeInfo describes type of metric. Registered type is supported by parsing, decoding from json,
+// storages and services without changing them
+type TypeInfo struct {
+	// Name is the type of metric which is used by API, e.g. gauge
+	Name string
+
+	// Code is the short stable identifier of type which is used for compact persisting, e.g. in dump files
+	Code string
+
+	// Aggregation defines how values of the same series are combined
+	Aggregation Aggregation
+
+	// Parse creates metric from text representation which is returned by Metric.Value
+	Parse func(name, value string) (Metric, error)
+
+	// DecodeJSON creates metric from json object, the object has fields id, type and labels
+	// and fields of value which depend on type. If object does not have value,
+	// metric with name and labels is returned with ErrEmptyValue
+	DecodeJSON func(content []byte) (Metric, error)
+
+	// Merge joins values to one metric, labels are taken from the first metric.
+	// Result must not share state with sources. It is required for AggregateSum and AggregateMerge
+	Merge func(name string, metrics []Metric) (Metric, error)
+}
+
+func (t TypeInfo) validate() error {
+	if t.Name == "" || t.Code == "" {
+		return errors.New("name and code of type are required")
+	}
+	if t.Parse == nil || t.DecodeJSON == nil {
+		return errors.New("parser and json decoder are required")
+	}
+	if t.Aggregation != AggregateLast && t.Merge == nil {
+		return fmt.Errorf("merge is required for type %s", t.Name)
+	}
+	return nil
+}
+
+type typeRegistry struct {
+	mx     *sync.RWMutex
+	byName map[string]TypeInfo
+	byCode map[string]TypeInfo
+	names  []string
+}
+
+var registry = newTypeRegistry(gaugeType, counterType, histogramType, summaryType)
+
+func newTypeRegistry(types ...TypeInfo) *typeRegistry {
+	r := &typeRegistry{
+		mx:     &sync.RWMutex{},
+		byName: make(map[string]TypeInfo, len(types)),
+		byCode: make(map[string]TypeInfo, len(types)),
+		names:  make([]string, 0, len(types)),
+	}
+	for _, t := range types {
+		if err := r.register(t); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+func (r *typeRegistry) register(t TypeInfo) error {
+	if err := t.validate(); err != nil {
+		return errors.Join(ErrInvalidType, err)
+	}
+
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if _, ok := r.byName[t.Name]; ok {
+		return errors.Join(ErrTypeIsRegistered, fmt.Errorf("name %s", t.Name))
+	}
+	if _, ok := r.byCode[t.Code]; ok {
+		return errors.Join(ErrTypeIsRegistered, fmt.Errorf("code %s", t.Code))
+	}
+
+	r.byName[t.Name] = t
+	r.byCode[t.Code] = t
+	r.names = append(r.names, t.Name)
+	return nil
+}
+
+// RegisterType registers new type of metric,
+// returns error if type is not valid or type with the same name or code is registered
+func RegisterType(t TypeInfo) error {
+	return registry.register(t)
+}
+
+// LookupType returns registered type by name
+func LookupType(name string) (TypeInfo, bool) {
+	registry.mx.RLock()
+	defer registry.mx.RUnlock()
+
+	t, ok := registry.byName[name]
+	return t, ok
+}
+
+// LookupTypeByCode returns registered type by code
+func LookupTypeByCode(code string) (TypeInfo, bool) {
+	registry.mx.RLock()
+	defer registry.mx.RUnlock()
+
+	t, ok := registry.byCode[code]
+	return t, ok
+}
+
+// Types returns names of registered types in order of registration
+func Types() []string {
+	registry.mx.RLock()
+	defer registry.mx.RUnlock()
+
+	return slices.Clone(registry.names)
+}
@@ -0,0 +1,117 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rate is the custom type for tests, it is the gauge which is summed by series
+type rate struct {
+	Metric
+}
+
+func (r rate) Type() string {
+	return "rate"
+}
+
+var rateType = TypeInfo{
+	Name:        "rate",
+	Code:        "r",
+	Aggregation: AggregateSum,
+	Parse: func(name, value string) (Metric, error) {
+		m, err := parseGauge(name, value)
+		if err != nil {
+			return nil, err
+		}
+		return rate{m}, nil
+	},
+	DecodeJSON: func(content []byte) (Metric, error) {
+		m, err := decodeGauge(content)
+		if m == nil {
+			return nil, err
+		}
+		return rate{m}, err
+	},
+	Merge: func(name string, metrics []Metric) (Metric, error) {
+		var sum float64
+		for _, m := range metrics {
+			sum += m.Float64()
+		}
+		return rate{WithLabels(NewGaugeMetric(name, sum), metrics[0].Labels().Clone())}, nil
+	},
+}
+
+// withTestRegistry replaces global registry by registry with built-in types for the test
+func withTestRegistry(t *testing.T) {
+	prev := registry
+	registry = newTypeRegistry(gaugeType, counterType, histogramType, summaryType)
+	t.Cleanup(func() { registry = prev })
+}
+
+func TestRegisterType(t *testing.T) {
+	withTestRegistry(t)
+
+	require.NoError(t, RegisterType(rateType))
+
+	info, ok := LookupType("rate")
+	require.True(t, ok)
+	assert.Equal(t, AggregateSum, info.Aggregation)
+
+	info, ok = LookupTypeByCode("r")
+	require.True(t, ok)
+	assert.Equal(t, "rate", info.Name)
+
+	assert.Equal(t, []string{TypeGauge, TypeCounter, TypeHistogram, TypeSummary, "rate"}, Types())
+
+	m, err := ParseMetric("requests", "1.5", "rate")
+	require.NoError(t, err)
+	assert.Equal(t, "rate", m.Type())
+	assert.Equal(t, 1.5, m.Float64())
+
+	m, err = FromJSON([]byte(`{"id":"requests","type":"rate","value":2.5,"labels":{"host":"a"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "rate", m.Type())
+	assert.Equal(t, Labels{"host": "a"}, m.Labels())
+
+	ms, err := FromJSONArray([]byte(`[{"id":"requests","type":"rate","value":2.5},{"id":"bytes","type":"bytes","value":1}]`))
+	assert.ErrorIs(t, err, ErrUnknownMetricType)
+	require.Len(t, ms, 1)
+	assert.Equal(t, "rate", ms[0].Type())
+}
+
+func TestRegisterType_invalid(t *testing.T) {
+	withTestRegistry(t)
+
+	withoutMerge := rateType
+	withoutMerge.Name, withoutMerge.Code, withoutMerge.Merge = "rate2", "r2", nil
+
+	withoutParser := rateType
+	withoutParser.Name, withoutParser.Code, withoutParser.Parse = "rate3", "r3", nil
+
+	withoutCode := rateType
+	withoutCode.Code = ""
+
+	sameCode := rateType
+	sameCode.Name, sameCode.Code = "rate4", "0"
+
+	testCases := []struct {
+		name string
+		info TypeInfo
+		want error
+	}{
+		{name: "merge is required for sum", info: withoutMerge, want: ErrInvalidType},
+		{name: "parser is required", info: withoutParser, want: ErrInvalidType},
+		{name: "code is required", info: withoutCode, want: ErrInvalidType},
+		{name: "name is registered", info: gaugeType, want: ErrTypeIsRegistered},
+		{name: "code is registered", info: sameCode, want: ErrTypeIsRegistered},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, RegisterType(tt.info), tt.want)
+		})
+	}
+
+	assert.Equal(t, []string{TypeGauge, TypeCounter, TypeHistogram, TypeSummary}, Types())
+}
@@ -0,0 +1,500 @@
+package metric
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SummaryRelativeAccuracy is the maximal relative error of quantiles which are calculated by summary
+const SummaryRelativeAccuracy = 0.01
+
+// minSummaryValue is the smallest absolute value which is not counted as zero
+const minSummaryValue = 1e-9
+
+// DefaultQuantiles are quantiles which are shown for summary
+var DefaultQuantiles = []float64{0.5, 0.9, 0.99}
+
+var (
+	summaryGamma    = (1 + SummaryRelativeAccuracy) / (1 - SummaryRelativeAccuracy)
+	summaryLogGamma = math.Log(summaryGamma)
+)
+
+// Summary is the metric which keeps approximate distribution of observations for calculation of quantiles.
+// Observations are kept in sketch with logarithmic buckets, so summaries from different reports
+// are merged without losing accuracy
+type Summary interface {
+	Metric
+
+	// Data returns copy of sketch and sum of observations
+	Data() SummaryData
+
+	// Observe adds observation to the sketch, NaN and infinite values are ignored
+	Observe(float64)
+
+	// Merge adds observations of other summary
+	Merge(SummaryData) error
+
+	// Quantile returns approximate quantile of observations, q must be in [0, 1].
+	// Returns NaN if summary does not have observations
+	Quantile(q float64) float64
+}
+
+// SummaryData is the sketch of observations.
+// Positive and Negative are counts of observations by index of bucket,
+// bucket with index i keeps absolute values in (gamma^(i-1), gamma^i],
+// where gamma is defined by SummaryRelativeAccuracy.
+// Zero is count of observations which are too close to zero
+type SummaryData struct {
+	Positive map[int32]int64
+	Negative map[int32]int64
+	Zero     int64
+	Sum      float64
+}
+
+// Count returns count of all observations
+func (d SummaryData) Count() int64 {
+	count := d.Zero
+	for _, c := range d.Positive {
+		count += c
+	}
+	for _, c := range d.Negative {
+		count += c
+	}
+	return count
+}
+
+// Clone returns deep copy of data
+func (d SummaryData) Clone() SummaryData {
+	return SummaryData{
+		Positive: maps.Clone(d.Positive),
+		Negative: maps.Clone(d.Negative),
+		Zero:     d.Zero,
+		Sum:      d.Sum,
+	}
+}
+
+func (d SummaryData) validate() error {
+	for _, bins := range []map[int32]int64{d.Positive, d.Negative} {
+		for _, c := range bins {
+			if c < 0 {
+				return errors.Join(ErrInvalidSummary, errors.New("count of bucket is negative"))
+			}
+		}
+	}
+	if d.Zero < 0 {
+		return errors.Join(ErrInvalidSummary, errors.New("count of zero bucket is negative"))
+	}
+	if math.IsNaN(d.Sum) {
+		return errors.Join(ErrInvalidSummary, errors.New("sum is NaN"))
+	}
+	return nil
+}
+
+// summaryIndex returns index of bucket for positive value
+func summaryIndex(v float64) int32 {
+	return int32(math.Ceil(math.Log(v) / summaryLogGamma))
+}
+
+// summaryValue returns value of bucket, relative error of this is not greater than SummaryRelativeAccuracy
+func summaryValue(i int32) float64 {
+	return 2 * math.Pow(summaryGamma, float64(i)) / (1 + summaryGamma)
+}
+
+// summaryType describes summary for registry of types
+var summaryType = TypeInfo{
+	Name:        TypeSummary,
+	Code:        "3",
+	Aggregation: AggregateMerge,
+	Parse:       parseSummary,
+	DecodeJSON:  decodeSummary,
+	Merge:       CreateMergedSummary,
+}
+
+type summary struct {
+	name   string
+	data   SummaryData
+	labels Labels
+	stamp
+}
+
+var _ Summary = (*summary)(nil)
+
+// NewSummaryMetric returns new summary metric with observations
+func NewSummaryMetric(name string, values ...float64) Summary {
+	s := &summary{name: name}
+	for _, v := range values {
+		s.Observe(v)
+	}
+	return s
+}
+
+// NewSummaryMetricFromData returns new summary metric with copy of data
+func NewSummaryMetricFromData(name string, data SummaryData) (Summary, error) {
+	return newSummary(name, data.Clone())
+}
+
+func newSummary(name string, data SummaryData) (*summary, error) {
+	if err := data.validate(); err != nil {
+		return nil, err
+	}
+	return &summary{name: name, data: data}, nil
+}
+
+// Name returns name of metric
+func (s summary) Name() string {
+	return s.name
+}
+
+// Value returns sketch and sum as string like n3:1,z:2,p-5:3,p12:1,sum:1.45
+// where n and p are buckets of negative and positive observations with their indexes,
+// z is bucket of observations which are close to zero
+func (s summary) Value() string {
+	sb := strings.Builder{}
+	for _, i := range slices.Backward(slices.Sorted(maps.Keys(s.data.Negative))) {
+		writeSummaryBucket(&sb, "n"+strconv.FormatInt(int64(i), 10), s.data.Negative[i])
+	}
+	if s.data.Zero > 0 {
+		writeSummaryBucket(&sb, "z", s.data.Zero)
+	}
+	for _, i := range slices.Sorted(maps.Keys(s.data.Positive)) {
+		writeSummaryBucket(&sb, "p"+strconv.FormatInt(int64(i), 10), s.data.Positive[i])
+	}
+	sb.WriteString("sum:")
+	sb.WriteString(strconv.FormatFloat(s.data.Sum, 'f', -1, 64))
+	return sb.String()
+}
+
+func writeSummaryBucket(sb *strings.Builder, key string, count int64) {
+	sb.WriteString(key)
+	sb.WriteByte(':')
+	sb.WriteString(strconv.FormatInt(count, 10))
+	sb.WriteByte(',')
+}
+
+// Labels returns labels of metric
+func (s summary) Labels() Labels {
+	return s.labels
+}
+
+// SetLabels sets copy of labels to metric
+func (s *summary) SetLabels(labels Labels) {
+	s.labels = labels.Clone()
+}
+
+// Type returns type of metric
+func (s summary) Type() string {
+	return TypeSummary
+}
+
+// Data returns copy of sketch and sum of observations
+func (s summary) Data() SummaryData {
+	return s.data.Clone()
+}
+
+// Observe adds observation to the sketch, NaN and infinite values are ignored
+func (s *summary) Observe(v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+
+	switch {
+	case v > minSummaryValue:
+		if s.data.Positive == nil {
+			s.data.Positive = make(map[int32]int64)
+		}
+		s.data.Positive[summaryIndex(v)]++
+	case v < -minSummaryValue:
+		if s.data.Negative == nil {
+			s.data.Negative = make(map[int32]int64)
+		}
+		s.data.Negative[summaryIndex(-v)]++
+	default:
+		s.data.Zero++
+	}
+	s.data.Sum += v
+}
+
+// Merge adds observations of other summary
+func (s *summary) Merge(other SummaryData) error {
+	if err := other.validate(); err != nil {
+		return err
+	}
+	s.data.Positive = mergeSummaryBuckets(s.data.Positive, other.Positive)
+	s.data.Negative = mergeSummaryBuckets(s.data.Negative, other.Negative)
+	s.data.Zero += other.Zero
+	s.data.Sum += other.Sum
+	return nil
+}
+
+func mergeSummaryBuckets(dst, src map[int32]int64) map[int32]int64 {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[int32]int64, len(src))
+	}
+	for i, c := range src {
+		dst[i] += c
+	}
+	return dst
+}
+
+// Quantile returns approximate quantile of observations, q must be in [0, 1].
+// Returns NaN if summary does not have observations
+func (s summary) Quantile(q float64) float64 {
+	count := s.data.Count()
+	if count == 0 || math.IsNaN(q) {
+		return math.NaN()
+	}
+
+	rank := math.Max(0, math.Min(q, 1)) * float64(count-1)
+
+	var passed int64
+	// observations are walked in increasing order: negative from the biggest absolute value, zero, positive
+	for _, i := range slices.Backward(slices.Sorted(maps.Keys(s.data.Negative))) {
+		if passed += s.data.Negative[i]; float64(passed) > rank {
+			return -summaryValue(i)
+		}
+	}
+	if passed += s.data.Zero; float64(passed) > rank {
+		return 0
+	}
+
+	positive := slices.Sorted(maps.Keys(s.data.Positive))
+	for _, i := range positive {
+		if passed += s.data.Positive[i]; float64(passed) > rank {
+			return summaryValue(i)
+		}
+	}
+	// unreachable while counts are not negative, but keep the biggest value for safety
+	return summaryValue(positive[len(positive)-1])
+}
+
+// AddValue adds observation to metric, accept float64, int64 or int.
+// SummaryData is merged with observations of metric
+func (s *summary) AddValue(val any) error {
+	switch v := val.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return errors.Join(ErrInvalidSummary, fmt.Errorf("observation %v is not finite", v))
+		}
+		s.Observe(v)
+	case int64:
+		s.Observe(float64(v))
+	case int:
+		s.Observe(float64(v))
+	case SummaryData:
+		return s.Merge(v)
+	default:
+		return fmt.Errorf("value is %T, expect float64, int64 or SummaryData", val)
+	}
+	return nil
+}
+
+// SetValue replaces observations of metric, accept SummaryData
+func (s *summary) SetValue(val any) error {
+	v, ok := val.(SummaryData)
+	if !ok {
+		return fmt.Errorf("value is %T, expect SummaryData", val)
+	}
+	if err := v.validate(); err != nil {
+		return err
+	}
+	s.data = v.Clone()
+	return nil
+}
+
+// Float64 returns sum of observations
+func (s summary) Float64() float64 {
+	return s.data.Sum
+}
+
+// Int64 returns count of observations
+func (s summary) Int64() int64 {
+	return s.data.Count()
+}
+
+// String returns string representation of metric
+// representation string likes {type: metric_type; name: metric_name; value: metric_value}
+// labels are added to representation if metric has them
+func (s summary) String() string {
+	if len(s.labels) > 0 {
+		return fmt.Sprintf("{type: %s; name: %s; value: %s; labels: %s}", s.Type(), s.name, s.Value(), s.labels)
+	}
+	return fmt.Sprintf("{type: %s; name: %s; value: %s}", s.Type(), s.name, s.Value())
+}
+
+// summarySketch is json representation of buckets of summary
+type summarySketch struct {
+	Positive map[int32]int64 `json:"positive,omitempty"`
+	Negative map[int32]int64 `json:"negative,omitempty"`
+	Zero     int64           `json:"zero,omitempty"`
+}
+
+// MarshalJSON returns json representation of metric, quantiles are added if summary has observations
+func (s summary) MarshalJSON() ([]byte, error) {
+	metric := struct {
+		ID        string             `json:"id"`
+		MType     string             `json:"type"`
+		Sketch    summarySketch      `json:"sketch"`
+		Sum       float64            `json:"sum"`
+		Count     int64              `json:"count"`
+		Quantiles map[string]float64 `json:"quantiles,omitempty"`
+		Labels    Labels             `json:"labels,omitempty"`
+		Timestamp *time.Time         `json:"timestamp,omitempty"`
+	}{
+		ID:    s.name,
+		MType: s.Type(),
+		Sketch: summarySketch{
+			Positive: s.data.Positive,
+			Negative: s.data.Negative,
+			Zero:     s.data.Zero,
+		},
+		Sum:       s.data.Sum,
+		Count:     s.data.Count(),
+		Labels:    s.labels,
+		Timestamp: s.jsonTimestamp(),
+	}
+	if metric.Count > 0 {
+		metric.Quantiles = make(map[string]float64, len(DefaultQuantiles))
+		for _, q := range DefaultQuantiles {
+			metric.Quantiles[strconv.FormatFloat(q, 'f', -1, 64)] = s.Quantile(q)
+		}
+	}
+	return json.Marshal(metric)
+}
+
+// parseSummary parses value in format of summary.Value, single number is parsed as one observation
+func parseSummary(name string, value string) (Metric, error) {
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		s := &summary{name: name}
+		if err := s.AddValue(v); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	parts := strings.Split(value, ",")
+	last := parts[len(parts)-1]
+	if !strings.HasPrefix(last, "sum:") {
+		return nil, ErrConvertingRawValue
+	}
+
+	sum, err := strconv.ParseFloat(strings.TrimPrefix(last, "sum:"), 64)
+	if err != nil {
+		return nil, errors.Join(err, ErrConvertingRawValue)
+	}
+
+	data := SummaryData{Sum: sum}
+	for _, bucket := range parts[:len(parts)-1] {
+		key, rawCount, ok := strings.Cut(bucket, ":")
+		if !ok || key == "" {
+			return nil, ErrConvertingRawValue
+		}
+
+		count, err := strconv.ParseInt(rawCount, 10, 64)
+		if err != nil {
+			return nil, errors.Join(err, ErrConvertingRawValue)
+		}
+
+		if key == "z" {
+			data.Zero += count
+			continue
+		}
+
+		i, err := strconv.ParseInt(key[1:], 10, 32)
+		if err != nil {
+			return nil, errors.Join(err, ErrConvertingRawValue)
+		}
+
+		switch key[0] {
+		case 'p':
+			data.Positive = mergeSummaryBuckets(data.Positive, map[int32]int64{int32(i): count})
+		case 'n':
+			data.Negative = mergeSummaryBuckets(data.Negative, map[int32]int64{int32(i): count})
+		default:
+			return nil, errors.Join(ErrConvertingRawValue, fmt.Errorf("unknown bucket %s", key))
+		}
+	}
+
+	s, err := newSummary(name, data)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func decodeSummary(content []byte) (Metric, error) {
+	object := struct {
+		ID     string         `json:"id"`
+		Value  *float64       `json:"value,omitempty"`
+		Sketch *summarySketch `json:"sketch,omitempty"`
+		Sum    *float64       `json:"sum,omitempty"`
+		Count  *int64         `json:"count,omitempty"`
+		Labels Labels         `json:"labels,omitempty"`
+	}{}
+	if err := json.Unmarshal(content, &object); err != nil {
+		return nil, errors.Join(ErrInvalidMetric, err)
+	}
+	return createSummaryMetric(object.ID, object.Sketch, object.Value, object.Sum, object.Count, object.Labels)
+}
+
+// createSummaryMetric creates summary from sketch or from single observation
+func createSummaryMetric(name string, sketch *summarySketch, value, sum *float64, count *int64, labels Labels) (Metric, error) {
+	if sketch == nil {
+		if value == nil {
+			return &summary{name: name, labels: labels.Clone()}, ErrEmptyValue
+		}
+		s := &summary{name: name, labels: labels.Clone()}
+		return s, s.AddValue(*value)
+	}
+
+	if sum == nil {
+		return &summary{name: name, labels: labels.Clone()}, ErrEmptyValue
+	}
+
+	s, err := newSummary(name, SummaryData{
+		Positive: maps.Clone(sketch.Positive),
+		Negative: maps.Clone(sketch.Negative),
+		Zero:     sketch.Zero,
+		Sum:      *sum,
+	})
+	if err != nil {
+		return &summary{name: name, labels: labels.Clone()}, err
+	}
+	if count != nil && *count != s.data.Count() {
+		return s, errors.Join(ErrInvalidSummary, errors.New("count does not match counts of buckets"))
+	}
+	s.labels = labels.Clone()
+	return s, nil
+}
+
+// CreateMergedSummary joins summaries to one and return this, labels are taken from the first metric
+func CreateMergedSummary(name string, metrics []Metric) (Metric, error) {
+	if len(metrics) == 0 {
+		return nil, ErrEmptyValue
+	}
+
+	rs := &summary{name: name}
+	for i, m := range metrics {
+		s, ok := m.(Summary)
+		if !ok {
+			return nil, fmt.Errorf("metric { name: %s; type: %s; value: %s} is not a summary", m.Name(), m.Type(), m.Value())
+		}
+		if i == 0 {
+			rs.labels = s.Labels().Clone()
+		}
+		if err := rs.Merge(s.Data()); err != nil {
+			return nil, err
+		}
+		rs.timestamp = latest(rs.timestamp, s.Timestamp())
+	}
+	return rs, nil
+}
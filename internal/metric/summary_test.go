@@ -0,0 +1,131 @@
+package metric
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummary_Quantile(t *testing.T) {
+	s := NewSummaryMetric("latency")
+	for i := 1; i <= 1000; i++ {
+		s.Observe(float64(i))
+	}
+
+	for _, q := range []float64{0, 0.5, 0.9, 0.99, 1} {
+		want := 1 + q*999
+		assert.InEpsilon(t, want, s.Quantile(q), SummaryRelativeAccuracy, "quantile %v", q)
+	}
+	assert.Equal(t, int64(1000), s.Int64())
+	assert.Equal(t, float64(500500), s.Float64())
+
+	assert.True(t, math.IsNaN(NewSummaryMetric("empty").Quantile(0.5)))
+}
+
+func TestSummary_QuantileWithNegativeAndZero(t *testing.T) {
+	s := NewSummaryMetric("delta", -100, -10, 0, 0, 10)
+
+	assert.InEpsilon(t, -100, s.Quantile(0), SummaryRelativeAccuracy)
+	assert.InEpsilon(t, -10, s.Quantile(0.25), SummaryRelativeAccuracy)
+	assert.Equal(t, float64(0), s.Quantile(0.5))
+	assert.InEpsilon(t, 10, s.Quantile(1), SummaryRelativeAccuracy)
+}
+
+func TestSummary_Merge(t *testing.T) {
+	a := NewSummaryMetric("latency")
+	b := NewSummaryMetric("latency")
+	all := NewSummaryMetric("latency")
+	for i := 1; i <= 100; i++ {
+		if i%3 == 0 {
+			a.Observe(float64(i))
+		} else {
+			b.Observe(float64(i))
+		}
+		all.Observe(float64(i))
+	}
+
+	require.NoError(t, a.AddValue(b.Data()))
+	assert.Equal(t, all.Data(), a.Data())
+	assert.Equal(t, all.Quantile(0.9), a.Quantile(0.9))
+
+	err := a.Merge(SummaryData{Zero: -1})
+	assert.ErrorIs(t, err, ErrInvalidSummary)
+
+	assert.ErrorIs(t, a.AddValue(math.NaN()), ErrInvalidSummary)
+}
+
+func TestParseMetric_Summary(t *testing.T) {
+	m, err := ParseMetric("latency", "n3:1,z:2,p-5:3,p12:1,sum:1.45", TypeSummary)
+	require.NoError(t, err)
+	assert.Equal(t, SummaryData{
+		Positive: map[int32]int64{-5: 3, 12: 1},
+		Negative: map[int32]int64{3: 1},
+		Zero:     2,
+		Sum:      1.45,
+	}, m.(Summary).Data())
+	assert.Equal(t, "n3:1,z:2,p-5:3,p12:1,sum:1.45", m.Value())
+
+	// single number is one observation
+	m, err = ParseMetric("latency", "2", TypeSummary)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), m.Int64())
+	assert.Equal(t, float64(2), m.Float64())
+
+	for _, value := range []string{"p1:1", "x1:1,sum:1", "pa:1,sum:1", "p1:b,sum:1", "p1:-1,sum:1", "Inf"} {
+		_, err := ParseMetric("latency", value, TypeSummary)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestFromJSON_Summary(t *testing.T) {
+	m, err := FromJSON([]byte(`{"id":"latency","type":"summary","sketch":{"positive":{"0":2},"zero":1},"sum":2,"labels":{"host":"a"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, TypeSummary, m.Type())
+	assert.Equal(t, Labels{"host": "a"}, m.Labels())
+	assert.Equal(t, int64(3), m.Int64())
+
+	b, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"latency","type":"summary","sketch":{"positive":{"0":2},"zero":1},"sum":2,"count":3,
+		"quantiles":{"0.5":`+formatJSONFloat(summaryValue(0))+`,"0.9":`+formatJSONFloat(summaryValue(0))+`,"0.99":`+formatJSONFloat(summaryValue(0))+`},
+		"labels":{"host":"a"}}`, string(b))
+
+	m, err = FromJSON([]byte(`{"id":"latency","type":"summary","value":1.5}`))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), m.Int64())
+
+	_, err = FromJSON([]byte(`{"id":"latency","type":"summary","sketch":{"positive":{"0":2}},"sum":2,"count":3}`))
+	assert.ErrorIs(t, err, ErrInvalidSummary)
+
+	_, err = FromJSON([]byte(`{"id":"latency","type":"summary"}`))
+	assert.ErrorIs(t, err, ErrEmptyValue)
+
+	ms, err := FromJSONArray([]byte(`[{"id":"latency","type":"summary","value":1},{"id":"c","type":"counter","delta":1}]`))
+	require.NoError(t, err)
+	assert.Len(t, ms, 2)
+}
+
+func TestCreateMergedSummary(t *testing.T) {
+	a := NewSummaryMetric("latency", 1, 2)
+	a.SetLabels(Labels{"host": "a"})
+	b := NewSummaryMetric("latency", 3)
+
+	m, err := CreateMergedSummary("latency", []Metric{a, b})
+	require.NoError(t, err)
+	assert.Equal(t, NewSummaryMetric("latency", 1, 2, 3).Data(), m.(Summary).Data())
+	assert.Equal(t, Labels{"host": "a"}, m.Labels())
+
+	// sources are not changed
+	assert.Equal(t, int64(2), a.Int64())
+
+	_, err = CreateMergedSummary("latency", []Metric{a, NewCounterMetric("latency", 1)})
+	assert.Error(t, err)
+}
+
+func formatJSONFloat(v float64) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
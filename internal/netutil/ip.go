@@ -0,0 +1,17 @@
+package netutil
+
+import (
+	"net"
+)
+
+// OutboundIP returns local ip address which is used for connection to host.
+// host must be in format host:port, packets are not sent, connection is used only for choosing of route
+func OutboundIP(host string) (net.IP, error) {
+	conn, err := net.Dial("udp", host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
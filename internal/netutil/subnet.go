@@ -0,0 +1,37 @@
+package netutil
+
+import (
+	"net"
+	"strings"
+)
+
+// IsTrustedIP returns true if ip belongs any of subnets
+func IsTrustedIP(ip net.IP, subnets []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, subnet := range subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSubnets parses comma separated list of subnets in CIDR notation e.g. "192.168.1.0/24,10.0.0.0/8"
+func ParseSubnets(cidrs string) ([]*net.IPNet, error) {
+	rs := make([]*net.IPNet, 0)
+	for _, cidr := range strings.Split(cidrs, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, subnet)
+	}
+	return rs, nil
+}
@@ -0,0 +1,33 @@
+package netutil
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTrustedIP(t *testing.T) {
+	subnets, err := ParseSubnets("192.168.1.0/24, 10.0.0.0/8")
+	require.NoError(t, err)
+
+	assert.True(t, IsTrustedIP(net.ParseIP("192.168.1.15"), subnets))
+	assert.True(t, IsTrustedIP(net.ParseIP("10.1.2.3"), subnets))
+	assert.False(t, IsTrustedIP(net.ParseIP("192.168.2.15"), subnets))
+	assert.False(t, IsTrustedIP(net.ParseIP("not ip"), subnets))
+	assert.False(t, IsTrustedIP(net.ParseIP("10.1.2.3"), nil))
+}
+
+func TestParseSubnets(t *testing.T) {
+	subnets, err := ParseSubnets("")
+	require.NoError(t, err)
+	assert.Len(t, subnets, 0)
+
+	subnets, err = ParseSubnets("127.0.0.0/8,::1/128")
+	require.NoError(t, err)
+	assert.Len(t, subnets, 2)
+
+	_, err = ParseSubnets("127.0.0.1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+type jsonReceiver struct {
+	Name     string       `json:"name"`
+	Webhook  *jsonWebhook `json:"webhook"`
+	SMTP     *jsonSMTP    `json:"smtp"`
+	Retries  []string     `json:"retries"`
+	Interval string       `json:"interval"`
+}
+
+type jsonWebhook struct {
+	URL string `json:"url"`
+	Key string `json:"key"`
+}
+
+type jsonSMTP struct {
+	Addr     string   `json:"addr"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+}
+
+// LoadRoutes reads receivers from file, see ParseRoutes
+func LoadRoutes(path string, key []byte) ([]Route, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseRoutes(file, key)
+}
+
+// ParseRoutes reads receivers in json format like
+//
+//	{"receivers": [
+//		{"name": "ops", "webhook": {"url": "http://localhost:9000/alerts"}, "interval": "1m"},
+//		{"name": "mail", "smtp": {"addr": "localhost:25", "from": "metrics@localhost", "to": ["ops@localhost"]}, "retries": ["10s", "1m"]}
+//	]}
+//
+// Receivers are in the same file as rules, so other fields are ignored.
+// Payload of webhook is signed by key if receiver does not have own key.
+// Retries are 1s, 3s, 5s if they are not set
+func ParseRoutes(r io.Reader, key []byte) ([]Route, error) {
+	content := struct {
+		Receivers []jsonReceiver `json:"receivers"`
+	}{}
+	if err := json.NewDecoder(r).Decode(&content); err != nil {
+		return nil, errors.Join(ErrInvalidReceiver, err)
+	}
+
+	routes := make([]Route, 0, len(content.Receivers))
+	names := make(map[string]struct{}, len(content.Receivers))
+	for _, raw := range content.Receivers {
+		route, err := raw.route(key)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidReceiver, fmt.Errorf("receiver %s", raw.Name), err)
+		}
+		if _, ok := names[raw.Name]; ok {
+			return nil, errors.Join(ErrInvalidReceiver, fmt.Errorf("receiver %s is defined twice", raw.Name))
+		}
+		names[raw.Name] = struct{}{}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+func (r jsonReceiver) route(key []byte) (Route, error) {
+	if r.Name == "" {
+		return Route{}, errors.New("name is empty")
+	}
+
+	route := Route{Retries: defaultRetries}
+	switch {
+	case r.Webhook != nil && r.SMTP != nil:
+		return Route{}, errors.New("receiver must have only one channel")
+	case r.Webhook != nil:
+		if r.Webhook.URL == "" {
+			return Route{}, errors.New("url of webhook is empty")
+		}
+		if r.Webhook.Key != "" {
+			key = []byte(r.Webhook.Key)
+		}
+		route.Receiver = NewWebhook(r.Name, r.Webhook.URL, WithHashKey(key))
+	case r.SMTP != nil:
+		if r.SMTP.Addr == "" || r.SMTP.From == "" || len(r.SMTP.To) == 0 {
+			return Route{}, errors.New("addr, from and to of smtp must be set")
+		}
+		route.Receiver = NewSMTP(r.Name, r.SMTP.Addr, r.SMTP.From, r.SMTP.To,
+			WithAuth(r.SMTP.Username, r.SMTP.Password))
+	default:
+		return Route{}, errors.New("receiver must have webhook or smtp")
+	}
+
+	if r.Retries != nil {
+		route.Retries = make([]time.Duration, 0, len(r.Retries))
+		for _, raw := range r.Retries {
+			d, err := time.ParseDuration(raw)
+			if err != nil {
+				return Route{}, err
+			}
+			route.Retries = append(route.Retries, d)
+		}
+	}
+
+	if r.Interval != "" {
+		d, err := time.ParseDuration(r.Interval)
+		if err != nil {
+			return Route{}, err
+		}
+		route.Interval = d
+	}
+	return route, nil
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRoutes(t *testing.T) {
+	content := `{
+		"rules": [{"name": "heap", "type": "gauge", "metric": "HeapAlloc", "op": ">", "threshold": 1}],
+		"receivers": [
+			{"name": "ops", "webhook": {"url": "http://localhost:9000/alerts"}, "interval": "1m"},
+			{"name": "own", "webhook": {"url": "http://localhost:9000/alerts", "key": "own"}, "retries": []},
+			{"name": "mail", "smtp": {"addr": "localhost:25", "from": "metrics@localhost", "to": ["ops@localhost"]}, "retries": ["10s", "1m"]}
+		]
+	}`
+
+	routes, err := ParseRoutes(strings.NewReader(content), []byte("server"))
+	require.NoError(t, err)
+	require.Len(t, routes, 3)
+
+	ops, ok := routes[0].Receiver.(*Webhook)
+	require.True(t, ok)
+	assert.Equal(t, "ops", ops.Name())
+	assert.Equal(t, []byte("server"), ops.key)
+	assert.Equal(t, time.Minute, routes[0].Interval)
+	assert.Equal(t, defaultRetries, routes[0].Retries)
+
+	own, ok := routes[1].Receiver.(*Webhook)
+	require.True(t, ok)
+	assert.Equal(t, []byte("own"), own.key)
+	assert.Empty(t, routes[1].Retries)
+
+	mail, ok := routes[2].Receiver.(*SMTP)
+	require.True(t, ok)
+	assert.Equal(t, []string{"ops@localhost"}, mail.to)
+	assert.Nil(t, mail.auth)
+	assert.Equal(t, []time.Duration{10 * time.Second, time.Minute}, routes[2].Retries)
+}
+
+func TestParseRoutes_Invalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{name: "not json", content: `receivers`},
+		{name: "without name", content: `{"receivers": [{"webhook": {"url": "http://localhost"}}]}`},
+		{name: "without channel", content: `{"receivers": [{"name": "ops"}]}`},
+		{name: "two channels", content: `{"receivers": [{"name": "ops", "webhook": {"url": "http://localhost"}, "smtp": {"addr": "localhost:25", "from": "a@localhost", "to": ["b@localhost"]}}]}`},
+		{name: "without url", content: `{"receivers": [{"name": "ops", "webhook": {}}]}`},
+		{name: "without recipients", content: `{"receivers": [{"name": "ops", "smtp": {"addr": "localhost:25", "from": "a@localhost"}}]}`},
+		{name: "invalid interval", content: `{"receivers": [{"name": "ops", "webhook": {"url": "http://localhost"}, "interval": "1x"}]}`},
+		{name: "invalid retries", content: `{"receivers": [{"name": "ops", "webhook": {"url": "http://localhost"}, "retries": ["1x"]}]}`},
+		{name: "duplicate", content: `{"receivers": [{"name": "ops", "webhook": {"url": "http://localhost"}}, {"name": "ops", "webhook": {"url": "http://localhost"}}]}`},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRoutes(strings.NewReader(tt.content), nil)
+			assert.ErrorIs(t, err, ErrInvalidReceiver)
+		})
+	}
+}
@@ -0,0 +1,6 @@
+package notify
+
+import "errors"
+
+var ErrInvalidReceiver = errors.New("invalid receiver of notifications")
+var ErrDeliveryFailed = errors.New("notification is not delivered")
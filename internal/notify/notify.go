@@ -0,0 +1,204 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vilasle/metrics/internal/alert"
+	"github.com/vilasle/metrics/internal/logger"
+)
+
+// defaultQueueSize is the count of notifications which wait for delivery to receiver by default
+const defaultQueueSize = 100
+
+// defaultRetries are pauses between attempts of delivery by default
+var defaultRetries = []time.Duration{time.Second * 1, time.Second * 3, time.Second * 5}
+
+// Receiver delivers notifications about changes of alerts, e.g. to webhook or by email
+type Receiver interface {
+	Name() string
+	Send(ctx context.Context, ev alert.Event) error
+}
+
+// Route is the receiver with policy of delivery
+type Route struct {
+	Receiver Receiver
+	// Retries are pauses between attempts of delivery, notification is dropped after the last attempt
+	Retries []time.Duration
+	// Interval is the minimal period between notifications to receiver, notifications wait for it in queue
+	Interval time.Duration
+}
+
+// Dispatcher delivers notifications to receivers in background. Each receiver has own queue,
+// so slow receiver does not delay others. Receiver gets only changes of state of alert:
+// if receiver already got notification about the same state of alert, notification is skipped
+type Dispatcher struct {
+	workers   []*worker
+	queueSize int
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        *sync.WaitGroup
+	// mx guards queues of workers, they are closed once and notifications are not queued after it
+	mx     *sync.RWMutex
+	closed bool
+}
+
+// Option is the setting of Dispatcher
+type Option func(*Dispatcher)
+
+// WithQueueSize sets the count of notifications which wait for delivery to each receiver.
+// Notifications are dropped when queue is full
+func WithQueueSize(size int) Option {
+	return func(d *Dispatcher) {
+		if size > 0 {
+			d.queueSize = size
+		}
+	}
+}
+
+// NewDispatcher returns new instance of Dispatcher and starts delivery to receivers
+func NewDispatcher(routes []Route, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		queueSize: defaultQueueSize,
+		wg:        &sync.WaitGroup{},
+		mx:        &sync.RWMutex{},
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	d.workers = make([]*worker, 0, len(routes))
+	for _, r := range routes {
+		w := newWorker(r, d.queueSize)
+		d.workers = append(d.workers, w)
+
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			w.run(d.ctx)
+		}()
+	}
+	return d
+}
+
+// Notify puts notification to queue of each receiver, it does not wait for delivery.
+// Notification is dropped if Dispatcher is closed
+func (d *Dispatcher) Notify(ev alert.Event) {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+
+	if d.closed {
+		logger.Warnw("dispatcher is closed, notification is dropped", "rule", ev.Alert.Rule, "state", ev.Alert.State)
+		return
+	}
+
+	for _, w := range d.workers {
+		select {
+		case w.queue <- ev:
+		default:
+			logger.Warnw("queue of notifications is full, notification is dropped",
+				"receiver", w.route.Receiver.Name(), "rule", ev.Alert.Rule, "state", ev.Alert.State)
+		}
+	}
+}
+
+// Close stops receiving of notifications and waits for delivery of queued ones until ctx is done
+func (d *Dispatcher) Close(ctx context.Context) {
+	d.mx.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, w := range d.workers {
+			close(w.queue)
+		}
+	}
+	d.mx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+	}
+	d.cancel()
+}
+
+// worker delivers notifications to one receiver
+type worker struct {
+	route     Route
+	queue     chan alert.Event
+	delivered map[string]alert.State
+	last      time.Time
+}
+
+func newWorker(r Route, size int) *worker {
+	return &worker{
+		route:     r,
+		queue:     make(chan alert.Event, size),
+		delivered: make(map[string]alert.State),
+	}
+}
+
+func (w *worker) run(ctx context.Context) {
+	for ev := range w.queue {
+		if ctx.Err() != nil {
+			continue
+		}
+
+		if w.delivered[ev.Alert.Rule] == ev.Alert.State {
+			logger.Debugw("notification is skipped as duplicate",
+				"receiver", w.route.Receiver.Name(), "rule", ev.Alert.Rule, "state", ev.Alert.State)
+			continue
+		}
+
+		if !sleep(ctx, time.Until(w.last.Add(w.route.Interval))) {
+			continue
+		}
+
+		w.last = time.Now()
+		if err := w.deliver(ctx, ev); err != nil {
+			logger.Errorw("can not deliver notification",
+				"receiver", w.route.Receiver.Name(), "rule", ev.Alert.Rule, "state", ev.Alert.State, "error", err)
+			continue
+		}
+		w.delivered[ev.Alert.Rule] = ev.Alert.State
+	}
+}
+
+// deliver sends notification to receiver, the next attempt is made after pause if sending failed
+func (w *worker) deliver(ctx context.Context, ev alert.Event) error {
+	err := w.route.Receiver.Send(ctx, ev)
+	for _, pause := range w.route.Retries {
+		if err == nil {
+			return nil
+		}
+		if !sleep(ctx, pause) {
+			return err
+		}
+		err = w.route.Receiver.Send(ctx, ev)
+	}
+	return err
+}
+
+// sleep waits for d and returns false if ctx is done before
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
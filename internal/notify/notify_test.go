@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/alert"
+)
+
+// receiverStub records delivered notifications, the first fails attempts of delivery fail
+type receiverStub struct {
+	mx       sync.Mutex
+	fails    int
+	attempts int
+	events   []alert.Event
+	times    []time.Time
+}
+
+func (r *receiverStub) Name() string {
+	return "stub"
+}
+
+func (r *receiverStub) Send(ctx context.Context, ev alert.Event) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.attempts++
+	if r.attempts <= r.fails {
+		return ErrDeliveryFailed
+	}
+	r.events = append(r.events, ev)
+	r.times = append(r.times, time.Now())
+	return nil
+}
+
+func (r *receiverStub) delivered() ([]alert.Event, []time.Time, int) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.events, r.times, r.attempts
+}
+
+func TestDispatcher_Retry(t *testing.T) {
+	rcv := &receiverStub{fails: 2}
+	d := NewDispatcher([]Route{{Receiver: rcv, Retries: []time.Duration{time.Millisecond, time.Millisecond}}})
+
+	d.Notify(testEvent("heap", alert.StateFiring))
+	d.Close(context.Background())
+
+	events, _, attempts := rcv.delivered()
+	assert.Equal(t, 3, attempts)
+	require.Len(t, events, 1)
+	assert.Equal(t, "heap", events[0].Alert.Rule)
+}
+
+func TestDispatcher_RetryExhausted(t *testing.T) {
+	rcv := &receiverStub{fails: 5}
+	d := NewDispatcher([]Route{{Receiver: rcv, Retries: []time.Duration{time.Millisecond}}})
+
+	d.Notify(testEvent("heap", alert.StateFiring))
+	// notification which is not delivered is not counted by deduplication
+	d.Notify(testEvent("heap", alert.StateFiring))
+	d.Close(context.Background())
+
+	events, _, attempts := rcv.delivered()
+	assert.Equal(t, 4, attempts)
+	assert.Empty(t, events)
+}
+
+func TestDispatcher_Deduplicate(t *testing.T) {
+	rcv := &receiverStub{}
+	d := NewDispatcher([]Route{{Receiver: rcv}})
+
+	d.Notify(testEvent("heap", alert.StateFiring))
+	d.Notify(testEvent("heap", alert.StateFiring))
+	d.Notify(testEvent("poll", alert.StateFiring))
+	d.Notify(testEvent("heap", alert.StateResolved))
+	d.Notify(testEvent("heap", alert.StateFiring))
+	d.Close(context.Background())
+
+	events, _, _ := rcv.delivered()
+	states := make([]string, 0, len(events))
+	for _, ev := range events {
+		states = append(states, ev.Alert.Rule+":"+string(ev.Alert.State))
+	}
+	assert.Equal(t, []string{"heap:firing", "poll:firing", "heap:resolved", "heap:firing"}, states)
+}
+
+func TestDispatcher_RateLimit(t *testing.T) {
+	interval := 50 * time.Millisecond
+	limited, free := &receiverStub{}, &receiverStub{}
+	d := NewDispatcher([]Route{{Receiver: limited, Interval: interval}, {Receiver: free}})
+
+	d.Notify(testEvent("heap", alert.StateFiring))
+	d.Notify(testEvent("poll", alert.StateFiring))
+	d.Notify(testEvent("gc", alert.StateFiring))
+	d.Close(context.Background())
+
+	events, times, _ := limited.delivered()
+	require.Len(t, events, 3)
+	for i := 1; i < len(times); i++ {
+		assert.GreaterOrEqual(t, times[i].Sub(times[i-1]), interval)
+	}
+
+	events, _, _ = free.delivered()
+	assert.Len(t, events, 3)
+}
+
+func TestDispatcher_QueueIsFull(t *testing.T) {
+	rcv := &receiverStub{}
+	d := NewDispatcher([]Route{{Receiver: rcv, Interval: time.Hour}}, WithQueueSize(1))
+
+	// the first notification is taken by worker or waits in queue, the rest are dropped
+	for _, rule := range []string{"a", "b", "c", "d"} {
+		d.Notify(testEvent(rule, alert.StateFiring))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	d.Close(ctx)
+
+	events, _, _ := rcv.delivered()
+	assert.LessOrEqual(t, len(events), 2)
+	assert.Equal(t, "a", events[0].Alert.Rule)
+}
+
+func TestDispatcher_CloseCancelsDelivery(t *testing.T) {
+	rcv := &receiverStub{fails: 100}
+	d := NewDispatcher([]Route{{Receiver: rcv, Retries: []time.Duration{time.Hour}}})
+	d.Notify(testEvent("heap", alert.StateFiring))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		d.Close(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal(errors.New("dispatcher is not closed"))
+	}
+}
+
+func TestDispatcher_NotifyAfterClose(t *testing.T) {
+	rcv := &receiverStub{}
+	d := NewDispatcher([]Route{{Receiver: rcv}})
+	d.Close(context.Background())
+
+	// late notification of evaluation which is in progress is dropped
+	assert.NotPanics(t, func() { d.Notify(testEvent("heap", alert.StateFiring)) })
+	assert.NotPanics(t, func() { d.Close(context.Background()) })
+
+	events, _, _ := rcv.delivered()
+	assert.Empty(t, events)
+}
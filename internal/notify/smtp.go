@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/vilasle/metrics/internal/alert"
+)
+
+// defaultSMTPTimeout is the limit of time of sending of one email by default
+const defaultSMTPTimeout = 30 * time.Second
+
+// SMTP sends notifications by email
+type SMTP struct {
+	name    string
+	addr    string
+	from    string
+	to      []string
+	auth    smtp.Auth
+	timeout time.Duration
+}
+
+// SMTPOption is the setting of SMTP
+type SMTPOption func(*SMTP)
+
+// WithAuth sets credentials for PLAIN authentication on the server
+func WithAuth(username, password string) SMTPOption {
+	return func(s *SMTP) {
+		if username == "" {
+			return
+		}
+		host, _, err := net.SplitHostPort(s.addr)
+		if err != nil {
+			host = s.addr
+		}
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+}
+
+// WithTimeout sets the limit of time of sending of one email, sending is also stopped when ctx of Send is done
+func WithTimeout(timeout time.Duration) SMTPOption {
+	return func(s *SMTP) {
+		if timeout > 0 {
+			s.timeout = timeout
+		}
+	}
+}
+
+// NewSMTP returns new instance of SMTP which sends emails via server on addr
+func NewSMTP(name, addr, from string, to []string, opts ...SMTPOption) *SMTP {
+	s := &SMTP{
+		name:    name,
+		addr:    addr,
+		from:    from,
+		to:      to,
+		timeout: defaultSMTPTimeout,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *SMTP) Name() string {
+	return s.name
+}
+
+func (s *SMTP) Send(ctx context.Context, ev alert.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := s.send(ctx, s.message(ev)); err != nil {
+		return errors.Join(ErrDeliveryFailed, err)
+	}
+	return nil
+}
+
+// send does the same as smtp.SendMail, but connection is closed when ctx is done or timeout is over
+func (s *SMTP) send(ctx context.Context, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	d := net.Dialer{}
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	// smtp.Client does not take ctx, so blocked reads and writes are interrupted by deadline
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	host, _, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		host = s.addr
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(s.auth); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.Mail(s.from); err != nil {
+		return err
+	}
+	for _, addr := range s.to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (s *SMTP) message(ev alert.Event) []byte {
+	a := ev.Alert
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "From: %s\r\n", s.from)
+	fmt.Fprintf(buf, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(buf, "Subject: [%s] %s: %s\r\n", strings.ToUpper(string(a.State)), a.Rule, a.Summary)
+	fmt.Fprintf(buf, "Date: %s\r\n", a.UpdatedAt.Format(time.RFC1123Z))
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+
+	fmt.Fprintf(buf, "rule: %s\r\n", a.Rule)
+	fmt.Fprintf(buf, "state: %s\r\n", a.State)
+	if ev.Previous != "" {
+		fmt.Fprintf(buf, "previous state: %s\r\n", ev.Previous)
+	}
+	fmt.Fprintf(buf, "summary: %s\r\n", a.Summary)
+	fmt.Fprintf(buf, "value: %g\r\n", a.Value)
+	fmt.Fprintf(buf, "active since: %s\r\n", a.ActiveSince.Format(time.RFC3339))
+	if !a.FiredAt.IsZero() {
+		fmt.Fprintf(buf, "fired at: %s\r\n", a.FiredAt.Format(time.RFC3339))
+	}
+	if !a.ResolvedAt.IsZero() {
+		fmt.Fprintf(buf, "resolved at: %s\r\n", a.ResolvedAt.Format(time.RFC3339))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/alert"
+)
+
+// mail is the message which is received by smtpStandIn
+type mail struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpStandIn is the minimal SMTP server which accepts all messages
+type smtpStandIn struct {
+	ln    net.Listener
+	mx    sync.Mutex
+	mails []mail
+	wg    sync.WaitGroup
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &smtpStandIn{ln: ln}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		ln.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+func (s *smtpStandIn) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *smtpStandIn) received() []mail {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return append([]mail(nil), s.mails...)
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	m := mail{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			m.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			m.to = append(m.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			data := &strings.Builder{}
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			m.data = data.String()
+			s.mx.Lock()
+			s.mails = append(s.mails, m)
+			s.mx.Unlock()
+			m = mail{}
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTP_Send(t *testing.T) {
+	srv := newSMTPStandIn(t)
+
+	s := NewSMTP("mail", srv.addr(), "metrics@localhost", []string{"ops@localhost", "dev@localhost"})
+	assert.Equal(t, "mail", s.Name())
+	require.NoError(t, s.Send(context.Background(), testEvent("heap", alert.StateFiring)))
+
+	mails := srv.received()
+	require.Len(t, mails, 1)
+	assert.Equal(t, "metrics@localhost", mails[0].from)
+	assert.Equal(t, []string{"ops@localhost", "dev@localhost"}, mails[0].to)
+	assert.Contains(t, mails[0].data, "Subject: [FIRING] heap: HeapAlloc > 100 for 5m0s\r\n")
+	assert.Contains(t, mails[0].data, "To: ops@localhost, dev@localhost\r\n")
+	assert.Contains(t, mails[0].data, "previous state: pending\r\n")
+	assert.Contains(t, mails[0].data, "value: 150\r\n")
+}
+
+func TestSMTP_SendFailed(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	s := NewSMTP("mail", addr, "metrics@localhost", []string{"ops@localhost"})
+	assert.ErrorIs(t, s.Send(context.Background(), testEvent("heap", alert.StateFiring)), ErrDeliveryFailed)
+}
+
+func TestSMTP_SendStopsOnContext(t *testing.T) {
+	// server accepts connection but never greets client
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	s := NewSMTP("mail", ln.Addr().String(), "metrics@localhost", []string{"ops@localhost"}, WithTimeout(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	err = s.Send(ctx, alert.Event{Alert: alert.Alert{Rule: "heap", State: alert.StateFiring}})
+	assert.ErrorIs(t, err, ErrDeliveryFailed)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the same is done by timeout
+	s = NewSMTP("mail", ln.Addr().String(), "metrics@localhost", []string{"ops@localhost"}, WithTimeout(20*time.Millisecond))
+	start = time.Now()
+	err = s.Send(context.Background(), alert.Event{Alert: alert.Alert{Rule: "heap", State: alert.StateFiring}})
+	assert.ErrorIs(t, err, ErrDeliveryFailed)
+	assert.Less(t, time.Since(start), time.Second)
+}
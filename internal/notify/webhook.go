@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/vilasle/metrics/internal/alert"
+)
+
+// hashSumHeader is the header with signature of payload, it has the same scheme as requests of agent
+const hashSumHeader = "HashSHA256"
+
+// defaultWebhookTimeout is the limit of time of one request to webhook by default
+const defaultWebhookTimeout = 10 * time.Second
+
+// Webhook sends notifications as JSON to url by POST request
+type Webhook struct {
+	name    string
+	url     string
+	key     []byte
+	client  *http.Client
+	timeout time.Duration
+}
+
+// WebhookOption is the setting of Webhook
+type WebhookOption func(*Webhook)
+
+// WithHashKey sets the key for signing of payload, signature is sent in header HashSHA256
+func WithHashKey(key []byte) WebhookOption {
+	return func(w *Webhook) {
+		w.key = key
+	}
+}
+
+// WithHTTPClient sets the client which sends requests
+func WithHTTPClient(client *http.Client) WebhookOption {
+	return func(w *Webhook) {
+		if client != nil {
+			w.client = client
+		}
+	}
+}
+
+// WithWebhookTimeout sets the limit of time of one request, sending is also stopped when ctx of Send is done
+func WithWebhookTimeout(timeout time.Duration) WebhookOption {
+	return func(w *Webhook) {
+		if timeout > 0 {
+			w.timeout = timeout
+		}
+	}
+}
+
+// NewWebhook returns new instance of Webhook
+func NewWebhook(name, url string, opts ...WebhookOption) *Webhook {
+	w := &Webhook{
+		name:    name,
+		url:     url,
+		client:  http.DefaultClient,
+		timeout: defaultWebhookTimeout,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+func (w *Webhook) Send(ctx context.Context, ev alert.Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(w.key) > 0 {
+		h := hmac.New(sha256.New, w.key)
+		h.Write(body)
+		req.Header.Set(hashSumHeader, base64.URLEncoding.EncodeToString(h.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return errors.Join(ErrDeliveryFailed, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Join(ErrDeliveryFailed, fmt.Errorf("webhook responded with status %d", resp.StatusCode))
+	}
+	return nil
+}
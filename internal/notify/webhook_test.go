@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/alert"
+)
+
+func testEvent(rule string, state alert.State) alert.Event {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return alert.Event{
+		Alert: alert.Alert{
+			Rule:        rule,
+			State:       state,
+			Summary:     "HeapAlloc > 100 for 5m0s",
+			Value:       150,
+			ActiveSince: now,
+			FiredAt:     now.Add(5 * time.Minute),
+			UpdatedAt:   now.Add(5 * time.Minute),
+		},
+		Previous: alert.StatePending,
+	}
+}
+
+func TestWebhook_Send(t *testing.T) {
+	key := []byte("secret")
+	ev := testEvent("heap", alert.StateFiring)
+
+	var received alert.Event
+	var sign string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		h := hmac.New(sha256.New, key)
+		h.Write(body)
+		sign = base64.URLEncoding.EncodeToString(h.Sum(nil))
+		assert.Equal(t, sign, r.Header.Get("HashSHA256"))
+
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	wh := NewWebhook("ops", srv.URL, WithHashKey(key), WithHTTPClient(srv.Client()))
+	assert.Equal(t, "ops", wh.Name())
+	require.NoError(t, wh.Send(context.Background(), ev))
+	assert.Equal(t, ev, received)
+	assert.NotEmpty(t, sign)
+}
+
+func TestWebhook_SendWithoutKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("HashSHA256"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	wh := NewWebhook("ops", srv.URL)
+	require.NoError(t, wh.Send(context.Background(), testEvent("heap", alert.StateFiring)))
+}
+
+func TestWebhook_SendFailed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	wh := NewWebhook("ops", srv.URL)
+	err := wh.Send(context.Background(), testEvent("heap", alert.StateFiring))
+	assert.ErrorIs(t, err, ErrDeliveryFailed)
+
+	srv.Close()
+	err = wh.Send(context.Background(), testEvent("heap", alert.StateFiring))
+	assert.ErrorIs(t, err, ErrDeliveryFailed)
+}
+
+func TestWebhook_SendTimeout(t *testing.T) {
+	// webhook does not respond until the end of test
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	wh := NewWebhook("ops", srv.URL, WithWebhookTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	err := wh.Send(context.Background(), testEvent("heap", alert.StateFiring))
+	assert.ErrorIs(t, err, ErrDeliveryFailed)
+	assert.Less(t, time.Since(start), time.Second)
+}
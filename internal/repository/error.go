@@ -6,4 +6,9 @@ var (
 	ErrUnknownMetricType  = errors.New("unknown metric type")
 	ErrInitializeMetadata = errors.New("failed to initialize metadata")
 	ErrEmptySetOfMetric   = errors.New("empty set of metric")
+	ErrHistoryDisabled    = errors.New("history of metric is not stored")
+	ErrAlertsNotSupported = errors.New("storage does not keep alerts")
+	ErrKeysNotSupported   = errors.New("storage does not keep idempotency keys")
+	ErrTokensNotSupported = errors.New("storage does not keep tokens")
+	ErrTokenNotFound      = errors.New("token is not found")
 )
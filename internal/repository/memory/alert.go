@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/vilasle/metrics/internal/alert"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+var _ repository.AlertRepository = (*MemoryMetricRepository)(nil)
+
+// alertStorage keeps state of alerts by name of rule
+type alertStorage struct {
+	mx     *sync.Mutex
+	alerts map[string]alert.Alert
+}
+
+func newAlertStorage() *alertStorage {
+	return &alertStorage{
+		mx:     &sync.Mutex{},
+		alerts: make(map[string]alert.Alert),
+	}
+}
+
+// SaveAlert saves state of alert, previous state of alert of the same rule is replaced
+func (r *MemoryMetricRepository) SaveAlert(ctx context.Context, a alert.Alert) error {
+	r.alerts.mx.Lock()
+	defer r.alerts.mx.Unlock()
+
+	r.alerts.alerts[a.Rule] = a
+	return nil
+}
+
+// DeleteAlert deletes alert of rule
+func (r *MemoryMetricRepository) DeleteAlert(ctx context.Context, rule string) error {
+	r.alerts.mx.Lock()
+	defer r.alerts.mx.Unlock()
+
+	delete(r.alerts.alerts, rule)
+	return nil
+}
+
+// Alerts returns alerts ordered by name of rule
+func (r *MemoryMetricRepository) Alerts(ctx context.Context) ([]alert.Alert, error) {
+	r.alerts.mx.Lock()
+	defer r.alerts.mx.Unlock()
+
+	rs := make([]alert.Alert, 0, len(r.alerts.alerts))
+	for _, a := range r.alerts.alerts {
+		rs = append(rs, a)
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Rule < rs[j].Rule })
+	return rs, nil
+}
@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/alert"
+)
+
+func TestMemoryMetricRepository_Alerts(t *testing.T) {
+	r := NewMetricRepository()
+	ctx := context.Background()
+
+	require.NoError(t, r.SaveAlert(ctx, alert.Alert{Rule: "poll", State: alert.StatePending}))
+	require.NoError(t, r.SaveAlert(ctx, alert.Alert{Rule: "heap", State: alert.StatePending}))
+	require.NoError(t, r.SaveAlert(ctx, alert.Alert{Rule: "poll", State: alert.StateFiring}))
+
+	got, err := r.Alerts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []alert.Alert{
+		{Rule: "heap", State: alert.StatePending},
+		{Rule: "poll", State: alert.StateFiring},
+	}, got)
+
+	require.NoError(t, r.DeleteAlert(ctx, "heap"))
+
+	got, err = r.Alerts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []alert.Alert{{Rule: "poll", State: alert.StateFiring}}, got)
+}
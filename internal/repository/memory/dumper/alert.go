@@ -0,0 +1,159 @@
+package dumper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vilasle/metrics/internal/alert"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+// alertCode is the first part of dumped line of alert like alert;rule;{"rule":"rule","state":"firing",...}.
+// Line without state of alert means that alert was deleted
+const alertCode = "alert"
+
+var _ repository.AlertRepository = (*FileDumper)(nil)
+
+// SaveAlert saves state of alert to storage and if FileDumper works in syncMode, will add new line after saving
+func (d *FileDumper) SaveAlert(ctx context.Context, a alert.Alert) error {
+	storage, err := d.alertStorage()
+	if err != nil {
+		return err
+	}
+
+	d.srvMx.Lock()
+	defer d.srvMx.Unlock()
+
+	if err := storage.SaveAlert(ctx, a); err != nil {
+		return err
+	}
+	if !d.syncSave {
+		return nil
+	}
+
+	content, err := dumpedAlert(a)
+	if err != nil {
+		return err
+	}
+	_, err = d.fs.Write(content)
+	return err
+}
+
+// DeleteAlert deletes alert of rule from storage and if FileDumper works in syncMode, will add line about deleting
+func (d *FileDumper) DeleteAlert(ctx context.Context, rule string) error {
+	storage, err := d.alertStorage()
+	if err != nil {
+		return err
+	}
+
+	d.srvMx.Lock()
+	defer d.srvMx.Unlock()
+
+	if err := storage.DeleteAlert(ctx, rule); err != nil {
+		return err
+	}
+	if !d.syncSave {
+		return nil
+	}
+
+	_, err = d.fs.Write([]byte(fmt.Sprintf("%s;%s;\n", alertCode, rule)))
+	return err
+}
+
+// Alerts gets alerts from storage
+func (d *FileDumper) Alerts(ctx context.Context) ([]alert.Alert, error) {
+	storage, err := d.alertStorage()
+	if err != nil {
+		return nil, err
+	}
+	return storage.Alerts(ctx)
+}
+
+func (d *FileDumper) alertStorage() (repository.AlertRepository, error) {
+	if s, ok := d.storage.(repository.AlertRepository); ok {
+		return s, nil
+	}
+	return nil, repository.ErrAlertsNotSupported
+}
+
+func dumpedAlert(a alert.Alert) ([]byte, error) {
+	content, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%s;%s;%s\n", alertCode, a.Rule, content)), nil
+}
+
+func isDumpedAlert(line string) bool {
+	return strings.HasPrefix(line, alertCode+";")
+}
+
+// restoreAlerts restores the last state of each alert from dumped lines
+func (d *FileDumper) restoreAlerts(ctx context.Context, lines []string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	storage, err := d.alertStorage()
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, 0)
+	alerts := make(map[string]*alert.Alert)
+	for _, line := range lines {
+		raw := strings.SplitN(line, ";", 3)
+		if len(raw) < 3 {
+			errs = append(errs, errors.Join(ErrWrongDumpedLine, fmt.Errorf("wrong line. content=%s", line)))
+			continue
+		}
+
+		rule, content := raw[1], raw[2]
+		if content == "" {
+			alerts[rule] = nil
+			continue
+		}
+
+		a := alert.Alert{}
+		if err := json.Unmarshal([]byte(content), &a); err != nil {
+			errs = append(errs, errors.Join(ErrWrongDumpedLine, fmt.Errorf("wrong alert. content=%s", line), err))
+			continue
+		}
+		alerts[rule] = &a
+	}
+
+	for rule, a := range alerts {
+		if a == nil {
+			errs = append(errs, storage.DeleteAlert(ctx, rule))
+			continue
+		}
+		errs = append(errs, storage.SaveAlert(ctx, *a))
+	}
+	return errors.Join(errs...)
+}
+
+// dumpAlerts returns dumped lines of all alerts if storage keeps alerts
+func (d *FileDumper) dumpAlerts(ctx context.Context) ([]byte, error) {
+	storage, err := d.alertStorage()
+	if err != nil {
+		return nil, nil
+	}
+
+	alerts, err := storage.Alerts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := make([]byte, 0)
+	for _, a := range alerts {
+		content, err := dumpedAlert(a)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, content...)
+	}
+	return rs, nil
+}
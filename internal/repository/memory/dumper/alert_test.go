@@ -0,0 +1,85 @@
+package dumper
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/alert"
+	"github.com/vilasle/metrics/internal/repository"
+	"github.com/vilasle/metrics/internal/repository/memory"
+)
+
+func Test_FileDumper_SaveAlert(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	fs := NewMockSerialWriter(ctrl)
+	d := &FileDumper{fs: fs, storage: memory.NewMetricRepository(), srvMx: &sync.Mutex{}, syncSave: true}
+
+	a := alert.Alert{Rule: "heap", State: alert.StateFiring, ActiveSince: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	line, err := dumpedAlert(a)
+	require.NoError(t, err)
+
+	fs.EXPECT().Write(line).Return(len(line), nil)
+	fs.EXPECT().Write([]byte("alert;heap;\n")).Return(12, nil)
+
+	require.NoError(t, d.SaveAlert(ctx, a))
+
+	got, err := d.Alerts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []alert.Alert{a}, got)
+
+	require.NoError(t, d.DeleteAlert(ctx, "heap"))
+
+	got, err = d.Alerts(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func Test_FileDumper_SaveAlertNotSupported(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	d := &FileDumper{fs: NewMockSerialWriter(ctrl), storage: NewMockMetricRepository(ctrl), srvMx: &sync.Mutex{}}
+
+	err := d.SaveAlert(context.Background(), alert.Alert{Rule: "heap"})
+	assert.ErrorIs(t, err, repository.ErrAlertsNotSupported)
+}
+
+func Test_FileDumper_restoreAlerts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	fs := NewMockSerialWriter(ctrl)
+	storage := memory.NewMetricRepository()
+
+	fs.EXPECT().ScanAll().Return([]string{
+		`alert;heap;{"rule":"heap","state":"pending"}`,
+		`0;gauge1;1`,
+		`alert;poll;{"rule":"poll","state":"firing"}`,
+		`alert;heap;{"rule":"heap","state":"firing"}`,
+		`alert;poll;`,
+	}, nil)
+
+	d := &FileDumper{fs: fs, storage: storage, srvMx: &sync.Mutex{}}
+	require.NoError(t, d.restore(ctx))
+
+	got, err := storage.Alerts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []alert.Alert{{Rule: "heap", State: alert.StateFiring}}, got)
+
+	// alerts are dumped with metrics
+	fs.EXPECT().Rewrite(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+		assert.Contains(t, string(b), "0;gauge1;1\n")
+		assert.Contains(t, string(b), `alert;heap;{"rule":"heap","state":"firing"`)
+		return len(b), nil
+	})
+	require.NoError(t, d.DumpAll(ctx))
+}
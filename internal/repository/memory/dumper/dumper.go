@@ -3,8 +3,10 @@ package dumper
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,11 +17,6 @@ import (
 	"go.uber.org/zap"
 )
 
-const (
-	gaugeID   = "0"
-	counterID = "1"
-)
-
 type initOpt func(context.Context, *FileDumper) error
 
 // Config is setting for FileDumper
@@ -42,17 +39,31 @@ type dumpedMetric struct {
 var ErrWrongDumpedLine = fmt.Errorf("wrong dumped line")
 
 func (d dumpedMetric) dumpedContent() []byte {
+	// kind is the code of registered type, it is shorter than the name of type
 	var (
-		kind        = 0
+		kind        = d.Type()
 		name, value = d.Name(), d.Value()
 	)
 
-	if d.Type() == metric.TypeCounter {
-		kind = 1
+	if t, ok := metric.LookupType(kind); ok {
+		kind = t.Code
+	}
+
+	var l []byte
+	if labels := d.Labels(); len(labels) > 0 {
+		// json encoding sorts labels by name, so the same labels always have the same representation
+		l, _ = json.Marshal(labels)
 	}
 
-	c := fmt.Sprintf("%d;%s;%s\n", kind, name, value)
-	return []byte(c)
+	ts := d.Timestamp()
+	switch {
+	case !ts.IsZero():
+		return []byte(fmt.Sprintf("%s;%s;%s;%s;%d\n", kind, name, value, l, ts.UnixNano()))
+	case len(l) > 0:
+		return []byte(fmt.Sprintf("%s;%s;%s;%s\n", kind, name, value, l))
+	default:
+		return []byte(fmt.Sprintf("%s;%s;%s\n", kind, name, value))
+	}
 }
 
 // FileDumper if wrapper front MetricRepository and stores metrics in file immediately or by timer
@@ -64,8 +75,19 @@ func (d dumpedMetric) dumpedContent() []byte {
 // 	1;counter1;126
 // 	1;counter1;126
 // 	1;counter1;126
+// 	0;gauge1;127;{"host":"a"}
+// 	0;gauge1;128;{"host":"a"};1704067200000000000
+// 	0;gauge1;129;;1704067260000000000
+// 	2;latency;0.1:3,0.5:2,+Inf:1,sum:1.45
+// 	3;PauseNs;p288:2,p290:1,sum:309000
+// 	alert;heap;{"rule":"heap","state":"firing",...}
+// 	token;3f2a9c0d1e4b5a6f;{"id":"3f2a9c0d1e4b5a6f","hash":"...","scopes":["read"],...}
 // 
-// For counter such situation is ok, for gauge not is.
+// The optional parts of line are labels of metric in json format and time of observation in unix nanoseconds,
+// labels are empty if metric with timestamp does not have them.
+// Lines which begin with alert keep state of alerts if storage keeps alerts, the last line of rule wins.
+// Lines which begin with token keep API tokens in the same way.
+// For counter, histogram and summary such situation is ok, because their values are merged, for gauge not is.
 // 
 // In general if the last launch worked on sync mode we would have all history transactions.
 // When we restore repository from file we will have unique values for gauge and historical data for counter
@@ -103,6 +125,11 @@ func (d *FileDumper) Save(ctx context.Context, entity ...metric.Metric) error {
 	if err := d.storage.Save(ctx, entity...); err != nil {
 		return err
 	}
+	return d.write(entity...)
+}
+
+// write adds lines of metrics to file if FileDumper works in sync mode. The caller must hold the lock of FileDumper
+func (d *FileDumper) write(entity ...metric.Metric) error {
 	if !d.syncSave {
 		return nil
 	}
@@ -137,6 +164,18 @@ func (d *FileDumper) DumpAll(ctx context.Context) error {
 		}
 	}
 
+	alerts, err := d.dumpAlerts(ctx)
+	if err != nil {
+		return err
+	}
+	buf.Write(alerts)
+
+	tokens, err := d.dumpTokens(ctx)
+	if err != nil {
+		return err
+	}
+	buf.Write(tokens)
+
 	logger.Debugw("content on dump before dumping", zap.String("content", buf.String()))
 
 	_, err = d.fs.Rewrite(buf.Bytes())
@@ -148,6 +187,11 @@ func (d *FileDumper) Get(ctx context.Context, metricType string, filterName ...s
 	return d.storage.Get(ctx, metricType, filterName...)
 }
 
+// History - gets values of metric for period from storage. Method is necessary for implementation of MetricRepository's methods
+func (d *FileDumper) History(ctx context.Context, metricType, name string, labels metric.Labels, from, to time.Time) ([]metric.Point, error) {
+	return d.storage.History(ctx, metricType, name, labels, from, to)
+}
+
 // Get - checks connection with storage. Method is necessary for implementation of MetricRepository's methods 
 func (d *FileDumper) Ping(ctx context.Context) error {
 	return d.storage.Ping(ctx)
@@ -168,43 +212,89 @@ func (d *FileDumper) restore(ctx context.Context) error {
 
 	errs := make([]error, 0)
 
-	rawGauge := make(map[string]metric.Metric)
-	rawCounter := make([]metric.Metric, 0)
+	// only the last value is restored for types like gauge, values of other types are merged by storage
+	rawLast := make(map[string]metric.Metric)
+	rawOther := make([]metric.Metric, 0)
+
+	alertLines := make([]string, 0)
+	tokenLines := make([]string, 0)
 
 	for i, b := range all {
-		raw := strings.Split(b, ";")
+		if isDumpedAlert(b) {
+			alertLines = append(alertLines, b)
+			continue
+		}
+		if isDumpedToken(b) {
+			tokenLines = append(tokenLines, b)
+			continue
+		}
 
-		if len(raw) != 3 {
+		raw := strings.SplitN(b, ";", 4)
+
+		if len(raw) < 3 {
 			errs = append(errs, errors.Join(ErrWrongDumpedLine, fmt.Errorf("wrong line. offset=%d;content=%s", i, b)))
 			continue
 		}
 		name, value := raw[1], raw[2]
-		if strings.HasPrefix(b, gaugeID) {
-			m, err := metric.ParseMetric(name, value, metric.TypeGauge)
-			if err != nil {
-				errs = append(errs, err)
-				continue
+
+		var (
+			labels metric.Labels
+			ts     time.Time
+		)
+		if len(raw) == 4 {
+			rest := raw[3]
+			// labels are json object, so line which does not end by it has timestamp
+			if !strings.HasSuffix(rest, "}") {
+				pos := strings.LastIndex(rest, ";")
+				nsec, err := strconv.ParseInt(rest[pos+1:], 10, 64)
+				if pos < 0 || err != nil {
+					errs = append(errs, errors.Join(ErrWrongDumpedLine, fmt.Errorf("wrong timestamp. offset=%d;content=%s", i, b), err))
+					continue
+				}
+				ts, rest = time.Unix(0, nsec), rest[:pos]
+			}
+			if rest != "" {
+				if err := json.Unmarshal([]byte(rest), &labels); err != nil {
+					errs = append(errs, errors.Join(ErrWrongDumpedLine, fmt.Errorf("wrong labels. offset=%d;content=%s", i, b), err))
+					continue
+				}
 			}
-			rawGauge[name] = m
-		} else if strings.HasPrefix(b, counterID) {
-			m, err := metric.ParseMetric(name, value, metric.TypeCounter)
-			if err != nil {
-				errs = append(errs, err)
-				continue
+		}
+
+		t, ok := metric.LookupTypeByCode(raw[0])
+		if !ok {
+			errs = append(errs, errors.Join(ErrWrongDumpedLine, fmt.Errorf("unknown type. offset=%d;content=%s", i, b)))
+			continue
+		}
+
+		m, err := t.Parse(name, value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		m.SetLabels(labels)
+		m.SetTimestamp(ts)
+
+		if t.Aggregation == metric.AggregateLast {
+			// the latest line wins unless it keeps the older sample
+			key := t.Code + ";" + metric.SeriesID(name, labels)
+			if prev, ok := rawLast[key]; !ok || !prev.Timestamp().After(ts) {
+				rawLast[key] = m
 			}
-			rawCounter = append(rawCounter, m)
+		} else {
+			rawOther = append(rawOther, m)
 		}
 	}
-	qty := len(rawGauge) + len(rawCounter)
-	for _, g := range rawGauge {
-		if err := d.storage.Save(ctx, g); err != nil {
+	qty := len(rawLast) + len(rawOther)
+	for _, m := range rawLast {
+		if err := d.storage.Save(ctx, m); err != nil {
 			errs = append(errs, err)
 			qty--
 		}
 	}
 
-	for _, c := range rawCounter {
-		if err := d.storage.Save(ctx, c); err != nil {
+	for _, m := range rawOther {
+		if err := d.storage.Save(ctx, m); err != nil {
 			errs = append(errs, err)
 			qty--
 		}
@@ -212,6 +302,14 @@ func (d *FileDumper) restore(ctx context.Context) error {
 
 	logger.Debugf("after restoring there are %d metrics", qty)
 
+	if err := d.restoreAlerts(ctx, alertLines); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := d.restoreTokens(ctx, tokenLines); err != nil {
+		errs = append(errs, err)
+	}
+
 	return errors.Join(errs...)
 }
 
@@ -234,7 +332,15 @@ func (d *FileDumper) prepareToRun(ctx context.Context, opts ...initOpt) error {
 func (d *FileDumper) runByMode(ctx context.Context, timeout time.Duration) {
 	if !d.syncSave {
 		go d.dumpOnBackground(ctx, timeout)
+		return
 	}
+	// file is up to date in sync mode, so it is only closed
+	go func() {
+		<-ctx.Done()
+		d.srvMx.Lock()
+		defer d.srvMx.Unlock()
+		d.fs.Close()
+	}()
 }
 
 func (d *FileDumper) dumpOnBackground(ctx context.Context, timeout time.Duration) {
@@ -259,16 +365,15 @@ func (d *FileDumper) stop(ctx context.Context) {
 }
 
 func (d *FileDumper) all(ctx context.Context) ([]metric.Metric, error) {
-	gauges, err := d.storage.Get(ctx, metric.TypeGauge)
-	if err != nil {
-		return nil, err
-	}
-
-	counters, err := d.storage.Get(ctx, metric.TypeCounter)
-	if err != nil {
-		return nil, err
+	rs := make([]metric.Metric, 0)
+	for _, metricType := range metric.Types() {
+		metrics, err := d.storage.Get(ctx, metricType)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, metrics...)
 	}
-	return append(gauges, counters...), nil
+	return rs, nil
 }
 
 func withRestore(ctx context.Context, d *FileDumper) error {
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"math/rand/v2"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -52,6 +54,18 @@ func Test_FileStream_NewFileStream(t *testing.T) {
 
 }
 
+func Test_FileStream_InUse(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.metrics")
+	assert.False(t, InUse(file))
+
+	fs, err := NewFileStream(file)
+	require.NoError(t, err)
+	assert.True(t, InUse(file), "file is in use until it is closed")
+
+	require.NoError(t, fs.Close())
+	assert.False(t, InUse(file))
+}
+
 func Test_FileStream_Write(t *testing.T) {
 	file := "test.txt"
 	fs, err := NewFileStream(file)
@@ -225,6 +239,41 @@ func Test_dumpedMetric_dumpedContent(t *testing.T) {
 			},
 			want: []byte("0;gauge1;123.123\n"),
 		},
+		{
+			name: "dump gauge with labels",
+			metric: dumpedMetric{
+				metric.WithLabels(metric.NewGaugeMetric("gauge1", 1), metric.Labels{"region": "b", "host": "a;b"}),
+			},
+			want: []byte("0;gauge1;1;{\"host\":\"a;b\",\"region\":\"b\"}\n"),
+		},
+		{
+			name: "dump gauge with timestamp",
+			metric: dumpedMetric{
+				stamped(metric.NewGaugeMetric("gauge1", 1), 1704067200000000000),
+			},
+			want: []byte("0;gauge1;1;;1704067200000000000\n"),
+		},
+		{
+			name: "dump counter with labels and timestamp",
+			metric: dumpedMetric{
+				stamped(metric.WithLabels(metric.NewCounterMetric("counter1", 1), metric.Labels{"host": "a"}), 1704067200000000000),
+			},
+			want: []byte("1;counter1;1;{\"host\":\"a\"};1704067200000000000\n"),
+		},
+		{
+			name: "dump histogram",
+			metric: dumpedMetric{
+				newHistogram(t, "latency", 0.5, 0.1),
+			},
+			want: []byte("2;latency;0.1:0,+Inf:1,sum:0.5\n"),
+		},
+		{
+			name: "dump summary",
+			metric: dumpedMetric{
+				metric.NewSummaryMetric("PauseNs", 1, 0, 1),
+			},
+			want: []byte("3;PauseNs;z:1,p0:2,sum:2\n"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -407,10 +456,24 @@ func Test_FileDumper_DumpAll(t *testing.T) {
 					},
 					err: nil,
 				},
+				{
+					mtype: metric.TypeHistogram,
+					metrics: []metric.Metric{
+						newHistogram(t, "latency", 0.05, 0.1),
+					},
+					err: nil,
+				},
+				{
+					mtype: metric.TypeSummary,
+					metrics: []metric.Metric{
+						metric.NewSummaryMetric("PauseNs", 1),
+					},
+					err: nil,
+				},
 			},
 			writerArgs: []writerArgs{
 				{
-					content: []byte("0;gauge1;123.123\n1;counter1;123\n"),
+					content: []byte("0;gauge1;123.123\n1;counter1;123\n2;latency;0.1:1,+Inf:0,sum:0.05\n3;PauseNs;p0:1,sum:1\n"),
 					n:       1,
 					err:     nil,
 				},
@@ -455,6 +518,16 @@ func Test_FileDumper_DumpAll(t *testing.T) {
 					},
 					err: nil,
 				},
+				{
+					mtype:   metric.TypeHistogram,
+					metrics: []metric.Metric{},
+					err:     nil,
+				},
+				{
+					mtype:   metric.TypeSummary,
+					metrics: []metric.Metric{},
+					err:     nil,
+				},
 			},
 			writerArgs: []writerArgs{
 				{
@@ -562,6 +635,8 @@ func Test_FileDumper_all(t *testing.T) {
 		name       string
 		gauges     []metric.Metric
 		counters   []metric.Metric
+		histograms []metric.Metric
+		summaries  []metric.Metric
 		result     []metric.Metric
 		ctx        context.Context
 		err        error
@@ -576,9 +651,17 @@ func Test_FileDumper_all(t *testing.T) {
 			counters: []metric.Metric{
 				metric.NewCounterMetric("counter1", 123), metric.NewCounterMetric("counter2", 321),
 			},
+			histograms: []metric.Metric{
+				newHistogram(t, "latency", 0.05, 0.1),
+			},
+			summaries: []metric.Metric{
+				metric.NewSummaryMetric("PauseNs", 1),
+			},
 			result: []metric.Metric{
 				metric.NewGaugeMetric("gauge1", 123.123), metric.NewGaugeMetric("gauge2", 321.321),
 				metric.NewCounterMetric("counter1", 123), metric.NewCounterMetric("counter2", 321),
+				newHistogram(t, "latency", 0.05, 0.1),
+				metric.NewSummaryMetric("PauseNs", 1),
 			},
 			ctx:        context.Background(),
 			err:        nil,
@@ -622,6 +705,11 @@ func Test_FileDumper_all(t *testing.T) {
 				setup(repo, tt.ctx, metric.TypeCounter, tt.counters, tt.counterErr)
 			}
 
+			if tt.gaugeErr == nil && tt.counterErr == nil {
+				setup(repo, tt.ctx, metric.TypeHistogram, tt.histograms, nil)
+				setup(repo, tt.ctx, metric.TypeSummary, tt.summaries, nil)
+			}
+
 			fd := &FileDumper{storage: repo}
 
 			r, err := fd.all(tt.ctx)
@@ -698,6 +786,77 @@ func Test_FileDumper_restore(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "success with labels",
+			writerArg: writerArg{
+				result: []string{`0;gauge1;1;{"host":"a;b"}`},
+				err:    nil,
+			},
+			storageArgs: []storageArg{
+				{
+					metrics: []metric.Metric{
+						metric.WithLabels(metric.NewGaugeMetric("gauge1", 1), metric.Labels{"host": "a;b"}),
+					},
+					err: nil,
+				},
+			},
+		},
+		{
+			name: "success with timestamps",
+			writerArg: writerArg{
+				result: []string{
+					`0;gauge1;2;{"host":"a;b"};1704067260000000000`,
+					`0;gauge1;1;{"host":"a;b"};1704067200000000000`,
+					"1;counter1;3;;1704067200000000000",
+				},
+				err: nil,
+			},
+			storageArgs: []storageArg{
+				{
+					// late line does not replace the newer sample
+					metrics: []metric.Metric{
+						stamped(metric.WithLabels(metric.NewGaugeMetric("gauge1", 2), metric.Labels{"host": "a;b"}), 1704067260000000000),
+					},
+					err: nil,
+				},
+				{
+					metrics: []metric.Metric{
+						stamped(metric.NewCounterMetric("counter1", 3), 1704067200000000000),
+					},
+					err: nil,
+				},
+			},
+		},
+		{
+			name: "success with histogram",
+			writerArg: writerArg{
+				result: []string{"2;latency;0.1:0,+Inf:1,sum:0.5"},
+				err:    nil,
+			},
+			storageArgs: []storageArg{
+				{
+					metrics: []metric.Metric{
+						newHistogram(t, "latency", 0.5, 0.1),
+					},
+					err: nil,
+				},
+			},
+		},
+		{
+			name: "success with summary",
+			writerArg: writerArg{
+				result: []string{"3;PauseNs;z:1,p0:2,sum:2"},
+				err:    nil,
+			},
+			storageArgs: []storageArg{
+				{
+					metrics: []metric.Metric{
+						metric.NewSummaryMetric("PauseNs", 1, 0, 1),
+					},
+					err: nil,
+				},
+			},
+		},
 	}
 
 	for _, tt := range testCase {
@@ -861,3 +1020,16 @@ func Benchmark_FileDumper_restore(b *testing.B) {
 		}
 	}
 }
+
+// newHistogram returns histogram with buckets bounded by bound and one observation v
+func newHistogram(t *testing.T, name string, v float64, bound float64) metric.Metric {
+	h, err := metric.NewHistogramMetric(name, bound)
+	require.NoError(t, err)
+	h.Observe(v)
+	return h
+}
+
+func stamped(m metric.Metric, nsec int64) metric.Metric {
+	m.SetTimestamp(time.Unix(0, nsec))
+	return m
+}
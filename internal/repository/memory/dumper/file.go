@@ -2,11 +2,16 @@ package dumper
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"sync"
 )
 
+// lockSuffix is the suffix of lock file which exists while file is opened by FileStream
+const lockSuffix = ".lock"
+
 // SerialWrite is the interface that group method for Writing, Rewriting and Scanning from source
 type SerialWriter interface {
 	Write(b []byte) (int, error)
@@ -22,23 +27,40 @@ var (
 
 // File is wrapper over simple file and implements SerialWriter interface
 type File struct {
-	fd *os.File
-	mx *sync.Mutex
+	fd   *os.File
+	mx   *sync.Mutex
+	lock string
 	io.Closer
 }
 
-// NewFileStream opens or create file and return pointer to entity or error if can not open file
+// NewFileStream opens or create file and return pointer to entity or error if can not open file.
+// Lock file with suffix .lock is kept next to file until Close, so other processes can find that file is in use
 func NewFileStream(path string) (*File, error) {
 	fd, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
 		return nil, err
 	}
+
+	lock := path + lockSuffix
+	if err := os.WriteFile(lock, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644); err != nil {
+		fd.Close()
+		return nil, err
+	}
+
 	return &File{
-		fd: fd,
-		mx: &sync.Mutex{},
+		fd:   fd,
+		mx:   &sync.Mutex{},
+		lock: lock,
 	}, nil
 }
 
+// InUse reports whether file is opened by FileStream of running process, e.g. by server.
+// Lock file is left if process was killed, then it has to be deleted manually
+func InUse(path string) bool {
+	_, err := os.Stat(path + lockSuffix)
+	return err == nil
+}
+
 // Write - writes data to file
 func (f *File) Write(b []byte) (int, error) {
 	f.mx.Lock()
@@ -77,7 +99,13 @@ func (f *File) Clear() error {
 	return f.fd.Truncate(0)
 }
 
-// Close - closes file
+// Close - closes file and deletes its lock file
 func (f *File) Close() error {
-	return f.fd.Close()
+	err := f.fd.Close()
+	if f.lock != "" {
+		if rmErr := os.Remove(f.lock); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+			err = errors.Join(err, rmErr)
+		}
+	}
+	return err
 }
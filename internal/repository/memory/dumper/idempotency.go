@@ -0,0 +1,36 @@
+package dumper
+
+import (
+	"context"
+	"time"
+
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+var _ repository.IdempotencyRepository = (*FileDumper)(nil)
+
+// SaveOnce saves metrics and remembers key in storage, if FileDumper works in sync mode lines of saved metrics are added to file.
+// Keys live shorter than dumped metrics, so they are not dumped
+func (d *FileDumper) SaveOnce(ctx context.Context, key string, expire time.Time, entity ...metric.Metric) (bool, error) {
+	storage, err := d.keyStorage()
+	if err != nil {
+		return false, err
+	}
+
+	d.srvMx.Lock()
+	defer d.srvMx.Unlock()
+
+	saved, err := storage.SaveOnce(ctx, key, expire, entity...)
+	if err != nil || !saved {
+		return saved, err
+	}
+	return true, d.write(entity...)
+}
+
+func (d *FileDumper) keyStorage() (repository.IdempotencyRepository, error) {
+	if s, ok := d.storage.(repository.IdempotencyRepository); ok {
+		return s, nil
+	}
+	return nil, repository.ErrKeysNotSupported
+}
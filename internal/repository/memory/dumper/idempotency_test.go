@@ -0,0 +1,39 @@
+package dumper
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository"
+	"github.com/vilasle/metrics/internal/repository/memory"
+)
+
+func Test_FileDumper_SaveOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	fs := NewMockSerialWriter(ctrl)
+	d := &FileDumper{fs: fs, storage: memory.NewMetricRepository(), srvMx: &sync.Mutex{}, syncSave: true}
+
+	// line is added only for applied batch
+	fs.EXPECT().Write([]byte("1;PollCount;5\n")).Return(14, nil).Times(1)
+
+	saved, err := d.SaveOnce(ctx, "agent/1", time.Now().Add(time.Hour), metric.NewCounterMetric("PollCount", 5))
+	require.NoError(t, err)
+	assert.True(t, saved)
+
+	saved, err = d.SaveOnce(ctx, "agent/1", time.Now().Add(time.Hour), metric.NewCounterMetric("PollCount", 5))
+	require.NoError(t, err)
+	assert.False(t, saved)
+
+	d = &FileDumper{fs: NewMockSerialWriter(ctrl), storage: NewMockMetricRepository(ctrl), srvMx: &sync.Mutex{}}
+	_, err = d.SaveOnce(ctx, "agent/1", time.Now().Add(time.Hour), metric.NewCounterMetric("PollCount", 5))
+	assert.ErrorIs(t, err, repository.ErrKeysNotSupported)
+}
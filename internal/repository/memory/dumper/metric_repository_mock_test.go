@@ -7,8 +7,11 @@ package dumper
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	alert "github.com/vilasle/metrics/internal/alert"
+	auth "github.com/vilasle/metrics/internal/auth"
 	metric "github.com/vilasle/metrics/internal/metric"
 )
 
@@ -67,6 +70,21 @@ func (mr *MockMetricRepositoryMockRecorder) Get(ctx, metricType interface{}, fil
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockMetricRepository)(nil).Get), varargs...)
 }
 
+// History mocks base method.
+func (m *MockMetricRepository) History(ctx context.Context, metricType, name string, labels metric.Labels, from, to time.Time) ([]metric.Point, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", ctx, metricType, name, labels, from, to)
+	ret0, _ := ret[0].([]metric.Point)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// History indicates an expected call of History.
+func (mr *MockMetricRepositoryMockRecorder) History(ctx, metricType, name, labels, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockMetricRepository)(nil).History), ctx, metricType, name, labels, from, to)
+}
+
 // Ping mocks base method.
 func (m *MockMetricRepository) Ping(ctx context.Context) error {
 	m.ctrl.T.Helper()
@@ -99,3 +117,194 @@ func (mr *MockMetricRepositoryMockRecorder) Save(arg0 interface{}, arg1 ...inter
 	varargs := append([]interface{}{arg0}, arg1...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockMetricRepository)(nil).Save), varargs...)
 }
+
+// MockAlertRepository is a mock of AlertRepository interface.
+type MockAlertRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAlertRepositoryMockRecorder
+}
+
+// MockAlertRepositoryMockRecorder is the mock recorder for MockAlertRepository.
+type MockAlertRepositoryMockRecorder struct {
+	mock *MockAlertRepository
+}
+
+// NewMockAlertRepository creates a new mock instance.
+func NewMockAlertRepository(ctrl *gomock.Controller) *MockAlertRepository {
+	mock := &MockAlertRepository{ctrl: ctrl}
+	mock.recorder = &MockAlertRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAlertRepository) EXPECT() *MockAlertRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Alerts mocks base method.
+func (m *MockAlertRepository) Alerts(ctx context.Context) ([]alert.Alert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Alerts", ctx)
+	ret0, _ := ret[0].([]alert.Alert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Alerts indicates an expected call of Alerts.
+func (mr *MockAlertRepositoryMockRecorder) Alerts(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Alerts", reflect.TypeOf((*MockAlertRepository)(nil).Alerts), ctx)
+}
+
+// DeleteAlert mocks base method.
+func (m *MockAlertRepository) DeleteAlert(ctx context.Context, rule string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAlert", ctx, rule)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAlert indicates an expected call of DeleteAlert.
+func (mr *MockAlertRepositoryMockRecorder) DeleteAlert(ctx, rule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAlert", reflect.TypeOf((*MockAlertRepository)(nil).DeleteAlert), ctx, rule)
+}
+
+// SaveAlert mocks base method.
+func (m *MockAlertRepository) SaveAlert(ctx context.Context, a alert.Alert) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAlert", ctx, a)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAlert indicates an expected call of SaveAlert.
+func (mr *MockAlertRepositoryMockRecorder) SaveAlert(ctx, a interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAlert", reflect.TypeOf((*MockAlertRepository)(nil).SaveAlert), ctx, a)
+}
+
+// MockIdempotencyRepository is a mock of IdempotencyRepository interface.
+type MockIdempotencyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyRepositoryMockRecorder
+}
+
+// MockIdempotencyRepositoryMockRecorder is the mock recorder for MockIdempotencyRepository.
+type MockIdempotencyRepositoryMockRecorder struct {
+	mock *MockIdempotencyRepository
+}
+
+// NewMockIdempotencyRepository creates a new mock instance.
+func NewMockIdempotencyRepository(ctrl *gomock.Controller) *MockIdempotencyRepository {
+	mock := &MockIdempotencyRepository{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotencyRepository) EXPECT() *MockIdempotencyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// SaveOnce mocks base method.
+func (m *MockIdempotencyRepository) SaveOnce(ctx context.Context, key string, expire time.Time, entity ...metric.Metric) (bool, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, key, expire}
+	for _, a := range entity {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SaveOnce", varargs...)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveOnce indicates an expected call of SaveOnce.
+func (mr *MockIdempotencyRepositoryMockRecorder) SaveOnce(ctx, key, expire interface{}, entity ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, key, expire}, entity...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOnce", reflect.TypeOf((*MockIdempotencyRepository)(nil).SaveOnce), varargs...)
+}
+
+// MockTokenRepository is a mock of TokenRepository interface.
+type MockTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenRepositoryMockRecorder
+}
+
+// MockTokenRepositoryMockRecorder is the mock recorder for MockTokenRepository.
+type MockTokenRepositoryMockRecorder struct {
+	mock *MockTokenRepository
+}
+
+// NewMockTokenRepository creates a new mock instance.
+func NewMockTokenRepository(ctrl *gomock.Controller) *MockTokenRepository {
+	mock := &MockTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenRepository) EXPECT() *MockTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteToken mocks base method.
+func (m *MockTokenRepository) DeleteToken(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteToken", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteToken indicates an expected call of DeleteToken.
+func (mr *MockTokenRepositoryMockRecorder) DeleteToken(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteToken", reflect.TypeOf((*MockTokenRepository)(nil).DeleteToken), ctx, id)
+}
+
+// SaveToken mocks base method.
+func (m *MockTokenRepository) SaveToken(ctx context.Context, t auth.Token) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveToken", ctx, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveToken indicates an expected call of SaveToken.
+func (mr *MockTokenRepositoryMockRecorder) SaveToken(ctx, t interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveToken", reflect.TypeOf((*MockTokenRepository)(nil).SaveToken), ctx, t)
+}
+
+// Token mocks base method.
+func (m *MockTokenRepository) Token(ctx context.Context, id string) (auth.Token, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Token", ctx, id)
+	ret0, _ := ret[0].(auth.Token)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Token indicates an expected call of Token.
+func (mr *MockTokenRepositoryMockRecorder) Token(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Token", reflect.TypeOf((*MockTokenRepository)(nil).Token), ctx, id)
+}
+
+// Tokens mocks base method.
+func (m *MockTokenRepository) Tokens(ctx context.Context) ([]auth.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Tokens", ctx)
+	ret0, _ := ret[0].([]auth.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Tokens indicates an expected call of Tokens.
+func (mr *MockTokenRepositoryMockRecorder) Tokens(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Tokens", reflect.TypeOf((*MockTokenRepository)(nil).Tokens), ctx)
+}
@@ -0,0 +1,171 @@
+package dumper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vilasle/metrics/internal/auth"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+// tokenCode is the first part of dumped line of API token like token;id;{"id":"id","hash":"...",...}.
+// Line without token means that token was revoked
+const tokenCode = "token"
+
+var _ repository.TokenRepository = (*FileDumper)(nil)
+
+// SaveToken saves token to storage and if FileDumper works in syncMode, will add new line after saving
+func (d *FileDumper) SaveToken(ctx context.Context, t auth.Token) error {
+	storage, err := d.tokenStorage()
+	if err != nil {
+		return err
+	}
+
+	d.srvMx.Lock()
+	defer d.srvMx.Unlock()
+
+	if err := storage.SaveToken(ctx, t); err != nil {
+		return err
+	}
+	if !d.syncSave {
+		return nil
+	}
+
+	content, err := dumpedToken(t)
+	if err != nil {
+		return err
+	}
+	_, err = d.fs.Write(content)
+	return err
+}
+
+// DeleteToken deletes token from storage and if FileDumper works in syncMode, will add line about deleting
+func (d *FileDumper) DeleteToken(ctx context.Context, id string) error {
+	storage, err := d.tokenStorage()
+	if err != nil {
+		return err
+	}
+
+	d.srvMx.Lock()
+	defer d.srvMx.Unlock()
+
+	if err := storage.DeleteToken(ctx, id); err != nil {
+		return err
+	}
+	if !d.syncSave {
+		return nil
+	}
+
+	_, err = d.fs.Write([]byte(fmt.Sprintf("%s;%s;\n", tokenCode, id)))
+	return err
+}
+
+// Token gets token from storage
+func (d *FileDumper) Token(ctx context.Context, id string) (auth.Token, bool, error) {
+	storage, err := d.tokenStorage()
+	if err != nil {
+		return auth.Token{}, false, err
+	}
+	return storage.Token(ctx, id)
+}
+
+// Tokens gets tokens from storage
+func (d *FileDumper) Tokens(ctx context.Context) ([]auth.Token, error) {
+	storage, err := d.tokenStorage()
+	if err != nil {
+		return nil, err
+	}
+	return storage.Tokens(ctx)
+}
+
+func (d *FileDumper) tokenStorage() (repository.TokenRepository, error) {
+	if s, ok := d.storage.(repository.TokenRepository); ok {
+		return s, nil
+	}
+	return nil, repository.ErrTokensNotSupported
+}
+
+func dumpedToken(t auth.Token) ([]byte, error) {
+	content, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%s;%s;%s\n", tokenCode, t.ID, content)), nil
+}
+
+func isDumpedToken(line string) bool {
+	return strings.HasPrefix(line, tokenCode+";")
+}
+
+// restoreTokens restores tokens from dumped lines, revoked tokens are not restored
+func (d *FileDumper) restoreTokens(ctx context.Context, lines []string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	storage, err := d.tokenStorage()
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, 0)
+	tokens := make(map[string]*auth.Token)
+	for _, line := range lines {
+		raw := strings.SplitN(line, ";", 3)
+		if len(raw) < 3 {
+			errs = append(errs, errors.Join(ErrWrongDumpedLine, fmt.Errorf("wrong line. content=%s", line)))
+			continue
+		}
+
+		id, content := raw[1], raw[2]
+		if content == "" {
+			tokens[id] = nil
+			continue
+		}
+
+		t := auth.Token{}
+		if err := json.Unmarshal([]byte(content), &t); err != nil {
+			errs = append(errs, errors.Join(ErrWrongDumpedLine, fmt.Errorf("wrong token. content=%s", line), err))
+			continue
+		}
+		tokens[id] = &t
+	}
+
+	for id, t := range tokens {
+		if t == nil {
+			// token may be issued and revoked after the last full dump
+			if err := storage.DeleteToken(ctx, id); !errors.Is(err, repository.ErrTokenNotFound) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		errs = append(errs, storage.SaveToken(ctx, *t))
+	}
+	return errors.Join(errs...)
+}
+
+// dumpTokens returns dumped lines of all tokens if storage keeps tokens
+func (d *FileDumper) dumpTokens(ctx context.Context) ([]byte, error) {
+	storage, err := d.tokenStorage()
+	if err != nil {
+		return nil, nil
+	}
+
+	tokens, err := storage.Tokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := make([]byte, 0)
+	for _, t := range tokens {
+		content, err := dumpedToken(t)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, content...)
+	}
+	return rs, nil
+}
@@ -0,0 +1,71 @@
+package dumper
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/auth"
+	"github.com/vilasle/metrics/internal/repository"
+	"github.com/vilasle/metrics/internal/repository/memory"
+)
+
+func Test_FileDumper_SaveToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	fs := NewMockSerialWriter(ctrl)
+	d := &FileDumper{fs: fs, storage: memory.NewMetricRepository(), srvMx: &sync.Mutex{}, syncSave: true}
+
+	fs.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+		assert.True(t, strings.HasPrefix(string(b), `token;a;{"id":"a","name":"dashboard","hash":"h","scopes":["read"]`))
+		return len(b), nil
+	})
+	fs.EXPECT().Write([]byte("token;a;\n")).Return(9, nil)
+
+	require.NoError(t, d.SaveToken(ctx, auth.Token{ID: "a", Name: "dashboard", Hash: "h", Scopes: []auth.Scope{auth.ScopeRead}}))
+	require.NoError(t, d.DeleteToken(ctx, "a"))
+
+	_, ok, err := d.Token(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// unknown token is not dumped
+	assert.ErrorIs(t, d.DeleteToken(ctx, "a"), repository.ErrTokenNotFound)
+}
+
+func Test_FileDumper_restoreTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	fs := NewMockSerialWriter(ctrl)
+	storage := memory.NewMetricRepository()
+
+	fs.EXPECT().ScanAll().Return([]string{
+		`token;a;{"id":"a","hash":"h1","scopes":["read"]}`,
+		`0;gauge1;1`,
+		`token;b;{"id":"b","hash":"h2","scopes":["write"]}`,
+		`token;a;`,
+	}, nil)
+
+	d := &FileDumper{fs: fs, storage: storage, srvMx: &sync.Mutex{}}
+	require.NoError(t, d.restore(ctx))
+
+	got, err := storage.Tokens(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []auth.Token{{ID: "b", Hash: "h2", Scopes: []auth.Scope{auth.ScopeWrite}}}, got)
+
+	// tokens are dumped with metrics
+	fs.EXPECT().Rewrite(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+		assert.Contains(t, string(b), "0;gauge1;1\n")
+		assert.Contains(t, string(b), `token;b;{"id":"b"`)
+		return len(b), nil
+	})
+	require.NoError(t, d.DumpAll(ctx))
+}
@@ -1,8 +1,6 @@
 package memory
 
 import (
-	"sync"
-
 	"github.com/vilasle/metrics/internal/metric"
 )
 
@@ -10,66 +8,44 @@ type getter interface {
 	get(nameFilter ...string) ([]metric.Metric, error)
 }
 
-type gaugeGetter struct {
-	mx      *sync.Mutex
-	storage gaugeStorage
+// seriesGetter returns stored values of series as is, they are not changed after saving
+type seriesGetter struct {
+	storage *typeStorage
 }
 
-func (g gaugeGetter) get(nameFilter ...string) ([]metric.Metric, error) {
-	g.mx.Lock()
-	defer g.mx.Unlock()
-
-	if len(nameFilter) == 0 {
-		return g.all(), nil
-	}
+func (g seriesGetter) get(nameFilter ...string) ([]metric.Metric, error) {
+	g.storage.mx.Lock()
+	defer g.storage.mx.Unlock()
 
 	metrics := make([]metric.Metric, 0)
-	for _, name := range nameFilter {
-		if m, ok := g.storage[name]; ok {
-			metrics = append(metrics, m)
+	for _, series := range g.storage.filter(nameFilter...) {
+		for _, values := range series {
+			metrics = append(metrics, values...)
 		}
 	}
 	return metrics, nil
-
-}
-
-func (g gaugeGetter) all() []metric.Metric {
-	rs := make([]metric.Metric, 0, len(g.storage))
-	for _, m := range g.storage {
-		rs = append(rs, m)
-	}
-	return rs
 }
 
-type counterGetter struct {
-	mx      *sync.Mutex
-	storage counterStorage
+// mergeGetter returns copies of stored values, because stored ones are changed by merging
+type mergeGetter struct {
+	storage *typeStorage
 }
 
-func (g counterGetter) get(nameFilter ...string) ([]metric.Metric, error) {
-	g.mx.Lock()
-	defer g.mx.Unlock()
-
-	if len(nameFilter) == 0 {
-		return g.all(), nil
-	}
+func (g mergeGetter) get(nameFilter ...string) ([]metric.Metric, error) {
+	g.storage.mx.Lock()
+	defer g.storage.mx.Unlock()
 
 	metrics := make([]metric.Metric, 0)
-	for _, name := range nameFilter {
-		if m, ok := g.storage[name]; ok {
-			metrics = append(metrics, m...)
+	for name, series := range g.storage.filter(nameFilter...) {
+		for _, values := range series {
+			m, err := g.storage.info.Merge(name, values)
+			if err != nil {
+				continue
+			}
+			metrics = append(metrics, m)
 		}
 	}
 	return metrics, nil
-
-}
-
-func (g counterGetter) all() []metric.Metric {
-	rs := make([]metric.Metric, 0, len(g.storage))
-	for _, m := range g.storage {
-		rs = append(rs, m...)
-	}
-	return rs
 }
 
 type unknownGetter struct{}
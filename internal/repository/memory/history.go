@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vilasle/metrics/internal/metric"
+)
+
+// defaultHistoryLimit is the count of points which are kept for each metric by default
+const defaultHistoryLimit = 10000
+
+// historyStorage keeps values of metrics with time of saving by type and identifier of series
+type historyStorage struct {
+	mx     *sync.Mutex
+	limit  int
+	series map[string]map[string]*historySeries
+}
+
+// historySeries is the values of one series sorted by time
+type historySeries struct {
+	name   string
+	labels metric.Labels
+	points []metric.Point
+}
+
+func newHistoryStorage(limit int) *historyStorage {
+	return &historyStorage{
+		mx:     &sync.Mutex{},
+		limit:  limit,
+		series: make(map[string]map[string]*historySeries),
+	}
+}
+
+func (h *historyStorage) add(m metric.Metric, t time.Time) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	bySeries, ok := h.series[m.Type()]
+	if !ok {
+		bySeries = make(map[string]*historySeries)
+		h.series[m.Type()] = bySeries
+	}
+
+	id := metric.SeriesID(m.Name(), m.Labels())
+	series, ok := bySeries[id]
+	if !ok {
+		series = &historySeries{name: m.Name(), labels: m.Labels().Clone()}
+		bySeries[id] = series
+	}
+
+	points := series.points
+	// late samples are inserted to their place, so points stay sorted by time
+	i := sort.Search(len(points), func(i int) bool {
+		return points[i].Time.After(t)
+	})
+	points = slices.Insert(points, i, metric.Point{Time: t, Value: m.Float64()})
+	if h.limit > 0 && len(points) > h.limit {
+		points = points[len(points)-h.limit:]
+	}
+	series.points = points
+}
+
+// get returns points between from and to of series of metric whose labels contain all pairs of labels,
+// points of several series are merged by time
+func (h *historyStorage) get(metricType, name string, labels metric.Labels, from, to time.Time) []metric.Point {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	rs := make([]metric.Point, 0)
+	matched := 0
+	for _, series := range h.series[metricType] {
+		if series.name != name || !series.labels.Match(labels) {
+			continue
+		}
+		rs = append(rs, series.between(from, to)...)
+		matched++
+	}
+	if matched > 1 {
+		sort.SliceStable(rs, func(i, j int) bool { return rs[i].Time.Before(rs[j].Time) })
+	}
+	return rs
+}
+
+// between returns points between from and to, points are stored sorted by time
+func (s *historySeries) between(from, to time.Time) []metric.Point {
+	points := s.points
+
+	start := sort.Search(len(points), func(i int) bool {
+		return !points[i].Time.Before(from)
+	})
+	end := sort.Search(len(points), func(i int) bool {
+		return points[i].Time.After(to)
+	})
+
+	if start >= end {
+		return nil
+	}
+	return points[start:end]
+}
@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+func Test_historyStorage(t *testing.T) {
+	h := newHistoryStorage(3)
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		h.add(metric.NewGaugeMetric("gauge1", float64(i)), begin.Add(time.Duration(i)*time.Minute))
+	}
+
+	// the oldest points must be dropped by limit
+	got := h.get(metric.TypeGauge, "gauge1", nil, begin, begin.Add(time.Hour))
+	assert.Equal(t, []metric.Point{
+		{Time: begin.Add(2 * time.Minute), Value: 2},
+		{Time: begin.Add(3 * time.Minute), Value: 3},
+		{Time: begin.Add(4 * time.Minute), Value: 4},
+	}, got)
+
+	got = h.get(metric.TypeGauge, "gauge1", nil, begin.Add(3*time.Minute), begin.Add(3*time.Minute))
+	assert.Equal(t, []metric.Point{{Time: begin.Add(3 * time.Minute), Value: 3}}, got)
+
+	got = h.get(metric.TypeCounter, "gauge1", nil, begin, begin.Add(time.Hour))
+	assert.Empty(t, got)
+}
+
+func TestMemoryMetricRepository_History(t *testing.T) {
+	r := NewMetricRepository()
+	ctx := context.Background()
+
+	from := time.Now()
+	require.NoError(t, r.Save(ctx,
+		metric.NewGaugeMetric("gauge1", 1.5),
+		metric.NewCounterMetric("counter1", 2),
+		metric.NewCounterMetric("counter1", 3),
+	))
+	to := time.Now()
+
+	gauges, err := r.History(ctx, metric.TypeGauge, "gauge1", nil, from, to)
+	require.NoError(t, err)
+	require.Len(t, gauges, 1)
+	assert.Equal(t, 1.5, gauges[0].Value)
+
+	counters, err := r.History(ctx, metric.TypeCounter, "counter1", nil, from, to)
+	require.NoError(t, err)
+	require.Len(t, counters, 2)
+	assert.Equal(t, 2.0, counters[0].Value)
+	assert.Equal(t, 3.0, counters[1].Value)
+
+	_, err = r.History(ctx, "unknown", "counter1", nil, from, to)
+	assert.ErrorIs(t, err, repository.ErrUnknownMetricType)
+}
+
+func TestMemoryMetricRepository_HistoryLabels(t *testing.T) {
+	r := NewMetricRepository()
+	ctx := context.Background()
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	stamped := func(m metric.Metric, labels metric.Labels, ts time.Time) metric.Metric {
+		m.SetTimestamp(ts)
+		return metric.WithLabels(m, labels)
+	}
+
+	// stored series has extra label which is not in filter
+	require.NoError(t, r.Save(ctx,
+		stamped(metric.NewGaugeMetric("gauge1", 1), metric.Labels{"host": "a", "region": "eu"}, begin),
+		stamped(metric.NewGaugeMetric("gauge1", 2), metric.Labels{"host": "b", "region": "eu"}, begin.Add(time.Minute)),
+		stamped(metric.NewGaugeMetric("gauge1", 3), metric.Labels{"host": "a", "region": "us"}, begin.Add(2*time.Minute)),
+	))
+	to := begin.Add(time.Hour)
+
+	got, err := r.History(ctx, metric.TypeGauge, "gauge1", metric.Labels{"host": "a", "region": "eu"}, begin, to)
+	require.NoError(t, err)
+	assert.Equal(t, []metric.Point{{Time: begin, Value: 1}}, got)
+
+	got, err = r.History(ctx, metric.TypeGauge, "gauge1", metric.Labels{"host": "a"}, begin, to)
+	require.NoError(t, err)
+	assert.Equal(t, []metric.Point{{Time: begin, Value: 1}, {Time: begin.Add(2 * time.Minute), Value: 3}}, got)
+
+	// series of all labels are merged by time
+	got, err = r.History(ctx, metric.TypeGauge, "gauge1", nil, begin, to)
+	require.NoError(t, err)
+	assert.Equal(t, []metric.Point{
+		{Time: begin, Value: 1},
+		{Time: begin.Add(time.Minute), Value: 2},
+		{Time: begin.Add(2 * time.Minute), Value: 3},
+	}, got)
+
+	got, err = r.History(ctx, metric.TypeGauge, "gauge1", metric.Labels{"host": "c"}, begin, to)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestMemoryMetricRepository_LateSamples(t *testing.T) {
+	r := NewMetricRepository()
+	ctx := context.Background()
+	begin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	stamped := func(m metric.Metric, ts time.Time) metric.Metric {
+		m.SetTimestamp(ts)
+		return m
+	}
+
+	require.NoError(t, r.Save(ctx, stamped(metric.NewGaugeMetric("gauge1", 2), begin.Add(2*time.Minute))))
+	// late sample does not replace the last value but takes its place in history
+	require.NoError(t, r.Save(ctx, stamped(metric.NewGaugeMetric("gauge1", 1), begin.Add(time.Minute))))
+
+	last, err := r.Get(ctx, metric.TypeGauge, "gauge1")
+	require.NoError(t, err)
+	require.Len(t, last, 1)
+	assert.Equal(t, "2", last[0].Value())
+
+	points, err := r.History(ctx, metric.TypeGauge, "gauge1", nil, begin, begin.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []metric.Point{
+		{Time: begin.Add(time.Minute), Value: 1},
+		{Time: begin.Add(2 * time.Minute), Value: 2},
+	}, points)
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+var _ repository.IdempotencyRepository = (*MemoryMetricRepository)(nil)
+
+// purgePeriod is the minimal period between deletions of expired keys
+const purgePeriod = time.Minute
+
+// keyStorage keeps idempotency keys with time of their expiration
+type keyStorage struct {
+	mx     *sync.Mutex
+	keys   map[string]time.Time
+	purged time.Time
+	now    func() time.Time
+}
+
+func newKeyStorage() *keyStorage {
+	return &keyStorage{
+		mx:   &sync.Mutex{},
+		keys: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+// SaveOnce saves metrics and remembers key until expire, it returns false if key is already remembered and is not expired.
+// Batches with keys are saved under one lock, so retry of batch waits for the first attempt.
+// Batch is saved wholly or is not saved at all, so key of failed batch is not remembered and its retry is applied once
+func (r *MemoryMetricRepository) SaveOnce(ctx context.Context, key string, expire time.Time, entity ...metric.Metric) (bool, error) {
+	return r.keys.once(key, expire, func() error {
+		return r.Save(ctx, entity...)
+	})
+}
+
+// once calls save if key is not remembered, key is remembered only if save succeeds
+func (s *keyStorage) once(key string, expire time.Time, save func() error) (bool, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := s.now()
+	s.purge(now)
+
+	if e, ok := s.keys[key]; ok && e.After(now) {
+		return false, nil
+	}
+	if err := save(); err != nil {
+		return false, err
+	}
+	s.keys[key] = expire
+	return true, nil
+}
+
+// purge deletes expired keys not often than once per purgePeriod. The caller must hold the lock of storage
+func (s *keyStorage) purge(now time.Time) {
+	if now.Sub(s.purged) < purgePeriod {
+		return
+	}
+	for key, expire := range s.keys {
+		if !expire.After(now) {
+			delete(s.keys, key)
+		}
+	}
+	s.purged = now
+}
@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+func TestMemoryMetricRepository_SaveOnce(t *testing.T) {
+	r := NewMetricRepository()
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.keys.now = func() time.Time { return now }
+
+	// values of counter are summed by service
+	counter := func() int64 {
+		t.Helper()
+		rs, err := r.Get(ctx, metric.TypeCounter, "PollCount")
+		require.NoError(t, err)
+		var sum int64
+		for _, m := range rs {
+			sum += m.Int64()
+		}
+		return sum
+	}
+
+	saved, err := r.SaveOnce(ctx, "agent/1", now.Add(time.Hour), metric.NewCounterMetric("PollCount", 5))
+	require.NoError(t, err)
+	assert.True(t, saved)
+
+	saved, err = r.SaveOnce(ctx, "agent/1", now.Add(time.Hour), metric.NewCounterMetric("PollCount", 5))
+	require.NoError(t, err)
+	assert.False(t, saved)
+	assert.Equal(t, int64(5), counter())
+
+	saved, err = r.SaveOnce(ctx, "agent/2", now.Add(time.Minute), metric.NewCounterMetric("PollCount", 1))
+	require.NoError(t, err)
+	assert.True(t, saved)
+	assert.Equal(t, int64(6), counter())
+
+	// key of batch which is not saved is not remembered
+	_, err = r.SaveOnce(ctx, "agent/3", now.Add(time.Hour))
+	require.Error(t, err)
+	assert.NotContains(t, r.keys.keys, "agent/3")
+
+	// batch with metric which can not be saved is not applied partially, so its retry is applied once
+	_, err = r.SaveOnce(ctx, "agent/5", now.Add(time.Hour), metric.NewCounterMetric("PollCount", 1), wrongMetric{})
+	require.ErrorIs(t, err, repository.ErrUnknownMetricType)
+	assert.NotContains(t, r.keys.keys, "agent/5")
+	assert.Equal(t, int64(6), counter())
+
+	saved, err = r.SaveOnce(ctx, "agent/5", now.Add(time.Hour), metric.NewCounterMetric("PollCount", 1))
+	require.NoError(t, err)
+	assert.True(t, saved)
+	assert.Equal(t, int64(7), counter())
+
+	// expired key can be used again and is deleted by purge
+	now = now.Add(2 * time.Minute)
+	_, err = r.SaveOnce(ctx, "agent/4", now.Add(time.Hour), metric.NewCounterMetric("PollCount", 1))
+	require.NoError(t, err)
+	assert.NotContains(t, r.keys.keys, "agent/2")
+
+	saved, err = r.SaveOnce(ctx, "agent/2", now.Add(time.Hour), metric.NewCounterMetric("PollCount", 1))
+	require.NoError(t, err)
+	assert.True(t, saved)
+	assert.Equal(t, int64(9), counter())
+}
+
+func TestKeyStorage_OnceConcurrent(t *testing.T) {
+	s := newKeyStorage()
+	expire := time.Now().Add(time.Hour)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// the first attempt fails after the retry has come
+		saved, err := s.once("agent/1", expire, func() error {
+			close(started)
+			<-release
+			return errors.New("failed")
+		})
+		assert.Error(t, err)
+		assert.False(t, saved)
+	}()
+
+	<-started
+	retried := make(chan bool)
+	go func() {
+		saved, err := s.once("agent/1", expire, func() error { return nil })
+		assert.NoError(t, err)
+		retried <- saved
+	}()
+
+	close(release)
+	wg.Wait()
+	assert.True(t, <-retried, "retry must be applied after the first attempt failed")
+}
@@ -4,31 +4,92 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/vilasle/metrics/internal/metric"
 	"github.com/vilasle/metrics/internal/repository"
 )
 
-type gaugeStorage map[string]metric.Metric
+// typeStorage keeps series of one type of metric by name and labels.
+// How values of the series are kept depends on aggregation of type
+type typeStorage struct {
+	mx     *sync.Mutex
+	info   metric.TypeInfo
+	series map[string]map[string][]metric.Metric
+}
+
+func newTypeStorage(info metric.TypeInfo) *typeStorage {
+	return &typeStorage{
+		mx:     &sync.Mutex{},
+		info:   info,
+		series: make(map[string]map[string][]metric.Metric),
+	}
+}
+
+// byName returns series of metric by labels, series is created if it does not exist.
+// The caller must hold the lock of storage
+func (s *typeStorage) byName(name string) map[string][]metric.Metric {
+	series, ok := s.series[name]
+	if !ok {
+		series = make(map[string][]metric.Metric, 1)
+		s.series[name] = series
+	}
+	return series
+}
+
+// filter returns series of metrics with names from nameFilter or all series if nameFilter is empty.
+// The caller must hold the lock of storage
+func (s *typeStorage) filter(nameFilter ...string) map[string]map[string][]metric.Metric {
+	if len(nameFilter) == 0 {
+		return s.series
+	}
 
-type counterStorage map[string][]metric.Metric
+	rs := make(map[string]map[string][]metric.Metric, len(nameFilter))
+	for _, name := range nameFilter {
+		if series, ok := s.series[name]; ok {
+			rs[name] = series
+		}
+	}
+	return rs
+}
 
 // MemoryMetricRepository is the struct that implements the repository.MetricRepository interface and stores the metrics in memory.
 type MemoryMetricRepository struct {
-	mxGauge   *sync.Mutex
-	gauges    gaugeStorage
-	mxCounter *sync.Mutex
-	counters  counterStorage
+	mx       *sync.Mutex
+	storages map[string]*typeStorage
+	history  *historyStorage
+	alerts   *alertStorage
+	keys     *keyStorage
+	tokens   *tokenStorage
+}
+
+// Option is the setting of MemoryMetricRepository
+type Option func(*MemoryMetricRepository)
+
+// WithHistoryLimit sets the count of points which are kept in history for each metric.
+// The oldest points are dropped when limit is exceeded, 0 means without limit
+func WithHistoryLimit(limit int) Option {
+	return func(r *MemoryMetricRepository) {
+		r.history.limit = limit
+	}
 }
 
 // NewMetricRepository returns a new instance of MemoryMetricRepository.
-func NewMetricRepository() *MemoryMetricRepository {
-	return &MemoryMetricRepository{
-		mxGauge:   &sync.Mutex{},
-		gauges:    make(gaugeStorage),
-		mxCounter: &sync.Mutex{},
-		counters:  make(counterStorage),
+func NewMetricRepository(opts ...Option) *MemoryMetricRepository {
+	r := &MemoryMetricRepository{
+		mx:       &sync.Mutex{},
+		storages: make(map[string]*typeStorage),
+		history:  newHistoryStorage(defaultHistoryLimit),
+		alerts:   newAlertStorage(),
+		keys:     newKeyStorage(),
+		tokens:   newTokenStorage(),
 	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
 }
 
 // Save saves the metrics in the repository
@@ -38,8 +99,7 @@ func (r *MemoryMetricRepository) Save(ctx context.Context, entity ...metric.Metr
 	case 0:
 		return repository.ErrEmptySetOfMetric
 	case 1:
-		e := entity[0]
-		return r.getSaver(e.Type()).save(e)
+		return r.save(entity[0])
 	default:
 		return r.saveAll(entity...)
 	}
@@ -50,6 +110,15 @@ func (r *MemoryMetricRepository) Get(ctx context.Context, metricType string, fil
 	return r.getGetter(metricType).get(filterName...)
 }
 
+// History - returns values of series of metric which were saved between from and to ordered by time.
+// Series are matched by subset of labels, so values of all series which have labels are returned
+func (r *MemoryMetricRepository) History(ctx context.Context, metricType, name string, labels metric.Labels, from, to time.Time) ([]metric.Point, error) {
+	if t, ok := metric.LookupType(metricType); !ok || !withHistory(t) {
+		return nil, repository.ErrUnknownMetricType
+	}
+	return r.history.get(metricType, name, labels, from, to), nil
+}
+
 // Ping - check connection with repository
 func (r *MemoryMetricRepository) Ping(ctx context.Context) error {
 	return nil
@@ -58,29 +127,98 @@ func (r *MemoryMetricRepository) Ping(ctx context.Context) error {
 // Close - closes the repository
 func (r *MemoryMetricRepository) Close() {}
 
+// storage returns storage of registered type, storage is created on the first call
+func (r *MemoryMetricRepository) storage(metricType string) (*typeStorage, bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if s, ok := r.storages[metricType]; ok {
+		return s, true
+	}
+
+	t, ok := metric.LookupType(metricType)
+	if !ok {
+		return nil, false
+	}
+	s := newTypeStorage(t)
+	r.storages[metricType] = s
+	return s, true
+}
+
 func (r *MemoryMetricRepository) getSaver(metricType string) saver {
-	if metricType == metric.TypeGauge {
-		return gaugeSaver{storage: r.gauges, mx: r.mxGauge}
-	} else if metricType == metric.TypeCounter {
-		return counterSaver{storage: r.counters, mx: r.mxCounter}
+	s, ok := r.storage(metricType)
+	if !ok {
+		return unknownSaver{}
+	}
+
+	switch s.info.Aggregation {
+	case metric.AggregateLast:
+		return lastSaver{storage: s}
+	case metric.AggregateSum:
+		return sumSaver{storage: s}
+	default:
+		return mergeSaver{storage: s}
+	}
+}
+
+func (r *MemoryMetricRepository) save(entity metric.Metric) error {
+	if err := r.getSaver(entity.Type()).save(entity); err != nil {
+		return err
+	}
+	if t, ok := metric.LookupType(entity.Type()); ok && withHistory(t) {
+		ts := entity.Timestamp()
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		r.history.add(entity, ts)
 	}
-	return unknownSaver{}
+	return nil
 }
 
+// saveAll saves metrics only if all of them can be saved, so batch is not applied partially as in transaction
 func (r *MemoryMetricRepository) saveAll(entity ...metric.Metric) error {
-	errs := make([]error, len(entity))
+	errs := make([]error, 0, len(entity))
+	for _, e := range entity {
+		errs = append(errs, r.check(e))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
 
 	for _, e := range entity {
-		errs = append(errs, r.getSaver(e.Type()).save(e))
+		errs = append(errs, r.save(e))
 	}
 	return errors.Join(errs...)
 }
 
+// check returns error which save returns for entity without saving it
+func (r *MemoryMetricRepository) check(entity metric.Metric) error {
+	s, ok := r.storage(entity.Type())
+	if !ok {
+		return repository.ErrUnknownMetricType
+	}
+	if s.info.Aggregation == metric.AggregateLast || s.info.Aggregation == metric.AggregateSum {
+		return nil
+	}
+	// merged types are saved if entity itself can be merged, see mergeSaver
+	_, err := s.info.Merge(entity.Name(), []metric.Metric{entity})
+	return err
+}
+
 func (r *MemoryMetricRepository) getGetter(metricType string) getter {
-	if metricType == metric.TypeGauge {
-		return gaugeGetter{storage: r.gauges, mx: r.mxGauge}
-	} else if metricType == metric.TypeCounter {
-		return counterGetter{storage: r.counters, mx: r.mxCounter}
+	s, ok := r.storage(metricType)
+	if !ok {
+		return unknownGetter{}
+	}
+
+	if s.info.Aggregation == metric.AggregateMerge {
+		return mergeGetter{storage: s}
 	}
-	return unknownGetter{}
+	return seriesGetter{storage: s}
+}
+
+// withHistory returns true if history is kept for type. Values of merged types are not numbers
+// that's why history is kept only for types like gauge and counter
+func withHistory(t metric.TypeInfo) bool {
+	return t.Aggregation == metric.AggregateLast || t.Aggregation == metric.AggregateSum
 }
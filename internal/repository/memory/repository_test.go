@@ -5,10 +5,12 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository"
 )
 
 type wrongMetric struct{}
@@ -44,6 +46,18 @@ func (m wrongMetric) Int64() int64 {
 	return 0
 }
 
+func (m wrongMetric) Labels() metric.Labels {
+	return nil
+}
+
+func (m wrongMetric) SetLabels(metric.Labels) {}
+
+func (m wrongMetric) Timestamp() time.Time {
+	return time.Time{}
+}
+
+func (m wrongMetric) SetTimestamp(time.Time) {}
+
 func TestMemoryMetricRepository_Save(t *testing.T) {
 	testCases := []struct {
 		name    string
@@ -102,11 +116,8 @@ func TestMemoryMetricRepository_Save(t *testing.T) {
 			}
 
 			for _, m := range tt.value {
-				if m.Type() == metric.TypeGauge {
-					v := r.gauges[m.Name()]
-					assert.True(t, reflect.DeepEqual(v, m))
-				} else if m.Type() == metric.TypeCounter {
-					v := r.counters[m.Name()][0]
+				if m.Type() == metric.TypeGauge || m.Type() == metric.TypeCounter {
+					v := r.storages[m.Type()].series[m.Name()][m.Labels().String()][0]
 					assert.True(t, reflect.DeepEqual(v, m))
 				}
 			}
@@ -267,3 +278,73 @@ func TestMemoryMetricRepository_saveAll(t *testing.T) {
 	err := r.saveAll(testMetrics...)
 	require.NoError(t, err)
 }
+
+func TestMemoryMetricRepository_SaveLabels(t *testing.T) {
+	r := NewMetricRepository()
+	ctx := context.Background()
+
+	require.NoError(t, r.Save(ctx,
+		metric.WithLabels(metric.NewGaugeMetric("cpu", 1), metric.Labels{"host": "a"}),
+		metric.WithLabels(metric.NewGaugeMetric("cpu", 2), metric.Labels{"host": "b"}),
+		metric.WithLabels(metric.NewGaugeMetric("cpu", 3), metric.Labels{"host": "a"}),
+		metric.NewGaugeMetric("cpu", 4),
+	))
+
+	got, err := r.Get(ctx, metric.TypeGauge, "cpu")
+	require.NoError(t, err)
+
+	values := make(map[string]string, len(got))
+	for _, m := range got {
+		values[m.Labels().String()] = m.Value()
+	}
+	assert.Equal(t, map[string]string{`host="a"`: "3", `host="b"`: "2", "": "4"}, values)
+}
+
+func TestMemoryMetricRepository_SaveHistogram(t *testing.T) {
+	r := NewMetricRepository()
+	ctx := context.Background()
+
+	newHistogram := func(bounds []float64, counts []int64, sum float64) metric.Metric {
+		h, err := metric.NewHistogramMetricFromData("latency", metric.HistogramData{Bounds: bounds, Counts: counts, Sum: sum})
+		require.NoError(t, err)
+		return h
+	}
+
+	first := newHistogram([]float64{0.1, 1}, []int64{1, 2, 0}, 1.5)
+	require.NoError(t, r.Save(ctx, first, newHistogram([]float64{0.1, 1}, []int64{0, 1, 3}, 10)))
+
+	// stored histogram is not shared with saved metric
+	require.NoError(t, first.AddValue(0.05))
+
+	got, err := r.Get(ctx, metric.TypeHistogram, "latency")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "0.1:1,1:3,+Inf:3,sum:11.5", got[0].Value())
+
+	// bounds are changed, series starts again
+	require.NoError(t, r.Save(ctx, newHistogram([]float64{5}, []int64{2, 0}, 3)))
+
+	got, err = r.Get(ctx, metric.TypeHistogram, "latency")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "5:2,+Inf:0,sum:3", got[0].Value())
+}
+
+func TestMemoryMetricRepository_SaveSummary(t *testing.T) {
+	r := NewMetricRepository()
+	ctx := context.Background()
+
+	first := metric.NewSummaryMetric("PauseNs", 1, 2)
+	require.NoError(t, r.Save(ctx, first, metric.NewSummaryMetric("PauseNs", 3)))
+
+	// stored summary is not shared with saved metric
+	first.Observe(4)
+
+	got, err := r.Get(ctx, metric.TypeSummary, "PauseNs")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, metric.NewSummaryMetric("PauseNs", 1, 2, 3).Value(), got[0].Value())
+
+	_, err = r.History(ctx, metric.TypeSummary, "PauseNs", nil, time.Time{}, time.Now())
+	assert.ErrorIs(t, err, repository.ErrUnknownMetricType)
+}
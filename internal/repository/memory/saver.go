@@ -1,8 +1,6 @@
 package memory
 
 import (
-	"sync"
-
 	"github.com/vilasle/metrics/internal/metric"
 	"github.com/vilasle/metrics/internal/repository"
 )
@@ -11,34 +9,69 @@ type saver interface {
 	save(metric.Metric) error
 }
 
-type gaugeSaver struct {
-	storage gaugeStorage
-	mx      *sync.Mutex
+// lastSaver replaces value of series by entity, it is used for types like gauge.
+// Value is not replaced by entity which is older than it, entity without timestamp is received now, so it is the newest
+type lastSaver struct {
+	storage *typeStorage
 }
 
-func (s gaugeSaver) save(entity metric.Metric) error {
-	s.mx.Lock()
-	defer s.mx.Unlock()
-	s.storage[entity.Name()] = entity
+func (s lastSaver) save(entity metric.Metric) error {
+	s.storage.mx.Lock()
+	defer s.storage.mx.Unlock()
+
+	series := s.storage.byName(entity.Name())
+	key := entity.Labels().String()
+	if ts := entity.Timestamp(); !ts.IsZero() {
+		if last := series[key]; len(last) > 0 && last[0].Timestamp().After(ts) {
+			return nil
+		}
+	}
+	series[key] = []metric.Metric{entity}
 
 	return nil
 }
 
-type counterSaver struct {
-	storage counterStorage
-	mx      *sync.Mutex
+// sumSaver appends entity to values of series, it is used for types like counter
+type sumSaver struct {
+	storage *typeStorage
 }
 
-func (s counterSaver) save(entity metric.Metric) error {
-	s.mx.Lock()
-	defer s.mx.Unlock()
+func (s sumSaver) save(entity metric.Metric) error {
+	s.storage.mx.Lock()
+	defer s.storage.mx.Unlock()
 
-	name := entity.Name()
-	if _, ok := s.storage[name]; !ok {
-		s.storage[name] = make([]metric.Metric, 0, 1)
+	series := s.storage.byName(entity.Name())
+	key := entity.Labels().String()
+	series[key] = append(series[key], entity)
+
+	return nil
+}
+
+// mergeSaver merges entity to the stored value of series, it is used for types like histogram.
+// If entity can not be merged, e.g. bounds of buckets of histogram are changed, the series is replaced by entity
+type mergeSaver struct {
+	storage *typeStorage
+}
+
+func (s mergeSaver) save(entity metric.Metric) error {
+	s.storage.mx.Lock()
+	defer s.storage.mx.Unlock()
+
+	series := s.storage.byName(entity.Name())
+	key := entity.Labels().String()
+	if current, ok := series[key]; ok {
+		if merged, err := s.storage.info.Merge(entity.Name(), append(current, entity)); err == nil {
+			series[key] = []metric.Metric{merged}
+			return nil
+		}
 	}
 
-	s.storage[name] = append(s.storage[name], entity)
+	// entity is copied, because it may be changed by caller
+	stored, err := s.storage.info.Merge(entity.Name(), []metric.Metric{entity})
+	if err != nil {
+		return err
+	}
+	series[key] = []metric.Metric{stored}
 
 	return nil
 }
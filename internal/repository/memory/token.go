@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/vilasle/metrics/internal/auth"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+var _ repository.TokenRepository = (*MemoryMetricRepository)(nil)
+
+// tokenStorage keeps API tokens by id
+type tokenStorage struct {
+	mx     *sync.Mutex
+	tokens map[string]auth.Token
+}
+
+func newTokenStorage() *tokenStorage {
+	return &tokenStorage{
+		mx:     &sync.Mutex{},
+		tokens: make(map[string]auth.Token),
+	}
+}
+
+// SaveToken saves token, token with the same id is replaced
+func (r *MemoryMetricRepository) SaveToken(ctx context.Context, t auth.Token) error {
+	r.tokens.mx.Lock()
+	defer r.tokens.mx.Unlock()
+
+	r.tokens.tokens[t.ID] = t
+	return nil
+}
+
+// DeleteToken deletes token by id, it returns repository.ErrTokenNotFound if token does not exist
+func (r *MemoryMetricRepository) DeleteToken(ctx context.Context, id string) error {
+	r.tokens.mx.Lock()
+	defer r.tokens.mx.Unlock()
+
+	if _, ok := r.tokens.tokens[id]; !ok {
+		return errors.Join(repository.ErrTokenNotFound, fmt.Errorf("id %s", id))
+	}
+	delete(r.tokens.tokens, id)
+	return nil
+}
+
+// Token returns token by id, it returns false if token does not exist
+func (r *MemoryMetricRepository) Token(ctx context.Context, id string) (auth.Token, bool, error) {
+	r.tokens.mx.Lock()
+	defer r.tokens.mx.Unlock()
+
+	t, ok := r.tokens.tokens[id]
+	return t, ok, nil
+}
+
+// Tokens returns tokens ordered by id
+func (r *MemoryMetricRepository) Tokens(ctx context.Context) ([]auth.Token, error) {
+	r.tokens.mx.Lock()
+	defer r.tokens.mx.Unlock()
+
+	rs := make([]auth.Token, 0, len(r.tokens.tokens))
+	for _, t := range r.tokens.tokens {
+		rs = append(rs, t)
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].ID < rs[j].ID })
+	return rs, nil
+}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/auth"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+func TestMemoryMetricRepository_Tokens(t *testing.T) {
+	r := NewMetricRepository()
+	ctx := context.Background()
+
+	require.NoError(t, r.SaveToken(ctx, auth.Token{ID: "b", Scopes: []auth.Scope{auth.ScopeWrite}}))
+	require.NoError(t, r.SaveToken(ctx, auth.Token{ID: "a", Scopes: []auth.Scope{auth.ScopeRead}}))
+
+	got, ok, err := r.Token(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a", got.ID)
+
+	all, err := r.Tokens(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "a", all[0].ID)
+
+	require.NoError(t, r.DeleteToken(ctx, "a"))
+	_, ok, err = r.Token(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.ErrorIs(t, r.DeleteToken(ctx, "a"), repository.ErrTokenNotFound)
+}
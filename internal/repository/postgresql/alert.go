@@ -0,0 +1,55 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/vilasle/metrics/internal/alert"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+var _ repository.AlertRepository = (*PostgresqlMetricRepository)(nil)
+
+// SaveAlert saves state of alert to table alerts, previous state of alert of the same rule is replaced
+func (r *PostgresqlMetricRepository) SaveAlert(ctx context.Context, a alert.Alert) error {
+	content, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	txt := `
+	INSERT INTO alerts ("rule", "state", "alert")
+	VALUES ($1, $2, $3::jsonb)
+	ON CONFLICT ("rule") DO UPDATE SET "state" = EXCLUDED."state", "alert" = EXCLUDED."alert";
+	`
+	return r.db.exec(ctx, txt, a.Rule, string(a.State), string(content))
+}
+
+// DeleteAlert deletes alert of rule from table alerts
+func (r *PostgresqlMetricRepository) DeleteAlert(ctx context.Context, rule string) error {
+	return r.db.exec(ctx, `DELETE FROM alerts WHERE "rule" = $1`, rule)
+}
+
+// Alerts returns alerts ordered by name of rule
+func (r *PostgresqlMetricRepository) Alerts(ctx context.Context) ([]alert.Alert, error) {
+	rows, err := r.db.query(ctx, `SELECT alert FROM alerts ORDER BY "rule"`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rs := make([]alert.Alert, 0)
+	for rows.Next() {
+		var content []byte
+		if err := rows.Scan(&content); err != nil {
+			return nil, err
+		}
+
+		var a alert.Alert
+		if err := json.Unmarshal(content, &a); err != nil {
+			return nil, err
+		}
+		rs = append(rs, a)
+	}
+	return rs, rows.Err()
+}
@@ -0,0 +1,42 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/alert"
+)
+
+func TestPostgresqlMetricRepository_Alerts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "can not create sqlmock")
+
+	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
+	repo := PostgresqlMetricRepository{db: r}
+	ctx := context.Background()
+
+	a := alert.Alert{Rule: "heap", State: alert.StateFiring, Value: 2e9, ActiveSince: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	content, err := json.Marshal(a)
+	require.NoError(t, err)
+
+	mock.ExpectExec(`INSERT INTO alerts`).WithArgs("heap", "firing", string(content)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT alert FROM alerts`).
+		WillReturnRows(sqlmock.NewRows([]string{"alert"}).AddRow(content))
+	mock.ExpectExec(`DELETE FROM alerts`).WithArgs("heap").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	require.NoError(t, repo.SaveAlert(ctx, a))
+
+	got, err := repo.Alerts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []alert.Alert{a}, got)
+
+	require.NoError(t, repo.DeleteAlert(ctx, "heap"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
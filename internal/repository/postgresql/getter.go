@@ -3,6 +3,7 @@ package postgresql
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 
 	"github.com/vilasle/metrics/internal/metric"
 )
@@ -30,10 +31,10 @@ func (g *counterGetter) get(ctx context.Context, filterName ...string) ([]metric
 
 func (g *counterGetter) getByFilter(ctx context.Context, name ...string) ([]metric.Metric, error) {
 	txt := `
-		SELECT id, SUM(value) 
+		SELECT id, SUM(value), labels 
 		FROM counters 
 		WHERE "id" = any($1)
-		GROUP BY id
+		GROUP BY id, labels
 		`
 
 	if r, err := g.db.query(ctx, txt, name); err == nil {
@@ -44,7 +45,7 @@ func (g *counterGetter) getByFilter(ctx context.Context, name ...string) ([]metr
 }
 
 func (g *counterGetter) getAll(ctx context.Context) ([]metric.Metric, error) {
-	txt := `SELECT id, value FROM counters`
+	txt := `SELECT id, value, labels FROM counters`
 	if r, err := g.db.query(ctx, txt); err == nil {
 		return g.parseResult(r)
 	} else {
@@ -57,10 +58,15 @@ func (g *counterGetter) parseResult(rows *sql.Rows) ([]metric.Metric, error) {
 	for rows.Next() {
 		var name string
 		var value int64
-		if err := rows.Scan(&name, &value); err != nil {
+		var rawLabels []byte
+		if err := rows.Scan(&name, &value, &rawLabels); err != nil {
 			return nil, err
 		}
-		rs = append(rs, metric.NewCounterMetric(name, value))
+		labels, err := parseLabels(rawLabels)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, metric.WithLabels(metric.NewCounterMetric(name, value), labels))
 	}
 	return rs, rows.Err()
 
@@ -78,7 +84,7 @@ func (g *gaugeGetter) get(ctx context.Context, filterName ...string) ([]metric.M
 }
 
 func (g *gaugeGetter) getByFilter(ctx context.Context, name ...string) ([]metric.Metric, error) {
-	txt := `SELECT id, value FROM gauges WHERE "id" = any($1)`
+	txt := `SELECT id, value, labels FROM gauges WHERE "id" = any($1)`
 	if r, err := g.db.query(ctx, txt, name); err == nil {
 		return g.parseResult(r)
 	} else {
@@ -87,7 +93,7 @@ func (g *gaugeGetter) getByFilter(ctx context.Context, name ...string) ([]metric
 }
 
 func (g *gaugeGetter) getAll(ctx context.Context) ([]metric.Metric, error) {
-	txt := `SELECT id, value FROM gauges`
+	txt := `SELECT id, value, labels FROM gauges`
 	if r, err := g.db.query(ctx, txt); err == nil {
 		return g.parseResult(r)
 	} else {
@@ -100,11 +106,224 @@ func (g *gaugeGetter) parseResult(rows *sql.Rows) ([]metric.Metric, error) {
 	for rows.Next() {
 		var name string
 		var value float64
-		if err := rows.Scan(&name, &value); err != nil {
+		var rawLabels []byte
+		if err := rows.Scan(&name, &value, &rawLabels); err != nil {
+			return nil, err
+		}
+		labels, err := parseLabels(rawLabels)
+		if err != nil {
 			return nil, err
 		}
-		rs = append(rs, metric.NewGaugeMetric(name, value))
+		rs = append(rs, metric.WithLabels(metric.NewGaugeMetric(name, value), labels))
 	}
 	return rs, nil
 
 }
+
+type histogramGetter struct {
+	db repeater
+}
+
+func (g *histogramGetter) get(ctx context.Context, filterName ...string) ([]metric.Metric, error) {
+	if len(filterName) == 0 {
+		return g.getAll(ctx)
+	}
+	return g.getByFilter(ctx, filterName...)
+}
+
+func (g *histogramGetter) getByFilter(ctx context.Context, name ...string) ([]metric.Metric, error) {
+	txt := `SELECT id, bounds, counts, sum, labels FROM histograms WHERE "id" = any($1)`
+	if r, err := g.db.query(ctx, txt, name); err == nil {
+		return g.parseResult(r)
+	} else {
+		return []metric.Metric{}, err
+	}
+}
+
+func (g *histogramGetter) getAll(ctx context.Context) ([]metric.Metric, error) {
+	txt := `SELECT id, bounds, counts, sum, labels FROM histograms`
+	if r, err := g.db.query(ctx, txt); err == nil {
+		return g.parseResult(r)
+	} else {
+		return []metric.Metric{}, err
+	}
+}
+
+func (g *histogramGetter) parseResult(rows *sql.Rows) ([]metric.Metric, error) {
+	rs := make([]metric.Metric, 0)
+	for rows.Next() {
+		var (
+			name                            string
+			data                            metric.HistogramData
+			rawBounds, rawCounts, rawLabels []byte
+		)
+		if err := rows.Scan(&name, &rawBounds, &rawCounts, &data.Sum, &rawLabels); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(rawBounds, &data.Bounds); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(rawCounts, &data.Counts); err != nil {
+			return nil, err
+		}
+		labels, err := parseLabels(rawLabels)
+		if err != nil {
+			return nil, err
+		}
+
+		h, err := metric.NewHistogramMetricFromData(name, data)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, metric.WithLabels(h, labels))
+	}
+	return rs, rows.Err()
+}
+
+type summaryGetter struct {
+	db repeater
+}
+
+func (g *summaryGetter) get(ctx context.Context, filterName ...string) ([]metric.Metric, error) {
+	if len(filterName) == 0 {
+		return g.getAll(ctx)
+	}
+	return g.getByFilter(ctx, filterName...)
+}
+
+func (g *summaryGetter) getByFilter(ctx context.Context, name ...string) ([]metric.Metric, error) {
+	txt := `SELECT id, positive, negative, zero, sum, labels FROM summaries WHERE "id" = any($1)`
+	if r, err := g.db.query(ctx, txt, name); err == nil {
+		return g.parseResult(r)
+	} else {
+		return []metric.Metric{}, err
+	}
+}
+
+func (g *summaryGetter) getAll(ctx context.Context) ([]metric.Metric, error) {
+	txt := `SELECT id, positive, negative, zero, sum, labels FROM summaries`
+	if r, err := g.db.query(ctx, txt); err == nil {
+		return g.parseResult(r)
+	} else {
+		return []metric.Metric{}, err
+	}
+}
+
+func (g *summaryGetter) parseResult(rows *sql.Rows) ([]metric.Metric, error) {
+	rs := make([]metric.Metric, 0)
+	for rows.Next() {
+		var (
+			name                                string
+			data                                metric.SummaryData
+			rawPositive, rawNegative, rawLabels []byte
+		)
+		if err := rows.Scan(&name, &rawPositive, &rawNegative, &data.Zero, &data.Sum, &rawLabels); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(rawPositive, &data.Positive); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(rawNegative, &data.Negative); err != nil {
+			return nil, err
+		}
+		labels, err := parseLabels(rawLabels)
+		if err != nil {
+			return nil, err
+		}
+
+		s, err := metric.NewSummaryMetricFromData(name, data)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, metric.WithLabels(s, labels))
+	}
+	return rs, rows.Err()
+}
+
+// registeredGetter reads metrics of registered types which do not have own tables
+// and aggregates values of each series by rule of type
+type registeredGetter struct {
+	db   repeater
+	info metric.TypeInfo
+}
+
+func (g *registeredGetter) get(ctx context.Context, filterName ...string) ([]metric.Metric, error) {
+	txt := `SELECT id, value, labels FROM metric_values WHERE "type" = $1 ORDER BY created_at`
+	args := []any{g.info.Name}
+	if len(filterName) > 0 {
+		txt = `SELECT id, value, labels FROM metric_values WHERE "type" = $1 AND "id" = any($2) ORDER BY created_at`
+		args = append(args, filterName)
+	}
+
+	if r, err := g.db.query(ctx, txt, args...); err == nil {
+		return g.parseResult(r)
+	} else {
+		return []metric.Metric{}, err
+	}
+}
+
+func (g *registeredGetter) parseResult(rows *sql.Rows) ([]metric.Metric, error) {
+	order := make([]string, 0)
+	series := make(map[string][]metric.Metric)
+	for rows.Next() {
+		var name, value string
+		var rawLabels []byte
+		if err := rows.Scan(&name, &value, &rawLabels); err != nil {
+			return nil, err
+		}
+		labels, err := parseLabels(rawLabels)
+		if err != nil {
+			return nil, err
+		}
+		m, err := g.info.Parse(name, value)
+		if err != nil {
+			return nil, err
+		}
+		m.SetLabels(labels)
+
+		id := metric.SeriesID(name, labels)
+		if _, ok := series[id]; !ok {
+			order = append(order, id)
+		}
+		series[id] = append(series[id], m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rs := make([]metric.Metric, 0, len(order))
+	for _, id := range order {
+		values := series[id]
+		if g.info.Aggregation == metric.AggregateLast {
+			rs = append(rs, values[len(values)-1])
+			continue
+		}
+		m, err := g.info.Merge(values[0].Name(), values)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, m)
+	}
+	return rs, nil
+}
+
+// labelsArg returns labels as json for passing to query, empty labels are passed as empty object
+func labelsArg(labels metric.Labels) string {
+	if len(labels) == 0 {
+		return "{}"
+	}
+	b, _ := json.Marshal(labels)
+	return string(b)
+}
+
+func parseLabels(raw []byte) (metric.Labels, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var labels metric.Labels
+	if err := json.Unmarshal(raw, &labels); err != nil {
+		return nil, err
+	}
+	return labels.Clone(), nil
+}
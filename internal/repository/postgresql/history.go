@@ -0,0 +1,139 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/vilasle/metrics/internal/logger"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+// Option is the setting of PostgresqlMetricRepository
+type Option func(*PostgresqlMetricRepository)
+
+// WithGaugeHistory enables storing of all values of gauges with time of saving in table gauge_history.
+// retention defines how long values are kept, if it is 0 values are kept forever
+func WithGaugeHistory(retention time.Duration) Option {
+	return func(r *PostgresqlMetricRepository) {
+		r.gaugeHistory = true
+		r.retention = retention
+	}
+}
+
+// History returns values of series of metric which were saved between from and to ordered by time.
+// Series are matched by subset of labels, so values of all series which have labels are returned.
+// For gauges history has to be enabled by WithGaugeHistory, counters are always stored with time of saving
+func (r *PostgresqlMetricRepository) History(ctx context.Context, metricType, name string, labels metric.Labels, from, to time.Time) ([]metric.Point, error) {
+	var txt string
+	switch metricType {
+	case metric.TypeGauge:
+		if !r.gaugeHistory {
+			return nil, repository.ErrHistoryDisabled
+		}
+		txt = `
+		SELECT "created_at", "value"
+		FROM gauge_history
+		WHERE "id" = $1 AND "labels" @> $4::jsonb AND "created_at" BETWEEN $2 AND $3
+		ORDER BY "created_at"
+		`
+	case metric.TypeCounter:
+		txt = `
+		SELECT "created_at", "value"
+		FROM counters
+		WHERE "id" = $1 AND "labels" @> $4::jsonb AND "created_at" BETWEEN $2 AND $3
+		ORDER BY "created_at"
+		`
+	default:
+		return nil, metric.ErrUnknownMetricType
+	}
+
+	rows, err := r.db.query(ctx, txt, name, from, to, labelsArg(labels))
+	if err != nil {
+		return nil, err
+	}
+	return parsePoints(rows)
+}
+
+func parsePoints(rows *sql.Rows) ([]metric.Point, error) {
+	defer rows.Close()
+
+	rs := make([]metric.Point, 0)
+	for rows.Next() {
+		var p metric.Point
+		if err := rows.Scan(&p.Time, &p.Value); err != nil {
+			return nil, err
+		}
+		rs = append(rs, p)
+	}
+	return rs, rows.Err()
+}
+
+type gaugeHistorySaver struct {
+	db repeater
+}
+
+func (s gaugeHistorySaver) save(ctx context.Context, m metric.Metric) error {
+	return s.db.exec(ctx, s.saveTxt(), m.Name(), m.Float64(), observedAt(m), labelsArg(m.Labels()))
+}
+
+func (s gaugeHistorySaver) saveTxt() string {
+	return `
+	INSERT INTO gauge_history ("id", "value", "created_at", "labels")
+	VALUES ($1, $2, $3, $4::jsonb)
+	`
+}
+
+// savers saves metric by each saver in order
+type savers []saver
+
+func (s savers) save(ctx context.Context, m metric.Metric) error {
+	for _, sv := range s {
+		if err := sv.save(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PostgresqlMetricRepository) runRetention() {
+	if !r.gaugeHistory || r.retention <= 0 {
+		return
+	}
+
+	go func() {
+		t := time.NewTicker(min(r.retention, time.Hour))
+		defer t.Stop()
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-t.C:
+				if err := r.deleteExpiredHistory(context.Background()); err != nil {
+					logger.Error("can not delete expired gauge history", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (r *PostgresqlMetricRepository) deleteExpiredHistory(ctx context.Context) error {
+	txt := `DELETE FROM gauge_history WHERE "created_at" < $1`
+	return r.db.exec(ctx, txt, time.Now().Add(-r.retention))
+}
+
+func createHistoryTableTxt() string {
+	return `
+	CREATE TABLE IF NOT EXISTS gauge_history (
+    	"id" VARCHAR(100) NOT NULL,
+    	"value" DOUBLE PRECISION NOT NULL,
+    	"created_at" TIMESTAMPTZ NOT NULL,
+    	"labels" JSONB NOT NULL DEFAULT '{}'
+	);
+
+	ALTER TABLE gauge_history ADD COLUMN IF NOT EXISTS "labels" JSONB NOT NULL DEFAULT '{}';
+
+	CREATE INDEX IF NOT EXISTS gauge_history_id_created_at_idx ON gauge_history ("id", "created_at");
+	`
+}
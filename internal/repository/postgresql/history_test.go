@@ -0,0 +1,142 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+func Test_gaugeHistorySaver_save(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "can not create sqlmock")
+
+	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
+	s := gaugeHistorySaver{r}
+
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`INSERT INTO gauge_history`).WithArgs("gauge1", 1.123, ts, "{}").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	m := metric.NewGaugeMetric("gauge1", 1.123)
+	m.SetTimestamp(ts)
+	err = s.save(context.Background(), m)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlMetricRepository_SaveWithHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "can not create sqlmock")
+
+	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
+	repo := PostgresqlMetricRepository{db: r, gaugeHistory: true}
+
+	mock.ExpectExec(`INSERT INTO gauges`).WithArgs("gauge1", 1.123, "{}", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO gauge_history`).WithArgs("gauge1", 1.123, sqlmock.AnyArg(), "{}").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = repo.Save(context.Background(), metric.NewGaugeMetric("gauge1", 1.123))
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresqlMetricRepository_History(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	testCases := []struct {
+		name       string
+		history    bool
+		metricType string
+		setup      func(mock sqlmock.Sqlmock)
+		want       []metric.Point
+		wantErr    error
+	}{
+		{
+			name:       "gauge",
+			history:    true,
+			metricType: metric.TypeGauge,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`FROM gauge_history\s+WHERE "id" = \$1 AND "labels" @> \$4::jsonb`).WithArgs("metric", from, to, `{"host":"a"}`).
+					WillReturnRows(sqlmock.NewRows([]string{"created_at", "value"}).
+						AddRow(from, 1.5).
+						AddRow(from.Add(time.Minute), 2.5))
+			},
+			want: []metric.Point{
+				{Time: from, Value: 1.5},
+				{Time: from.Add(time.Minute), Value: 2.5},
+			},
+		},
+		{
+			name:       "counter",
+			metricType: metric.TypeCounter,
+			// filter is the subset of labels, so series with extra labels e.g. {"host":"a","region":"eu"} are matched
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`FROM counters\s+WHERE "id" = \$1 AND "labels" @> \$4::jsonb`).WithArgs("metric", from, to, `{"host":"a"}`).
+					WillReturnRows(sqlmock.NewRows([]string{"created_at", "value"}).
+						AddRow(from, 3))
+			},
+			want: []metric.Point{
+				{Time: from, Value: 3},
+			},
+		},
+		{
+			name:       "gauge history is disabled",
+			metricType: metric.TypeGauge,
+			setup:      func(mock sqlmock.Sqlmock) {},
+			wantErr:    repository.ErrHistoryDisabled,
+		},
+		{
+			name:       "unknown type",
+			metricType: "unknown",
+			setup:      func(mock sqlmock.Sqlmock) {},
+			wantErr:    metric.ErrUnknownMetricType,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err, "can not create sqlmock")
+
+			tt.setup(mock)
+
+			r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
+			repo := PostgresqlMetricRepository{db: r, gaugeHistory: tt.history}
+
+			got, err := repo.History(context.Background(), tt.metricType, "metric", metric.Labels{"host": "a"}, from, to)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresqlMetricRepository_NewRepositoryWithHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "can not create sqlmock")
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS counters").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS gauge_history").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	repo, err := NewRepository(db, WithGaugeHistory(time.Hour))
+	require.NoError(t, err)
+	defer repo.Close()
+
+	assert.True(t, repo.gaugeHistory)
+	assert.Equal(t, time.Hour, repo.retention)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,56 @@
+package postgresql
+
+import (
+	"context"
+	"time"
+
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+var _ repository.IdempotencyRepository = (*PostgresqlMetricRepository)(nil)
+
+// SaveOnce inserts key to table idempotency_keys and saves metrics in one transaction, expired keys are deleted before.
+// Insert of key which is inserted by transaction in progress waits for its end, so retry of batch can not be applied
+// while the first attempt is not finished and is applied if the first attempt is rolled back
+func (r *PostgresqlMetricRepository) SaveOnce(ctx context.Context, key string, expire time.Time, entity ...metric.Metric) (bool, error) {
+	if err := r.db.exec(ctx, `DELETE FROM idempotency_keys WHERE "expire_at" <= $1`, time.Now()); err != nil {
+		return false, err
+	}
+
+	tx, err := r.db.begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	txRepo := r.inTx(tx)
+
+	claimed, err := txRepo.claimKey(ctx, key, expire)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	if err := txRepo.saveEach(ctx, entity...); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// claimKey inserts key to table idempotency_keys, it returns false if key already exists
+func (r *PostgresqlMetricRepository) claimKey(ctx context.Context, key string, expire time.Time) (bool, error) {
+	txt := `
+	INSERT INTO idempotency_keys ("key", "expire_at")
+	VALUES ($1, $2)
+	ON CONFLICT ("key") DO NOTHING
+	RETURNING "key";
+	`
+	rows, err := r.db.query(ctx, txt, key, expire)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	claimed := rows.Next()
+	return claimed, rows.Err()
+}
@@ -0,0 +1,65 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+)
+
+func TestPostgresqlMetricRepository_SaveOnce(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "can not create sqlmock")
+
+	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
+	repo := PostgresqlMetricRepository{db: r}
+	ctx := context.Background()
+	expire := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	m := metric.NewCounterMetric("PollCount", 5)
+
+	expectClaim := func(claimed bool) {
+		mock.ExpectExec(`DELETE FROM idempotency_keys WHERE "expire_at"`).WithArgs(sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectBegin()
+		rows := sqlmock.NewRows([]string{"key"})
+		if claimed {
+			rows.AddRow("agent/1")
+		}
+		mock.ExpectQuery(`INSERT INTO idempotency_keys`).WithArgs("agent/1", expire).WillReturnRows(rows)
+	}
+
+	// key and metrics are saved in one transaction
+	expectClaim(true)
+	mock.ExpectExec("INSERT INTO counters").
+		WithArgs(m.Name(), m.Int64(), sqlmock.AnyArg(), labelsArg(m.Labels())).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// batch with known key is not saved
+	expectClaim(false)
+	mock.ExpectRollback()
+
+	// key is rolled back together with metrics which are not saved
+	expectClaim(true)
+	mock.ExpectExec("INSERT INTO counters").WillReturnError(errors.New("failed"))
+	mock.ExpectRollback()
+
+	saved, err := repo.SaveOnce(ctx, "agent/1", expire, m)
+	require.NoError(t, err)
+	assert.True(t, saved)
+
+	saved, err = repo.SaveOnce(ctx, "agent/1", expire, m)
+	require.NoError(t, err)
+	assert.False(t, saved)
+
+	saved, err = repo.SaveOnce(ctx, "agent/1", expire, m)
+	require.Error(t, err)
+	assert.False(t, saved)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -29,15 +29,17 @@ func Test_counterGetter_get(t *testing.T) {
 	getter := counterGetter{r}
 
 	mock.
-		ExpectQuery(`SELECT id, value FROM counters`).
+		ExpectQuery(`SELECT id, value, labels FROM counters`).
 		WillReturnRows(
-			sqlmock.NewRows([]string{"id", "value"}).
-				AddRow("counter1", 1))
+			sqlmock.NewRows([]string{"id", "value", "labels"}).
+				AddRow("counter1", 1, []byte("{}")).
+				AddRow("counter1", 2, []byte(`{"host":"a"}`)))
 
 	result, err := getter.get(context.Background())
 
 	expected := []metric.Metric{
 		metric.NewCounterMetric("counter1", 1),
+		metric.WithLabels(metric.NewCounterMetric("counter1", 2), metric.Labels{"host": "a"}),
 	}
 
 	assert.NoError(t, err)
@@ -52,10 +54,10 @@ func Test_gaugeGetter_get(t *testing.T) {
 	getter := gaugeGetter{r}
 
 	mock.
-		ExpectQuery(`SELECT id, value FROM gauges`).
+		ExpectQuery(`SELECT id, value, labels FROM gauges`).
 		WillReturnRows(
-			sqlmock.NewRows([]string{"id", "value"}).
-				AddRow("gauge1", 1.123))
+			sqlmock.NewRows([]string{"id", "value", "labels"}).
+				AddRow("gauge1", 1.123, []byte("{}")))
 
 	result, err := getter.get(context.Background())
 
@@ -82,10 +84,13 @@ func Test_counterSaver_save(t *testing.T) {
 	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
 	s := counterSaver{r}
 
-	mock.ExpectExec(`INSERT INTO counters`).WithArgs("counter1", int64(1)).
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`INSERT INTO counters`).WithArgs("counter1", int64(1), ts, "{}").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	err = s.save(context.Background(), metric.NewCounterMetric("counter1", 1))
+	m := metric.NewCounterMetric("counter1", 1)
+	m.SetTimestamp(ts)
+	err = s.save(context.Background(), m)
 	assert.NoError(t, err)
 }
 
@@ -96,13 +101,161 @@ func Test_gaugeSaver_save(t *testing.T) {
 	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
 	s := gaugeSaver{r}
 
-	mock.ExpectExec(`INSERT INTO gauges`).WithArgs("gauge1", 1.123).
+	mock.ExpectExec(`INSERT INTO gauges`).WithArgs("gauge1", 1.123, `{"host":"a"}`, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	err = s.save(context.Background(), metric.NewGaugeMetric("gauge1", 1.123))
+	err = s.save(context.Background(), metric.WithLabels(metric.NewGaugeMetric("gauge1", 1.123), metric.Labels{"host": "a"}))
 	assert.NoError(t, err)
 }
 
+func Test_histogramSaver_save(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "can not create sqlmock")
+
+	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
+	s := histogramSaver{r}
+
+	h, err := metric.NewHistogramMetric("latency", 0.1, 0.5)
+	require.NoError(t, err)
+	h.Observe(0.3)
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	h.SetTimestamp(ts)
+
+	mock.ExpectExec(`INSERT INTO histograms`).WithArgs("latency", "{}", "[0.1,0.5]", "[0,1,0]", 0.3, ts).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = s.save(context.Background(), h)
+	assert.NoError(t, err)
+
+	err = s.save(context.Background(), metric.NewGaugeMetric("gauge1", 1))
+	assert.ErrorIs(t, err, metric.ErrUnknownMetricType)
+}
+
+func Test_histogramGetter_get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "can not create sqlmock")
+
+	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
+	getter := histogramGetter{r}
+
+	mock.
+		ExpectQuery(`SELECT id, bounds, counts, sum, labels FROM histograms`).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "bounds", "counts", "sum", "labels"}).
+				AddRow("latency", []byte("[0.1,0.5]"), []byte("[1,2,0]"), 0.75, []byte(`{"host":"a"}`)))
+
+	result, err := getter.get(context.Background())
+	require.NoError(t, err)
+
+	h, err := metric.NewHistogramMetricFromData("latency", metric.HistogramData{
+		Bounds: []float64{0.1, 0.5},
+		Counts: []int64{1, 2, 0},
+		Sum:    0.75,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []metric.Metric{metric.WithLabels(h, metric.Labels{"host": "a"})}, result)
+}
+
+func Test_summarySaver_save(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "can not create sqlmock")
+
+	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
+	s := summarySaver{r}
+
+	sm := metric.NewSummaryMetric("PauseNs", 1, 0, -1)
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	sm.SetTimestamp(ts)
+
+	mock.ExpectExec(`INSERT INTO summaries`).WithArgs("PauseNs", "{}", `{"0":1}`, `{"0":1}`, int64(1), float64(0), ts).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = s.save(context.Background(), sm)
+	assert.NoError(t, err)
+
+	err = s.save(context.Background(), metric.NewGaugeMetric("gauge1", 1))
+	assert.ErrorIs(t, err, metric.ErrUnknownMetricType)
+}
+
+func Test_summaryGetter_get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "can not create sqlmock")
+
+	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
+	getter := summaryGetter{r}
+
+	mock.
+		ExpectQuery(`SELECT id, positive, negative, zero, sum, labels FROM summaries`).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "positive", "negative", "zero", "sum", "labels"}).
+				AddRow("PauseNs", []byte(`{"0":2,"18":1}`), []byte("{}"), int64(1), 4.0, []byte(`{"host":"a"}`)))
+
+	result, err := getter.get(context.Background())
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	assert.Equal(t, metric.TypeSummary, result[0].Type())
+	assert.Equal(t, "z:1,p0:2,p18:1,sum:4", result[0].Value())
+	assert.Equal(t, metric.Labels{"host": "a"}, result[0].Labels())
+}
+
+func Test_registeredSaver_save(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "can not create sqlmock")
+
+	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
+	saver := registeredSaver{r}
+
+	m := metric.WithLabels(metric.NewGaugeMetric("uptime", 1.5), metric.Labels{"host": "a"})
+	mock.ExpectExec("INSERT INTO metric_values").
+		WithArgs(metric.TypeGauge, "uptime", `{"host":"a"}`, "1.5", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	require.NoError(t, saver.save(context.Background(), m))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_registeredGetter_get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "can not create sqlmock")
+
+	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
+
+	// gauge and counter are used as types without own tables
+	gauge, _ := metric.LookupType(metric.TypeGauge)
+	counter, _ := metric.LookupType(metric.TypeCounter)
+
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "value", "labels"}).
+			AddRow("uptime", "1", []byte(`{"host":"a"}`)).
+			AddRow("uptime", "3", []byte(`{"host":"b"}`)).
+			AddRow("uptime", "2", []byte(`{"host":"a"}`))
+	}
+
+	mock.ExpectQuery(`SELECT id, value, labels FROM metric_values WHERE "type" = \$1 ORDER BY`).
+		WithArgs(metric.TypeGauge).
+		WillReturnRows(rows())
+	mock.ExpectQuery(`SELECT id, value, labels FROM metric_values WHERE "type" = \$1 ORDER BY`).
+		WithArgs(metric.TypeCounter).
+		WillReturnRows(rows())
+
+	// only the last value of series is kept for gauge, values of counter are summed
+	result, err := (&registeredGetter{db: r, info: gauge}).get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []metric.Metric{
+		metric.WithLabels(metric.NewGaugeMetric("uptime", 2), metric.Labels{"host": "a"}),
+		metric.WithLabels(metric.NewGaugeMetric("uptime", 3), metric.Labels{"host": "b"}),
+	}, result)
+
+	result, err = (&registeredGetter{db: r, info: counter}).get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []metric.Metric{
+		metric.WithLabels(metric.NewCounterMetric("uptime", 3), metric.Labels{"host": "a"}),
+		metric.WithLabels(metric.NewCounterMetric("uptime", 3), metric.Labels{"host": "b"}),
+	}, result)
+}
+
 type mockMetric struct{}
 
 func (mockMetric) Name() string {
@@ -127,6 +280,16 @@ func (mockMetric) Float64() float64 {
 func (mockMetric) Int64() int64 {
 	return 1
 }
+
+func (mockMetric) Labels() metric.Labels {
+	return nil
+}
+
+func (mockMetric) SetLabels(metric.Labels) {}
+func (mockMetric) Timestamp() time.Time {
+	return time.Time{}
+}
+func (mockMetric) SetTimestamp(time.Time) {}
 func (mockMetric) String() string {
 	return "mock"
 }
@@ -142,11 +305,11 @@ func TestPostgresqlMetricRepository_Save(t *testing.T) {
 		for _, m := range metrics {
 			if m.Type() == metric.TypeCounter {
 				mock.ExpectExec("INSERT INTO counters").
-					WithArgs(m.Name(), m.Int64()).
+					WithArgs(m.Name(), m.Int64(), sqlmock.AnyArg(), labelsArg(m.Labels())).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 			} else if m.Type() == metric.TypeGauge {
 				mock.ExpectExec("INSERT INTO gauges").
-					WithArgs(m.Name(), m.Float64()).
+					WithArgs(m.Name(), m.Float64(), labelsArg(m.Labels()), sqlmock.AnyArg()).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 			}
 		}
@@ -221,7 +384,7 @@ func TestPostgresqlMetricRepository_Save(t *testing.T) {
 
 			r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
 
-			repo := PostgresqlMetricRepository{r}
+			repo := PostgresqlMetricRepository{db: r}
 
 			err = repo.Save(tt.ctx, tt.metrics...)
 			if tt.want != nil {
@@ -253,7 +416,7 @@ func TestPostgresqlMetricRepository_getGetter(t *testing.T) {
 	db, _, err := sqlmock.New()
 	require.NoError(t, err)
 	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
-	repo := PostgresqlMetricRepository{r}
+	repo := PostgresqlMetricRepository{db: r}
 
 	gGetter := repo.getGetter("gauge")
 	cGetter := repo.getGetter("counter")
@@ -6,11 +6,31 @@ import (
 	"time"
 )
 
+// executor executes statements on database or inside transaction
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
 type repeater struct {
 	db          *sql.DB
+	tx          *sql.Tx
 	repeatSteps []time.Duration
 }
 
+// inTx returns repeater which executes statements inside tx. Failed statement aborts transaction,
+// so statements are not repeated
+func (r repeater) inTx(tx *sql.Tx) repeater {
+	return repeater{db: r.db, tx: tx, repeatSteps: []time.Duration{0}}
+}
+
+func (r repeater) executor() executor {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.db
+}
+
 func (r repeater) repeat(fn func() error) (err error) {
 	for _, d := range r.repeatSteps {
 		if err = fn(); err == nil {
@@ -23,14 +43,27 @@ func (r repeater) repeat(fn func() error) (err error) {
 
 func (r repeater) exec(ctx context.Context, sql string, args ...interface{}) (err error) {
 	return r.repeat(func() error {
-		_, err := r.db.ExecContext(ctx, sql, args...)
+		_, err := r.executor().ExecContext(ctx, sql, args...)
+		return err
+	})
+}
+
+// execAffected executes query and returns number of affected rows
+func (r repeater) execAffected(ctx context.Context, sql string, args ...interface{}) (affected int64, err error) {
+	err = r.repeat(func() error {
+		rs, err := r.executor().ExecContext(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		affected, err = rs.RowsAffected()
 		return err
 	})
+	return affected, err
 }
 
 func (r repeater) query(ctx context.Context, sql string, args ...interface{}) (rows *sql.Rows, err error) {
 	r.repeat(func() error {
-		rows, err = r.db.QueryContext(ctx, sql, args...)
+		rows, err = r.executor().QueryContext(ctx, sql, args...)
 		if err == nil && rows.Err() != nil {
 			err = rows.Err()
 		}
@@ -17,16 +17,24 @@ var (
 // PostgresqlMetricRepository is the structure that implements the repository.MetricRepository interface 
 // and stores the metrics in a Postgresql database.
 type PostgresqlMetricRepository struct {
-	db repeater
+	db           repeater
+	gaugeHistory bool
+	retention    time.Duration
+	done         chan struct{}
 }
 
 // NewRepository creates instance of PostgresqlMetricRepository
-func NewRepository(db *sql.DB) (*PostgresqlMetricRepository, error) {
+func NewRepository(db *sql.DB, opts ...Option) (*PostgresqlMetricRepository, error) {
 	r := &PostgresqlMetricRepository{
 		db: repeater{
 			db:          db,
 			repeatSteps: []time.Duration{time.Second * 1, time.Second * 3, time.Second * 5},
 		},
+		done: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
 	}
 
 	ctx := context.Background()
@@ -37,8 +45,13 @@ func NewRepository(db *sql.DB) (*PostgresqlMetricRepository, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = r.initMetadata(ctx)
-	return r, err
+	if err = r.initMetadata(ctx); err != nil {
+		return r, err
+	}
+
+	r.runRetention()
+
+	return r, nil
 }
 
 // Save saves the metrics in the repository or returns an error if the set of metrics is empty or the metric type is unknown.
@@ -66,18 +79,52 @@ func (r *PostgresqlMetricRepository) Ping(ctx context.Context) error {
 
 // Close closes the connection with the repository
 func (r *PostgresqlMetricRepository) Close() {
+	if r.done != nil {
+		close(r.done)
+	}
 	r.db.close()
 }
 
+// adapter creates saver and getter for type of metric. Each type is stored in its own tables
+type adapter struct {
+	saver  func(r *PostgresqlMetricRepository) saver
+	getter func(r *PostgresqlMetricRepository) getter
+}
+
+// adapters keeps adapters by type of metric. Other registered types are stored in table metric_values
+// by their text representation, see registeredSaver
+var adapters = map[string]adapter{
+	metric.TypeGauge: {
+		saver: func(r *PostgresqlMetricRepository) saver {
+			if r.gaugeHistory {
+				return savers{&gaugeSaver{db: r.db}, &gaugeHistorySaver{db: r.db}}
+			}
+			return &gaugeSaver{db: r.db}
+		},
+		getter: func(r *PostgresqlMetricRepository) getter { return &gaugeGetter{db: r.db} },
+	},
+	metric.TypeCounter: {
+		saver:  func(r *PostgresqlMetricRepository) saver { return &counterSaver{db: r.db} },
+		getter: func(r *PostgresqlMetricRepository) getter { return &counterGetter{db: r.db} },
+	},
+	metric.TypeHistogram: {
+		saver:  func(r *PostgresqlMetricRepository) saver { return &histogramSaver{db: r.db} },
+		getter: func(r *PostgresqlMetricRepository) getter { return &histogramGetter{db: r.db} },
+	},
+	metric.TypeSummary: {
+		saver:  func(r *PostgresqlMetricRepository) saver { return &summarySaver{db: r.db} },
+		getter: func(r *PostgresqlMetricRepository) getter { return &summaryGetter{db: r.db} },
+	},
+}
+
 func (r *PostgresqlMetricRepository) getSaver(metricType string) saver {
-	switch metricType {
-	case metric.TypeGauge:
-		return &gaugeSaver{db: r.db}
-	case metric.TypeCounter:
-		return &counterSaver{db: r.db}
-	default:
-		return &unknownSaver{}
+	if a, ok := adapters[metricType]; ok {
+		return a.saver(r)
+	}
+	if _, ok := metric.LookupType(metricType); ok {
+		return &registeredSaver{db: r.db}
 	}
+	return &unknownSaver{}
 }
 
 func (r *PostgresqlMetricRepository) saveAll(ctx context.Context, entity ...metric.Metric) error {
@@ -87,6 +134,14 @@ func (r *PostgresqlMetricRepository) saveAll(ctx context.Context, entity ...metr
 	}
 	defer tx.Rollback()
 
+	if err := r.inTx(tx).saveEach(ctx, entity...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// saveEach saves metrics one by one and returns joined errors of all of them
+func (r *PostgresqlMetricRepository) saveEach(ctx context.Context, entity ...metric.Metric) error {
 	errs := make([]error, 0)
 
 	for _, e := range entity {
@@ -94,27 +149,38 @@ func (r *PostgresqlMetricRepository) saveAll(ctx context.Context, entity ...metr
 			errs = append(errs, err)
 		}
 	}
-	if len(errs) > 0 {
-		return errors.Join(errs...)
-	}
-	return tx.Commit()
+	return errors.Join(errs...)
+}
+
+// inTx returns copy of repository which savers and getters work inside tx
+func (r *PostgresqlMetricRepository) inTx(tx *sql.Tx) *PostgresqlMetricRepository {
+	rs := *r
+	rs.db = r.db.inTx(tx)
+	return &rs
 }
 
 func (r *PostgresqlMetricRepository) getGetter(metricType string) getter {
-	switch metricType {
-	case metric.TypeGauge:
-		return &gaugeGetter{db: r.db}
-	case metric.TypeCounter:
-		return &counterGetter{db: r.db}
-	default:
-		return &unknownGetter{}
+	if a, ok := adapters[metricType]; ok {
+		return a.getter(r)
 	}
+	if t, ok := metric.LookupType(metricType); ok {
+		return &registeredGetter{db: r.db, info: t}
+	}
+	return &unknownGetter{}
 }
 
 func (r *PostgresqlMetricRepository) initMetadata(ctx context.Context) error {
 	if err := r.db.exec(ctx, createTableTxt()); err != nil {
 		return errors.Join(repository.ErrInitializeMetadata, err)
 	}
+
+	if !r.gaugeHistory {
+		return nil
+	}
+
+	if err := r.db.exec(ctx, createHistoryTableTxt()); err != nil {
+		return errors.Join(repository.ErrInitializeMetadata, err)
+	}
 	return nil
 }
 
@@ -122,15 +188,89 @@ func createTableTxt() string {
 	return `
 	CREATE TABLE IF NOT EXISTS gauges (
     	"value" DOUBLE PRECISION NOT NULL,
-    	"id" VARCHAR(100) NOT NULL PRIMARY KEY
+    	"id" VARCHAR(100) NOT NULL,
+    	"labels" JSONB NOT NULL DEFAULT '{}',
+    	"created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
 	);
 
 	CREATE TABLE IF NOT EXISTS counters (
     	"value" BIGINT NOT NULL,
     	"id" VARCHAR(100) NOT NULL,
-    	"created_at" TIMESTAMP NOT NULL
+    	"created_at" TIMESTAMPTZ NOT NULL,
+    	"labels" JSONB NOT NULL DEFAULT '{}'
 	);
 
 	CREATE INDEX IF NOT EXISTS counter_name_idx ON counters ("id");
+
+	CREATE TABLE IF NOT EXISTS histograms (
+    	"id" VARCHAR(100) NOT NULL,
+    	"labels" JSONB NOT NULL DEFAULT '{}',
+    	"bounds" JSONB NOT NULL,
+    	"counts" JSONB NOT NULL,
+    	"sum" DOUBLE PRECISION NOT NULL,
+    	"created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS histograms_id_labels_idx ON histograms ("id", "labels");
+
+	CREATE TABLE IF NOT EXISTS summaries (
+    	"id" VARCHAR(100) NOT NULL,
+    	"labels" JSONB NOT NULL DEFAULT '{}',
+    	"positive" JSONB NOT NULL DEFAULT '{}',
+    	"negative" JSONB NOT NULL DEFAULT '{}',
+    	"zero" BIGINT NOT NULL,
+    	"sum" DOUBLE PRECISION NOT NULL,
+    	"created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS summaries_id_labels_idx ON summaries ("id", "labels");
+
+	CREATE TABLE IF NOT EXISTS metric_values (
+    	"type" VARCHAR(100) NOT NULL,
+    	"id" VARCHAR(100) NOT NULL,
+    	"labels" JSONB NOT NULL DEFAULT '{}',
+    	"value" TEXT NOT NULL,
+    	"created_at" TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS metric_values_type_id_idx ON metric_values ("type", "id");
+
+	CREATE TABLE IF NOT EXISTS alerts (
+    	"rule" VARCHAR(100) PRIMARY KEY,
+    	"state" VARCHAR(20) NOT NULL,
+    	"alert" JSONB NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+    	"key" VARCHAR(200) PRIMARY KEY,
+    	"expire_at" TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS tokens (
+    	"id" VARCHAR(100) PRIMARY KEY,
+    	"name" VARCHAR(200) NOT NULL DEFAULT '',
+    	"hash" VARCHAR(100) NOT NULL,
+    	"scopes" JSONB NOT NULL,
+    	"created_at" TIMESTAMPTZ NOT NULL
+	);
+
+	-- tables which were created before labels
+	ALTER TABLE gauges ADD COLUMN IF NOT EXISTS "labels" JSONB NOT NULL DEFAULT '{}';
+	ALTER TABLE gauges DROP CONSTRAINT IF EXISTS gauges_pkey;
+	ALTER TABLE counters ADD COLUMN IF NOT EXISTS "labels" JSONB NOT NULL DEFAULT '{}';
+
+	-- tables which were created before timestamps of samples
+	ALTER TABLE gauges ADD COLUMN IF NOT EXISTS "created_at" TIMESTAMPTZ NOT NULL DEFAULT now();
+	ALTER TABLE histograms ADD COLUMN IF NOT EXISTS "created_at" TIMESTAMPTZ NOT NULL DEFAULT now();
+	ALTER TABLE summaries ADD COLUMN IF NOT EXISTS "created_at" TIMESTAMPTZ NOT NULL DEFAULT now();
+
+	-- tables which were created with timestamps without time zone, stored values are taken in time zone of session
+	ALTER TABLE gauges ALTER COLUMN "created_at" TYPE TIMESTAMPTZ;
+	ALTER TABLE counters ALTER COLUMN "created_at" TYPE TIMESTAMPTZ;
+	ALTER TABLE metric_values ALTER COLUMN "created_at" TYPE TIMESTAMPTZ;
+	ALTER TABLE idempotency_keys ALTER COLUMN "expire_at" TYPE TIMESTAMPTZ;
+	ALTER TABLE tokens ALTER COLUMN "created_at" TYPE TIMESTAMPTZ;
+
+	CREATE UNIQUE INDEX IF NOT EXISTS gauges_id_labels_idx ON gauges ("id", "labels");
 	`
 }
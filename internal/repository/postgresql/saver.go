@@ -2,6 +2,8 @@ package postgresql
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/vilasle/metrics/internal/metric"
 )
@@ -10,6 +12,14 @@ type saver interface {
 	save(context.Context, metric.Metric) error
 }
 
+// observedAt returns time when value of metric was observed, metric without timestamp is observed now
+func observedAt(m metric.Metric) time.Time {
+	if ts := m.Timestamp(); !ts.IsZero() {
+		return ts
+	}
+	return time.Now()
+}
+
 type unknownSaver struct{}
 
 func (s unknownSaver) save(context.Context, metric.Metric) error {
@@ -21,14 +31,16 @@ type gaugeSaver struct {
 }
 
 func (s gaugeSaver) save(ctx context.Context, m metric.Metric) error {
-	return s.db.exec(ctx, s.saveTxt(), m.Name(), m.Float64())
+	return s.db.exec(ctx, s.saveTxt(), m.Name(), m.Float64(), labelsArg(m.Labels()), observedAt(m))
 }
 
+// saveTxt replaces value of series unless stored value is newer
 func (s gaugeSaver) saveTxt() string {
 	return `
-	INSERT INTO gauges ("id", "value")
-	VALUES ($1, $2) 
-	ON CONFLICT ("id") DO UPDATE SET "value" = EXCLUDED."value";
+	INSERT INTO gauges ("id", "value", "labels", "created_at")
+	VALUES ($1, $2, $3::jsonb, $4) 
+	ON CONFLICT ("id", "labels") DO UPDATE SET "value" = EXCLUDED."value", "created_at" = EXCLUDED."created_at"
+	WHERE gauges."created_at" <= EXCLUDED."created_at";
 	`
 }
 
@@ -37,12 +49,154 @@ type counterSaver struct {
 }
 
 func (s counterSaver) save(ctx context.Context, m metric.Metric) error {
-	return s.db.exec(ctx, s.saveTxt(), m.Name(), m.Int64())
+	return s.db.exec(ctx, s.saveTxt(), m.Name(), m.Int64(), observedAt(m), labelsArg(m.Labels()))
 }
 
 func (s counterSaver) saveTxt() string {
 	return `
-	INSERT INTO counters ("id", "value", "created_at")
-	VALUES ($1, $2, now())
+	INSERT INTO counters ("id", "value", "created_at", "labels")
+	VALUES ($1, $2, $3, $4::jsonb)
+	`
+}
+
+type histogramSaver struct {
+	db repeater
+}
+
+func (s histogramSaver) save(ctx context.Context, m metric.Metric) error {
+	h, ok := m.(metric.Histogram)
+	if !ok {
+		return metric.ErrUnknownMetricType
+	}
+
+	data := h.Data()
+	bounds, counts, err := histogramArgs(data)
+	if err != nil {
+		return err
+	}
+	return s.db.exec(ctx, s.saveTxt(), h.Name(), labelsArg(h.Labels()), bounds, counts, data.Sum, observedAt(h))
+}
+
+// saveTxt merges observations with stored series if bounds of buckets are the same,
+// otherwise replaces stored series. Observations are deltas, so late ones are merged too
+// and series keeps time of the latest observation
+func (s histogramSaver) saveTxt() string {
+	return `
+	INSERT INTO histograms ("id", "labels", "bounds", "counts", "sum", "created_at")
+	VALUES ($1, $2::jsonb, $3::jsonb, $4::jsonb, $5, $6)
+	ON CONFLICT ("id", "labels") DO UPDATE SET
+		"counts" = CASE WHEN histograms."bounds" = EXCLUDED."bounds" THEN (
+			SELECT jsonb_agg(o.v::bigint + n.v::bigint ORDER BY o.i)
+			FROM jsonb_array_elements_text(histograms."counts") WITH ORDINALITY AS o(v, i)
+			JOIN jsonb_array_elements_text(EXCLUDED."counts") WITH ORDINALITY AS n(v, i) ON o.i = n.i
+		) ELSE EXCLUDED."counts" END,
+		"sum" = CASE WHEN histograms."bounds" = EXCLUDED."bounds"
+			THEN histograms."sum" + EXCLUDED."sum" ELSE EXCLUDED."sum" END,
+		"bounds" = EXCLUDED."bounds",
+		"created_at" = GREATEST(histograms."created_at", EXCLUDED."created_at");
+	`
+}
+
+// histogramArgs returns bounds and counts of buckets as json arrays for passing to query
+func histogramArgs(data metric.HistogramData) (string, string, error) {
+	if data.Bounds == nil {
+		data.Bounds = []float64{}
+	}
+	bounds, err := json.Marshal(data.Bounds)
+	if err != nil {
+		return "", "", err
+	}
+	counts, err := json.Marshal(data.Counts)
+	if err != nil {
+		return "", "", err
+	}
+	return string(bounds), string(counts), nil
+}
+
+type summarySaver struct {
+	db repeater
+}
+
+func (s summarySaver) save(ctx context.Context, m metric.Metric) error {
+	sm, ok := m.(metric.Summary)
+	if !ok {
+		return metric.ErrUnknownMetricType
+	}
+
+	data := sm.Data()
+	positive, negative, err := summaryArgs(data)
+	if err != nil {
+		return err
+	}
+	return s.db.exec(ctx, s.saveTxt(), sm.Name(), labelsArg(sm.Labels()), positive, negative, data.Zero, data.Sum, observedAt(sm))
+}
+
+// saveTxt merges sketch with stored series, counts of buckets with the same index are summed.
+// Like histograms series keeps time of the latest observation
+func (s summarySaver) saveTxt() string {
+	return `
+	INSERT INTO summaries ("id", "labels", "positive", "negative", "zero", "sum", "created_at")
+	VALUES ($1, $2::jsonb, $3::jsonb, $4::jsonb, $5, $6, $7)
+	ON CONFLICT ("id", "labels") DO UPDATE SET
+		"positive" = (
+			SELECT COALESCE(jsonb_object_agg(b.key, b.count), '{}')
+			FROM (
+				SELECT key, SUM(value::bigint) AS count
+				FROM (
+					SELECT * FROM jsonb_each_text(summaries."positive")
+					UNION ALL
+					SELECT * FROM jsonb_each_text(EXCLUDED."positive")
+				) AS u
+				GROUP BY key
+			) AS b
+		),
+		"negative" = (
+			SELECT COALESCE(jsonb_object_agg(b.key, b.count), '{}')
+			FROM (
+				SELECT key, SUM(value::bigint) AS count
+				FROM (
+					SELECT * FROM jsonb_each_text(summaries."negative")
+					UNION ALL
+					SELECT * FROM jsonb_each_text(EXCLUDED."negative")
+				) AS u
+				GROUP BY key
+			) AS b
+		),
+		"zero" = summaries."zero" + EXCLUDED."zero",
+		"sum" = summaries."sum" + EXCLUDED."sum",
+		"created_at" = GREATEST(summaries."created_at", EXCLUDED."created_at");
+	`
+}
+
+// summaryArgs returns buckets of positive and negative observations as json objects for passing to query
+func summaryArgs(data metric.SummaryData) (string, string, error) {
+	buckets := make([]string, 0, 2)
+	for _, b := range []map[int32]int64{data.Positive, data.Negative} {
+		if b == nil {
+			b = map[int32]int64{}
+		}
+		raw, err := json.Marshal(b)
+		if err != nil {
+			return "", "", err
+		}
+		buckets = append(buckets, string(raw))
+	}
+	return buckets[0], buckets[1], nil
+}
+
+// registeredSaver saves metrics of registered types which do not have own tables.
+// Each value is kept by its text representation, values are aggregated on reading by registeredGetter
+type registeredSaver struct {
+	db repeater
+}
+
+func (s registeredSaver) save(ctx context.Context, m metric.Metric) error {
+	return s.db.exec(ctx, s.saveTxt(), m.Type(), m.Name(), labelsArg(m.Labels()), m.Value(), observedAt(m))
+}
+
+func (s registeredSaver) saveTxt() string {
+	return `
+	INSERT INTO metric_values ("type", "id", "labels", "value", "created_at")
+	VALUES ($1, $2, $3::jsonb, $4, $5)
 	`
 }
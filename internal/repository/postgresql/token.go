@@ -0,0 +1,78 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vilasle/metrics/internal/auth"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+var _ repository.TokenRepository = (*PostgresqlMetricRepository)(nil)
+
+// SaveToken saves token to table tokens, token with the same id is replaced
+func (r *PostgresqlMetricRepository) SaveToken(ctx context.Context, t auth.Token) error {
+	scopes, err := json.Marshal(t.Scopes)
+	if err != nil {
+		return err
+	}
+
+	txt := `
+	INSERT INTO tokens ("id", "name", "hash", "scopes", "created_at")
+	VALUES ($1, $2, $3, $4::jsonb, $5)
+	ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "hash" = EXCLUDED."hash", "scopes" = EXCLUDED."scopes";
+	`
+	return r.db.exec(ctx, txt, t.ID, t.Name, t.Hash, string(scopes), t.CreatedAt)
+}
+
+// DeleteToken deletes token from table tokens, it returns repository.ErrTokenNotFound if token does not exist
+func (r *PostgresqlMetricRepository) DeleteToken(ctx context.Context, id string) error {
+	deleted, err := r.db.execAffected(ctx, `DELETE FROM tokens WHERE "id" = $1`, id)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return errors.Join(repository.ErrTokenNotFound, fmt.Errorf("id %s", id))
+	}
+	return nil
+}
+
+// Token returns token by id, it returns false if token does not exist
+func (r *PostgresqlMetricRepository) Token(ctx context.Context, id string) (auth.Token, bool, error) {
+	tokens, err := r.tokens(ctx, `SELECT "id", "name", "hash", "scopes", "created_at" FROM tokens WHERE "id" = $1`, id)
+	if err != nil || len(tokens) == 0 {
+		return auth.Token{}, false, err
+	}
+	return tokens[0], true, nil
+}
+
+// Tokens returns tokens ordered by id
+func (r *PostgresqlMetricRepository) Tokens(ctx context.Context) ([]auth.Token, error) {
+	return r.tokens(ctx, `SELECT "id", "name", "hash", "scopes", "created_at" FROM tokens ORDER BY "id"`)
+}
+
+func (r *PostgresqlMetricRepository) tokens(ctx context.Context, txt string, args ...any) ([]auth.Token, error) {
+	rows, err := r.db.query(ctx, txt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rs := make([]auth.Token, 0)
+	for rows.Next() {
+		var (
+			t      auth.Token
+			scopes []byte
+		)
+		if err := rows.Scan(&t.ID, &t.Name, &t.Hash, &scopes, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(scopes, &t.Scopes); err != nil {
+			return nil, err
+		}
+		rs = append(rs, t)
+	}
+	return rs, rows.Err()
+}
@@ -0,0 +1,57 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/auth"
+	"github.com/vilasle/metrics/internal/repository"
+)
+
+func TestPostgresqlMetricRepository_Tokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "can not create sqlmock")
+
+	r := repeater{db: db, repeatSteps: []time.Duration{time.Second}}
+	repo := PostgresqlMetricRepository{db: r}
+	ctx := context.Background()
+
+	token := auth.Token{
+		ID:        "a",
+		Name:      "dashboard",
+		Hash:      "h",
+		Scopes:    []auth.Scope{auth.ScopeRead, auth.ScopeWrite},
+		CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	columns := []string{"id", "name", "hash", "scopes", "created_at"}
+
+	mock.ExpectExec(`INSERT INTO tokens`).WithArgs("a", "dashboard", "h", `["read","write"]`, token.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT "id", "name", "hash", "scopes", "created_at" FROM tokens WHERE`).WithArgs("a").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("a", "dashboard", "h", []byte(`["read","write"]`), token.CreatedAt))
+	mock.ExpectQuery(`SELECT "id", "name", "hash", "scopes", "created_at" FROM tokens WHERE`).WithArgs("b").
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectExec(`DELETE FROM tokens`).WithArgs("a").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`DELETE FROM tokens`).WithArgs("a").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.SaveToken(ctx, token))
+
+	got, ok, err := repo.Token(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, token, got)
+
+	_, ok, err = repo.Token(ctx, "b")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, repo.DeleteToken(ctx, "a"))
+	assert.ErrorIs(t, repo.DeleteToken(ctx, "a"), repository.ErrTokenNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
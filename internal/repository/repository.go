@@ -2,13 +2,48 @@ package repository
 
 import (
 	"context"
+	"time"
 
+	"github.com/vilasle/metrics/internal/alert"
+	"github.com/vilasle/metrics/internal/auth"
 	"github.com/vilasle/metrics/internal/metric"
 )
 // MetricRepository is the interface that group methods for work with metrics' storage
 type MetricRepository interface {
 	Save(context.Context, ...metric.Metric) error
 	Get(ctx context.Context, metricType string, filterName ...string) ([]metric.Metric, error)
+	// History returns values of series of metric with passed name and labels saved between from and to ordered by time
+	History(ctx context.Context, metricType, name string, labels metric.Labels, from, to time.Time) ([]metric.Point, error)
 	Ping(ctx context.Context) error
 	Close()
 }
+
+// AlertRepository is the interface that group methods for work with storage of alerts' state.
+// Storage keeps one alert per rule
+type AlertRepository interface {
+	SaveAlert(ctx context.Context, a alert.Alert) error
+	DeleteAlert(ctx context.Context, rule string) error
+	// Alerts returns alerts ordered by name of rule
+	Alerts(ctx context.Context) ([]alert.Alert, error)
+}
+
+// IdempotencyRepository is the interface that group methods for work with storage of idempotency keys
+// of applied batches of metrics
+type IdempotencyRepository interface {
+	// SaveOnce saves metrics and remembers key until expire as one operation, so concurrent batches with the same key
+	// are applied once and key of batch which is not saved is not remembered.
+	// It returns false and does not save metrics if key is already remembered and is not expired
+	SaveOnce(ctx context.Context, key string, expire time.Time, entity ...metric.Metric) (bool, error)
+}
+
+// TokenRepository is the interface that group methods for work with storage of API tokens.
+// Storage keeps hashes of tokens only
+type TokenRepository interface {
+	SaveToken(ctx context.Context, t auth.Token) error
+	// DeleteToken deletes token by id, it returns ErrTokenNotFound if token does not exist
+	DeleteToken(ctx context.Context, id string) error
+	// Token returns token by id, it returns false if token does not exist
+	Token(ctx context.Context, id string) (auth.Token, bool, error)
+	// Tokens returns tokens ordered by id
+	Tokens(ctx context.Context) ([]auth.Token, error)
+}
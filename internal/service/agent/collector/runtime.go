@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"runtime"
 	"sync"
+	"time"
 
 	"github.com/vilasle/metrics/internal/logger"
 	"github.com/vilasle/metrics/internal/metric"
@@ -13,24 +14,35 @@ import (
 
 type eventHandler func(c service.Collector)
 
+// summarySources are fields of runtime.MemStats which can be collected as summaries,
+// source returns observations which appeared after GC cycle lastNumGC
+var summarySources = map[string]func(ms *runtime.MemStats, lastNumGC uint32) []float64{
+	"PauseNs": newPauses,
+}
+
 // RuntimeCollector provided way for collection runtime metrics 
 // with options registration extra events where can add extra metrics or make postprocess collected metrics  
 type RuntimeCollector struct {
-	counters map[string]metric.Metric
-	gauges   map[string]metric.Metric
-	metrics  []string
-	events   []eventHandler
-	mxMetric *sync.Mutex
+	counters  map[string]metric.Metric
+	gauges    map[string]metric.Metric
+	summaries map[string]metric.Metric
+	metrics   []string
+	sources   []string
+	lastNumGC uint32
+	events    []eventHandler
+	mxMetric  *sync.Mutex
 }
 
 // NewRuntimeCollector returns new instance of RuntimeCollector
 func NewRuntimeCollector() *RuntimeCollector {
 	return &RuntimeCollector{
-		counters: make(map[string]metric.Metric, 0),
-		gauges:   make(map[string]metric.Metric, 0),
-		metrics:  make([]string, 0),
-		events:   make([]eventHandler, 0),
-		mxMetric: &sync.Mutex{},
+		counters:  make(map[string]metric.Metric, 0),
+		gauges:    make(map[string]metric.Metric, 0),
+		summaries: make(map[string]metric.Metric, 0),
+		metrics:   make([]string, 0),
+		sources:   make([]string, 0),
+		events:    make([]eventHandler, 0),
+		mxMetric:  &sync.Mutex{},
 	}
 }
 
@@ -53,6 +65,23 @@ func (c *RuntimeCollector) RegisterMetric(metrics ...string) error {
 	return errors.Join(errs...)
 }
 
+// RegisterSummary register field of runtime.MemStats which will be collected as summary of observations,
+// e.g. PauseNs gives durations of GC pauses which happened between collections.
+// return error if field is not supported
+func (c *RuntimeCollector) RegisterSummary(metrics ...string) error {
+	errs := make([]error, 0)
+
+	for _, v := range metrics {
+		if _, ok := summarySources[v]; ok {
+			c.sources = append(c.sources, v)
+		} else {
+			errs = append(errs, errors.Join(ErrInvalidMetric, errors.New(v)))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 // RegisterEvent register new event handler, events will raise after function Collect()
 func (c *RuntimeCollector) RegisterEvent(event eventHandler) {
 	c.events = append(c.events, event)
@@ -61,7 +90,7 @@ func (c *RuntimeCollector) RegisterEvent(event eventHandler) {
 // Collect reads stats from runtime.MemStats, transform it to suited metrics and stores they in collections
 // raises events after collecting 
 func (c *RuntimeCollector) Collect() {
-	if len(c.metrics) == 0 {
+	if len(c.metrics) == 0 && len(c.sources) == 0 {
 		return
 	}
 	c.mxMetric.Lock()
@@ -71,6 +100,7 @@ func (c *RuntimeCollector) Collect() {
 
 	logger.Debug("get runtime stats", "stat", ms)
 
+	now := time.Now()
 	value := reflect.ValueOf(ms)
 	for _, v := range c.metrics {
 		fld := value.FieldByName(v)
@@ -88,19 +118,60 @@ func (c *RuntimeCollector) Collect() {
 			c.gauges[v] = metric.NewGaugeMetric(v, fld.Float())
 		default:
 			logger.Error("unsupported type", "type", fld.Kind().String())
+			continue
 		}
+		c.gauges[v].SetTimestamp(now)
 	}
+	c.collectSummaries(&ms, now)
 	c.mxMetric.Unlock()
 
 	c.execEvents()
 }
 
-// AllMetrics collects counter and gauge to slice of metric and return this
+// collectSummaries adds new observations to summaries, summary is replaced by its copy,
+// so metrics which were returned by AllMetrics are not changed
+func (c *RuntimeCollector) collectSummaries(ms *runtime.MemStats, now time.Time) {
+	for _, v := range c.sources {
+		observations := summarySources[v](ms, c.lastNumGC)
+		if len(observations) == 0 {
+			continue
+		}
+
+		s := metric.NewSummaryMetric(v, observations...)
+		if current, ok := c.summaries[v].(metric.Summary); ok {
+			if err := s.Merge(current.Data()); err != nil {
+				logger.Error("can not merge summary", "name", v, "err", err)
+			}
+		}
+		s.SetTimestamp(now)
+		c.summaries[v] = s
+	}
+	c.lastNumGC = ms.NumGC
+}
+
+// newPauses returns durations of GC pauses in nanoseconds for cycles after lastNumGC,
+// runtime keeps only the last 256 of them
+func newPauses(ms *runtime.MemStats, lastNumGC uint32) []float64 {
+	size := uint32(len(ms.PauseNs))
+
+	from := lastNumGC + 1
+	if ms.NumGC > size && from <= ms.NumGC-size {
+		from = ms.NumGC - size + 1
+	}
+
+	rs := make([]float64, 0)
+	for n := from; n <= ms.NumGC; n++ {
+		rs = append(rs, float64(ms.PauseNs[(n+size-1)%size]))
+	}
+	return rs
+}
+
+// AllMetrics collects counter, gauge and summary to slice of metric and return this
 func (c *RuntimeCollector) AllMetrics() []metric.Metric {
 	c.mxMetric.Lock()
 	defer c.mxMetric.Unlock()
 
-	metrics := make([]metric.Metric, len(c.gauges)+len(c.counters))
+	metrics := make([]metric.Metric, len(c.gauges)+len(c.counters)+len(c.summaries))
 
 	var i int
 	for _, v := range c.gauges {
@@ -113,6 +184,11 @@ func (c *RuntimeCollector) AllMetrics() []metric.Metric {
 		i++
 	}
 
+	for _, v := range c.summaries {
+		metrics[i] = v
+		i++
+	}
+
 	return metrics
 }
 
@@ -139,14 +215,19 @@ func (c *RuntimeCollector) GetGaugeValue(name string) metric.Metric {
 	return metric.NewGaugeMetric(name, 0)
 }
 
-// SetValue replaces metric on collections to metric which are passed to input
+// SetValue replaces metric on collections to metric which are passed to input,
+// metric is stamped by time of setting
 func (c *RuntimeCollector) SetValue(value metric.Metric) {
+	value.SetTimestamp(time.Now())
+
 	c.mxMetric.Lock()
 	switch value.Type() {
 	case metric.TypeGauge:
 		c.gauges[value.Name()] = value
 	case metric.TypeCounter:
 		c.counters[value.Name()] = value
+	case metric.TypeSummary:
+		c.summaries[value.Name()] = value
 	}
 	c.mxMetric.Unlock()
 }
@@ -160,6 +241,14 @@ func (c *RuntimeCollector) ResetCounter(counterName string) {
 	c.mxMetric.Unlock()
 }
 
+// ResetSummary drops observations of summary which were collected before,
+// summary without observations is not returned by AllMetrics
+func (c *RuntimeCollector) ResetSummary(summaryName string) {
+	c.mxMetric.Lock()
+	delete(c.summaries, summaryName)
+	c.mxMetric.Unlock()
+}
+
 func (c *RuntimeCollector) execEvents() {
 	for _, v := range c.events {
 		v(c)
@@ -1,10 +1,12 @@
 package collector
 
 import (
+	"runtime"
 	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/vilasle/metrics/internal/metric"
 	"github.com/vilasle/metrics/internal/service"
 )
@@ -247,3 +249,53 @@ func BenchmarkRuntimeCollector_Collect(b *testing.B) {
 		c.Collect()
 	}
 }
+
+func TestRuntimeCollector_RegisterSummary(t *testing.T) {
+	c := NewRuntimeCollector()
+	assert.NoError(t, c.RegisterSummary("PauseNs"))
+	assert.ErrorIs(t, c.RegisterSummary("Alloc"), ErrInvalidMetric)
+	assert.Equal(t, []string{"PauseNs"}, c.sources)
+}
+
+func TestRuntimeCollector_CollectSummary(t *testing.T) {
+	c := NewRuntimeCollector()
+	require.NoError(t, c.RegisterSummary("PauseNs"))
+
+	runtime.GC()
+	c.Collect()
+
+	got := c.AllMetrics()
+	require.Len(t, got, 1)
+	assert.Equal(t, metric.TypeSummary, got[0].Type())
+	assert.Equal(t, "PauseNs", got[0].Name())
+
+	collected := got[0].Int64()
+	assert.Positive(t, collected)
+
+	runtime.GC()
+	runtime.GC()
+	c.Collect()
+
+	// only pauses of new cycles are added, collected metric is not changed
+	assert.Equal(t, collected, got[0].Int64())
+	assert.GreaterOrEqual(t, c.AllMetrics()[0].Int64(), collected+2)
+
+	c.ResetSummary("PauseNs")
+	assert.Empty(t, c.AllMetrics())
+}
+
+func Test_newPauses(t *testing.T) {
+	ms := &runtime.MemStats{NumGC: 3}
+	ms.PauseNs[0], ms.PauseNs[1], ms.PauseNs[2] = 10, 20, 30
+
+	assert.Equal(t, []float64{10, 20, 30}, newPauses(ms, 0))
+	assert.Equal(t, []float64{30}, newPauses(ms, 2))
+	assert.Empty(t, newPauses(ms, 3))
+
+	// runtime keeps only the last 256 pauses
+	ms = &runtime.MemStats{NumGC: 300}
+	ms.PauseNs[(300+255)%256] = 1
+	pauses := newPauses(ms, 0)
+	assert.Len(t, pauses, 256)
+	assert.Equal(t, float64(1), pauses[len(pauses)-1])
+}
@@ -0,0 +1,5 @@
+package outbox
+
+import "errors"
+
+var ErrBatchTooLarge = errors.New("batch of metrics is larger than outbox")
@@ -0,0 +1,274 @@
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vilasle/metrics/internal/logger"
+	"github.com/vilasle/metrics/internal/metric"
+)
+
+const (
+	// batchExt is the extension of files with batches, temporary files are written with tmpExt and renamed
+	batchExt = ".batch"
+	tmpExt   = ".tmp"
+
+	defaultMaxSize = 10 << 20
+	defaultMaxAge  = time.Hour
+)
+
+// Outbox is the bounded queue of batches of metrics on disk. It keeps batches which are not sent to server,
+// so they survive restart of agent. Each batch is kept in own file which is named by sequence number,
+// so batches are replayed in order of pushing. The oldest batches are dropped when size of outbox exceeds the limit,
+// batches which are older than the limit of age are dropped on replay
+type Outbox struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+	// rejected are errors of send which are not fixed by repeating, batches with them are dropped
+	rejected []error
+	now      func() time.Time
+
+	mx      *sync.Mutex
+	seq     uint64
+	batches []batchFile
+	size    int64
+}
+
+type batchFile struct {
+	seq  uint64
+	size int64
+}
+
+type batch struct {
+	Key       string          `json:"key"`
+	CreatedAt time.Time       `json:"created_at"`
+	Metrics   json.RawMessage `json:"metrics"`
+}
+
+// Option is the setting of Outbox
+type Option func(*Outbox)
+
+// WithMaxSize sets the limit of total size of batches in bytes
+func WithMaxSize(size int64) Option {
+	return func(o *Outbox) {
+		if size > 0 {
+			o.maxSize = size
+		}
+	}
+}
+
+// WithMaxAge sets the limit of age of batch, older batches are not sent
+func WithMaxAge(age time.Duration) Option {
+	return func(o *Outbox) {
+		if age > 0 {
+			o.maxAge = age
+		}
+	}
+}
+
+// WithRejected sets errors of send which mean that server rejects batch itself e.g. wrong name or value of metric.
+// Batches which are rejected are dropped on replay, so they do not block the next ones
+func WithRejected(errs ...error) Option {
+	return func(o *Outbox) {
+		o.rejected = append(o.rejected, errs...)
+	}
+}
+
+// NewOutbox returns outbox which keeps batches in dir. Batches which are left in dir
+// by previous run of agent are loaded and will be replayed
+func NewOutbox(dir string, opts ...Option) (*Outbox, error) {
+	o := &Outbox{
+		dir:     dir,
+		maxSize: defaultMaxSize,
+		maxAge:  defaultMaxAge,
+		now:     time.Now,
+		mx:      &sync.Mutex{},
+		seq:     1,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+	o.trim()
+
+	return o, nil
+}
+
+// Len returns count of batches in outbox
+func (o *Outbox) Len() int {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	return len(o.batches)
+}
+
+// Push puts batch to the end of outbox. Key is the idempotency key of batch, it is kept for replay,
+// so server does not apply batch twice if it was saved but response was lost
+func (o *Outbox) Push(key string, metrics []metric.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(metrics)
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(batch{Key: key, CreatedAt: o.now(), Metrics: raw})
+	if err != nil {
+		return err
+	}
+
+	size := int64(len(content))
+	if size > o.maxSize {
+		return errors.Join(ErrBatchTooLarge, fmt.Errorf("size of batch %d, limit %d", size, o.maxSize))
+	}
+
+	o.mx.Lock()
+	defer o.mx.Unlock()
+
+	seq := o.seq
+	path := o.path(seq)
+	if err := os.WriteFile(path+tmpExt, content, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(path+tmpExt, path); err != nil {
+		return err
+	}
+
+	o.seq++
+	o.batches = append(o.batches, batchFile{seq: seq, size: size})
+	o.size += size
+	o.trim()
+
+	return nil
+}
+
+// Replay sends batches from the beginning of outbox and removes sent ones.
+// Batches which are rejected by server are dropped, Replay stops on other errors of send
+// e.g. transport or authorization ones, so the rest of batches is kept in order
+func (o *Outbox) Replay(send func(key string, metrics []metric.Metric) error) error {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+
+	for len(o.batches) > 0 {
+		b := o.batches[0]
+
+		v, metrics, err := o.read(b)
+		if err != nil {
+			logger.Warnw("batch is dropped from outbox", "batch", b.seq, "reason", err)
+		} else if err := send(v.Key, metrics); err != nil {
+			if !o.isRejected(err) {
+				return err
+			}
+			logger.Warnw("batch is rejected by server and dropped from outbox", "batch", b.seq, "key", v.Key, "reason", err)
+		}
+
+		o.remove()
+	}
+	return nil
+}
+
+// isRejected reports whether error of send means that batch is rejected by server
+func (o *Outbox) isRejected(err error) bool {
+	for _, rejected := range o.rejected {
+		if errors.Is(err, rejected) {
+			return true
+		}
+	}
+	return false
+}
+
+// read returns batch and its metrics, error is returned if batch is broken or expired
+func (o *Outbox) read(b batchFile) (batch, []metric.Metric, error) {
+	var v batch
+	content, err := os.ReadFile(o.path(b.seq))
+	if err != nil {
+		return v, nil, err
+	}
+
+	if err := json.Unmarshal(content, &v); err != nil {
+		return v, nil, err
+	}
+	if age := o.now().Sub(v.CreatedAt); age > o.maxAge {
+		return v, nil, fmt.Errorf("batch is expired, age %s", age)
+	}
+	metrics, err := metric.FromJSONArray(v.Metrics)
+	return v, metrics, err
+}
+
+// trim drops the oldest batches while size of outbox exceeds the limit
+func (o *Outbox) trim() {
+	dropped := 0
+	for o.size > o.maxSize && len(o.batches) > 0 {
+		o.remove()
+		dropped++
+	}
+	if dropped > 0 {
+		logger.Warnw("outbox is full, the oldest batches are dropped", "count", dropped)
+	}
+}
+
+// remove removes the first batch
+func (o *Outbox) remove() {
+	b := o.batches[0]
+	if err := os.Remove(o.path(b.seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Errorw("can not remove batch from outbox", "batch", b.seq, "error", err)
+	}
+	o.batches = o.batches[1:]
+	o.size -= b.size
+}
+
+// load reads list of batches which are in dir, temporary files of unfinished writes are removed
+func (o *Outbox) load() error {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(name, tmpExt) {
+			os.Remove(filepath.Join(o.dir, name))
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, batchExt), 10, 64)
+		if !strings.HasSuffix(name, batchExt) || err != nil {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		o.batches = append(o.batches, batchFile{seq: seq, size: info.Size()})
+		o.size += info.Size()
+	}
+
+	sort.Slice(o.batches, func(i, j int) bool { return o.batches[i].seq < o.batches[j].seq })
+	if n := len(o.batches); n > 0 {
+		o.seq = o.batches[n-1].seq + 1
+	}
+	return nil
+}
+
+func (o *Outbox) path(seq uint64) string {
+	return filepath.Join(o.dir, fmt.Sprintf("%020d%s", seq, batchExt))
+}
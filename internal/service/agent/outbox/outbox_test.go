@@ -0,0 +1,183 @@
+package outbox
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+	sender "github.com/vilasle/metrics/internal/service/agent/sender/http"
+)
+
+func batchOf(name string, value float64) []metric.Metric {
+	return []metric.Metric{
+		metric.NewGaugeMetric(name, value),
+		metric.NewCounterMetric("PollCount", 5),
+	}
+}
+
+// collect replays outbox and returns names of gauges of sent batches
+func collect(t *testing.T, o *Outbox) []string {
+	t.Helper()
+	names := make([]string, 0)
+	require.NoError(t, o.Replay(func(key string, ms []metric.Metric) error {
+		names = append(names, ms[0].Name())
+		return nil
+	}))
+	return names
+}
+
+func TestOutbox_PushReplay(t *testing.T) {
+	o, err := NewOutbox(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, o.Push("", nil))
+	assert.Equal(t, 0, o.Len())
+
+	require.NoError(t, o.Push("key-a", batchOf("a", 1)))
+	require.NoError(t, o.Push("key-b", batchOf("b", 2)))
+	assert.Equal(t, 2, o.Len())
+
+	sent := make([][]metric.Metric, 0)
+	keys := make([]string, 0)
+	require.NoError(t, o.Replay(func(key string, ms []metric.Metric) error {
+		sent = append(sent, ms)
+		keys = append(keys, key)
+		return nil
+	}))
+	require.Len(t, sent, 2)
+	assert.Equal(t, []string{"key-a", "key-b"}, keys)
+	assert.Equal(t, "a", sent[0][0].Name())
+	assert.Equal(t, "1", sent[0][0].Value())
+	assert.Equal(t, "PollCount", sent[0][1].Name())
+	assert.Equal(t, "5", sent[0][1].Value())
+	assert.Equal(t, "b", sent[1][0].Name())
+	assert.Equal(t, 0, o.Len())
+}
+
+func TestOutbox_ReplayStopsOnError(t *testing.T) {
+	o, err := NewOutbox(t.TempDir())
+	require.NoError(t, err)
+
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, o.Push("key-"+name, batchOf(name, 1)))
+	}
+
+	errUnavailable := errors.New("server is unavailable")
+	calls := 0
+	err = o.Replay(func(key string, ms []metric.Metric) error {
+		calls++
+		if calls == 2 {
+			return errUnavailable
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, 2, o.Len())
+
+	assert.Equal(t, []string{"b", "c"}, collect(t, o))
+}
+
+func TestOutbox_ReplayDropsRejected(t *testing.T) {
+	o, err := NewOutbox(t.TempDir(), WithRejected(sender.ErrWrongMetricTypeOrValue, sender.ErrWrongMetricName))
+	require.NoError(t, err)
+
+	for _, name := range []string{"poison", "good", "next"} {
+		require.NoError(t, o.Push("key-"+name, batchOf(name, 1)))
+	}
+
+	errUnavailable := errors.New("server is unavailable")
+	sent := make([]string, 0)
+	err = o.Replay(func(key string, ms []metric.Metric) error {
+		switch ms[0].Name() {
+		case "poison":
+			return errors.Join(sender.ErrWrongMetricTypeOrValue, errors.New("status code 400"))
+		case "next":
+			return errUnavailable
+		}
+		sent = append(sent, ms[0].Name())
+		return nil
+	})
+	// poison batch does not block the good one, the batch which is not sent by transport error is kept
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, []string{"good"}, sent)
+	assert.Equal(t, []string{"next"}, collect(t, o))
+}
+
+func TestOutbox_Restore(t *testing.T) {
+	dir := t.TempDir()
+	o, err := NewOutbox(dir)
+	require.NoError(t, err)
+	require.NoError(t, o.Push("key-a", batchOf("a", 1)))
+	require.NoError(t, o.Push("key-b", batchOf("b", 2)))
+
+	// leftovers of unfinished write and foreign files are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000003.batch.tmp"), []byte("{"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("outbox"), 0o644))
+
+	restored, err := NewOutbox(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, restored.Len())
+	require.NoError(t, restored.Push("key-c", batchOf("c", 3)))
+
+	assert.Equal(t, []string{"a", "b", "c"}, collect(t, restored))
+	assert.NoFileExists(t, filepath.Join(dir, "00000000000000000003.batch.tmp"))
+}
+
+func TestOutbox_MaxSize(t *testing.T) {
+	dir := t.TempDir()
+	probe, err := NewOutbox(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, probe.Push("key-a", batchOf("a", 1)))
+	size := probe.size
+
+	// sizes of batches differ by few bytes of time of creation
+	o, err := NewOutbox(dir, WithMaxSize(size*2+size/2))
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, o.Push("key-"+name, batchOf(name, 1)))
+	}
+	assert.Equal(t, 2, o.Len())
+
+	// limit is applied to batches which are restored
+	restored, err := NewOutbox(dir, WithMaxSize(size+size/2))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, collect(t, restored))
+
+	large := batchOf("d", 1)
+	for i := 0; i < 10; i++ {
+		large = append(large, metric.NewGaugeMetric("e", float64(i)))
+	}
+	err = restored.Push("key-d", large)
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+}
+
+func TestOutbox_MaxAge(t *testing.T) {
+	o, err := NewOutbox(t.TempDir(), WithMaxAge(time.Minute))
+	require.NoError(t, err)
+
+	now := time.Now()
+	o.now = func() time.Time { return now }
+	require.NoError(t, o.Push("key-a", batchOf("a", 1)))
+
+	now = now.Add(30 * time.Second)
+	require.NoError(t, o.Push("key-b", batchOf("b", 1)))
+
+	now = now.Add(45 * time.Second)
+	assert.Equal(t, []string{"b"}, collect(t, o))
+}
+
+func TestOutbox_BrokenBatch(t *testing.T) {
+	dir := t.TempDir()
+	o, err := NewOutbox(dir)
+	require.NoError(t, err)
+	require.NoError(t, o.Push("key-a", batchOf("a", 1)))
+	require.NoError(t, o.Push("key-b", batchOf("b", 1)))
+
+	require.NoError(t, os.WriteFile(o.path(1), []byte("broken"), 0o644))
+	assert.Equal(t, []string{"b"}, collect(t, o))
+}
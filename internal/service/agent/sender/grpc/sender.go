@@ -0,0 +1,301 @@
+package grpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/vilasle/metrics/internal/encrypt"
+	"github.com/vilasle/metrics/internal/keyring"
+	"github.com/vilasle/metrics/internal/logger"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/netutil"
+	"github.com/vilasle/metrics/internal/service"
+	"github.com/vilasle/metrics/internal/transport/grpc/convert"
+	"github.com/vilasle/metrics/internal/transport/grpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// hashSumKey is the key of metadata which contains hash sum of messages
+	hashSumKey = "hashsha256"
+	// hashKeyIDKey is the key of metadata which contains id of key of hash sum
+	hashKeyIDKey = "hashkeyid"
+	// realIPKey is the key of metadata which contains ip address of agent
+	realIPKey = "x-real-ip"
+	// agentIDKey is the key of metadata which contains instance ID of agent
+	agentIDKey = "x-agent-id"
+	// idempotencyKey is the key of metadata which contains idempotency key of batch
+	idempotencyKey = "idempotency-key"
+	// authorizationKey is the key of metadata which contains API token
+	authorizationKey = "authorization"
+	// timestampKey is the key of metadata which contains time of signing in unix seconds
+	timestampKey = "x-request-timestamp"
+	// nonceKey is the key of metadata which contains random nonce of signed request
+	nonceKey = "x-request-nonce"
+)
+
+var _ service.Sender = (*GRPCSender)(nil)
+
+type SenderOption func(*GRPCSender)
+
+// WithCalculateHashSum sets key for calculation HMAC-SHA256 of sent messages.
+// Hash sum is passed to server in metadata
+func WithCalculateHashSum(hashSumKey []byte) SenderOption {
+	return func(s *GRPCSender) {
+		if len(hashSumKey) == 0 {
+			return
+		}
+		s.hashKey = hashSumKey
+	}
+}
+
+// WithHashKeyID sets id of key of hash sum, server uses it for choosing key from keyring
+func WithHashKeyID(id string) SenderOption {
+	return func(s *GRPCSender) {
+		s.hashKeyID = id
+	}
+}
+
+// WithEncryption sets public key for envelope encryption of metrics
+func WithEncryption(key *rsa.PublicKey) SenderOption {
+	return func(s *GRPCSender) {
+		if key == nil {
+			return
+		}
+		s.key = key
+		s.keyID, _ = encrypt.KeyID(key)
+	}
+}
+
+// WithInstanceID sets instance ID of agent which is passed to server in metadata with each batch
+func WithInstanceID(id string) SenderOption {
+	return func(s *GRPCSender) {
+		s.instanceID = id
+	}
+}
+
+// WithCompressing enables gzip compression of messages
+func WithCompressing() SenderOption {
+	return func(s *GRPCSender) {
+		s.callOpts = append(s.callOpts, grpc.UseCompressor(gzip.Name))
+	}
+}
+
+// WithToken sets API token which is passed to server in metadata with each batch
+func WithToken(token string) SenderOption {
+	return func(s *GRPCSender) {
+		s.token = token
+	}
+}
+
+// WithTLS makes connection to server by TLS with config, config may contain certificate of agent for mutual TLS.
+// Connection is plaintext without it
+func WithTLS(config *tls.Config) SenderOption {
+	return func(s *GRPCSender) {
+		if config == nil {
+			return
+		}
+		s.creds = credentials.NewTLS(config)
+	}
+}
+
+// GRPCSender sends metrics to server by grpc client stream, the whole batch is sent by one stream
+type GRPCSender struct {
+	conn       *grpc.ClientConn
+	client     pb.MetricsClient
+	hashKey    []byte
+	hashKeyID  string
+	key        *rsa.PublicKey
+	keyID      string
+	realIP     string
+	instanceID string
+	callOpts   []grpc.CallOption
+	creds      credentials.TransportCredentials
+	token      string
+}
+
+// NewGRPCSender returns new instance of GRPCSender
+// addr is the address of grpc server in format host:port
+func NewGRPCSender(addr string, opts ...SenderOption) (*GRPCSender, error) {
+	s := &GRPCSender{
+		realIP:   realIP(addr),
+		callOpts: make([]grpc.CallOption, 0),
+		creds:    insecure.NewCredentials(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(s.creds))
+	if err != nil {
+		return nil, err
+	}
+	s.conn, s.client = conn, pb.NewMetricsClient(conn)
+
+	return s, nil
+}
+
+// Send sends metrics to server by one client stream, idempotency key of batch from ctx is passed in metadata
+func (s *GRPCSender) Send(ctx context.Context, objects ...metric.Metric) error {
+	if len(objects) == 0 {
+		return nil
+	}
+
+	reqs, err := s.makeRequests(objects...)
+	if err != nil {
+		return err
+	}
+
+	if key := service.IdempotencyKeyFromContext(ctx); key != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, idempotencyKey, key)
+	}
+
+	if s.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, authorizationKey, "Bearer "+s.token)
+	}
+
+	if s.realIP != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, realIPKey, s.realIP)
+	}
+
+	if s.instanceID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, agentIDKey, s.instanceID)
+	}
+
+	if s.key != nil {
+		ctx = metadata.AppendToOutgoingContext(ctx, encrypt.ModeHeader, encrypt.ModeEnvelope)
+		if s.keyID != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, encrypt.KeyIDHeader, s.keyID)
+		}
+	}
+
+	if len(s.hashKey) > 0 {
+		// timestamp and nonce are signed with messages, so server rejects replayed calls
+		timestamp, nonce, err := keyring.NewStamp()
+		if err != nil {
+			return err
+		}
+		sum, err := s.hashSum(reqs, timestamp, nonce)
+		if err != nil {
+			return err
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, hashSumKey, sum, timestampKey, timestamp, nonceKey, nonce)
+		if s.hashKeyID != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, hashKeyIDKey, s.hashKeyID)
+		}
+	}
+
+	if err := s.send(ctx, reqs); err != nil {
+		return errors.Join(err, fmt.Errorf("failed metrics: %s", objects))
+	}
+	return nil
+}
+
+// Close closes connection with server
+func (s *GRPCSender) Close() {
+	s.conn.Close()
+}
+
+func (s *GRPCSender) send(ctx context.Context, reqs []*pb.UpdateMetricRequest) error {
+	stream, err := s.client.UpdateMetrics(ctx, s.callOpts...)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	for _, req := range reqs {
+		if err := stream.Send(req); err != nil {
+			// real reason of error is returned by CloseAndRecv
+			break
+		}
+	}
+
+	_, err = stream.CloseAndRecv()
+	return wrapError(err)
+}
+
+func (s *GRPCSender) makeRequests(objects ...metric.Metric) ([]*pb.UpdateMetricRequest, error) {
+	reqs := make([]*pb.UpdateMetricRequest, 0, len(objects))
+	for _, m := range objects {
+		req, err := s.makeRequest(m)
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+func (s *GRPCSender) makeRequest(m metric.Metric) (*pb.UpdateMetricRequest, error) {
+	msg, err := convert.ToProto(m)
+	if err != nil {
+		return nil, err
+	}
+	if s.key == nil {
+		return &pb.UpdateMetricRequest{Metric: msg}, nil
+	}
+
+	content, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := encrypt.Encrypt(s.key, content)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdateMetricRequest{Encrypted: encrypted}, nil
+}
+
+func (s *GRPCSender) hashSum(reqs []*pb.UpdateMetricRequest, timestamp, nonce string) (string, error) {
+	h := hmac.New(sha256.New, s.hashKey)
+	h.Write(keyring.SignedContent(nil, timestamp, nonce))
+	for _, req := range reqs {
+		content, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+		if err != nil {
+			return "", err
+		}
+		if _, err := h.Write(content); err != nil {
+			return "", err
+		}
+	}
+	return base64.URLEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// realIP returns outbound ip address of agent for connection to server or empty string if it can not be defined
+func realIP(addr string) string {
+	ip, err := netutil.OutboundIP(addr)
+	if err != nil {
+		logger.Error("can not define outbound ip address", "addr", addr, "error", err)
+		return ""
+	}
+	return ip.String()
+}
+
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch status.Code(err) {
+	case codes.NotFound:
+		return errors.Join(ErrWrongMetricName, err)
+	case codes.InvalidArgument:
+		return errors.Join(ErrWrongMetricTypeOrValue, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,312 @@
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/auth"
+	"github.com/vilasle/metrics/internal/keyring"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository/memory"
+	"github.com/vilasle/metrics/internal/service/server"
+	"github.com/vilasle/metrics/internal/transport/grpc/interceptor"
+	"github.com/vilasle/metrics/internal/transport/grpc/pb"
+	grpcsrv "github.com/vilasle/metrics/internal/transport/grpc/server"
+	"github.com/vilasle/metrics/internal/transport/rest/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func runTestServer(t *testing.T, hashKey []byte, key *rsa.PrivateKey) (string, *server.MetricService) {
+	t.Helper()
+
+	svc := server.NewMetricService(memory.NewMetricRepository())
+	srv := grpcsrv.NewGRPCServer("", svc,
+		grpc.ChainStreamInterceptor(
+			interceptor.CheckHashSumStream(hashKey),
+			interceptor.DecryptContentStream(key),
+		),
+	)
+
+	listen, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	go srv.Serve(listen)
+	t.Cleanup(func() { srv.ForceStop() })
+
+	return listen.Addr().String(), svc
+}
+
+func TestGRPCSender_Send(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		serverHashKey []byte
+		serverKey     *rsa.PrivateKey
+		opts          []SenderOption
+		wantErr       bool
+	}{
+		{
+			name: "plain",
+		},
+		{
+			name:          "with hash sum",
+			serverHashKey: []byte("key"),
+			opts:          []SenderOption{WithCalculateHashSum([]byte("key"))},
+		},
+		{
+			name:          "with wrong hash sum",
+			serverHashKey: []byte("key"),
+			opts:          []SenderOption{WithCalculateHashSum([]byte("another key"))},
+			wantErr:       true,
+		},
+		{
+			name:      "with encryption",
+			serverKey: privateKey,
+			opts:      []SenderOption{WithEncryption(&privateKey.PublicKey)},
+		},
+		{
+			name:          "with all options",
+			serverHashKey: []byte("key"),
+			serverKey:     privateKey,
+			opts: []SenderOption{
+				WithCalculateHashSum([]byte("key")),
+				WithEncryption(&privateKey.PublicKey),
+				WithCompressing(),
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, svc := runTestServer(t, tt.serverHashKey, tt.serverKey)
+
+			sender, err := NewGRPCSender(addr, tt.opts...)
+			require.NoError(t, err)
+			defer sender.Close()
+
+			err = sender.Send(context.Background(),
+				metric.NewGaugeMetric("gauge1", 1.25),
+				metric.NewCounterMetric("counter1", 5),
+				metric.NewCounterMetric("counter1", 6),
+			)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, ErrWrongMetricTypeOrValue)
+
+				_, err = svc.Get(context.Background(), metric.TypeCounter, "counter1", nil)
+				assert.Error(t, err, "metrics must not be saved")
+				return
+			}
+			require.NoError(t, err)
+
+			gauge, err := svc.Get(context.Background(), metric.TypeGauge, "gauge1", nil)
+			require.NoError(t, err)
+			assert.Equal(t, 1.25, gauge.Float64())
+
+			counter, err := svc.Get(context.Background(), metric.TypeCounter, "counter1", nil)
+			require.NoError(t, err)
+			assert.Equal(t, int64(11), counter.Int64())
+		})
+	}
+}
+
+// newTLSConfigs returns configs of server and agent which trust each other by the same authority
+func newTLSConfigs(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(name string, serial int64) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	server := &tls.Config{
+		Certificates: []tls.Certificate{issue("server", 2)},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	agent := &tls.Config{
+		Certificates: []tls.Certificate{issue("agent", 3)},
+		RootCAs:      pool,
+	}
+	return server, agent
+}
+
+func TestGRPCSender_SendTLS(t *testing.T) {
+	serverConfig, agentConfig := newTLSConfigs(t)
+
+	svc := server.NewMetricService(memory.NewMetricRepository())
+	srv := grpcsrv.NewGRPCServer("", svc, grpc.Creds(credentials.NewTLS(serverConfig)))
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(listen)
+	t.Cleanup(func() { srv.ForceStop() })
+
+	anonymous := agentConfig.Clone()
+	anonymous.Certificates = nil
+
+	testCases := []struct {
+		name    string
+		opts    []SenderOption
+		wantErr bool
+	}{
+		{name: "mutual tls", opts: []SenderOption{WithTLS(agentConfig)}},
+		{name: "without certificate of agent", opts: []SenderOption{WithTLS(anonymous)}, wantErr: true},
+		{name: "plaintext", wantErr: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, err := NewGRPCSender(listen.Addr().String(), tt.opts...)
+			require.NoError(t, err)
+			defer sender.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			err = sender.Send(ctx, metric.NewGaugeMetric("gauge1", 1.25))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+type tokenMap map[string]auth.Token
+
+func (m tokenMap) Token(_ context.Context, id string) (auth.Token, bool, error) {
+	t, ok := m[id]
+	return t, ok, nil
+}
+
+func TestGRPCSender_SendToken(t *testing.T) {
+	read, readValue, err := auth.Issue("dashboard", auth.ScopeRead)
+	require.NoError(t, err)
+	write, writeValue, err := auth.Issue("agent", auth.ScopeWrite)
+	require.NoError(t, err)
+
+	svc := server.NewMetricService(memory.NewMetricRepository())
+	srv := grpcsrv.NewGRPCServer("", svc, grpc.ChainStreamInterceptor(
+		interceptor.AuthenticateStream(tokenMap{read.ID: read, write.ID: write}, interceptor.MethodScopes{
+			pb.Metrics_UpdateMetrics_FullMethodName: auth.ScopeWrite,
+		}),
+	))
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(listen)
+	t.Cleanup(func() { srv.ForceStop() })
+
+	testCases := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "write token", token: writeValue},
+		{name: "read token", token: readValue, wantErr: true},
+		{name: "without token", wantErr: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, err := NewGRPCSender(listen.Addr().String(), WithToken(tt.token))
+			require.NoError(t, err)
+			defer sender.Close()
+
+			err = sender.Send(context.Background(), metric.NewGaugeMetric("gauge1", 1.25))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+// recordingGuard remembers checked nonces
+type recordingGuard struct {
+	*middleware.ReplayGuard
+	nonces []string
+}
+
+func (g *recordingGuard) Check(timestamp, nonce string) error {
+	g.nonces = append(g.nonces, nonce)
+	return g.ReplayGuard.Check(timestamp, nonce)
+}
+
+func TestGRPCSender_SendStamp(t *testing.T) {
+	hashKey := []byte("key")
+	guard := &recordingGuard{ReplayGuard: middleware.NewReplayGuard(middleware.WithRequiredStamp())}
+
+	svc := server.NewMetricService(memory.NewMetricRepository())
+	srv := grpcsrv.NewGRPCServer("", svc,
+		grpc.ChainStreamInterceptor(interceptor.CheckHashSumKeyringStream(keyring.FromSecret(hashKey), guard)),
+	)
+	listen, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go srv.Serve(listen)
+	t.Cleanup(func() { srv.ForceStop() })
+
+	sender, err := NewGRPCSender(listen.Addr().String(), WithCalculateHashSum(hashKey))
+	require.NoError(t, err)
+	defer sender.Close()
+
+	// each call is signed with own nonce
+	for i := 0; i < 2; i++ {
+		require.NoError(t, sender.Send(context.Background(), metric.NewCounterMetric("counter1", 1)))
+	}
+	require.Len(t, guard.nonces, 2)
+	assert.NotEqual(t, guard.nonces[0], guard.nonces[1])
+
+	counter, err := svc.Get(context.Background(), metric.TypeCounter, "counter1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), counter.Int64())
+}
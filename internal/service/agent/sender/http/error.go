@@ -4,3 +4,5 @@ import "errors"
 
 var ErrWrongMetricName = errors.New("wrong metric name")
 var ErrWrongMetricTypeOrValue = errors.New("wrong metric type or value")
+var ErrUnauthorized = errors.New("token is not accepted by server")
+var ErrUnexpectedStatus = errors.New("unexpected status code")
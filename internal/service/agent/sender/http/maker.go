@@ -2,19 +2,52 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 
+	"github.com/vilasle/metrics/internal/logger"
 	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/netutil"
+	"github.com/vilasle/metrics/internal/service"
 )
 
+const (
+	// realIPHeader is the header which contains ip address of agent, server checks that it belongs trusted subnet
+	realIPHeader = "X-Real-IP"
+	// agentIDHeader is the header which contains instance ID of agent, server remembers it as source of metrics
+	agentIDHeader = "X-Agent-ID"
+	// idempotencyKeyHeader is the header which contains idempotency key of batch, server does not apply batch twice
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+type MakerOption func(*JSONRequestMaker)
+
+// WithInstanceID sets instance ID of agent which is sent with each batch
+func WithInstanceID(id string) MakerOption {
+	return func(m *JSONRequestMaker) {
+		m.instanceID = id
+	}
+}
+
+// WithToken sets API token which is sent in header Authorization
+func WithToken(token string) MakerOption {
+	return func(m *JSONRequestMaker) {
+		m.token = token
+	}
+}
+
 type JSONRequestMaker struct {
 	addr          *url.URL
 	contentWriter *JSONWriter
+	realIP        string
+	instanceID    string
+	token         string
 }
 
-func NewJSONRequestMaker(addr string, writer *JSONWriter) (*JSONRequestMaker, error) {
+func NewJSONRequestMaker(addr string, writer *JSONWriter, opts ...MakerOption) (*JSONRequestMaker, error) {
 	u, err := url.Parse(addr)
 	if err != nil {
 		return nil, err
@@ -22,40 +55,80 @@ func NewJSONRequestMaker(addr string, writer *JSONWriter) (*JSONRequestMaker, er
 	maker := &JSONRequestMaker{
 		addr:          u,
 		contentWriter: writer,
+		realIP:        realIP(u),
+	}
+
+	for _, opt := range opts {
+		opt(maker)
 	}
 
 	return maker, nil
 }
 
-func (maker *JSONRequestMaker) Make(objects ...metric.Metric) (*http.Request, error) {
+func (maker *JSONRequestMaker) Make(ctx context.Context, objects ...metric.Metric) (*http.Request, error) {
 
-	var err error
+	var (
+		content []byte
+		headers map[string]string
+		err     error
+	)
 	if len(objects) == 1 {
-		err = maker.contentWriter.Write(objects[0])
+		content, headers, err = maker.contentWriter.Write(objects[0])
 	} else {
-		err = maker.contentWriter.Write(objects)
+		content, headers, err = maker.contentWriter.Write(objects)
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	rd := bytes.NewReader(maker.contentWriter.Bytes())
+	rd := bytes.NewReader(content)
 
-	req, err := http.NewRequest(http.MethodPost, maker.addr.String(), rd)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, maker.addr.String(), rd)
 	if err != nil {
 		return nil, err
 	}
 
-	for k, v := range maker.contentWriter.headers {
+	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
 
 	req.Header.Set("Accept-Encoding", "gzip") //TODO why????
 
+	if maker.realIP != "" {
+		req.Header.Set(realIPHeader, maker.realIP)
+	}
+
+	if maker.instanceID != "" {
+		req.Header.Set(agentIDHeader, maker.instanceID)
+	}
+
+	if maker.token != "" {
+		req.Header.Set("Authorization", "Bearer "+maker.token)
+	}
+
+	if key := service.IdempotencyKeyFromContext(ctx); key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+
 	return req, nil
 }
 
+// realIP returns outbound ip address of agent for connection to server or empty string if it can not be defined
+func realIP(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+	}
+
+	ip, err := netutil.OutboundIP(net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		logger.Error("can not define outbound ip address", "addr", u.String(), "error", err)
+		return ""
+	}
+	return ip.String()
+}
+
 type TextRequestMaker struct {
 	addr *url.URL
 }
@@ -70,7 +143,7 @@ func NewTextRequestMaker(addr string) (*TextRequestMaker, error) {
 	return maker, err
 }
 
-func (maker *TextRequestMaker) Make(objects ...metric.Metric) (*http.Request, error) {
+func (maker *TextRequestMaker) Make(ctx context.Context, objects ...metric.Metric) (*http.Request, error) {
 	if len(objects) < 1 {
 		return nil, fmt.Errorf("objects does not have metrics")
 	}
@@ -83,11 +156,15 @@ func (maker *TextRequestMaker) Make(objects ...metric.Metric) (*http.Request, er
 
 	addr := maker.addr.JoinPath(metric.Type(), metric.Name(), metric.Value()).String()
 
-	req, err := http.NewRequest(http.MethodPost, addr, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, addr, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "text/plain")
 
+	if key := service.IdempotencyKeyFromContext(ctx); key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+
 	return req, nil
 }
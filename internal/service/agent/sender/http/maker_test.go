@@ -1,11 +1,19 @@
 package http
 
 import (
+	"context"
+	"encoding/base64"
+	"fmt"
 	"io"
+	"net/http"
+	"sync"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/keyring"
 	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/service"
 )
 
 func Test_TextRequestMaker(t *testing.T) {
@@ -13,7 +21,7 @@ func Test_TextRequestMaker(t *testing.T) {
 	require.NoError(t, err)
 
 	metric := metric.NewCounterMetric("test", 1)
-	req, err := maker.Make(metric)
+	req, err := maker.Make(context.Background(), metric)
 	require.NoError(t, err)
 
 	require.Equal(t, "http://localhost:8080/counter/test/1", req.URL.String())
@@ -50,17 +58,77 @@ func Test_JSONRequestMaker(t *testing.T) {
 	for _, tt := range testCases {
 		jw := NewJSONWriter()
 
-		maker, err := NewJSONRequestMaker("http://localhost:8080", jw)
+		maker, err := NewJSONRequestMaker("http://127.0.0.1:8080", jw)
 		require.NoError(t, err)
 
-		req, err := maker.Make(tt.metric...)
+		req, err := maker.Make(context.Background(), tt.metric...)
 		require.NoError(t, err)
 
 		content, err := io.ReadAll(req.Body)
 		require.NoError(t, err)
 
 		require.Equal(t, tt.expected, string(content))
+		require.Equal(t, "127.0.0.1", req.Header.Get("X-Real-IP"))
 
 	}
 
 }
+
+func Test_JSONRequestMaker_InstanceID(t *testing.T) {
+	maker, err := NewJSONRequestMaker("http://127.0.0.1:8080", NewJSONWriter(), WithInstanceID("host/id"))
+	require.NoError(t, err)
+
+	req, err := maker.Make(context.Background(), metric.NewCounterMetric("test", 1))
+	require.NoError(t, err)
+
+	require.Equal(t, "host/id", req.Header.Get("X-Agent-ID"))
+}
+
+func Test_JSONRequestMaker_IdempotencyKey(t *testing.T) {
+	maker, err := NewJSONRequestMaker("http://127.0.0.1:8080", NewJSONWriter())
+	require.NoError(t, err)
+
+	ctx := service.WithIdempotencyKey(context.Background(), "batch-1")
+	req, err := maker.Make(ctx, metric.NewCounterMetric("test", 1))
+	require.NoError(t, err)
+	require.Equal(t, "batch-1", req.Header.Get("Idempotency-Key"))
+
+	req, err = maker.Make(context.Background(), metric.NewCounterMetric("test", 1))
+	require.NoError(t, err)
+	require.Empty(t, req.Header.Get("Idempotency-Key"))
+}
+
+func Test_JSONRequestMaker_Concurrent(t *testing.T) {
+	key := []byte("KeyForHashSum")
+	maker, err := NewJSONRequestMaker("http://127.0.0.1:8080",
+		NewJSONWriter(WithCalculateHashSum(key), WithRequestStamp(), WithCompressing()))
+	require.NoError(t, err)
+
+	const workers = 16
+	reqs := make([]*http.Request, workers)
+	wg := &sync.WaitGroup{}
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := maker.Make(context.Background(), metric.NewCounterMetric(fmt.Sprintf("counter%d", i), int64(i)))
+			assert.NoError(t, err)
+			reqs[i] = req
+		}()
+	}
+	wg.Wait()
+
+	// each request is signed with own body and nonce
+	nonces := make(map[string]struct{}, workers)
+	for _, req := range reqs {
+		require.NotNil(t, req)
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+
+		timestamp, nonce := req.Header.Get(keyring.TimestampHeader), req.Header.Get(keyring.NonceHeader)
+		sum := keyring.Key{Secret: string(key)}.Sign(keyring.SignedContent(body, timestamp, nonce))
+		assert.Equal(t, base64.URLEncoding.EncodeToString(sum), req.Header.Get("HashSHA256"))
+		nonces[nonce] = struct{}{}
+	}
+	assert.Len(t, nonces, workers)
+}
@@ -4,15 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
 
 	"github.com/vilasle/metrics/internal/logger"
 	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/service"
 )
 
 type RequestMaker interface {
-	Make(objects ...metric.Metric) (*http.Request, error)
+	Make(ctx context.Context, objects ...metric.Metric) (*http.Request, error)
+}
+
+// request is the metric which is sent by worker with context of its batch
+type request struct {
+	ctx    context.Context
+	metric metric.Metric
 }
 
 type SenderOption func(*HTTPSender)
@@ -22,18 +30,28 @@ type HTTPSender struct {
 	client http.Client
 	//background sending
 	rateLimit int
-	reqCh     chan metric.Metric
+	reqCh     chan request
 	respCh    chan error
 }
 
 func WithRateLimit(limit int) SenderOption {
 	return func(e *HTTPSender) {
 		e.rateLimit = limit
-		e.reqCh = make(chan metric.Metric, limit)
+		e.reqCh = make(chan request, limit)
 		e.respCh = make(chan error, limit)
 	}
 }
 
+// WithTLS sends requests by connections which are established by dial, e.g. tlsutil.Reloader.DialTLSContext
+func WithTLS(dial func(ctx context.Context, network, addr string) (net.Conn, error)) SenderOption {
+	return func(e *HTTPSender) {
+		e.client.Transport = &http.Transport{
+			Proxy:          http.ProxyFromEnvironment,
+			DialTLSContext: dial,
+		}
+	}
+}
+
 func NewHTTPSender(rm RequestMaker, opts ...SenderOption) *HTTPSender {
 	s := &HTTPSender{
 		maker:  rm,
@@ -59,15 +77,17 @@ func (s *HTTPSender) Close() {
 
 }
 
-func (s *HTTPSender) Send(objects ...metric.Metric) error {
+// Send sends metrics to server. If rate limit is set, each metric is sent by separate request
+// and idempotency key of batch from ctx is extended by index of metric in batch
+func (s *HTTPSender) Send(ctx context.Context, objects ...metric.Metric) error {
 	if s.rateLimit > 0 {
-		return s.sendAsync(objects...)
+		return s.sendAsync(ctx, objects...)
 	}
-	return s.sendSync(objects...)
+	return s.sendSync(ctx, objects...)
 }
 
-func (s *HTTPSender) sendSync(objects ...metric.Metric) error {
-	req, err := s.maker.Make(objects...)
+func (s *HTTPSender) sendSync(ctx context.Context, objects ...metric.Metric) error {
+	req, err := s.maker.Make(ctx, objects...)
 	if err != nil {
 		return err
 	}
@@ -78,12 +98,17 @@ func (s *HTTPSender) sendSync(objects ...metric.Metric) error {
 	return nil
 }
 
-func (s *HTTPSender) sendAsync(objects ...metric.Metric) error {
+func (s *HTTPSender) sendAsync(ctx context.Context, objects ...metric.Metric) error {
 	limit := s.rateLimit
 	errs := make([]error, 0)
+	key := service.IdempotencyKeyFromContext(ctx)
 
-	for _, v := range objects {
-		s.reqCh <- v
+	for i, v := range objects {
+		reqCtx := ctx
+		if key != "" {
+			reqCtx = service.WithIdempotencyKey(ctx, fmt.Sprintf("%s-%d", key, i))
+		}
+		s.reqCh <- request{ctx: reqCtx, metric: v}
 		limit--
 
 		if limit > 0 {
@@ -108,15 +133,25 @@ func (s *HTTPSender) send(req *http.Request) error {
 
 	statusCode := resp.StatusCode
 
-	switch statusCode {
-	case http.StatusNotFound:
+	// metrics are sent again later if server does not accept them by any reason
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+	case statusCode == http.StatusNotFound:
 		err = errors.Join(
 			ErrWrongMetricName,
 			fmt.Errorf("status code %d", statusCode))
-	case http.StatusBadRequest:
+	case statusCode == http.StatusBadRequest:
 		err = errors.Join(
 			ErrWrongMetricTypeOrValue,
 			fmt.Errorf("status code %d", statusCode))
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		err = errors.Join(
+			ErrUnauthorized,
+			fmt.Errorf("status code %d", statusCode))
+	default:
+		err = errors.Join(
+			ErrUnexpectedStatus,
+			fmt.Errorf("status code %d", statusCode))
 	}
 
 	return err
@@ -138,12 +173,12 @@ func (s *HTTPSender) background(ctx context.Context, wg *sync.WaitGroup) {
 		select {
 		case <-ctx.Done():
 			for r := range s.reqCh {
-				s.respCh <- s.sendSync(r)
+				s.respCh <- s.sendSync(r.ctx, r.metric)
 			}
 			return
-		case m := <-s.reqCh:
-			logger.Info("got metrics", "metric", m)
-			s.respCh <- s.sendSync(m)
+		case r := <-s.reqCh:
+			logger.Info("got metrics", "metric", r.metric)
+			s.respCh <- s.sendSync(r.ctx, r.metric)
 		}
 	}
 }
@@ -1,12 +1,18 @@
 package http
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/service"
 )
 
 func TestNewHTTPSender(t *testing.T) {
@@ -20,6 +26,96 @@ func TestNewHTTPSender(t *testing.T) {
 
 	sender := NewHTTPSender(tm)
 
-	err = sender.Send(metric.NewCounterMetric("test", 1))
+	err = sender.Send(context.Background(), metric.NewCounterMetric("test", 1))
 	require.NoError(t, err)
 }
+
+func TestHTTPSender_WithTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tm, err := NewTextRequestMaker(server.URL)
+	require.NoError(t, err)
+
+	// server is not trusted by default client
+	err = NewHTTPSender(tm).Send(context.Background(), metric.NewCounterMetric("test", 1))
+	require.Error(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	dialer := &tls.Dialer{Config: &tls.Config{RootCAs: pool}}
+
+	err = NewHTTPSender(tm, WithTLS(dialer.DialContext)).Send(context.Background(), metric.NewCounterMetric("test", 1))
+	require.NoError(t, err)
+}
+
+func TestHTTPSender_IdempotencyKeyWithRateLimit(t *testing.T) {
+	mx := &sync.Mutex{}
+	keys := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		mx.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tm, err := NewTextRequestMaker(server.URL)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	sender := NewHTTPSender(tm, WithRateLimit(2))
+	sender.Start(ctx, wg)
+
+	err = sender.Send(service.WithIdempotencyKey(context.Background(), "batch-1"),
+		metric.NewCounterMetric("a", 1),
+		metric.NewCounterMetric("b", 1),
+	)
+	require.NoError(t, err)
+
+	cancel()
+	sender.Close()
+	wg.Wait()
+
+	// each metric is sent by separate request, so it has own key
+	sort.Strings(keys)
+	require.Equal(t, []string{"batch-1-0", "batch-1-1"}, keys)
+}
+
+func TestHTTPSender_StatusCodes(t *testing.T) {
+	testCases := []struct {
+		statusCode int
+		wantErr    error
+	}{
+		{statusCode: http.StatusOK},
+		{statusCode: http.StatusAccepted},
+		{statusCode: http.StatusBadRequest, wantErr: ErrWrongMetricTypeOrValue},
+		{statusCode: http.StatusNotFound, wantErr: ErrWrongMetricName},
+		{statusCode: http.StatusUnauthorized, wantErr: ErrUnauthorized},
+		{statusCode: http.StatusForbidden, wantErr: ErrUnauthorized},
+		{statusCode: http.StatusInternalServerError, wantErr: ErrUnexpectedStatus},
+		{statusCode: http.StatusMovedPermanently, wantErr: ErrUnexpectedStatus},
+	}
+
+	for _, tt := range testCases {
+		t.Run(http.StatusText(tt.statusCode), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			tm, err := NewTextRequestMaker(server.URL)
+			require.NoError(t, err)
+
+			err = NewHTTPSender(tm).Send(context.Background(), metric.NewCounterMetric("test", 1))
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
@@ -4,22 +4,23 @@ import (
 	"bytes"
 	"compress/gzip"
 	"crypto/hmac"
-	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
-	"io"
+	"maps"
 	"sync"
 
 	"github.com/vilasle/metrics/internal/compress"
+	"github.com/vilasle/metrics/internal/encrypt"
+	"github.com/vilasle/metrics/internal/keyring"
 )
 
 type WriterOption func(*JSONWriter)
 
 func WithCompressing() WriterOption {
 	return func(e *JSONWriter) {
-		e.wrt = newGzipWriter(e.wrt)
+		e.encoders = append(e.encoders, newGzipWriter())
 		e.headers["Content-Encoding"] = "gzip"
 	}
 }
@@ -29,7 +30,11 @@ func WithEncryption(key *rsa.PublicKey) WriterOption {
 		if key == nil {
 			return
 		}
-		e.wrt = newEncryptWriter(e.wrt, key)
+		e.encoders = append(e.encoders, newEncryptWriter(key))
+		e.headers[encrypt.ModeHeader] = encrypt.ModeEnvelope
+		if id, err := encrypt.KeyID(key); err == nil {
+			e.headers[encrypt.KeyIDHeader] = id
+		}
 	}
 }
 
@@ -38,21 +43,44 @@ func WithCalculateHashSum(hashSumKey []byte) WriterOption {
 		if len(hashSumKey) == 0 {
 			return
 		}
-		e.wrt = newHashSumWriter(e, hashSumKey)
+		e.encoders = append(e.encoders, newHashSumWriter(e, hashSumKey))
+	}
+}
+
+// WithHashKeyID sets id of key of hash sum, server uses it for choosing key from keyring
+func WithHashKeyID(id string) WriterOption {
+	return func(e *JSONWriter) {
+		if id == "" {
+			return
+		}
+		e.headers[keyring.IDHeader] = id
+	}
+}
+
+// WithRequestStamp signs timestamp and random nonce together with body, so server can reject replayed requests.
+// It works with WithCalculateHashSum only
+func WithRequestStamp() WriterOption {
+	return func(e *JSONWriter) {
+		e.stamp = true
 	}
 }
 
+// encoder changes content of request and sets headers which describe the change
+type encoder interface {
+	encode(content []byte, headers map[string]string) ([]byte, error)
+}
+
+// JSONWriter encodes objects to content of requests. It is not changed by writing,
+// so it is safe for concurrent use by several workers
 type JSONWriter struct {
+	stamp   bool
 	headers map[string]string
-	buf     *bytes.Buffer
-	wrt     io.Writer
+	// encoders are applied in reverse order of options, so the last option changes content first
+	encoders []encoder
 }
 
 func NewJSONWriter(opts ...WriterOption) *JSONWriter {
-	buf := &bytes.Buffer{}
 	e := &JSONWriter{
-		buf:     buf,
-		wrt:     buf,
 		headers: make(map[string]string),
 	}
 
@@ -65,30 +93,28 @@ func NewJSONWriter(opts ...WriterOption) *JSONWriter {
 	return e
 }
 
-func (e JSONWriter) Write(object any) error {
+// Write returns encoded content of object and headers of request which carries it
+func (e *JSONWriter) Write(object any) ([]byte, map[string]string, error) {
 	content, err := json.Marshal(&object)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	e.buf.Reset()
-
-	_, err = e.wrt.Write(content)
-	return err
-}
-
-func (e JSONWriter) Bytes() []byte {
-	return e.buf.Bytes()
+	headers := maps.Clone(e.headers)
+	for i := len(e.encoders) - 1; i >= 0; i-- {
+		if content, err = e.encoders[i].encode(content, headers); err != nil {
+			return nil, nil, err
+		}
+	}
+	return content, headers, nil
 }
 
 type gzipWriter struct {
-	wrt          io.Writer
 	encodersPool *sync.Pool
 }
 
-func newGzipWriter(wrt io.Writer) *gzipWriter {
+func newGzipWriter() *gzipWriter {
 	return &gzipWriter{
-		wrt: wrt,
 		encodersPool: &sync.Pool{
 			New: func() interface{} {
 				return compress.NewCompressor(gzip.BestCompression)
@@ -97,64 +123,61 @@ func newGzipWriter(wrt io.Writer) *gzipWriter {
 	}
 }
 
-func (e gzipWriter) Write(d []byte) (int, error) {
+func (e gzipWriter) encode(d []byte, _ map[string]string) ([]byte, error) {
 	w := e.encodersPool.Get().(compress.CompressorWriter)
-	n, err := w.Write(d)
-	if err != nil {
-		return n, err
-	}
-	content := w.Bytes()
+	defer e.encodersPool.Put(w)
+	defer w.Reset()
 
-	w.Reset()
-	e.encodersPool.Put(w)
-
-	return e.wrt.Write(content)
+	if _, err := w.Write(d); err != nil {
+		return nil, err
+	}
+	// content of compressor is reused after reset
+	return bytes.Clone(w.Bytes()), nil
 }
 
 type encryptWriter struct {
 	key *rsa.PublicKey
-	wrt io.Writer
 }
 
-func newEncryptWriter(wrt io.Writer, key *rsa.PublicKey) *encryptWriter {
+func newEncryptWriter(key *rsa.PublicKey) *encryptWriter {
 	return &encryptWriter{
 		key: key,
-		wrt: wrt,
 	}
 }
 
-func (e encryptWriter) Write(d []byte) (int, error) {
-	content, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, e.key, d, []byte{})
-	if err != nil {
-		return 0, err
-	}
-	return e.wrt.Write(content)
+func (e encryptWriter) encode(d []byte, _ map[string]string) ([]byte, error) {
+	return encrypt.Encrypt(e.key, d)
 }
 
 type hashSumWriter struct {
 	key []byte
-	wrt io.Writer
 	jw  *JSONWriter
 }
 
 func newHashSumWriter(jw *JSONWriter, key []byte) *hashSumWriter {
 	return &hashSumWriter{
 		key: key,
-		wrt: jw.wrt,
 		jw:  jw,
 	}
 }
 
-func (e hashSumWriter) Write(d []byte) (int, error) {
-	w := hmac.New(sha256.New, []byte(e.key))
-	if _, err := w.Write(d); err != nil {
-		return 0, err
+// encode signs content, timestamp and nonce of each request are generated here, so they are own for each request
+func (e hashSumWriter) encode(d []byte, headers map[string]string) ([]byte, error) {
+	var timestamp, nonce string
+	if e.jw.stamp {
+		var err error
+		if timestamp, nonce, err = keyring.NewStamp(); err != nil {
+			return nil, err
+		}
+		headers[keyring.TimestampHeader] = timestamp
+		headers[keyring.NonceHeader] = nonce
 	}
 
-	srcHash := w.Sum(nil)
-	hash := base64.URLEncoding.EncodeToString(srcHash)
-
-	e.jw.headers["HashSHA256"] = hash
+	w := hmac.New(sha256.New, []byte(e.key))
+	if _, err := w.Write(keyring.SignedContent(d, timestamp, nonce)); err != nil {
+		return nil, err
+	}
 
-	return e.wrt.Write(d)
+	headers["HashSHA256"] = base64.URLEncoding.EncodeToString(w.Sum(nil))
+	return d, nil
 }
@@ -3,11 +3,13 @@ package http
 import (
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
+	"encoding/base64"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/encrypt"
+	"github.com/vilasle/metrics/internal/keyring"
 )
 
 func Test_hashSumWriter(t *testing.T) {
@@ -46,7 +48,7 @@ func Test_hashSumWriter(t *testing.T) {
 			value: 54321,
 		}
 
-		err := j.Write(ob)
+		_, headers, err := j.Write(ob)
 
 		if tt.wantErr {
 			require.Error(t, err)
@@ -55,7 +57,7 @@ func Test_hashSumWriter(t *testing.T) {
 			require.NoError(t, err)
 		}
 
-		hash := j.headers["HashSHA256"]
+		hash := headers["HashSHA256"]
 		assert.Equal(t, tt.expected, hash)
 	}
 }
@@ -90,7 +92,7 @@ func Test_gzipWriter(t *testing.T) {
 			value: 54321,
 		}
 
-		err := j.Write(ob)
+		actual, _, err := j.Write(ob)
 
 		if tt.wantErr {
 			require.Error(t, err)
@@ -99,7 +101,6 @@ func Test_gzipWriter(t *testing.T) {
 			require.NoError(t, err)
 		}
 
-		actual := j.Bytes()
 		assert.Equal(t, tt.expected, actual)
 	}
 }
@@ -133,7 +134,7 @@ func Test_encryptWriter(t *testing.T) {
 	for _, tt := range testCases {
 		j := NewJSONWriter(WithEncryption(&privateKey.PublicKey))
 
-		err := j.Write(tt.object)
+		data, headers, err := j.Write(tt.object)
 
 		if tt.wantErr {
 			require.Error(t, err)
@@ -142,11 +143,42 @@ func Test_encryptWriter(t *testing.T) {
 			require.NoError(t, err)
 		}
 
-		data := j.Bytes()
-
-		actual, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, data, []byte{})
+		actual, err := encrypt.Decrypt(privateKey, data)
 		require.NoError(t, err)
 
 		assert.Equal(t, tt.expected, actual)
+		assert.Equal(t, encrypt.ModeEnvelope, headers[encrypt.ModeHeader])
 	}
 }
+
+func Test_WithHashKeyID(t *testing.T) {
+	j := NewJSONWriter(WithCalculateHashSum([]byte("KeyForHashSum")), WithHashKeyID("k2"))
+	_, headers, err := j.Write(struct{}{})
+	require.NoError(t, err)
+	assert.Equal(t, "k2", headers[keyring.IDHeader])
+	assert.NotEmpty(t, headers["HashSHA256"])
+
+	j = NewJSONWriter(WithCalculateHashSum([]byte("KeyForHashSum")), WithHashKeyID(""))
+	_, headers, err = j.Write(struct{}{})
+	require.NoError(t, err)
+	assert.NotContains(t, headers, keyring.IDHeader)
+}
+
+func Test_WithRequestStamp(t *testing.T) {
+	key := []byte("KeyForHashSum")
+	j := NewJSONWriter(WithRequestStamp(), WithCalculateHashSum(key))
+	content, headers, err := j.Write(struct{}{})
+	require.NoError(t, err)
+
+	timestamp, nonce := headers[keyring.TimestampHeader], headers[keyring.NonceHeader]
+	require.NotEmpty(t, timestamp)
+	require.NotEmpty(t, nonce)
+
+	sum := keyring.Key{Secret: string(key)}.Sign(keyring.SignedContent(content, timestamp, nonce))
+	assert.Equal(t, base64.URLEncoding.EncodeToString(sum), headers["HashSHA256"])
+
+	// each request has own nonce
+	_, next, err := j.Write(struct{}{})
+	require.NoError(t, err)
+	assert.NotEqual(t, nonce, next[keyring.NonceHeader])
+}
@@ -0,0 +1,284 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vilasle/metrics/internal/alert"
+	"github.com/vilasle/metrics/internal/logger"
+	"github.com/vilasle/metrics/internal/repository"
+	"github.com/vilasle/metrics/internal/service"
+)
+
+// defaultInterval is the period of evaluation of rules by default
+const defaultInterval = 15 * time.Second
+
+var _ service.AlertService = (*Engine)(nil)
+
+// Engine evaluates alerting rules against metrics of MetricService periodically
+// and keeps state of alerts in storage, so state survives restart of server
+type Engine struct {
+	metrics  service.MetricService
+	store    repository.AlertRepository
+	rules    []alert.Rule
+	interval time.Duration
+	now      func() time.Time
+	started  time.Time
+	notifier Notifier
+
+	mx     *sync.Mutex
+	alerts map[string]alert.Alert
+}
+
+// Option is the setting of Engine
+type Option func(*Engine)
+
+// WithInterval sets the period of evaluation of rules
+func WithInterval(interval time.Duration) Option {
+	return func(e *Engine) {
+		if interval > 0 {
+			e.interval = interval
+		}
+	}
+}
+
+// Notifier delivers changes of state of alerts, it must not block evaluation of rules
+type Notifier interface {
+	Notify(ev alert.Event)
+}
+
+// WithNotifier sets the notifier which gets alerts when they fire and when they are resolved
+func WithNotifier(n Notifier) Option {
+	return func(e *Engine) {
+		e.notifier = n
+	}
+}
+
+// NewEngine returns new instance of Engine. If store is nil, state of alerts is kept only in memory
+func NewEngine(metrics service.MetricService, store repository.AlertRepository, rules []alert.Rule, opts ...Option) *Engine {
+	e := &Engine{
+		metrics:  metrics,
+		store:    store,
+		rules:    rules,
+		interval: defaultInterval,
+		now:      time.Now,
+		mx:       &sync.Mutex{},
+		alerts:   make(map[string]alert.Alert),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.started = e.now()
+
+	return e
+}
+
+// Restore loads state of alerts from storage, alerts of rules which are not defined anymore are deleted
+func (e *Engine) Restore(ctx context.Context) error {
+	if e.store == nil {
+		return nil
+	}
+
+	alerts, err := e.store.Alerts(ctx)
+	if err != nil {
+		return err
+	}
+
+	defined := make(map[string]struct{}, len(e.rules))
+	for _, r := range e.rules {
+		defined[r.Name] = struct{}{}
+	}
+
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	errs := make([]error, 0)
+	for _, a := range alerts {
+		if _, ok := defined[a.Rule]; ok {
+			e.alerts[a.Rule] = a
+		} else {
+			errs = append(errs, e.store.DeleteAlert(ctx, a.Rule))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run evaluates rules every interval until ctx is done
+func (e *Engine) Run(ctx context.Context) {
+	if len(e.rules) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := e.Evaluate(ctx); err != nil {
+				logger.Errorw("evaluation of alerting rules failed", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Evaluate evaluates all rules once and saves alerts which changed state.
+// Rule which can not be evaluated keeps its state
+func (e *Engine) Evaluate(ctx context.Context) error {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	now := e.now()
+	errs := make([]error, 0)
+	for _, rule := range e.rules {
+		prev, exists := e.alerts[rule.Name]
+
+		active, value, err := e.evaluate(ctx, rule, prev, exists, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		next, act := transition(rule, prev, exists, active, value, now)
+		switch act {
+		case actionSave:
+			e.alerts[rule.Name] = next
+			errs = append(errs, e.save(ctx, next))
+			e.notify(prev, exists, next)
+		case actionDelete:
+			delete(e.alerts, rule.Name)
+			errs = append(errs, e.delete(ctx, rule.Name))
+		case actionUpdate:
+			e.alerts[rule.Name] = next
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Alerts returns current alerts ordered by name of rule
+func (e *Engine) Alerts(ctx context.Context) ([]alert.Alert, error) {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	rs := make([]alert.Alert, 0, len(e.alerts))
+	for _, a := range e.alerts {
+		rs = append(rs, a)
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Rule < rs[j].Rule })
+	return rs, nil
+}
+
+// evaluate returns true if condition of rule is met and value which is checked by rule
+func (e *Engine) evaluate(ctx context.Context, rule alert.Rule, prev alert.Alert, exists bool, now time.Time) (bool, float64, error) {
+	if rule.IsAbsence() {
+		return e.evaluateAbsence(ctx, rule, prev, exists, now)
+	}
+
+	m, err := e.metrics.Get(ctx, rule.Type, rule.Metric, rule.Labels)
+	if errors.Is(err, service.ErrMetricIsNotExist) {
+		return false, 0, nil
+	} else if err != nil {
+		return false, 0, err
+	}
+
+	v := m.Float64()
+	return rule.Match(v), v, nil
+}
+
+// evaluateAbsence checks time of the last update of series. Sources of series are not kept after restart,
+// so series which was not updated since start of engine is counted as updated at start
+// or at the moment when absence began if alert was active before restart
+func (e *Engine) evaluateAbsence(ctx context.Context, rule alert.Rule, prev alert.Alert, exists bool, now time.Time) (bool, float64, error) {
+	last := e.started
+	if exists && prev.State != alert.StateResolved {
+		last = prev.ActiveSince.Add(-rule.Absent)
+	}
+
+	src, err := e.metrics.Source(ctx, rule.Type, rule.Metric, rule.Labels)
+	if err == nil {
+		last = src.Time
+	} else if !errors.Is(err, service.ErrMetricIsNotExist) {
+		return false, 0, err
+	}
+
+	absent := now.Sub(last)
+	return absent >= rule.Absent, absent.Seconds(), nil
+}
+
+// notify sends alert to notifier if it fired or was resolved
+func (e *Engine) notify(prev alert.Alert, exists bool, next alert.Alert) {
+	if e.notifier == nil || next.State == alert.StatePending {
+		return
+	}
+
+	ev := alert.Event{Alert: next}
+	if exists {
+		ev.Previous = prev.State
+	}
+	e.notifier.Notify(ev)
+}
+
+func (e *Engine) save(ctx context.Context, a alert.Alert) error {
+	if e.store == nil {
+		return nil
+	}
+	return e.store.SaveAlert(ctx, a)
+}
+
+func (e *Engine) delete(ctx context.Context, rule string) error {
+	if e.store == nil {
+		return nil
+	}
+	return e.store.DeleteAlert(ctx, rule)
+}
+
+// action is what has to be done with alert after evaluation of rule
+type action int
+
+const (
+	// actionNone means that there is no alert
+	actionNone action = iota
+	// actionUpdate means that only value of alert is changed, it is not saved to storage
+	actionUpdate
+	// actionSave means that state of alert is changed
+	actionSave
+	// actionDelete means that pending alert is dropped
+	actionDelete
+)
+
+// transition returns next state of alert of rule
+func transition(rule alert.Rule, prev alert.Alert, exists, active bool, value float64, now time.Time) (alert.Alert, action) {
+	if !active {
+		if !exists || prev.State == alert.StateResolved {
+			return prev, actionNone
+		}
+		if prev.State == alert.StatePending {
+			return prev, actionDelete
+		}
+		prev.State, prev.ResolvedAt, prev.UpdatedAt = alert.StateResolved, now, now
+		return prev, actionSave
+	}
+
+	act := actionUpdate
+	if !exists || prev.State == alert.StateResolved {
+		prev = alert.Alert{
+			Rule:        rule.Name,
+			State:       alert.StatePending,
+			Summary:     rule.String(),
+			ActiveSince: now,
+		}
+		act = actionSave
+	}
+
+	if prev.State == alert.StatePending && now.Sub(prev.ActiveSince) >= rule.For {
+		prev.State, prev.FiredAt = alert.StateFiring, now
+		act = actionSave
+	}
+	prev.Value, prev.UpdatedAt = value, now
+	return prev, act
+}
@@ -0,0 +1,194 @@
+package alerting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/alert"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository/memory"
+	"github.com/vilasle/metrics/internal/service/server"
+)
+
+func TestEngine_EvaluateThreshold(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.NewMetricRepository()
+	svc := server.NewMetricService(storage)
+
+	rule := alert.Rule{Name: "heap", Type: metric.TypeGauge, Metric: "HeapAlloc", Op: alert.OpGreater, Threshold: 100, For: 5 * time.Minute}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	e := NewEngine(svc, storage, []alert.Rule{rule})
+	e.now = func() time.Time { return now }
+
+	step := func(offset time.Duration, value float64) []alert.Alert {
+		t.Helper()
+		now = start.Add(offset)
+		require.NoError(t, svc.Save(ctx, metric.NewGaugeMetric("HeapAlloc", value)))
+		require.NoError(t, e.Evaluate(ctx))
+
+		alerts, err := e.Alerts(ctx)
+		require.NoError(t, err)
+		stored, err := storage.Alerts(ctx)
+		require.NoError(t, err)
+		require.Equal(t, len(alerts), len(stored))
+		return alerts
+	}
+
+	assert.Empty(t, step(0, 50))
+
+	alerts := step(time.Minute, 150)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, alert.StatePending, alerts[0].State)
+	assert.Equal(t, "HeapAlloc > 100 for 5m0s", alerts[0].Summary)
+	assert.Equal(t, 150.0, alerts[0].Value)
+
+	// pending alert is dropped if condition is not met before it fires
+	assert.Empty(t, step(2*time.Minute, 50))
+
+	alerts = step(3*time.Minute, 150)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, alert.StatePending, alerts[0].State)
+	assert.Equal(t, start.Add(3*time.Minute), alerts[0].ActiveSince)
+
+	alerts = step(8*time.Minute, 200)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, alert.StateFiring, alerts[0].State)
+	assert.Equal(t, start.Add(8*time.Minute), alerts[0].FiredAt)
+	assert.Equal(t, 200.0, alerts[0].Value)
+
+	alerts = step(9*time.Minute, 50)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, alert.StateResolved, alerts[0].State)
+	assert.Equal(t, start.Add(9*time.Minute), alerts[0].ResolvedAt)
+
+	stored, err := storage.Alerts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, alerts, stored)
+
+	// resolved alert becomes pending again
+	alerts = step(10*time.Minute, 150)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, alert.StatePending, alerts[0].State)
+	assert.Equal(t, start.Add(10*time.Minute), alerts[0].ActiveSince)
+	assert.True(t, alerts[0].FiredAt.IsZero())
+}
+
+func TestEngine_EvaluateAbsence(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.NewMetricRepository()
+	svc := server.NewMetricService(storage)
+
+	rule := alert.Rule{Name: "poll", Type: metric.TypeCounter, Metric: "PollCount", Absent: 2 * time.Minute}
+
+	start := time.Now()
+	now := start
+	e := NewEngine(svc, storage, []alert.Rule{rule})
+	e.now = func() time.Time { return now }
+
+	evaluate := func(offset time.Duration) []alert.Alert {
+		t.Helper()
+		now = start.Add(offset)
+		require.NoError(t, e.Evaluate(ctx))
+		alerts, err := e.Alerts(ctx)
+		require.NoError(t, err)
+		return alerts
+	}
+
+	// series which was not reported since start is counted from start
+	assert.Empty(t, evaluate(time.Minute))
+
+	alerts := evaluate(3 * time.Minute)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, alert.StateFiring, alerts[0].State)
+	assert.Equal(t, "no PollCount update for 2m0s", alerts[0].Summary)
+	assert.InDelta(t, 180, alerts[0].Value, 1)
+
+	// series is reported by real time which is close to start
+	require.NoError(t, svc.Save(ctx, metric.NewCounterMetric("PollCount", 1)))
+
+	alerts = evaluate(90 * time.Second)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, alert.StateResolved, alerts[0].State)
+
+	// firing alert keeps firing after restart while series is not reported
+	restarted := NewEngine(server.NewMetricService(storage), storage, []alert.Rule{rule})
+	restarted.now = func() time.Time { return start.Add(10 * time.Minute) }
+	require.NoError(t, storage.SaveAlert(ctx, alert.Alert{Rule: "poll", State: alert.StateFiring, ActiveSince: start.Add(5 * time.Minute)}))
+	require.NoError(t, restarted.Restore(ctx))
+	require.NoError(t, restarted.Evaluate(ctx))
+
+	alerts, err := restarted.Alerts(ctx)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, alert.StateFiring, alerts[0].State)
+	assert.InDelta(t, 420, alerts[0].Value, 1)
+}
+
+func TestEngine_Restore(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.NewMetricRepository()
+
+	require.NoError(t, storage.SaveAlert(ctx, alert.Alert{Rule: "heap", State: alert.StateFiring}))
+	require.NoError(t, storage.SaveAlert(ctx, alert.Alert{Rule: "removed", State: alert.StateFiring}))
+
+	rule := alert.Rule{Name: "heap", Type: metric.TypeGauge, Metric: "HeapAlloc", Op: alert.OpGreater, Threshold: 100}
+	e := NewEngine(server.NewMetricService(storage), storage, []alert.Rule{rule})
+	require.NoError(t, e.Restore(ctx))
+
+	alerts, err := e.Alerts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []alert.Alert{{Rule: "heap", State: alert.StateFiring}}, alerts)
+
+	stored, err := storage.Alerts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, alerts, stored)
+}
+
+type notifierFunc func(alert.Event)
+
+func (f notifierFunc) Notify(ev alert.Event) { f(ev) }
+
+func TestEngine_Notify(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.NewMetricRepository()
+	svc := server.NewMetricService(storage)
+
+	rule := alert.Rule{Name: "heap", Type: metric.TypeGauge, Metric: "HeapAlloc", Op: alert.OpGreater, Threshold: 100, For: time.Minute}
+
+	events := make([]alert.Event, 0)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	e := NewEngine(svc, nil, []alert.Rule{rule}, WithNotifier(notifierFunc(func(ev alert.Event) {
+		events = append(events, ev)
+	})))
+	e.now = func() time.Time { return now }
+
+	step := func(offset time.Duration, value float64) {
+		t.Helper()
+		now = start.Add(offset)
+		require.NoError(t, svc.Save(ctx, metric.NewGaugeMetric("HeapAlloc", value)))
+		require.NoError(t, e.Evaluate(ctx))
+	}
+
+	// pending alert is not notified
+	step(0, 150)
+	assert.Empty(t, events)
+
+	step(time.Minute, 200)
+	step(2*time.Minute, 300)
+	require.Len(t, events, 1)
+	assert.Equal(t, alert.StateFiring, events[0].Alert.State)
+	assert.Equal(t, alert.StatePending, events[0].Previous)
+	assert.Equal(t, 200.0, events[0].Alert.Value)
+
+	step(3*time.Minute, 50)
+	step(4*time.Minute, 50)
+	require.Len(t, events, 2)
+	assert.Equal(t, alert.StateResolved, events[1].Alert.State)
+	assert.Equal(t, alert.StateFiring, events[1].Previous)
+}
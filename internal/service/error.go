@@ -8,3 +8,6 @@ var ErrEmptyName = errors.New("name is empty")
 var ErrEmptyKind = errors.New("type is empty")
 var ErrEmptyValue = errors.New("value is empty")
 var ErrUnknownKind = errors.New("unknown type of metric")
+var ErrUnknownAggregation = errors.New("unknown aggregation for type of metric")
+var ErrInvalidPeriod = errors.New("invalid period")
+var ErrHistoryIsNotStored = errors.New("history of metric is not stored")
@@ -0,0 +1,20 @@
+package service
+
+import "context"
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns copy of ctx which carries idempotency key of batch of metrics.
+// Batch which is sent again with the same key is not applied twice
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns idempotency key of batch from ctx or empty string if it is not set
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	return key
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository"
+	"github.com/vilasle/metrics/internal/service"
+)
+
+// History returns values of series of metric with query.Labels for period from query.From to query.To.
+// If query.Step is not 0, values are joined by query.Aggregation on each step,
+// when aggregation is empty values of types like gauge are joined by last value
+// and values of types like counter are summed
+func (s MetricService) History(ctx context.Context, query service.HistoryQuery) ([]metric.Point, error) {
+	if err := prepareHistoryQuery(&query); err != nil {
+		return nil, err
+	}
+
+	points, err := s.storage.History(ctx, query.Type, query.Name, query.Labels, query.From, query.To)
+	if errors.Is(err, repository.ErrHistoryDisabled) {
+		return nil, errors.Join(service.ErrHistoryIsNotStored, err)
+	} else if err != nil {
+		return nil, errors.Join(service.ErrStorage, err)
+	}
+
+	if query.Step == 0 {
+		return points, nil
+	}
+	return downsample(points, query), nil
+}
+
+func prepareHistoryQuery(query *service.HistoryQuery) error {
+	if query.Name == "" {
+		return service.ErrEmptyName
+	}
+	if query.To.Before(query.From) {
+		return service.ErrInvalidPeriod
+	}
+	if query.Step < 0 {
+		return service.ErrInvalidPeriod
+	}
+
+	if query.Type == "" {
+		return service.ErrEmptyKind
+	}
+
+	t, ok := metric.LookupType(query.Type)
+	if !ok {
+		return service.ErrUnknownKind
+	}
+
+	var supported []string
+	switch t.Aggregation {
+	case metric.AggregateLast:
+		supported = []string{service.AggregationLast, service.AggregationAvg, service.AggregationMin, service.AggregationMax}
+	case metric.AggregateSum:
+		supported = []string{service.AggregationSum, service.AggregationRate}
+	default:
+		return service.ErrUnknownKind
+	}
+
+	if query.Aggregation == "" {
+		if query.Step == 0 {
+			return nil
+		}
+		query.Aggregation = supported[0]
+	}
+
+	for _, a := range supported {
+		if a == query.Aggregation {
+			// aggregation without step is applied to whole period
+			if query.Step == 0 {
+				query.Step = max(query.To.Sub(query.From), 1)
+			}
+			return nil
+		}
+	}
+	return service.ErrUnknownAggregation
+}
+
+// downsample splits points on steps beginning from query.From and joins values of each step.
+// Steps without values are skipped, point of step has time of step's beginning
+func downsample(points []metric.Point, query service.HistoryQuery) []metric.Point {
+	rs := make([]metric.Point, 0)
+
+	for i := 0; i < len(points); {
+		bucket := points[i].Time.Sub(query.From) / query.Step
+		start := query.From.Add(bucket * query.Step)
+		end := start.Add(query.Step)
+
+		j := i
+		for j < len(points) && points[j].Time.Before(end) {
+			j++
+		}
+
+		rs = append(rs, metric.Point{Time: start, Value: aggregate(points[i:j], query)})
+		i = j
+	}
+	return rs
+}
+
+func aggregate(points []metric.Point, query service.HistoryQuery) float64 {
+	switch query.Aggregation {
+	case service.AggregationAvg:
+		return sum(points) / float64(len(points))
+	case service.AggregationMin:
+		v := math.Inf(1)
+		for _, p := range points {
+			v = math.Min(v, p.Value)
+		}
+		return v
+	case service.AggregationMax:
+		v := math.Inf(-1)
+		for _, p := range points {
+			v = math.Max(v, p.Value)
+		}
+		return v
+	case service.AggregationSum:
+		return sum(points)
+	case service.AggregationRate:
+		return sum(points) / query.Step.Seconds()
+	default:
+		return points[len(points)-1].Value
+	}
+}
+
+func sum(points []metric.Point) float64 {
+	var v float64
+	for _, p := range points {
+		v += p.Value
+	}
+	return v
+}
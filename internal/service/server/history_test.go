@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository/memory"
+	"github.com/vilasle/metrics/internal/service"
+)
+
+func Test_downsample(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time {
+		return from.Add(time.Duration(sec) * time.Second)
+	}
+
+	points := []metric.Point{
+		{Time: at(0), Value: 1},
+		{Time: at(30), Value: 5},
+		{Time: at(59), Value: 3},
+		{Time: at(150), Value: 4},
+	}
+
+	testCases := []struct {
+		aggregation string
+		want        []float64
+	}{
+		{aggregation: service.AggregationAvg, want: []float64{3, 4}},
+		{aggregation: service.AggregationMin, want: []float64{1, 4}},
+		{aggregation: service.AggregationMax, want: []float64{5, 4}},
+		{aggregation: service.AggregationLast, want: []float64{3, 4}},
+		{aggregation: service.AggregationSum, want: []float64{9, 4}},
+		{aggregation: service.AggregationRate, want: []float64{0.15, 4.0 / 60}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.aggregation, func(t *testing.T) {
+			got := downsample(points, service.HistoryQuery{
+				From:        from,
+				Step:        time.Minute,
+				Aggregation: tt.aggregation,
+			})
+
+			require.Len(t, got, len(tt.want))
+			// the empty step between 60 and 120 seconds is skipped
+			assert.Equal(t, at(0), got[0].Time)
+			assert.Equal(t, at(120), got[1].Time)
+			for i := range tt.want {
+				assert.InDelta(t, tt.want[i], got[i].Value, 1e-9)
+			}
+		})
+	}
+}
+
+func TestMetricService_History(t *testing.T) {
+	svc := NewMetricService(memory.NewMetricRepository())
+	ctx := context.Background()
+
+	from := time.Now()
+	require.NoError(t, svc.Save(ctx,
+		metric.NewCounterMetric("counter1", 2),
+		metric.NewCounterMetric("counter1", 3),
+		metric.NewGaugeMetric("gauge1", 1),
+	))
+	to := time.Now()
+
+	testCases := []struct {
+		name    string
+		query   service.HistoryQuery
+		want    []float64
+		wantErr error
+	}{
+		{
+			name:  "raw values",
+			query: service.HistoryQuery{Type: metric.TypeCounter, Name: "counter1", From: from, To: to},
+			want:  []float64{2, 3},
+		},
+		{
+			name:  "default aggregation of counter on whole period",
+			query: service.HistoryQuery{Type: metric.TypeCounter, Name: "counter1", From: from, To: to, Step: time.Hour},
+			want:  []float64{5},
+		},
+		{
+			name:  "aggregation without step",
+			query: service.HistoryQuery{Type: metric.TypeGauge, Name: "gauge1", From: from, To: to, Aggregation: service.AggregationMax},
+			want:  []float64{1},
+		},
+		{
+			name:    "gauge aggregation for counter",
+			query:   service.HistoryQuery{Type: metric.TypeCounter, Name: "counter1", From: from, To: to, Aggregation: service.AggregationAvg},
+			wantErr: service.ErrUnknownAggregation,
+		},
+		{
+			name:    "invalid period",
+			query:   service.HistoryQuery{Type: metric.TypeCounter, Name: "counter1", From: to, To: from.Add(-time.Second)},
+			wantErr: service.ErrInvalidPeriod,
+		},
+		{
+			name:    "unknown type",
+			query:   service.HistoryQuery{Type: "unknown", Name: "counter1", From: from, To: to},
+			wantErr: service.ErrUnknownKind,
+		},
+		{
+			name:    "empty name",
+			query:   service.HistoryQuery{Type: metric.TypeGauge, From: from, To: to},
+			wantErr: service.ErrEmptyName,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.History(ctx, tt.query)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			values := make([]float64, 0, len(got))
+			for _, p := range got {
+				values = append(values, p.Value)
+			}
+			assert.Equal(t, tt.want, values)
+		})
+	}
+}
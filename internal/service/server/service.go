@@ -11,41 +11,123 @@ import (
 	"github.com/vilasle/metrics/internal/service"
 )
 
+// defaultIdempotencyWindow is the period while idempotency keys of applied batches are remembered by default
+const defaultIdempotencyWindow = time.Hour
+
 // MetricService way for work with metrics. Connects storage with handlers
 type MetricService struct {
 	storage repository.MetricRepository
+	sources *sourceRegistry
+	keys    repository.IdempotencyRepository
+	window  time.Duration
+	maxAge  time.Duration
+}
+
+// Option is the setting of MetricService
+type Option func(*MetricService)
+
+// WithIdempotencyWindow sets the period while idempotency keys of applied batches are remembered
+func WithIdempotencyWindow(window time.Duration) Option {
+	return func(s *MetricService) {
+		if window > 0 {
+			s.window = window
+		}
+	}
+}
+
+// WithMaxSampleAge sets the age after which samples are dropped on saving
+func WithMaxSampleAge(age time.Duration) Option {
+	return func(s *MetricService) {
+		if age > 0 {
+			s.maxAge = age
+		}
+	}
 }
 
-// NewMetricService returns new instance of MetricService
-func NewMetricService(storage repository.MetricRepository) *MetricService {
-	return &MetricService{storage: storage}
+// NewMetricService returns new instance of MetricService.
+// Batches with idempotency keys are applied once if storage keeps idempotency keys
+func NewMetricService(storage repository.MetricRepository, opts ...Option) *MetricService {
+	s := &MetricService{
+		storage: storage,
+		sources: newSourceRegistry(),
+		window:  defaultIdempotencyWindow,
+		maxAge:  defaultMaxSampleAge,
+	}
+	s.keys, _ = storage.(repository.IdempotencyRepository)
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// Save saves metrics to storage
+// Save saves metrics to storage and remembers agent from ctx as their source.
+// Samples older than max sample age are dropped, storages keep the newest sample as the last value of series,
+// so late samples do not override it.
+// If ctx carries idempotency key and batch with the same key of the same agent was already saved,
+// metrics are not saved again and nil is returned
 func (s MetricService) Save(ctx context.Context, entity ...metric.Metric) error {
-	if err := s.storage.Save(ctx, entity...); err != nil {
+	if entity = checkSamples(time.Now(), s.maxAge, entity); len(entity) == 0 {
+		return nil
+	}
+
+	key := service.IdempotencyKeyFromContext(ctx)
+	if key == "" || s.keys == nil {
+		if err := s.storage.Save(ctx, entity...); err != nil {
+			return errors.Join(service.ErrStorage, err)
+		}
+		s.sources.record(service.AgentFromContext(ctx), entity...)
+		return nil
+	}
+
+	// keys are generated by agents, so they are unique only inside agent.
+	// Key is remembered together with saving of batch, so retry which comes while batch is saved
+	// is not acknowledged before batch is really saved
+	key = service.AgentFromContext(ctx) + "/" + key
+	saved, err := s.keys.SaveOnce(ctx, key, time.Now().Add(s.window), entity...)
+	if err != nil {
 		return errors.Join(service.ErrStorage, err)
 	}
+	if !saved {
+		logger.Debugw("batch is already saved", "key", key)
+		return nil
+	}
+	s.sources.record(service.AgentFromContext(ctx), entity...)
 	return nil
 }
 
-// Get returns metric by type and name
-// if metricType is gauge, returns last metric
-// if metricType is counter, returns summed counter 
-func (s MetricService) Get(ctx context.Context, metricType, name string) (metric.Metric, error) {
+// Get returns metric by type and name from series which labels match filter.
+// Matched series are joined by aggregation of type:
+// for types like gauge returns last metric of series with labels equal to filter
+// or of the first matched series in order of labels if there is no such series,
+// for types like counter returns metric summed by all matched series with labels of filter,
+// for types like histogram returns the only matched series or metric merged by all matched series
+// with labels of filter, e.g. matched histograms must have the same buckets
+func (s MetricService) Get(ctx context.Context, metricType, name string, filter metric.Labels) (metric.Metric, error) {
 	metrics, err := s.storage.Get(ctx, metricType, name)
 	if err != nil {
 		return nil, errors.Join(service.ErrStorage, err)
 	}
+
+	t, ok := metric.LookupType(metricType)
+	if !ok {
+		return nil, errors.Join(service.ErrStorage, metric.ErrUnknownMetricType)
+	}
+
+	metrics = filterByLabels(metrics, filter)
 	if len(metrics) == 0 {
 		return nil, service.ErrMetricIsNotExist
 	}
 
-	if metricType == metric.TypeGauge {
-		return metrics[0], nil
+	switch t.Aggregation {
+	case metric.AggregateLast:
+		return chooseLast(metrics, filter), nil
+	case metric.AggregateMerge:
+		return mergeSeries(name, metrics, filter, t.Merge)
 	}
 
-	if m, err := metric.CreateSummedCounter(name, metrics); err == nil {
+	if m, err := t.Merge(name, metrics); err == nil {
+		m.SetLabels(filter)
 		return m, nil
 	} else {
 		return nil, errors.Join(service.ErrStorage, err)
@@ -53,30 +135,39 @@ func (s MetricService) Get(ctx context.Context, metricType, name string) (metric
 }
 
 // All returns all metrics from storage
-// if metricType is gauge, returns last metric
-// if metricType is counter, returns summed counter
+// for types like gauge returns last metric of each series,
+// for types like counter returns summed metric of each series,
+// for types like histogram or summary returns merged observations of each series
 func (s MetricService) All(ctx context.Context) ([]metric.Metric, error) {
-	allGauges, allCounters, err := s.all(ctx)
+	byType, err := s.all(ctx)
 	if err != nil {
 		return nil, errors.Join(service.ErrStorage, err)
 	}
 
-	rs := make([]metric.Metric, 0, len(allGauges)+len(allCounters))
-	rs = append(rs, allGauges...)
+	rs := make([]metric.Metric, 0)
+	for _, metrics := range byType {
+		if metrics.info.Aggregation != metric.AggregateSum {
+			rs = append(rs, metrics.values...)
+			continue
+		}
 
-	counters := make(map[string][]metric.Metric)
-	for _, m := range allCounters {
-		if _, ok := counters[m.Name()]; !ok {
-			counters[m.Name()] = make([]metric.Metric, 0)
+		order := make([]string, 0)
+		series := make(map[string][]metric.Metric)
+		for _, m := range metrics.values {
+			id := metric.SeriesID(m.Name(), m.Labels())
+			if _, ok := series[id]; !ok {
+				order = append(order, id)
+			}
+			series[id] = append(series[id], m)
 		}
-		counters[m.Name()] = append(counters[m.Name()], m)
-	}
 
-	for name, metrics := range counters {
-		if v, err := metric.CreateSummedCounter(name, metrics); err == nil {
-			rs = append(rs, v)
-		} else {
-			return nil, errors.Join(service.ErrStorage, err)
+		for _, id := range order {
+			values := series[id]
+			if v, err := metrics.info.Merge(values[0].Name(), values); err == nil {
+				rs = append(rs, v)
+			} else {
+				return nil, errors.Join(service.ErrStorage, err)
+			}
 		}
 	}
 	return rs, nil
@@ -84,15 +175,15 @@ func (s MetricService) All(ctx context.Context) ([]metric.Metric, error) {
 
 // Stats returns all metrics from storage as is, without post-processing
 func (s MetricService) Stats(ctx context.Context) ([]metric.Metric, error) {
-	allGauges, allCounters, err := s.all(ctx)
+	byType, err := s.all(ctx)
 	if err != nil {
 		return nil, errors.Join(service.ErrStorage, err)
 	}
 
-	rs := make([]metric.Metric, 0, len(allGauges)+len(allCounters))
-	rs = append(rs, allGauges...)
-	rs = append(rs, allCounters...)
-
+	rs := make([]metric.Metric, 0)
+	for _, metrics := range byType {
+		rs = append(rs, metrics.values...)
+	}
 	return rs, nil
 }
 
@@ -112,16 +203,69 @@ func (s MetricService) Close() {
 	s.storage.Close()
 }
 
-func (s MetricService) all(ctx context.Context) (gauges, counters []metric.Metric, err error) {
-	allGauges, err := s.storage.Get(ctx, metric.TypeGauge)
-	if err != nil {
-		return nil, nil, errors.Join(service.ErrStorage, err)
+// typedMetrics is metrics of one type as they are returned by storage
+type typedMetrics struct {
+	info   metric.TypeInfo
+	values []metric.Metric
+}
+
+// all returns metrics of all registered types in order of registration
+func (s MetricService) all(ctx context.Context) ([]typedMetrics, error) {
+	types := metric.Types()
+	rs := make([]typedMetrics, 0, len(types))
+	for _, name := range types {
+		t, ok := metric.LookupType(name)
+		if !ok {
+			continue
+		}
+
+		values, err := s.storage.Get(ctx, name)
+		if err != nil {
+			return nil, errors.Join(service.ErrStorage, err)
+		}
+		rs = append(rs, typedMetrics{info: t, values: values})
 	}
+	return rs, nil
+}
 
-	allCounters, err := s.storage.Get(ctx, metric.TypeCounter)
-	if err != nil {
-		return nil, nil, errors.Join(service.ErrStorage, err)
+func filterByLabels(metrics []metric.Metric, filter metric.Labels) []metric.Metric {
+	if len(filter) == 0 {
+		return metrics
+	}
+
+	rs := make([]metric.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		if m.Labels().Match(filter) {
+			rs = append(rs, m)
+		}
 	}
+	return rs
+}
 
-	return allGauges, allCounters, nil
+// chooseLast returns metric of series with labels equal to filter or of the first series in order of labels
+func chooseLast(metrics []metric.Metric, filter metric.Labels) metric.Metric {
+	rs := metrics[0]
+	for _, m := range metrics {
+		if m.Labels().Equal(filter) {
+			return m
+		}
+		if m.Labels().String() < rs.Labels().String() {
+			rs = m
+		}
+	}
+	return rs
+}
+
+// mergeSeries joins series which are matched by filter, e.g. histograms or summaries
+func mergeSeries(name string, metrics []metric.Metric, filter metric.Labels, merge func(string, []metric.Metric) (metric.Metric, error)) (metric.Metric, error) {
+	if len(metrics) == 1 {
+		return metrics[0], nil
+	}
+
+	m, err := merge(name, metrics)
+	if err != nil {
+		return nil, err
+	}
+	m.SetLabels(filter)
+	return m, nil
 }
@@ -2,16 +2,19 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand/v2"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vilasle/metrics/internal/metric"
 	"github.com/vilasle/metrics/internal/repository"
 	"github.com/vilasle/metrics/internal/repository/memory"
+	"github.com/vilasle/metrics/internal/service"
 )
 
 type wrongMetric struct{}
@@ -47,6 +50,18 @@ func (m wrongMetric) Int64() int64 {
 	return 0
 }
 
+func (m wrongMetric) Labels() metric.Labels {
+	return nil
+}
+
+func (m wrongMetric) SetLabels(metric.Labels) {}
+
+func (m wrongMetric) Timestamp() time.Time {
+	return time.Time{}
+}
+
+func (m wrongMetric) SetTimestamp(time.Time) {}
+
 func TestMetricService_Save(t *testing.T) {
 	type fields struct {
 		storage repository.MetricRepository
@@ -194,7 +209,7 @@ func TestMetricService_Get(t *testing.T) {
 				require.NoError(t, err)
 			}
 
-			got, err := s.Get(context.TODO(), tt.args.metricType, tt.args.name)
+			got, err := s.Get(context.TODO(), tt.args.metricType, tt.args.name, nil)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -239,14 +254,14 @@ func BenchmarkMetricService_Get(b *testing.B) {
 	b.Run("getting gauge", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
 			name := fmt.Sprintf("gauge%d", i)
-			service.Get(ctx, metric.TypeGauge, name)
+			service.Get(ctx, metric.TypeGauge, name, nil)
 		}
 	})
 
 	b.Run("getting counter", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
 			name := fmt.Sprintf("counter%d", i)
-			service.Get(ctx, metric.TypeCounter, name)
+			service.Get(ctx, metric.TypeCounter, name, nil)
 		}
 	})
 }
@@ -391,3 +406,261 @@ func TestMetricService_Stats(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricService_GetWithLabels(t *testing.T) {
+	ctx := context.Background()
+	s := NewMetricService(memory.NewMetricRepository())
+
+	require.NoError(t, s.Save(ctx,
+		metric.WithLabels(metric.NewGaugeMetric("cpu", 10), metric.Labels{"host": "b"}),
+		metric.WithLabels(metric.NewGaugeMetric("cpu", 20), metric.Labels{"host": "a"}),
+		metric.WithLabels(metric.NewGaugeMetric("cpu", 30), metric.Labels{"host": "a", "core": "1"}),
+		metric.WithLabels(metric.NewCounterMetric("requests", 1), metric.Labels{"host": "a"}),
+		metric.WithLabels(metric.NewCounterMetric("requests", 2), metric.Labels{"host": "a"}),
+		metric.WithLabels(metric.NewCounterMetric("requests", 5), metric.Labels{"host": "b"}),
+	))
+
+	testCases := []struct {
+		name       string
+		metricType string
+		metricName string
+		filter     metric.Labels
+		want       string
+		wantErr    error
+	}{
+		{name: "gauge with equal labels", metricType: metric.TypeGauge, metricName: "cpu", filter: metric.Labels{"host": "a"}, want: "20"},
+		{name: "gauge with matched labels", metricType: metric.TypeGauge, metricName: "cpu", filter: metric.Labels{"core": "1"}, want: "30"},
+		{name: "gauge without filter", metricType: metric.TypeGauge, metricName: "cpu", want: "30"},
+		{name: "counter of series", metricType: metric.TypeCounter, metricName: "requests", filter: metric.Labels{"host": "a"}, want: "3"},
+		{name: "counter of all series", metricType: metric.TypeCounter, metricName: "requests", want: "8"},
+		{name: "not matched", metricType: metric.TypeGauge, metricName: "cpu", filter: metric.Labels{"host": "c"}, wantErr: service.ErrMetricIsNotExist},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Get(ctx, tt.metricType, tt.metricName, tt.filter)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Value())
+		})
+	}
+
+	all, err := s.All(ctx)
+	require.NoError(t, err)
+
+	series := make(map[string]string, len(all))
+	for _, m := range all {
+		series[metric.SeriesID(m.Name(), m.Labels())] = m.Value()
+	}
+	assert.Equal(t, map[string]string{
+		`cpu{host="a"}`:          "20",
+		`cpu{host="b"}`:          "10",
+		`cpu{core="1",host="a"}`: "30",
+		`requests{host="a"}`:     "3",
+		`requests{host="b"}`:     "5",
+	}, series)
+}
+
+func TestMetricService_GetHistogram(t *testing.T) {
+	ctx := context.Background()
+	s := NewMetricService(memory.NewMetricRepository())
+
+	newHistogram := func(counts []int64, sum float64, labels metric.Labels) metric.Metric {
+		h, err := metric.NewHistogramMetricFromData("latency", metric.HistogramData{Bounds: []float64{1}, Counts: counts, Sum: sum})
+		require.NoError(t, err)
+		return metric.WithLabels(h, labels)
+	}
+
+	require.NoError(t, s.Save(ctx,
+		newHistogram([]int64{1, 0}, 0.5, metric.Labels{"host": "a"}),
+		newHistogram([]int64{1, 2}, 6, metric.Labels{"host": "a"}),
+		newHistogram([]int64{0, 1}, 2, metric.Labels{"host": "b"}),
+	))
+
+	got, err := s.Get(ctx, metric.TypeHistogram, "latency", metric.Labels{"host": "a"})
+	require.NoError(t, err)
+	assert.Equal(t, "1:2,+Inf:2,sum:6.5", got.Value())
+	assert.Equal(t, metric.Labels{"host": "a"}, got.Labels())
+
+	got, err = s.Get(ctx, metric.TypeHistogram, "latency", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1:2,+Inf:3,sum:8.5", got.Value())
+	assert.Empty(t, got.Labels())
+
+	all, err := s.All(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestMetricService_GetSummary(t *testing.T) {
+	ctx := context.Background()
+	s := NewMetricService(memory.NewMetricRepository())
+
+	require.NoError(t, s.Save(ctx,
+		metric.WithLabels(metric.NewSummaryMetric("PauseNs", 1, 2), metric.Labels{"host": "a"}),
+		metric.WithLabels(metric.NewSummaryMetric("PauseNs", 3), metric.Labels{"host": "a"}),
+		metric.WithLabels(metric.NewSummaryMetric("PauseNs", 100), metric.Labels{"host": "b"}),
+	))
+
+	got, err := s.Get(ctx, metric.TypeSummary, "PauseNs", metric.Labels{"host": "a"})
+	require.NoError(t, err)
+	assert.Equal(t, metric.NewSummaryMetric("PauseNs", 1, 2, 3).Value(), got.Value())
+
+	got, err = s.Get(ctx, metric.TypeSummary, "PauseNs", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), got.Int64())
+	assert.InEpsilon(t, 100, got.(metric.Summary).Quantile(1), metric.SummaryRelativeAccuracy)
+	assert.Empty(t, got.Labels())
+
+	stats, err := s.Stats(ctx)
+	require.NoError(t, err)
+	assert.Len(t, stats, 2)
+}
+
+// rate is the custom type for tests, it is the gauge which is summed by series
+type rate struct {
+	metric.Metric
+}
+
+func (r rate) Type() string {
+	return "rate"
+}
+
+func newRate(name string, value float64, labels metric.Labels) metric.Metric {
+	return rate{metric.WithLabels(metric.NewGaugeMetric(name, value), labels)}
+}
+
+func registerRate(t *testing.T) {
+	err := metric.RegisterType(metric.TypeInfo{
+		Name:        "rate",
+		Code:        "r",
+		Aggregation: metric.AggregateSum,
+		Parse: func(name, value string) (metric.Metric, error) {
+			m, err := metric.ParseMetric(name, value, metric.TypeGauge)
+			if err != nil {
+				return nil, err
+			}
+			return rate{m}, nil
+		},
+		DecodeJSON: func(content []byte) (metric.Metric, error) {
+			return nil, metric.ErrInvalidMetric
+		},
+		Merge: func(name string, metrics []metric.Metric) (metric.Metric, error) {
+			var sum float64
+			for _, m := range metrics {
+				sum += m.Float64()
+			}
+			return newRate(name, sum, metrics[0].Labels().Clone()), nil
+		},
+	})
+	if err != nil && !errors.Is(err, metric.ErrTypeIsRegistered) {
+		t.Fatal(err)
+	}
+}
+
+func TestMetricService_RegisteredType(t *testing.T) {
+	registerRate(t)
+
+	ctx := context.Background()
+	svc := NewMetricService(memory.NewMetricRepository())
+
+	require.NoError(t, svc.Save(ctx,
+		newRate("requests", 1.5, metric.Labels{"host": "a"}),
+		newRate("requests", 2, metric.Labels{"host": "a"}),
+		newRate("requests", 3, metric.Labels{"host": "b"}),
+	))
+
+	got, err := svc.Get(ctx, "rate", "requests", metric.Labels{"host": "a"})
+	require.NoError(t, err)
+	assert.Equal(t, "rate", got.Type())
+	assert.Equal(t, 3.5, got.Float64())
+
+	got, err = svc.Get(ctx, "rate", "requests", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 6.5, got.Float64())
+
+	all, err := svc.All(ctx)
+	require.NoError(t, err)
+	values := make(map[string]float64)
+	for _, m := range all {
+		values[m.Labels()["host"]] = m.Float64()
+	}
+	assert.Equal(t, map[string]float64{"a": 3.5, "b": 3}, values)
+
+	// history of summed types supports the same aggregations as counter
+	_, err = svc.History(ctx, service.HistoryQuery{Type: "rate", Name: "requests", Aggregation: service.AggregationAvg, To: time.Now()})
+	assert.ErrorIs(t, err, service.ErrUnknownAggregation)
+}
+
+func TestMetricService_SaveIdempotent(t *testing.T) {
+	storage := memory.NewMetricRepository()
+	svc := NewMetricService(storage, WithIdempotencyWindow(time.Minute))
+
+	ctx := service.WithIdempotencyKey(service.WithAgent(context.Background(), "host/1"), "batch-1")
+	require.NoError(t, svc.Save(ctx, metric.NewCounterMetric("PollCount", 5)))
+	// replayed batch is acknowledged but is not applied
+	require.NoError(t, svc.Save(ctx, metric.NewCounterMetric("PollCount", 5)))
+
+	// keys of different agents do not conflict
+	other := service.WithIdempotencyKey(service.WithAgent(context.Background(), "host/2"), "batch-1")
+	require.NoError(t, svc.Save(other, metric.NewCounterMetric("PollCount", 5)))
+
+	// batch without key is applied each time
+	require.NoError(t, svc.Save(context.Background(), metric.NewCounterMetric("PollCount", 1)))
+
+	m, err := svc.Get(context.Background(), metric.TypeCounter, "PollCount", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "11", m.Value())
+
+	// batch which is not saved can be sent again with the same key
+	failed := service.WithIdempotencyKey(ctx, "batch-2")
+	require.Error(t, svc.Save(failed, wrongMetric{}))
+	require.NoError(t, svc.Save(failed, metric.NewCounterMetric("PollCount", 2)))
+
+	m, err = svc.Get(context.Background(), metric.TypeCounter, "PollCount", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "13", m.Value())
+}
+
+func TestMetricService_SaveTimestamps(t *testing.T) {
+	storage := memory.NewMetricRepository()
+	svc := NewMetricService(storage, WithMaxSampleAge(time.Hour))
+	ctx := context.Background()
+
+	stamped := func(m metric.Metric, ts time.Time) metric.Metric {
+		m.SetTimestamp(ts)
+		return m
+	}
+
+	now := time.Now()
+	require.NoError(t, svc.Save(ctx,
+		stamped(metric.NewGaugeMetric("Alloc", 2), now.Add(-time.Minute)),
+		// late sample does not replace the newer one
+		stamped(metric.NewGaugeMetric("Alloc", 1), now.Add(-2*time.Minute)),
+		// too old samples are dropped
+		stamped(metric.NewCounterMetric("PollCount", 5), now.Add(-2*time.Hour)),
+		metric.NewCounterMetric("PollCount", 1),
+	))
+
+	m, err := svc.Get(ctx, metric.TypeGauge, "Alloc", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "2", m.Value())
+
+	m, err = svc.Get(ctx, metric.TypeCounter, "PollCount", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1", m.Value())
+
+	// samples from the future are stamped by time of server
+	future := stamped(metric.NewGaugeMetric("Alloc", 3), now.Add(time.Hour))
+	require.NoError(t, svc.Save(ctx, future))
+	assert.False(t, future.Timestamp().After(time.Now()))
+
+	// batch of dropped samples is acknowledged
+	require.NoError(t, svc.Save(ctx, stamped(metric.NewGaugeMetric("Alloc", 4), now.Add(-2*time.Hour))))
+	m, err = svc.Get(ctx, metric.TypeGauge, "Alloc", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "3", m.Value())
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/service"
+)
+
+// Source returns agent which reported series of metric with labels last time.
+// Sources are kept in memory and are lost after restart of server
+func (s MetricService) Source(ctx context.Context, metricType, name string, labels metric.Labels) (service.Source, error) {
+	if src, ok := s.sources.get(metricType, metric.SeriesID(name, labels)); ok {
+		return src, nil
+	}
+	return service.Source{}, service.ErrMetricIsNotExist
+}
+
+// Agents returns agents which reported metrics sorted by time of the last report
+func (s MetricService) Agents(ctx context.Context) ([]service.AgentInfo, error) {
+	return s.sources.agents(), nil
+}
+
+// sourceRegistry keeps the last source of each series of metrics and the last report of each agent
+type sourceRegistry struct {
+	mx       sync.RWMutex
+	series   map[string]service.Source
+	lastSeen map[string]time.Time
+	now      func() time.Time
+}
+
+func newSourceRegistry() *sourceRegistry {
+	return &sourceRegistry{
+		series:   make(map[string]service.Source),
+		lastSeen: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// record remembers agent as source of metrics. Metrics without agent are recorded with empty agent,
+// so source of series never points to agent which does not report it anymore
+func (r *sourceRegistry) record(agent string, metrics ...metric.Metric) {
+	if r == nil {
+		return
+	}
+
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	src := service.Source{Agent: agent, Time: r.now()}
+	for _, m := range metrics {
+		r.series[sourceKey(m.Type(), metric.SeriesID(m.Name(), m.Labels()))] = src
+	}
+	if agent != "" {
+		r.lastSeen[agent] = src.Time
+	}
+}
+
+func (r *sourceRegistry) get(metricType, seriesID string) (service.Source, bool) {
+	if r == nil {
+		return service.Source{}, false
+	}
+
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+
+	src, ok := r.series[sourceKey(metricType, seriesID)]
+	return src, ok
+}
+
+func (r *sourceRegistry) agents() []service.AgentInfo {
+	if r == nil {
+		return []service.AgentInfo{}
+	}
+
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+
+	counts := make(map[string]int, len(r.lastSeen))
+	for _, src := range r.series {
+		counts[src.Agent]++
+	}
+
+	rs := make([]service.AgentInfo, 0, len(r.lastSeen))
+	for id, t := range r.lastSeen {
+		rs = append(rs, service.AgentInfo{ID: id, LastSeen: t, Metrics: counts[id]})
+	}
+
+	sort.Slice(rs, func(i, j int) bool {
+		if !rs[i].LastSeen.Equal(rs[j].LastSeen) {
+			return rs[i].LastSeen.Before(rs[j].LastSeen)
+		}
+		return rs[i].ID < rs[j].ID
+	})
+	return rs
+}
+
+func sourceKey(metricType, seriesID string) string {
+	return metricType + "/" + seriesID
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository/memory"
+	"github.com/vilasle/metrics/internal/service"
+)
+
+func TestMetricService_Source(t *testing.T) {
+	s := NewMetricService(memory.NewMetricRepository())
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.sources.now = func() time.Time { return now }
+
+	ctxA := service.WithAgent(context.Background(), "host-a/1")
+	ctxB := service.WithAgent(context.Background(), "host-b/2")
+
+	require.NoError(t, s.Save(ctxA,
+		metric.NewGaugeMetric("cpu", 1),
+		metric.WithLabels(metric.NewGaugeMetric("cpu", 1), metric.Labels{"core": "1"}),
+		metric.NewCounterMetric("PollCount", 1),
+	))
+
+	now = now.Add(time.Minute)
+	require.NoError(t, s.Save(ctxB, metric.NewGaugeMetric("cpu", 2)))
+
+	src, err := s.Source(ctxA, metric.TypeGauge, "cpu", nil)
+	require.NoError(t, err)
+	assert.Equal(t, service.Source{Agent: "host-b/2", Time: now}, src)
+
+	src, err = s.Source(ctxA, metric.TypeGauge, "cpu", metric.Labels{"core": "1"})
+	require.NoError(t, err)
+	assert.Equal(t, service.Source{Agent: "host-a/1", Time: now.Add(-time.Minute)}, src)
+
+	_, err = s.Source(ctxA, metric.TypeCounter, "cpu", nil)
+	assert.ErrorIs(t, err, service.ErrMetricIsNotExist)
+
+	agents, err := s.Agents(ctxA)
+	require.NoError(t, err)
+	assert.Equal(t, []service.AgentInfo{
+		{ID: "host-a/1", LastSeen: now.Add(-time.Minute), Metrics: 2},
+		{ID: "host-b/2", LastSeen: now, Metrics: 1},
+	}, agents)
+
+	// metric without agent is not attributed to the last agent anymore
+	require.NoError(t, s.Save(context.Background(), metric.NewCounterMetric("PollCount", 1)))
+
+	src, err = s.Source(ctxA, metric.TypeCounter, "PollCount", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", src.Agent)
+
+	agents, err = s.Agents(ctxA)
+	require.NoError(t, err)
+	assert.Equal(t, 1, agents[0].Metrics)
+}
+
+func TestMetricService_SourceIsNotRecordedOnError(t *testing.T) {
+	s := NewMetricService(memory.NewMetricRepository())
+
+	ctx := service.WithAgent(context.Background(), "host-a/1")
+	require.Error(t, s.Save(ctx, wrongMetric{}))
+
+	agents, err := s.Agents(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, agents)
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"time"
+
+	"github.com/vilasle/metrics/internal/logger"
+	"github.com/vilasle/metrics/internal/metric"
+)
+
+const (
+	// defaultMaxSampleAge is the age after which samples are dropped by default
+	defaultMaxSampleAge = 24 * time.Hour
+
+	// maxClockSkew is the allowed difference between clocks of agent and server,
+	// samples from the further future are stamped by time of server
+	maxClockSkew = time.Minute
+)
+
+// checkSamples stamps samples from the future by time of receiving and drops samples which are older than maxAge.
+// Metrics without timestamp are kept as is, storages treat them as received now
+func checkSamples(now time.Time, maxAge time.Duration, metrics []metric.Metric) []metric.Metric {
+	rs := metrics[:0:0]
+	for _, m := range metrics {
+		ts := m.Timestamp()
+		switch {
+		case ts.IsZero():
+		case ts.After(now.Add(maxClockSkew)):
+			logger.Warnw("sample is from the future, time of server is used", "metric", m.Name(), "timestamp", ts)
+			m.SetTimestamp(now)
+		case ts.Before(now.Add(-maxAge)):
+			logger.Warnw("sample is too old, it is dropped", "metric", m.Name(), "timestamp", ts)
+			continue
+		}
+		rs = append(rs, m)
+	}
+	return rs
+}
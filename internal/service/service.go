@@ -2,32 +2,68 @@ package service
 
 import (
 	"context"
+	"time"
 
+	"github.com/vilasle/metrics/internal/alert"
 	"github.com/vilasle/metrics/internal/metric"
 )
 
 // MetricService is the interface that group methods for work with metrics
 type MetricService interface {
 	Save(context.Context, ...metric.Metric) error
-	Get(ctx context.Context, metricType, name string) (metric.Metric, error)
+	Get(ctx context.Context, metricType, name string, filter metric.Labels) (metric.Metric, error)
 	All(context.Context) ([]metric.Metric, error)
 	Stats(context.Context) ([]metric.Metric, error)
+	History(ctx context.Context, query HistoryQuery) ([]metric.Point, error)
+	Source(ctx context.Context, metricType, name string, labels metric.Labels) (Source, error)
+	Agents(context.Context) ([]AgentInfo, error)
 	Ping(context.Context) error
 	Close()
 }
 
+// AlertService is the interface that group methods for work with alerts
+type AlertService interface {
+	Alerts(context.Context) ([]alert.Alert, error)
+}
+
+// Aggregations of values of metric on each step of history
+const (
+	AggregationAvg  = "avg"
+	AggregationMin  = "min"
+	AggregationMax  = "max"
+	AggregationLast = "last"
+	AggregationSum  = "sum"
+	AggregationRate = "rate"
+)
+
+// HistoryQuery describes values of which series of metric and for which period must be returned.
+// If Step is 0 values are returned as is, otherwise the period is split on steps
+// and values of each step are joined by Aggregation.
+// avg, min, max and last are supported for gauges, sum and rate are supported for counters
+type HistoryQuery struct {
+	Type        string
+	Name        string
+	Labels      metric.Labels
+	From        time.Time
+	To          time.Time
+	Step        time.Duration
+	Aggregation string
+}
+
 // Collector is the interface that group methods for collection of metrics
 type Collector interface {
 	Collect()
 	AllMetrics() []metric.Metric
 	ResetCounter(string)
+	ResetSummary(string)
 	SetValue(metric.Metric)
 	GetCounterValue(string) metric.Metric
 	GetGaugeValue(string) metric.Metric
 }
 
-// Sender is the interface that group methods for sending of metrics to server
+// Sender is the interface that group methods for sending of metrics to server.
+// ctx of Send may carry idempotency key of batch, see WithIdempotencyKey
 type Sender interface {
-	Send(...metric.Metric) error
+	Send(context.Context, ...metric.Metric) error
 	Close()
 }
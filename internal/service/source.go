@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+type agentKey struct{}
+
+// Source describes which agent reported series of metric last time and when
+type Source struct {
+	Agent string    `json:"agent"`
+	Time  time.Time `json:"time"`
+}
+
+// AgentInfo describes the last report of agent.
+// Metrics is the number of series which were reported by the agent last time
+type AgentInfo struct {
+	ID       string    `json:"id"`
+	LastSeen time.Time `json:"last_seen"`
+	Metrics  int       `json:"metrics"`
+}
+
+// WithAgent returns copy of ctx which carries instance ID of agent which sent metrics
+func WithAgent(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, agentKey{}, id)
+}
+
+// AgentFromContext returns instance ID of agent from ctx or empty string if it is not set
+func AgentFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(agentKey{}).(string)
+	return id
+}
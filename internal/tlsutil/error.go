@@ -0,0 +1,8 @@
+package tlsutil
+
+import "errors"
+
+var ErrIncompleteKeyPair = errors.New("certificate and private key must be set together")
+var ErrNoCertificate = errors.New("certificate is not set")
+var ErrInvalidCA = errors.New("file of certificate authority does not contain certificates")
+var ErrNoPeerCertificate = errors.New("server does not present certificate")
@@ -0,0 +1,243 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vilasle/metrics/internal/logger"
+)
+
+// defaultCheckInterval is the minimal period between checks of files for changes by default
+const defaultCheckInterval = 10 * time.Second
+
+// Files are paths to PEM encoded files for TLS
+type Files struct {
+	// CertFile and KeyFile are certificate and private key of this side of connection
+	CertFile string
+	KeyFile  string
+	// CAFile is certificate of authority which signs certificates of the other side of connection,
+	// server requires certificates of clients if it is set
+	CAFile string
+}
+
+// Option is the setting of Reloader
+type Option func(*Reloader)
+
+// WithCheckInterval sets the minimal period between checks of files for changes, files are checked on each handshake if it is 0
+func WithCheckInterval(interval time.Duration) Option {
+	return func(r *Reloader) {
+		if interval >= 0 {
+			r.interval = interval
+		}
+	}
+}
+
+// fileStamp describes version of file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reloader keeps tls config which is built from files and rebuilds it when files are changed.
+// Files are checked lazily on handshakes, so there are no background goroutines
+type Reloader struct {
+	files    Files
+	interval time.Duration
+
+	mx      *sync.Mutex
+	config  *tls.Config
+	stamps  []fileStamp
+	checked time.Time
+	now     func() time.Time
+}
+
+// NewReloader returns Reloader with config which is loaded from files
+func NewReloader(files Files, opts ...Option) (*Reloader, error) {
+	if (files.CertFile == "") != (files.KeyFile == "") {
+		return nil, ErrIncompleteKeyPair
+	}
+
+	r := &Reloader{
+		files:    files,
+		interval: defaultCheckInterval,
+		mx:       &sync.Mutex{},
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.checked = r.now()
+	return r, nil
+}
+
+// Config returns current tls config, files are checked for changes not often than once per check interval.
+// Previous config is kept if changed files can not be loaded
+func (r *Reloader) Config() *tls.Config {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if now := r.now(); now.Sub(r.checked) >= r.interval {
+		r.checked = now
+		if r.changed() {
+			if err := r.load(); err != nil {
+				logger.Warnw("can not reload tls files, previous ones are used", "error", err)
+			} else {
+				logger.Infow("tls files are reloaded", "cert", r.files.CertFile, "ca", r.files.CAFile)
+			}
+		}
+	}
+	return r.config
+}
+
+// ServerConfig returns config for server which takes certificates from Reloader on each handshake.
+// Config of handshake replaces the returned one, so application protocols e.g. h2 for grpc must be passed here
+func (r *Reloader) ServerConfig(protos ...string) (*tls.Config, error) {
+	if r.files.CertFile == "" {
+		return nil, ErrNoCertificate
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: protos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			config := r.Config()
+			if len(protos) == 0 {
+				return config, nil
+			}
+			config = config.Clone()
+			config.NextProtos = protos
+			return config, nil
+		},
+	}, nil
+}
+
+// ClientConfig returns config for client which takes certificate and authority from Reloader on each handshake,
+// it is suited for clients which keep config for all connections e.g. grpc
+func (r *Reloader) ClientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// certificate of server is verified by VerifyConnection with current authority
+		InsecureSkipVerify: true, //nolint:gosec
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			config := r.Config()
+			if len(config.Certificates) == 0 {
+				// empty certificate means that client does not have certificate
+				return &tls.Certificate{}, nil
+			}
+			return &config.Certificates[0], nil
+		},
+		VerifyConnection: r.verifyServer,
+	}
+}
+
+// verifyServer verifies chain of certificates of server by current authority as the default verification of client does
+func (r *Reloader) verifyServer(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return ErrNoPeerCertificate
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         r.Config().RootCAs,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// DialTLSContext establishes tls connection with config of Reloader, it is suited for http.Transport
+func (r *Reloader) DialTLSContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	config := r.Config().Clone()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		config.ServerName = host
+	}
+	d := tls.Dialer{Config: config}
+	return d.DialContext(ctx, network, addr)
+}
+
+// paths returns paths of files which are set
+func (r *Reloader) paths() []string {
+	rs := make([]string, 0, 3)
+	for _, p := range []string{r.files.CertFile, r.files.KeyFile, r.files.CAFile} {
+		if p != "" {
+			rs = append(rs, p)
+		}
+	}
+	return rs
+}
+
+// stat returns versions of files
+func (r *Reloader) stat() ([]fileStamp, error) {
+	paths := r.paths()
+	rs := make([]fileStamp, 0, len(paths))
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, fileStamp{modTime: info.ModTime(), size: info.Size()})
+	}
+	return rs, nil
+}
+
+// changed reports whether files were changed after loading. The caller must hold the lock
+func (r *Reloader) changed() bool {
+	stamps, err := r.stat()
+	if err != nil {
+		logger.Warnw("can not check tls files", "error", err)
+		return false
+	}
+	for i := range stamps {
+		if stamps[i] != r.stamps[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// load builds config from files. The caller must hold the lock if Reloader is shared
+func (r *Reloader) load() error {
+	// files are stated before reading, so changes during reading are found by the next check
+	stamps, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if r.files.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(r.files.CertFile, r.files.KeyFile)
+		if err != nil {
+			return err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if r.files.CAFile != "" {
+		content, err := os.ReadFile(r.files.CAFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return errors.Join(ErrInvalidCA, errors.New(r.files.CAFile))
+		}
+		// the same file verifies servers for clients and clients for servers
+		config.RootCAs = pool
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	r.config, r.stamps = config, stamps
+	return nil
+}
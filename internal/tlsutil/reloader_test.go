@@ -0,0 +1,274 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authority issues certificates for tests
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T, name string) authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes certificate and key which are signed by authority to dir
+func (a authority) issue(t *testing.T, dir, name string, serial int64) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	require.NoError(t, err)
+	rawKey, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPath, keyPath := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawKey}), 0o600))
+	return certPath, keyPath
+}
+
+func (a authority) write(t *testing.T, dir string) string {
+	path := filepath.Join(dir, a.cert.Subject.CommonName+".pem")
+	require.NoError(t, os.WriteFile(path, a.pem, 0o600))
+	return path
+}
+
+func startServer(t *testing.T, r *Reloader) *httptest.Server {
+	config, err := r.ServerConfig()
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+		}
+	}))
+	srv.TLS = config
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(r *Reloader, url string) (string, error) {
+	client := http.Client{Transport: &http.Transport{DialTLSContext: r.DialTLSContext}}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestReloader_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, "ca")
+	caPath := ca.write(t, dir)
+
+	serverCert, serverKey := ca.issue(t, dir, "server", 2)
+	srv, err := NewReloader(Files{CertFile: serverCert, KeyFile: serverKey, CAFile: caPath})
+	require.NoError(t, err)
+	ts := startServer(t, srv)
+
+	clientCert, clientKey := ca.issue(t, dir, "agent", 3)
+	client, err := NewReloader(Files{CertFile: clientCert, KeyFile: clientKey, CAFile: caPath})
+	require.NoError(t, err)
+
+	name, err := get(client, ts.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "agent", name)
+
+	// server requires certificate of client
+	anonymous, err := NewReloader(Files{CAFile: caPath})
+	require.NoError(t, err)
+	_, err = get(anonymous, ts.URL)
+	assert.Error(t, err)
+
+	// client does not trust server which is signed by other authority
+	other, err := NewReloader(Files{CertFile: clientCert, KeyFile: clientKey, CAFile: newAuthority(t, "other").write(t, dir)})
+	require.NoError(t, err)
+	_, err = get(other, ts.URL)
+	assert.Error(t, err)
+}
+
+func TestReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, "ca")
+	caPath := ca.write(t, dir)
+
+	serverCert, serverKey := ca.issue(t, dir, "server", 2)
+	srv, err := NewReloader(Files{CertFile: serverCert, KeyFile: serverKey}, WithCheckInterval(0))
+	require.NoError(t, err)
+	ts := startServer(t, srv)
+
+	client, err := NewReloader(Files{CAFile: caPath}, WithCheckInterval(0))
+	require.NoError(t, err)
+	_, err = get(client, ts.URL)
+	require.NoError(t, err)
+
+	// certificate of server is replaced by certificate of new authority, client trusts it after reloading of its CA
+	next := newAuthority(t, "ca")
+	next.issue(t, dir, "server", 4)
+	_, err = get(client, ts.URL)
+	assert.Error(t, err)
+
+	next.write(t, dir)
+	_, err = get(client, ts.URL)
+	assert.NoError(t, err)
+
+	// broken files do not replace loaded ones
+	require.NoError(t, os.WriteFile(caPath, []byte("broken"), 0o600))
+	_, err = get(client, ts.URL)
+	assert.NoError(t, err)
+}
+
+func TestNewReloader(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := NewReloader(Files{CertFile: filepath.Join(dir, "server.crt")})
+	assert.ErrorIs(t, err, ErrIncompleteKeyPair)
+
+	_, err = NewReloader(Files{CAFile: filepath.Join(dir, "none.pem")})
+	assert.Error(t, err)
+
+	invalid := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, os.WriteFile(invalid, []byte("invalid"), 0o600))
+	_, err = NewReloader(Files{CAFile: invalid})
+	assert.ErrorIs(t, err, ErrInvalidCA)
+
+	r, err := NewReloader(Files{CAFile: newAuthority(t, "ca").write(t, dir)})
+	require.NoError(t, err)
+	_, err = r.ServerConfig()
+	assert.ErrorIs(t, err, ErrNoCertificate)
+}
+
+func TestReloader_ServerConfigProtos(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, "ca")
+	caPath := ca.write(t, dir)
+
+	serverCert, serverKey := ca.issue(t, dir, "server", 2)
+	srv, err := NewReloader(Files{CertFile: serverCert, KeyFile: serverKey})
+	require.NoError(t, err)
+	config, err := srv.ServerConfig("h2")
+	require.NoError(t, err)
+
+	listen, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+	defer listen.Close()
+	go func() {
+		if conn, err := listen.Accept(); err == nil {
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	client, err := NewReloader(Files{CAFile: caPath})
+	require.NoError(t, err)
+	clientConfig := client.Config().Clone()
+	clientConfig.NextProtos = []string{"h2"}
+
+	conn, err := tls.Dial("tcp", listen.Addr().String(), clientConfig)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "h2", conn.ConnectionState().NegotiatedProtocol)
+}
+
+func TestReloader_ClientConfig(t *testing.T) {
+	serverDir, agentDir := t.TempDir(), t.TempDir()
+	ca := newAuthority(t, "ca")
+
+	serverCert, serverKey := ca.issue(t, serverDir, "server", 2)
+	srv, err := NewReloader(Files{CertFile: serverCert, KeyFile: serverKey, CAFile: ca.write(t, serverDir)}, WithCheckInterval(0))
+	require.NoError(t, err)
+	ts := startServer(t, srv)
+
+	agentCert, agentKey := ca.issue(t, agentDir, "agent", 3)
+	client, err := NewReloader(Files{CertFile: agentCert, KeyFile: agentKey, CAFile: ca.write(t, agentDir)}, WithCheckInterval(0))
+	require.NoError(t, err)
+
+	// the same config is used for all connections as grpc does
+	config := client.ClientConfig()
+	snapshot := client.Config().Clone()
+	get := func(config *tls.Config) (string, error) {
+		client := http.Client{Transport: &http.Transport{TLSClientConfig: config, DisableKeepAlives: true}}
+		resp, err := client.Get(ts.URL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	name, err := get(config)
+	require.NoError(t, err)
+	assert.Equal(t, "agent", name)
+
+	// all files of both sides are replaced by files of new authority between handshakes
+	next := newAuthority(t, "ca")
+	next.issue(t, serverDir, "server", 4)
+	next.write(t, serverDir)
+	next.issue(t, agentDir, "agent", 5)
+	next.write(t, agentDir)
+
+	name, err = get(config)
+	require.NoError(t, err)
+	assert.Equal(t, "agent", name)
+
+	_, err = get(snapshot)
+	assert.Error(t, err, "config which is taken before rotation does not trust new server")
+
+	// server is verified by authority of client
+	other, err := NewReloader(Files{CertFile: agentCert, KeyFile: agentKey, CAFile: newAuthority(t, "other").write(t, t.TempDir())})
+	require.NoError(t, err)
+	_, err = get(other.ClientConfig())
+	assert.Error(t, err)
+}
@@ -0,0 +1,124 @@
+// Package convert converts metrics to messages of grpc and back, it is shared by server and agent,
+// so both sides pass values of registered types in the same way
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/transport/grpc/pb"
+)
+
+// FromProto creates metric from message, values of types without own fields are parsed by registered type
+func FromProto(m *pb.Metric) (metric.Metric, error) {
+	if m == nil {
+		return nil, metric.ErrInvalidMetric
+	}
+
+	if m.GetId() == "" {
+		return nil, metric.ErrEmptyName
+	}
+
+	rs, err := fromProtoValue(m)
+	if err != nil {
+		return nil, err
+	}
+	if ts := m.GetTimestamp(); ts != 0 {
+		rs.SetTimestamp(time.Unix(0, ts))
+	}
+	return rs, nil
+}
+
+// fromProtoValue creates metric by its type from value fields of message.
+// Text of value is preferred over fields of built-in types
+func fromProtoValue(m *pb.Metric) (metric.Metric, error) {
+	if m.GetText() != "" {
+		return fromProtoText(m)
+	}
+
+	switch m.GetType() {
+	case metric.TypeGauge:
+		return metric.WithLabels(metric.NewGaugeMetric(m.GetId(), m.GetValue()), m.GetLabels()), nil
+	case metric.TypeCounter:
+		return metric.WithLabels(metric.NewCounterMetric(m.GetId(), m.GetDelta()), m.GetLabels()), nil
+	case metric.TypeHistogram:
+		h, err := metric.NewHistogramMetricFromData(m.GetId(), metric.HistogramData{
+			Bounds: m.GetHistogram().GetBounds(),
+			Counts: m.GetHistogram().GetCounts(),
+			Sum:    m.GetHistogram().GetSum(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return metric.WithLabels(h, m.GetLabels()), nil
+	case metric.TypeSummary:
+		s, err := metric.NewSummaryMetricFromData(m.GetId(), metric.SummaryData{
+			Positive: m.GetSummary().GetPositive(),
+			Negative: m.GetSummary().GetNegative(),
+			Zero:     m.GetSummary().GetZero(),
+			Sum:      m.GetSummary().GetSum(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return metric.WithLabels(s, m.GetLabels()), nil
+	default:
+		return fromProtoText(m)
+	}
+}
+
+// fromProtoText creates metric of any registered type by parsing text representation of value
+func fromProtoText(m *pb.Metric) (metric.Metric, error) {
+	t, ok := metric.LookupType(m.GetType())
+	if !ok {
+		return nil, errors.Join(metric.ErrUnknownMetricType, fmt.Errorf("type %s", m.GetType()))
+	}
+	if m.GetText() == "" {
+		return nil, metric.ErrEmptyValue
+	}
+
+	v, err := t.Parse(m.GetId(), m.GetText())
+	if err != nil {
+		return nil, err
+	}
+	return metric.WithLabels(v, m.GetLabels()), nil
+}
+
+// ToProto fills fields of built-in types, values of other registered types and of implementations
+// without data of histogram or summary are passed by text which the other side parses by the same registered type
+func ToProto(m metric.Metric) (*pb.Metric, error) {
+	if _, ok := metric.LookupType(m.Type()); !ok {
+		return nil, errors.Join(metric.ErrUnknownMetricType, fmt.Errorf("type %s", m.Type()))
+	}
+
+	rs := &pb.Metric{Id: m.Name(), Type: m.Type(), Labels: m.Labels()}
+	if ts := m.Timestamp(); !ts.IsZero() {
+		rs.Timestamp = ts.UnixNano()
+	}
+
+	switch m.Type() {
+	case metric.TypeGauge:
+		rs.Value = m.Float64()
+	case metric.TypeCounter:
+		rs.Delta = m.Int64()
+	case metric.TypeHistogram:
+		if h, ok := m.(metric.Histogram); ok {
+			data := h.Data()
+			rs.Histogram = &pb.Histogram{Bounds: data.Bounds, Counts: data.Counts, Sum: data.Sum}
+		} else {
+			rs.Text = m.Value()
+		}
+	case metric.TypeSummary:
+		if s, ok := m.(metric.Summary); ok {
+			data := s.Data()
+			rs.Summary = &pb.Summary{Positive: data.Positive, Negative: data.Negative, Zero: data.Zero, Sum: data.Sum}
+		} else {
+			rs.Text = m.Value()
+		}
+	default:
+		rs.Text = m.Value()
+	}
+	return rs, nil
+}
@@ -0,0 +1,83 @@
+package convert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/transport/grpc/pb"
+)
+
+// customMetric is the metric of type which is not registered
+type customMetric struct {
+	metric.Metric
+}
+
+func (customMetric) Type() string {
+	return "custom"
+}
+
+// plainHistogram is the histogram without data of buckets
+type plainHistogram struct {
+	metric.Metric
+}
+
+func (plainHistogram) Type() string {
+	return metric.TypeHistogram
+}
+
+func (plainHistogram) Value() string {
+	return "0.5:2,+Inf:1,sum:1.5"
+}
+
+func TestToProto(t *testing.T) {
+	msg, err := ToProto(metric.NewGaugeMetric("gauge1", 1.25))
+	require.NoError(t, err)
+	assert.Equal(t, 1.25, msg.GetValue())
+
+	// types without own fields are passed by text
+	msg, err = ToProto(plainHistogram{metric.NewGaugeMetric("latency", 0)})
+	require.NoError(t, err)
+	assert.Nil(t, msg.GetHistogram())
+	assert.Equal(t, "0.5:2,+Inf:1,sum:1.5", msg.GetText())
+
+	_, err = ToProto(customMetric{metric.NewGaugeMetric("gauge1", 1.25)})
+	assert.ErrorIs(t, err, metric.ErrUnknownMetricType)
+}
+
+func TestFromProto(t *testing.T) {
+	histogram, err := metric.NewHistogramMetric("latency", 0.5)
+	require.NoError(t, err)
+
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	gauge := metric.NewGaugeMetric("gauge1", 1.25)
+	gauge.SetTimestamp(ts)
+
+	// metrics are the same after passing through message
+	for _, m := range []metric.Metric{
+		gauge,
+		metric.WithLabels(metric.NewCounterMetric("counter1", 5), metric.Labels{"host": "a"}),
+		histogram,
+		metric.NewSummaryMetric("PauseNs", 1, 2),
+		plainHistogram{metric.NewGaugeMetric("latency", 0)},
+	} {
+		msg, err := ToProto(m)
+		require.NoError(t, err)
+		got, err := FromProto(msg)
+		require.NoError(t, err)
+		assert.Equal(t, m.Type(), got.Type())
+		assert.Equal(t, m.Name(), got.Name())
+		assert.Equal(t, m.Value(), got.Value())
+		assert.Equal(t, m.Labels(), got.Labels())
+		assert.True(t, m.Timestamp().Equal(got.Timestamp()))
+	}
+
+	_, err = FromProto(nil)
+	assert.ErrorIs(t, err, metric.ErrInvalidMetric)
+	_, err = FromProto(&pb.Metric{Type: metric.TypeGauge})
+	assert.ErrorIs(t, err, metric.ErrEmptyName)
+	_, err = FromProto(&pb.Metric{Id: "custom1", Type: "custom", Text: "1"})
+	assert.ErrorIs(t, err, metric.ErrUnknownMetricType)
+}
@@ -0,0 +1,96 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/vilasle/metrics/internal/auth"
+	"github.com/vilasle/metrics/internal/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AuthorizationKey is the key of metadata which contains API token as Bearer <token>
+const AuthorizationKey = "authorization"
+
+// TokenGetter returns API token by id, e.g. repository.TokenRepository
+type TokenGetter interface {
+	Token(ctx context.Context, id string) (auth.Token, bool, error)
+}
+
+// MethodScopes maps full names of methods to scopes which are required for them.
+// Methods without scope require admin scope, so new methods are not public by mistake
+type MethodScopes map[string]auth.Scope
+
+func (m MethodScopes) scope(method string) auth.Scope {
+	if s, ok := m[method]; ok && s != "" {
+		return s
+	}
+	return auth.ScopeAdmin
+}
+
+// AuthenticateUnary returns interceptor which checks token from metadata authorization: Bearer <token>.
+// Call without valid token is rejected with code Unauthenticated, call with token without required scope - with code PermissionDenied.
+// If tokens is nil, all calls are passed
+func AuthenticateUnary(tokens TokenGetter, scopes MethodScopes) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := checkToken(ctx, tokens, scopes.scope(info.FullMethod)); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthenticateStream is the same as AuthenticateUnary for stream calls
+func AuthenticateStream(tokens TokenGetter, scopes MethodScopes) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkToken(ss.Context(), tokens, scopes.scope(info.FullMethod)); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func checkToken(ctx context.Context, tokens TokenGetter, scope auth.Scope) error {
+	if tokens == nil {
+		return nil
+	}
+
+	token, err := bearerToken(ctx, tokens)
+	if err != nil && !errors.Is(err, auth.ErrInvalidToken) {
+		logger.Errorw("can not get token", "error", err)
+		return status.Error(codes.Internal, "can not check token")
+	} else if err != nil {
+		logger.Warnw("call without valid token", "error", err)
+		return status.Error(codes.Unauthenticated, auth.ErrInvalidToken.Error())
+	}
+
+	if !token.HasScope(scope) {
+		logger.Warnw("token does not have scope", "token", token.ID, "scope", scope)
+		return status.Errorf(codes.PermissionDenied, "token does not have scope %s", scope)
+	}
+	return nil
+}
+
+func bearerToken(ctx context.Context, tokens TokenGetter) (auth.Token, error) {
+	value, ok := strings.CutPrefix(metadataValue(ctx, AuthorizationKey), "Bearer ")
+	if !ok {
+		return auth.Token{}, auth.ErrInvalidToken
+	}
+
+	id, secret, err := auth.Split(strings.TrimSpace(value))
+	if err != nil {
+		return auth.Token{}, err
+	}
+
+	token, ok, err := tokens.Token(ctx, id)
+	if err != nil {
+		return auth.Token{}, err
+	}
+	if !ok || !token.Match(secret) {
+		return auth.Token{}, auth.ErrInvalidToken
+	}
+	return token, nil
+}
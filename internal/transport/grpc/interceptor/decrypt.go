@@ -0,0 +1,101 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/rsa"
+
+	"github.com/vilasle/metrics/internal/encrypt"
+	"github.com/vilasle/metrics/internal/transport/grpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// DecryptContentUnary returns interceptor which decrypts metric of pb.UpdateMetricRequest by the only key,
+// see DecryptContentKeySetUnary
+func DecryptContentUnary(key *rsa.PrivateKey) grpc.UnaryServerInterceptor {
+	return DecryptContentKeySetUnary(keySet(key))
+}
+
+// DecryptContentStream returns interceptor which decrypts metric of each pb.UpdateMetricRequest of client stream
+// by the only key, see DecryptContentKeySetStream
+func DecryptContentStream(key *rsa.PrivateKey) grpc.StreamServerInterceptor {
+	return DecryptContentKeySetStream(keySet(key))
+}
+
+// DecryptContentKeySetUnary returns interceptor which decrypts metric of pb.UpdateMetricRequest
+// envelope mode is chosen by metadata encrypt.ModeHeader, without it message is decrypted by RSA-OAEP as is
+// key is chosen by metadata encrypt.KeyIDHeader, without it message is decrypted by each key of set
+// if keys is nil or request is not encrypted, request is passed as is
+func DecryptContentKeySetUnary(keys *encrypt.KeySet) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if keys == nil {
+			return handler(ctx, req)
+		}
+
+		if err := decryptMessage(keys, metadataValue(ctx, encrypt.KeyIDHeader), metadataValue(ctx, encrypt.ModeHeader), req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// DecryptContentKeySetStream returns interceptor which decrypts metric of each pb.UpdateMetricRequest of client stream
+// key and mode are chosen like by DecryptContentKeySetUnary
+// if keys is nil or message is not encrypted, message is passed as is
+func DecryptContentKeySetStream(keys *encrypt.KeySet) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if keys == nil {
+			return handler(srv, ss)
+		}
+		return handler(srv, &decryptedStream{
+			ServerStream: ss,
+			keys:         keys,
+			keyID:        metadataValue(ss.Context(), encrypt.KeyIDHeader),
+			mode:         metadataValue(ss.Context(), encrypt.ModeHeader),
+		})
+	}
+}
+
+type decryptedStream struct {
+	grpc.ServerStream
+	keys  *encrypt.KeySet
+	keyID string
+	mode  string
+}
+
+func (s *decryptedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return decryptMessage(s.keys, s.keyID, s.mode, m)
+}
+
+func keySet(key *rsa.PrivateKey) *encrypt.KeySet {
+	if key == nil {
+		return nil
+	}
+	keys, _ := encrypt.NewKeySet(key)
+	return keys
+}
+
+func decryptMessage(keys *encrypt.KeySet, keyID, mode string, m any) error {
+	req, ok := m.(*pb.UpdateMetricRequest)
+	if !ok || len(req.GetEncrypted()) == 0 {
+		return nil
+	}
+
+	content, err := keys.Decrypt(keyID, mode, req.GetEncrypted())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	metric := &pb.Metric{}
+	if err := proto.Unmarshal(content, metric); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	req.Metric, req.Encrypted = metric, nil
+	return nil
+}
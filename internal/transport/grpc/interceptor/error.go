@@ -0,0 +1,7 @@
+package interceptor
+
+import "errors"
+
+var ErrInvalidHashSum = errors.New("invalid hash sum")
+var ErrUnexpectedMessage = errors.New("unexpected type of message")
+var ErrUntrustedAddress = errors.New("address is not in trusted subnet")
@@ -0,0 +1,217 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/vilasle/metrics/internal/keyring"
+	"github.com/vilasle/metrics/internal/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// HashSumKey is the key of metadata which contains hash sum of request messages
+const HashSumKey = "hashsha256"
+
+// HashKeyIDKey is the key of metadata which contains id of key of hash sum
+const HashKeyIDKey = "hashkeyid"
+
+// TimestampKey is the key of metadata which contains time of signing in unix seconds
+const TimestampKey = "x-request-timestamp"
+
+// NonceKey is the key of metadata which contains random nonce of signed request
+const NonceKey = "x-request-nonce"
+
+// ReplayChecker rejects timestamp which is out of clock skew and nonce which was already used
+type ReplayChecker interface {
+	Check(timestamp, nonce string) error
+}
+
+// CheckHashSumUnary returns interceptor which checks hash sum of request message by the only key
+// without checking of replays, see CheckHashSumKeyringUnary
+func CheckHashSumUnary(key []byte) grpc.UnaryServerInterceptor {
+	return CheckHashSumKeyringUnary(keyring.FromSecret(key), nil)
+}
+
+// CheckHashSumStream returns interceptor which checks hash sum of client stream by the only key
+// without checking of replays, see CheckHashSumKeyringStream
+func CheckHashSumStream(key []byte) grpc.StreamServerInterceptor {
+	return CheckHashSumKeyringStream(keyring.FromSecret(key), nil)
+}
+
+// CheckHashSumKeyringUnary returns interceptor which checks hash sum of request message
+// hash sum is HMAC-SHA256 of deterministic serialized message, it is passed in metadata by key HashSumKey,
+// id of key is passed by key HashKeyIDKey, request without id is checked by all keys which are not expired.
+// If metadata has TimestampKey and NonceKey, they are signed with message, see keyring.SignedContent,
+// and after checking of hash sum they are checked by replays
+// if keyring is nil or request does not have hash sum, checking is skipped, if replays is nil, replays are not checked
+func CheckHashSumKeyringUnary(keys *keyring.Keyring, replays ReplayChecker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		sum := metadataValue(ctx, HashSumKey)
+		if keys == nil || sum == "" {
+			return handler(ctx, req)
+		}
+
+		timestamp, nonce := metadataValue(ctx, TimestampKey), metadataValue(ctx, NonceKey)
+		hashes, err := candidateHashes(keys, metadataValue(ctx, HashKeyIDKey), timestamp, nonce)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeMessage(hashes, req); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		if err := compareHashSum(sum, hashes); err != nil {
+			return nil, err
+		}
+		if err := checkReplay(replays, timestamp, nonce); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// CheckHashSumKeyringStream returns interceptor which checks hash sum of all messages of client stream
+// hash sum is HMAC-SHA256 of deterministic serialized messages in order of sending,
+// it is passed in metadata by key HashSumKey, id of key is passed by key HashKeyIDKey.
+// Timestamp and nonce are signed and checked like in CheckHashSumKeyringUnary.
+// Checking is happened when client closes the stream, on mismatch RecvMsg returns error instead of io.EOF
+// if keyring is nil or request does not have hash sum, checking is skipped, if replays is nil, replays are not checked
+func CheckHashSumKeyringStream(keys *keyring.Keyring, replays ReplayChecker) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		sum := metadataValue(ss.Context(), HashSumKey)
+		if keys == nil || sum == "" {
+			return handler(srv, ss)
+		}
+
+		timestamp, nonce := metadataValue(ss.Context(), TimestampKey), metadataValue(ss.Context(), NonceKey)
+		hashes, err := candidateHashes(keys, metadataValue(ss.Context(), HashKeyIDKey), timestamp, nonce)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &hashCheckedStream{
+			ServerStream: ss,
+			hashes:       hashes,
+			sum:          sum,
+			replays:      replays,
+			timestamp:    timestamp,
+			nonce:        nonce,
+		})
+	}
+}
+
+// checkReplay checks signed timestamp and nonce of request
+func checkReplay(replays ReplayChecker, timestamp, nonce string) error {
+	if replays == nil {
+		return nil
+	}
+	if err := replays.Check(timestamp, nonce); err != nil {
+		logger.Warnw("rejected request", "error", err)
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// multiHash calculates hash sums by several keys at once, because request without key id may be signed by any of them
+type multiHash []hash.Hash
+
+func (m multiHash) Write(p []byte) (int, error) {
+	for _, h := range m {
+		h.Write(p)
+	}
+	return len(p), nil
+}
+
+// candidateHashes returns hashes by candidate keys, signed timestamp and nonce are already written to them
+func candidateHashes(keys *keyring.Keyring, id, timestamp, nonce string) (multiHash, error) {
+	candidates, err := keys.Candidates(id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, errors.Join(ErrInvalidHashSum, err).Error())
+	}
+
+	hashes := make(multiHash, 0, len(candidates))
+	for _, k := range candidates {
+		hashes = append(hashes, hmac.New(sha256.New, []byte(k.Secret)))
+	}
+	hashes.Write(keyring.SignedContent(nil, timestamp, nonce))
+	return hashes, nil
+}
+
+type hashCheckedStream struct {
+	grpc.ServerStream
+	hashes    multiHash
+	sum       string
+	replays   ReplayChecker
+	timestamp string
+	nonce     string
+}
+
+func (s *hashCheckedStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if errors.Is(err, io.EOF) {
+		if cmpErr := compareHashSum(s.sum, s.hashes); cmpErr != nil {
+			return cmpErr
+		}
+		if replayErr := checkReplay(s.replays, s.timestamp, s.nonce); replayErr != nil {
+			return replayErr
+		}
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if err := writeMessage(s.hashes, m); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func writeMessage(w io.Writer, m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return ErrUnexpectedMessage
+	}
+
+	content, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+func compareHashSum(sum string, hashes multiHash) error {
+	reqHash, err := base64.URLEncoding.DecodeString(sum)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	logger.Debug("request hash sum", "sum", reqHash)
+
+	for _, h := range hashes {
+		if hmac.Equal(reqHash, h.Sum(nil)) {
+			return nil
+		}
+	}
+	return status.Error(codes.InvalidArgument, ErrInvalidHashSum.Error())
+}
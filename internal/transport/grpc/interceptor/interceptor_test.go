@@ -0,0 +1,257 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/auth"
+	"github.com/vilasle/metrics/internal/keyring"
+	"github.com/vilasle/metrics/internal/transport/grpc/pb"
+	"github.com/vilasle/metrics/internal/transport/rest/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func echoHandler(_ context.Context, req any) (any, error) {
+	return req, nil
+}
+
+func Test_CheckHashSumUnary(t *testing.T) {
+	key := []byte("key")
+	req := &pb.UpdateMetricRequest{Metric: &pb.Metric{Id: "test", Type: "gauge", Value: 1.5}}
+
+	content, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	require.NoError(t, err)
+
+	h := hmac.New(sha256.New, key)
+	h.Write(content)
+	validSum := base64.URLEncoding.EncodeToString(h.Sum(nil))
+
+	testCases := []struct {
+		name string
+		sum  string
+		code codes.Code
+	}{
+		{name: "valid hash sum", sum: validSum, code: codes.OK},
+		{name: "without hash sum", sum: "", code: codes.OK},
+		{name: "invalid hash sum", sum: base64.URLEncoding.EncodeToString([]byte("wrong")), code: codes.InvalidArgument},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.sum != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(HashSumKey, tt.sum))
+			}
+
+			_, err := CheckHashSumUnary(key)(ctx, req, nil, echoHandler)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func Test_CheckHashSumKeyringUnary(t *testing.T) {
+	keys, err := keyring.New(
+		keyring.Key{ID: "new", Secret: "new key"},
+		keyring.Key{ID: "old", Secret: "old key", Expire: time.Now().Add(-time.Minute)},
+		keyring.Key{ID: "previous", Secret: "previous key"},
+	)
+	require.NoError(t, err)
+
+	req := &pb.UpdateMetricRequest{Metric: &pb.Metric{Id: "test", Type: "gauge", Value: 1.5}}
+	content, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	require.NoError(t, err)
+
+	sign := func(secret string) string {
+		return base64.URLEncoding.EncodeToString(keyring.Key{Secret: secret}.Sign(content))
+	}
+
+	testCases := []struct {
+		name string
+		id   string
+		sum  string
+		code codes.Code
+	}{
+		{name: "key by id", id: "new", sum: sign("new key"), code: codes.OK},
+		{name: "previous key by id", id: "previous", sum: sign("previous key"), code: codes.OK},
+		{name: "without id", sum: sign("previous key"), code: codes.OK},
+		{name: "another key by id", id: "new", sum: sign("previous key"), code: codes.InvalidArgument},
+		{name: "expired key", id: "old", sum: sign("old key"), code: codes.InvalidArgument},
+		{name: "expired key without id", sum: sign("old key"), code: codes.InvalidArgument},
+		{name: "unknown key", id: "unknown", sum: sign("new key"), code: codes.InvalidArgument},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			md := metadata.Pairs(HashSumKey, tt.sum)
+			if tt.id != "" {
+				md.Append(HashKeyIDKey, tt.id)
+			}
+			ctx := metadata.NewIncomingContext(context.Background(), md)
+
+			_, err := CheckHashSumKeyringUnary(keys, nil)(ctx, req, nil, echoHandler)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func Test_DecryptContentUnary(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	src := &pb.Metric{Id: "test", Type: "counter", Delta: 10}
+	content, err := proto.Marshal(src)
+	require.NoError(t, err)
+
+	encrypted, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &privateKey.PublicKey, content, []byte{})
+	require.NoError(t, err)
+
+	rs, err := DecryptContentUnary(privateKey)(context.Background(), &pb.UpdateMetricRequest{Encrypted: encrypted}, nil, echoHandler)
+	require.NoError(t, err)
+
+	req := rs.(*pb.UpdateMetricRequest)
+	assert.Empty(t, req.GetEncrypted())
+	assert.True(t, proto.Equal(src, req.GetMetric()))
+}
+
+func Test_TrustedSubnetUnary(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("192.168.1.0/24")
+	require.NoError(t, err)
+
+	interceptor := TrustedSubnetUnary([]*net.IPNet{subnet}, pb.Metrics_UpdateMetric_FullMethodName)
+
+	testCases := []struct {
+		name   string
+		method string
+		realIP string
+		code   codes.Code
+	}{
+		{name: "trusted address", method: pb.Metrics_UpdateMetric_FullMethodName, realIP: "192.168.1.10", code: codes.OK},
+		{name: "untrusted address", method: pb.Metrics_UpdateMetric_FullMethodName, realIP: "192.168.2.10", code: codes.PermissionDenied},
+		{name: "without address", method: pb.Metrics_UpdateMetric_FullMethodName, code: codes.PermissionDenied},
+		{name: "not protected method", method: pb.Metrics_GetMetric_FullMethodName, realIP: "192.168.2.10", code: codes.OK},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.realIP != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(RealIPKey, tt.realIP))
+			}
+
+			_, err := interceptor(ctx, &pb.UpdateMetricRequest{}, &grpc.UnaryServerInfo{FullMethod: tt.method}, echoHandler)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+type tokenMap map[string]auth.Token
+
+func (m tokenMap) Token(_ context.Context, id string) (auth.Token, bool, error) {
+	if id == "broken" {
+		return auth.Token{}, false, errors.New("storage is not available")
+	}
+	t, ok := m[id]
+	return t, ok, nil
+}
+
+func Test_AuthenticateUnary(t *testing.T) {
+	read, readValue, err := auth.Issue("dashboard", auth.ScopeRead)
+	require.NoError(t, err)
+	write, writeValue, err := auth.Issue("agent", auth.ScopeWrite)
+	require.NoError(t, err)
+
+	interceptor := AuthenticateUnary(tokenMap{read.ID: read, write.ID: write}, MethodScopes{
+		pb.Metrics_UpdateMetric_FullMethodName: auth.ScopeWrite,
+		pb.Metrics_GetMetric_FullMethodName:    auth.ScopeRead,
+	})
+
+	testCases := []struct {
+		name   string
+		method string
+		token  string
+		code   codes.Code
+	}{
+		{name: "write by write token", method: pb.Metrics_UpdateMetric_FullMethodName, token: writeValue, code: codes.OK},
+		{name: "write by read token", method: pb.Metrics_UpdateMetric_FullMethodName, token: readValue, code: codes.PermissionDenied},
+		{name: "read by read token", method: pb.Metrics_GetMetric_FullMethodName, token: readValue, code: codes.OK},
+		{name: "method without scope", method: pb.Metrics_ListMetrics_FullMethodName, token: readValue, code: codes.PermissionDenied},
+		{name: "without token", method: pb.Metrics_UpdateMetric_FullMethodName, code: codes.Unauthenticated},
+		{name: "wrong secret", method: pb.Metrics_UpdateMetric_FullMethodName, token: write.ID + ".wrong", code: codes.Unauthenticated},
+		{name: "storage failed", method: pb.Metrics_UpdateMetric_FullMethodName, token: "broken.secret", code: codes.Internal},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(AuthorizationKey, "Bearer "+tt.token))
+			}
+
+			_, err := interceptor(ctx, &pb.UpdateMetricRequest{}, &grpc.UnaryServerInfo{FullMethod: tt.method}, echoHandler)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+
+	// without tokens storage all calls are passed
+	_, err = AuthenticateUnary(nil, nil)(context.Background(), &pb.UpdateMetricRequest{},
+		&grpc.UnaryServerInfo{FullMethod: pb.Metrics_UpdateMetric_FullMethodName}, echoHandler)
+	assert.NoError(t, err)
+}
+
+func Test_CheckHashSumKeyringUnary_Replays(t *testing.T) {
+	keys := keyring.FromSecret([]byte("key"))
+	guard := middleware.NewReplayGuard(middleware.WithRequiredStamp())
+	interceptor := CheckHashSumKeyringUnary(keys, guard)
+
+	req := &pb.UpdateMetricRequest{Metric: &pb.Metric{Id: "test", Type: "gauge", Value: 1.5}}
+	content, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	require.NoError(t, err)
+
+	call := func(timestamp, nonce, signedNonce string) error {
+		sum := keyring.Key{Secret: "key"}.Sign(keyring.SignedContent(content, timestamp, signedNonce))
+		md := metadata.Pairs(HashSumKey, base64.URLEncoding.EncodeToString(sum))
+		if timestamp != "" {
+			md.Append(TimestampKey, timestamp)
+			md.Append(NonceKey, nonce)
+		}
+		_, err := interceptor(metadata.NewIncomingContext(context.Background(), md), req, nil, echoHandler)
+		return err
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	require.NoError(t, call(now, "n1", "n1"))
+
+	testCases := []struct {
+		name        string
+		timestamp   string
+		nonce       string
+		signedNonce string
+	}{
+		{name: "replayed call", timestamp: now, nonce: "n1", signedNonce: "n1"},
+		{name: "stale call", timestamp: strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10), nonce: "n2", signedNonce: "n2"},
+		{name: "nonce is not signed", timestamp: now, nonce: "n3", signedNonce: "n1"},
+		{name: "without stamp", nonce: "", signedNonce: ""},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := call(tt.timestamp, tt.nonce, tt.signedNonce)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package interceptor
+
+import (
+	"context"
+	"net"
+
+	"github.com/vilasle/metrics/internal/logger"
+	"github.com/vilasle/metrics/internal/netutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RealIPKey is the key of metadata which contains ip address of agent
+const RealIPKey = "x-real-ip"
+
+// TrustedSubnetUnary returns interceptor which rejects calls of passed methods with code PermissionDenied
+// if ip address from metadata x-real-ip does not belong any of subnets.
+// If subnets are empty, all calls are passed
+func TrustedSubnetUnary(subnets []*net.IPNet, methods ...string) grpc.UnaryServerInterceptor {
+	protected := toSet(methods)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := checkSubnet(ctx, subnets, protected, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// TrustedSubnetStream is the same as TrustedSubnetUnary for stream calls
+func TrustedSubnetStream(subnets []*net.IPNet, methods ...string) grpc.StreamServerInterceptor {
+	protected := toSet(methods)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkSubnet(ss.Context(), subnets, protected, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func checkSubnet(ctx context.Context, subnets []*net.IPNet, protected map[string]struct{}, method string) error {
+	if len(subnets) == 0 {
+		return nil
+	}
+	if _, ok := protected[method]; !ok {
+		return nil
+	}
+
+	var realIP string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(RealIPKey); len(v) > 0 {
+			realIP = v[0]
+		}
+	}
+
+	if netutil.IsTrustedIP(net.ParseIP(realIP), subnets) {
+		return nil
+	}
+
+	logger.Warnw("call from untrusted address", "method", method, "realIP", realIP)
+	return status.Error(codes.PermissionDenied, ErrUntrustedAddress.Error())
+}
+
+func toSet(values []string) map[string]struct{} {
+	rs := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		rs[v] = struct{}{}
+	}
+	return rs
+}
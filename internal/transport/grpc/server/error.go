@@ -0,0 +1,53 @@
+package grpcsrv
+
+import (
+	"errors"
+
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errEmptyRequiredFields = errors.New("empty required fields")
+
+// statusError wraps error to grpc status with code which matches http status code of rest handlers
+func statusError(err error) error {
+	return status.Error(getStatusCode(err), err.Error())
+}
+
+func getStatusCode(err error) codes.Code {
+	if errIs(err,
+		service.ErrEmptyKind,
+		service.ErrUnknownKind,
+		service.ErrEmptyValue,
+		metric.ErrConvertingRawValue,
+		metric.ErrEmptyValue,
+		metric.ErrUnknownMetricType,
+		metric.ErrInvalidMetric,
+		metric.ErrInvalidHistogram,
+		metric.ErrHistogramBoundsMismatch,
+		metric.ErrInvalidSummary,
+	) {
+		return codes.InvalidArgument
+	} else if errIs(err,
+		service.ErrEmptyName,
+		service.ErrMetricIsNotExist,
+		errEmptyRequiredFields,
+		metric.ErrEmptyName,
+	) {
+		return codes.NotFound
+	} else if err != nil {
+		return codes.Internal
+	}
+	return codes.OK
+}
+
+func errIs(err error, errs ...error) bool {
+	for _, e := range errs {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
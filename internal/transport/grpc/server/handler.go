@@ -0,0 +1,144 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/vilasle/metrics/internal/logger"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/service"
+	"github.com/vilasle/metrics/internal/transport/grpc/convert"
+	"github.com/vilasle/metrics/internal/transport/grpc/pb"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// agentIDKey is the key of metadata which contains instance ID of agent
+	agentIDKey = "x-agent-id"
+	// idempotencyKey is the key of metadata which contains idempotency key of batch
+	idempotencyKey = "idempotency-key"
+)
+
+// MetricsServer implements pb.MetricsServer and passes data to service.MetricService
+type MetricsServer struct {
+	pb.UnimplementedMetricsServer
+	svc service.MetricService
+}
+
+// NewMetricsServer returns new instance of MetricsServer
+func NewMetricsServer(svc service.MetricService) *MetricsServer {
+	return &MetricsServer{svc: svc}
+}
+
+// UpdateMetric saves metric and returns it back
+func (s *MetricsServer) UpdateMetric(ctx context.Context, req *pb.UpdateMetricRequest) (*pb.UpdateMetricResponse, error) {
+	m, err := convert.FromProto(req.GetMetric())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	if err := s.svc.Save(withIdempotencyKey(withAgent(ctx)), m); err != nil {
+		return nil, statusError(err)
+	}
+
+	logger.Debugw("updated metric", "metric", m)
+
+	msg, err := convert.ToProto(m)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.UpdateMetricResponse{Metric: msg}, nil
+}
+
+// UpdateMetrics reads metrics from stream and saves they by one batch when client closes the stream
+func (s *MetricsServer) UpdateMetrics(stream pb.Metrics_UpdateMetricsServer) error {
+	ms := make([]metric.Metric, 0)
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		m, err := convert.FromProto(req.GetMetric())
+		if err != nil {
+			return statusError(err)
+		}
+		ms = append(ms, m)
+	}
+
+	if len(ms) > 0 {
+		if err := s.svc.Save(withIdempotencyKey(withAgent(stream.Context())), ms...); err != nil {
+			return statusError(err)
+		}
+	}
+
+	logger.Debugw("updated metrics", "metric", ms)
+
+	return stream.SendAndClose(&pb.UpdateMetricsResponse{Saved: int64(len(ms))})
+}
+
+// GetMetric returns metric by type, name and labels
+func (s *MetricsServer) GetMetric(ctx context.Context, req *pb.GetMetricRequest) (*pb.GetMetricResponse, error) {
+	if req.GetId() == "" || req.GetType() == "" {
+		return nil, statusError(errEmptyRequiredFields)
+	}
+
+	m, err := s.svc.Get(ctx, req.GetType(), req.GetId(), req.GetLabels())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	msg, err := convert.ToProto(m)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.GetMetricResponse{Metric: msg}, nil
+}
+
+// ListMetrics returns all metrics
+func (s *MetricsServer) ListMetrics(ctx context.Context, _ *pb.ListMetricsRequest) (*pb.ListMetricsResponse, error) {
+	ms, err := s.svc.All(ctx)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	rs := make([]*pb.Metric, 0, len(ms))
+	for _, m := range ms {
+		msg, err := convert.ToProto(m)
+		if err != nil {
+			return nil, statusError(err)
+		}
+		rs = append(rs, msg)
+	}
+	return &pb.ListMetricsResponse{Metrics: rs}, nil
+}
+
+// Ping checks that storage is accessed
+func (s *MetricsServer) Ping(ctx context.Context, _ *pb.PingRequest) (*pb.PingResponse, error) {
+	if err := s.svc.Ping(ctx); err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.PingResponse{}, nil
+}
+
+// withAgent returns copy of ctx which carries instance ID of agent from metadata
+func withAgent(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(agentIDKey); len(v) > 0 {
+			return service.WithAgent(ctx, v[0])
+		}
+	}
+	return ctx
+}
+
+// withIdempotencyKey returns copy of ctx which carries idempotency key of batch from metadata
+func withIdempotencyKey(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(idempotencyKey); len(v) > 0 {
+			return service.WithIdempotencyKey(ctx, v[0])
+		}
+	}
+	return ctx
+}
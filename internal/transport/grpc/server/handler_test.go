@@ -0,0 +1,251 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository/memory"
+	"github.com/vilasle/metrics/internal/service"
+	"github.com/vilasle/metrics/internal/service/server"
+	"github.com/vilasle/metrics/internal/transport/grpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T, opts ...grpc.ServerOption) pb.MetricsClient {
+	t.Helper()
+
+	svc := server.NewMetricService(memory.NewMetricRepository())
+	srv := NewGRPCServer("", svc, opts...)
+
+	listen := bufconn.Listen(1024 * 1024)
+	go srv.Serve(listen)
+	t.Cleanup(func() { srv.ForceStop() })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listen.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewMetricsClient(conn)
+}
+
+func TestMetricsServer_UpdateMetric(t *testing.T) {
+	client := newTestClient(t)
+
+	testCases := []struct {
+		name   string
+		metric *pb.Metric
+		want   *pb.Metric
+		code   codes.Code
+	}{
+		{
+			name:   "gauge",
+			metric: &pb.Metric{Id: "test", Type: "gauge", Value: 1.05},
+			want:   &pb.Metric{Id: "test", Type: "gauge", Value: 1.05},
+			code:   codes.OK,
+		},
+		{
+			name:   "counter",
+			metric: &pb.Metric{Id: "test", Type: "counter", Delta: 10},
+			want:   &pb.Metric{Id: "test", Type: "counter", Delta: 10},
+			code:   codes.OK,
+		},
+		{
+			name:   "histogram",
+			metric: &pb.Metric{Id: "test", Type: "histogram", Histogram: &pb.Histogram{Bounds: []float64{0.5}, Counts: []int64{2, 1}, Sum: 1.5}},
+			want:   &pb.Metric{Id: "test", Type: "histogram", Histogram: &pb.Histogram{Bounds: []float64{0.5}, Counts: []int64{2, 1}, Sum: 1.5}},
+			code:   codes.OK,
+		},
+		{
+			name:   "invalid histogram",
+			metric: &pb.Metric{Id: "test", Type: "histogram", Histogram: &pb.Histogram{Bounds: []float64{0.5}, Counts: []int64{2}}},
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "summary",
+			metric: &pb.Metric{Id: "test", Type: "summary", Summary: &pb.Summary{Positive: map[int32]int64{0: 2}, Zero: 1, Sum: 2}},
+			want:   &pb.Metric{Id: "test", Type: "summary", Summary: &pb.Summary{Positive: map[int32]int64{0: 2}, Zero: 1, Sum: 2}},
+			code:   codes.OK,
+		},
+		{
+			name:   "invalid summary",
+			metric: &pb.Metric{Id: "test", Type: "summary", Summary: &pb.Summary{Zero: -1}},
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "gauge by text",
+			metric: &pb.Metric{Id: "test", Type: "gauge", Value: 1, Text: "2.5"},
+			want:   &pb.Metric{Id: "test", Type: "gauge", Value: 2.5},
+			code:   codes.OK,
+		},
+		{
+			name:   "unknown type",
+			metric: &pb.Metric{Id: "test", Type: "unknown", Delta: 10},
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "unknown type by text",
+			metric: &pb.Metric{Id: "test", Type: "unknown", Text: "10"},
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "empty name",
+			metric: &pb.Metric{Type: "gauge", Value: 10},
+			code:   codes.NotFound,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.UpdateMetric(context.Background(), &pb.UpdateMetricRequest{Metric: tt.metric})
+			require.Equal(t, tt.code, status.Code(err))
+			if tt.code != codes.OK {
+				return
+			}
+			assert.Equal(t, tt.want.GetId(), resp.GetMetric().GetId())
+			assert.Equal(t, tt.want.GetType(), resp.GetMetric().GetType())
+			assert.Equal(t, tt.want.GetValue(), resp.GetMetric().GetValue())
+			assert.Equal(t, tt.want.GetDelta(), resp.GetMetric().GetDelta())
+			assert.Equal(t, tt.want.GetHistogram().GetBounds(), resp.GetMetric().GetHistogram().GetBounds())
+			assert.Equal(t, tt.want.GetHistogram().GetCounts(), resp.GetMetric().GetHistogram().GetCounts())
+			assert.Equal(t, tt.want.GetHistogram().GetSum(), resp.GetMetric().GetHistogram().GetSum())
+			assert.Equal(t, tt.want.GetSummary().GetPositive(), resp.GetMetric().GetSummary().GetPositive())
+			assert.Equal(t, tt.want.GetSummary().GetZero(), resp.GetMetric().GetSummary().GetZero())
+			assert.Equal(t, tt.want.GetSummary().GetSum(), resp.GetMetric().GetSummary().GetSum())
+		})
+	}
+}
+
+// rate is the custom type for tests, it is the gauge which is summed by series
+type rate struct {
+	metric.Metric
+}
+
+func (r rate) Type() string {
+	return "rate"
+}
+
+func TestMetricsServer_RegisteredType(t *testing.T) {
+	err := metric.RegisterType(metric.TypeInfo{
+		Name:        "rate",
+		Code:        "r",
+		Aggregation: metric.AggregateSum,
+		Parse: func(name, value string) (metric.Metric, error) {
+			m, err := metric.ParseMetric(name, value, metric.TypeGauge)
+			if err != nil {
+				return nil, err
+			}
+			return rate{m}, nil
+		},
+		DecodeJSON: func(content []byte) (metric.Metric, error) {
+			return nil, metric.ErrInvalidMetric
+		},
+		Merge: func(name string, metrics []metric.Metric) (metric.Metric, error) {
+			var sum float64
+			for _, m := range metrics {
+				sum += m.Float64()
+			}
+			return rate{metric.WithLabels(metric.NewGaugeMetric(name, sum), metrics[0].Labels().Clone())}, nil
+		},
+	})
+	if err != nil && !errors.Is(err, metric.ErrTypeIsRegistered) {
+		t.Fatal(err)
+	}
+
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	// registered types without own field are passed by text
+	for _, v := range []string{"1.5", "2"} {
+		_, err := client.UpdateMetric(ctx, &pb.UpdateMetricRequest{Metric: &pb.Metric{Id: "requests", Type: "rate", Text: v}})
+		require.NoError(t, err)
+	}
+
+	resp, err := client.GetMetric(ctx, &pb.GetMetricRequest{Id: "requests", Type: "rate"})
+	require.NoError(t, err)
+	assert.Equal(t, "3.5", resp.GetMetric().GetText())
+
+	_, err = client.UpdateMetric(ctx, &pb.UpdateMetricRequest{Metric: &pb.Metric{Id: "requests", Type: "rate", Text: "abc"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.UpdateMetric(ctx, &pb.UpdateMetricRequest{Metric: &pb.Metric{Id: "requests", Type: "rate", Value: 1}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMetricsServer_UpdateMetrics(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	stream, err := client.UpdateMetrics(ctx)
+	require.NoError(t, err)
+
+	metrics := []*pb.Metric{
+		{Id: "gauge1", Type: "gauge", Value: 1.5},
+		{Id: "counter1", Type: "counter", Delta: 3},
+		{Id: "counter1", Type: "counter", Delta: 4},
+	}
+	for _, m := range metrics {
+		require.NoError(t, stream.Send(&pb.UpdateMetricRequest{Metric: m}))
+	}
+
+	resp, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), resp.GetSaved())
+
+	counter, err := client.GetMetric(ctx, &pb.GetMetricRequest{Id: "counter1", Type: "counter"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), counter.GetMetric().GetDelta())
+
+	gauge, err := client.GetMetric(ctx, &pb.GetMetricRequest{Id: "gauge1", Type: "gauge"})
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, gauge.GetMetric().GetValue())
+
+	list, err := client.ListMetrics(ctx, &pb.ListMetricsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, list.GetMetrics(), 2)
+}
+
+func TestMetricsServer_GetMetric(t *testing.T) {
+	client := newTestClient(t)
+
+	_, err := client.GetMetric(context.Background(), &pb.GetMetricRequest{Id: "not_exist", Type: "gauge"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.GetMetric(context.Background(), &pb.GetMetricRequest{Id: "test"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMetricsServer_Ping(t *testing.T) {
+	client := newTestClient(t)
+
+	_, err := client.Ping(context.Background(), &pb.PingRequest{})
+	assert.NoError(t, err)
+}
+
+func Test_withAgent(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(agentIDKey, "host/id"))
+	assert.Equal(t, "host/id", service.AgentFromContext(withAgent(ctx)))
+
+	assert.Equal(t, "", service.AgentFromContext(withAgent(context.Background())))
+}
+
+func Test_withIdempotencyKey(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(idempotencyKey, "batch-1"))
+	assert.Equal(t, "batch-1", service.IdempotencyKeyFromContext(withIdempotencyKey(ctx)))
+
+	assert.Equal(t, "", service.IdempotencyKeyFromContext(withIdempotencyKey(context.Background())))
+}
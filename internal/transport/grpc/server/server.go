@@ -0,0 +1,87 @@
+package grpcsrv
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+
+	"github.com/vilasle/metrics/internal/service"
+	"github.com/vilasle/metrics/internal/transport/grpc/pb"
+	"google.golang.org/grpc"
+	// registers gzip decompressor for compressed messages
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+// GRPCServer is the structure that holds and wraps the grpc server
+type GRPCServer struct {
+	addr    string
+	srv     *grpc.Server
+	running atomic.Bool
+}
+
+// NewGRPCServer create new instance of GRPCServer and registers metrics service on it
+// addr is the address to listen on
+// svc is the service which handles metrics, the same that uses rest handlers
+// opts are the options of grpc server e.g. interceptors
+func NewGRPCServer(addr string, svc service.MetricService, opts ...grpc.ServerOption) *GRPCServer {
+	srv := &GRPCServer{
+		addr:    addr,
+		srv:     grpc.NewServer(opts...),
+		running: atomic.Bool{},
+	}
+	srv.running.Store(false)
+
+	pb.RegisterMetricsServer(srv.srv, NewMetricsServer(svc))
+
+	return srv
+}
+
+// Start - start the server
+func (s *GRPCServer) Start() error {
+	listen, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listen)
+}
+
+// Serve - start the server on passed listener
+func (s *GRPCServer) Serve(listen net.Listener) error {
+	s.running.Swap(true)
+	defer s.running.Swap(false)
+
+	err := s.srv.Serve(listen)
+	if err == grpc.ErrServerStopped {
+		err = nil
+	}
+	return err
+}
+
+// IsRunning - check if the server is running
+func (s *GRPCServer) IsRunning() bool {
+	return s.running.Load()
+}
+
+// Stop - tries to stop the server gracefully,
+// if context is done before all rpc are finished, it stops the server forcefully and returns error of context
+func (s *GRPCServer) Stop(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.srv.Stop()
+		return ctx.Err()
+	}
+}
+
+// ForceStop - stops the server forcefully
+func (s *GRPCServer) ForceStop() error {
+	s.srv.Stop()
+	return nil
+}
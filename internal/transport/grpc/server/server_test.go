@@ -0,0 +1,29 @@
+package grpcsrv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/repository/memory"
+	"github.com/vilasle/metrics/internal/service/server"
+)
+
+func TestGRPCServer_StartStop(t *testing.T) {
+	s := NewGRPCServer("localhost:0", server.NewMetricService(memory.NewMetricRepository()))
+
+	go func() {
+		if err := s.Start(); err != nil {
+			t.Errorf("GRPCServer.Start() error = %v", err)
+		}
+	}()
+	time.Sleep(500 * time.Millisecond)
+
+	require.Equal(t, true, s.IsRunning(), "server should be running")
+
+	require.NoError(t, s.Stop(context.Background()))
+	time.Sleep(500 * time.Millisecond)
+
+	require.Equal(t, false, s.IsRunning(), "server should be stopped")
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/vilasle/metrics/internal/auth"
+	"github.com/vilasle/metrics/internal/logger"
+)
+
+// TokenGetter returns API token by id, e.g. repository.TokenRepository
+type TokenGetter interface {
+	Token(ctx context.Context, id string) (auth.Token, bool, error)
+}
+
+// ScopeRules maps paths to scopes which are required for them, the longest path which is prefix of request path wins.
+// Empty scope makes path public, paths without rule are public too
+type ScopeRules map[string]auth.Scope
+
+func (r ScopeRules) scope(path string) (auth.Scope, bool) {
+	path = withSlashes(path)
+
+	var (
+		matched string
+		scope   auth.Scope
+		found   bool
+	)
+	for p, s := range r {
+		p = withSlashes(p)
+		if strings.HasPrefix(path, p) && len(p) > len(matched) {
+			matched, scope, found = p, s, true
+		}
+	}
+	return scope, found && scope != ""
+}
+
+// Authenticate checks token from header Authorization: Bearer <token> for paths of rules.
+// Request without valid token is rejected with status 401, request with token without required scope - with status 403.
+// If tokens is nil, all requests are passed
+func Authenticate(tokens TokenGetter, rules ScopeRules) func(h http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			scope, ok := rules.scope(r.URL.Path)
+			if tokens == nil || !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, err := bearerToken(r, tokens)
+			if err != nil && !errors.Is(err, auth.ErrInvalidToken) && !errors.Is(err, ErrMissingToken) {
+				logger.Errorw("can not get token", "error", err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			} else if err != nil {
+				logger.Warnw("request without valid token", "uri", r.URL.String(), "remoteAddr", r.RemoteAddr, "error", err)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				http.Error(w, auth.ErrInvalidToken.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			if !token.HasScope(scope) {
+				logger.Warnw("token does not have scope", "uri", r.URL.String(), "token", token.ID, "scope", scope)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics", error="insufficient_scope", scope="`+string(scope)+`"`)
+				http.Error(w, ErrInsufficientScope.Error(), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func bearerToken(r *http.Request, tokens TokenGetter) (auth.Token, error) {
+	value, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return auth.Token{}, ErrMissingToken
+	}
+
+	id, secret, err := auth.Split(strings.TrimSpace(value))
+	if err != nil {
+		return auth.Token{}, err
+	}
+
+	token, ok, err := tokens.Token(r.Context(), id)
+	if err != nil {
+		return auth.Token{}, err
+	}
+	if !ok || !token.Match(secret) {
+		return auth.Token{}, auth.ErrInvalidToken
+	}
+	return token, nil
+}
+
+func withSlashes(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if !strings.HasSuffix(path, "/") {
+		path = path + "/"
+	}
+	return path
+}
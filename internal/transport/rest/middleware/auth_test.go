@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/auth"
+)
+
+type tokenMap map[string]auth.Token
+
+func (m tokenMap) Token(_ context.Context, id string) (auth.Token, bool, error) {
+	if id == "broken" {
+		return auth.Token{}, false, errors.New("storage is not available")
+	}
+	t, ok := m[id]
+	return t, ok, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	read, readValue, err := auth.Issue("dashboard", auth.ScopeRead)
+	require.NoError(t, err)
+	write, writeValue, err := auth.Issue("agent", auth.ScopeWrite)
+	require.NoError(t, err)
+
+	tokens := tokenMap{read.ID: read, write.ID: write}
+	rules := ScopeRules{
+		"/":        auth.ScopeRead,
+		"/ping":    "",
+		"/update":  auth.ScopeWrite,
+		"/updates": auth.ScopeWrite,
+		"/debug":   auth.ScopeAdmin,
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name       string
+		path       string
+		token      string
+		statusCode int
+	}{
+		{name: "public path", path: "/ping", statusCode: http.StatusOK},
+		{name: "without token", path: "/value/gauge/x", statusCode: http.StatusUnauthorized},
+		{name: "read by read token", path: "/value/gauge/x", token: readValue, statusCode: http.StatusOK},
+		{name: "root by read token", path: "/", token: readValue, statusCode: http.StatusOK},
+		{name: "write by read token", path: "/update/gauge/x/1", token: readValue, statusCode: http.StatusForbidden},
+		{name: "batch by write token", path: "/updates/", token: writeValue, statusCode: http.StatusOK},
+		{name: "read by write token", path: "/value/gauge/x", token: writeValue, statusCode: http.StatusForbidden},
+		{name: "debug by read token", path: "/debug/pprof/", token: readValue, statusCode: http.StatusForbidden},
+		{name: "wrong secret", path: "/value/gauge/x", token: read.ID + ".wrong", statusCode: http.StatusUnauthorized},
+		{name: "unknown token", path: "/value/gauge/x", token: "unknown.secret", statusCode: http.StatusUnauthorized},
+		{name: "malformed token", path: "/value/gauge/x", token: "malformed", statusCode: http.StatusUnauthorized},
+		{name: "storage failed", path: "/value/gauge/x", token: "broken.secret", statusCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			rr := httptest.NewRecorder()
+			Authenticate(tokens, rules)(next).ServeHTTP(rr, req)
+			assert.Equal(t, tt.statusCode, rr.Code)
+		})
+	}
+
+	t.Run("without tokens storage", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		Authenticate(nil, rules)(next).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/update/gauge/x/1", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
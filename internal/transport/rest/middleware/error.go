@@ -4,3 +4,9 @@ import "errors"
 
 var ErrInvalidKeyType = errors.New("invalid hash key type")
 var ErrInvalidHashSum = errors.New("invalid hash sum")
+var ErrUntrustedAddress = errors.New("address is not in trusted subnet")
+var ErrStaleRequest = errors.New("timestamp of request is out of allowed clock skew")
+var ErrReplayedRequest = errors.New("nonce of request is already used")
+var ErrMissingRequestStamp = errors.New("signed request does not have timestamp and nonce")
+var ErrMissingToken = errors.New("request does not have bearer token")
+var ErrInsufficientScope = errors.New("token does not have required scope")
@@ -17,6 +17,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vilasle/metrics/internal/compress"
+	"github.com/vilasle/metrics/internal/encrypt"
 	"github.com/vilasle/metrics/internal/logger"
 )
 
@@ -154,3 +155,50 @@ func Test_WithLogger(t *testing.T) {
 
 	assert.Regexp(t, r, content)
 }
+
+func Test_DecryptContent(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	small := []byte(`{"message": "hello world"}`)
+	// content is bigger than RSA-OAEP can encrypt with 2048 bits key
+	big := bytes.Repeat([]byte(`{"id":"test","type":"gauge","value":1.123},`), 100)
+
+	legacy, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &privateKey.PublicKey, small, []byte{})
+	require.NoError(t, err)
+
+	envelope, err := encrypt.Encrypt(&privateKey.PublicKey, big)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		mode    string
+		content []byte
+		want    []byte
+		wantErr bool
+	}{
+		{name: "legacy mode", content: legacy, want: small},
+		{name: "envelope mode", mode: encrypt.ModeEnvelope, content: envelope, want: big},
+		{name: "envelope content without mode", content: envelope, wantErr: true},
+	}
+
+	unpack := DecryptContent(privateKey, "updates")
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/updates/", nil)
+			require.NoError(t, err)
+			if tt.mode != "" {
+				req.Header.Set(encrypt.ModeHeader, tt.mode)
+			}
+
+			got, err := unpack(tt.content, req)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
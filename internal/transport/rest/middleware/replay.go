@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vilasle/metrics/internal/keyring"
+	"github.com/vilasle/metrics/internal/logger"
+)
+
+const defaultClockSkew = time.Minute * 5
+
+type ReplayOption func(*ReplayGuard)
+
+// WithClockSkew sets max difference between time of signing of request and time of server, 5 minutes by default
+func WithClockSkew(skew time.Duration) ReplayOption {
+	return func(g *ReplayGuard) {
+		if skew > 0 {
+			g.skew = skew
+		}
+	}
+}
+
+// WithRequiredStamp rejects signed requests without timestamp and nonce,
+// by default they are passed for agents which do not send them
+func WithRequiredStamp() ReplayOption {
+	return func(g *ReplayGuard) {
+		g.required = true
+	}
+}
+
+// ReplayGuard remembers nonces of signed requests while their timestamps are in allowed clock skew.
+// Nonces are kept in memory, so several servers behind balancer do not share them
+type ReplayGuard struct {
+	mx       sync.Mutex
+	skew     time.Duration
+	required bool
+	nonces   map[string]time.Time
+	pruned   time.Time
+	now      func() time.Time
+}
+
+func NewReplayGuard(opts ...ReplayOption) *ReplayGuard {
+	g := &ReplayGuard{
+		skew:   defaultClockSkew,
+		nonces: make(map[string]time.Time),
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// RejectReplays rejects signed requests which timestamp from header keyring.TimestampHeader is out of clock skew
+// or which nonce from header keyring.NonceHeader was already used.
+// It has to follow CheckHashSum in chain, because only signed timestamp and nonce can be trusted.
+// Requests without hash sum are passed. If guard is nil, checking is skipped
+func RejectReplays(guard *ReplayGuard) UnpackFunc {
+	return func(b []byte, req *http.Request) ([]byte, error) {
+		if guard == nil || req.Header.Get("HashSHA256") == "" {
+			return b, nil
+		}
+
+		if err := guard.Check(req.Header.Get(keyring.TimestampHeader), req.Header.Get(keyring.NonceHeader)); err != nil {
+			logger.Warnw("rejected request", "uri", req.URL.String(), "remoteAddr", req.RemoteAddr, "error", err)
+			return b, err
+		}
+		return b, nil
+	}
+}
+
+// Check rejects timestamp in unix seconds which is out of clock skew and nonce which was already used.
+// Timestamp and nonce have to be verified by signature before checking
+func (g *ReplayGuard) Check(timestamp, nonce string) error {
+	if timestamp == "" && nonce == "" {
+		if g.required {
+			return ErrMissingRequestStamp
+		}
+		return nil
+	}
+	if timestamp == "" || nonce == "" {
+		return ErrMissingRequestStamp
+	}
+
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrStaleRequest
+	}
+
+	now, signed := g.now(), time.Unix(sec, 0)
+	if signed.Before(now.Add(-g.skew)) || signed.After(now.Add(g.skew)) {
+		return ErrStaleRequest
+	}
+
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	g.prune(now)
+
+	if _, ok := g.nonces[nonce]; ok {
+		return ErrReplayedRequest
+	}
+	// after this moment request is rejected by timestamp, so nonce can be forgotten
+	g.nonces[nonce] = signed.Add(g.skew)
+	return nil
+}
+
+// prune forgets expired nonces not often than once per clock skew
+func (g *ReplayGuard) prune(now time.Time) {
+	if now.Sub(g.pruned) < g.skew {
+		return
+	}
+	for nonce, expire := range g.nonces {
+		if now.After(expire) {
+			delete(g.nonces, nonce)
+		}
+	}
+	g.pruned = now
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/keyring"
+)
+
+func TestRejectReplays(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	key := keyring.Key{Secret: "hashKey"}
+	body := []byte(`{"id":"requests","type":"counter","delta":1}`)
+
+	newRequest := func(timestamp time.Time, nonce string) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "/updates/", nil)
+		require.NoError(t, err)
+
+		var ts string
+		if !timestamp.IsZero() {
+			ts = strconv.FormatInt(timestamp.Unix(), 10)
+			req.Header.Set(keyring.TimestampHeader, ts)
+		}
+		if nonce != "" {
+			req.Header.Set(keyring.NonceHeader, nonce)
+		}
+		sum := key.Sign(keyring.SignedContent(body, ts, nonce))
+		req.Header.Set("HashSHA256", base64.URLEncoding.EncodeToString(sum))
+		return req
+	}
+
+	guard := NewReplayGuard(WithClockSkew(time.Minute))
+	guard.now = func() time.Time { return now }
+
+	chain := NewUnpackerChain(CheckHashSum([]byte(key.Secret)), RejectReplays(guard))
+
+	t.Run("fresh request", func(t *testing.T) {
+		_, err := chain.Unpack(body, newRequest(now, "n1"))
+		require.NoError(t, err)
+	})
+
+	t.Run("replayed request", func(t *testing.T) {
+		_, err := chain.Unpack(body, newRequest(now, "n1"))
+		require.ErrorIs(t, err, ErrReplayedRequest)
+	})
+
+	t.Run("stale request", func(t *testing.T) {
+		_, err := chain.Unpack(body, newRequest(now.Add(-2*time.Minute), "n2"))
+		require.ErrorIs(t, err, ErrStaleRequest)
+
+		_, err = chain.Unpack(body, newRequest(now.Add(2*time.Minute), "n3"))
+		require.ErrorIs(t, err, ErrStaleRequest)
+	})
+
+	t.Run("changed nonce breaks signature", func(t *testing.T) {
+		req := newRequest(now, "n4")
+		req.Header.Set(keyring.NonceHeader, "n5")
+		_, err := chain.Unpack(body, req)
+		require.ErrorIs(t, err, ErrInvalidHashSum)
+	})
+
+	t.Run("request without stamp", func(t *testing.T) {
+		_, err := chain.Unpack(body, newRequest(time.Time{}, ""))
+		require.NoError(t, err)
+
+		required := NewReplayGuard(WithRequiredStamp())
+		_, err = NewUnpackerChain(CheckHashSum([]byte(key.Secret)), RejectReplays(required)).Unpack(body, newRequest(time.Time{}, ""))
+		require.ErrorIs(t, err, ErrMissingRequestStamp)
+	})
+
+	t.Run("expired nonces are forgotten", func(t *testing.T) {
+		guard.now = func() time.Time { return now.Add(3 * time.Minute) }
+		_, err := chain.Unpack(body, newRequest(now.Add(3*time.Minute), "n6"))
+		require.NoError(t, err)
+		assert.NotContains(t, guard.nonces, "n1")
+	})
+}
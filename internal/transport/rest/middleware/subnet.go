@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/vilasle/metrics/internal/logger"
+	"github.com/vilasle/metrics/internal/netutil"
+)
+
+// RealIPHeader is the header which contains ip address of agent
+const RealIPHeader = "X-Real-IP"
+
+// TrustedSubnet rejects requests to passed paths (and their sub paths) with status 403
+// if ip address from header X-Real-IP does not belong any of subnets.
+// If subnets are empty, all requests are passed
+func TrustedSubnet(subnets []*net.IPNet, path ...string) func(h http.Handler) http.Handler {
+	protectedPath := make([]string, 0, len(path))
+	for i := range path {
+		rs := path[i]
+
+		if !strings.HasPrefix(rs, "/") {
+			rs = "/" + rs
+		}
+
+		if !strings.HasSuffix(rs, "/") {
+			rs = rs + "/"
+		}
+
+		protectedPath = append(protectedPath, rs)
+	}
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if len(subnets) == 0 || !isProtectedPath(r.URL.Path, protectedPath) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ip := net.ParseIP(r.Header.Get(RealIPHeader))
+			if !netutil.IsTrustedIP(ip, subnets) {
+				logger.Warnw("request from untrusted address",
+					"uri", r.URL.String(),
+					"realIP", r.Header.Get(RealIPHeader),
+					"remoteAddr", r.RemoteAddr)
+				http.Error(w, ErrUntrustedAddress.Error(), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func isProtectedPath(path string, protectedPath []string) bool {
+	if !strings.HasSuffix(path, "/") {
+		path = path + "/"
+	}
+
+	for _, p := range protectedPath {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
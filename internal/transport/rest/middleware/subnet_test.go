@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/netutil"
+)
+
+func Test_TrustedSubnet(t *testing.T) {
+	subnets, err := netutil.ParseSubnets("192.168.1.0/24, 10.0.0.0/8")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		subnets string
+		path    string
+		realIP  string
+		code    int
+	}{
+		{name: "trusted address", path: "/updates/", realIP: "192.168.1.15", code: http.StatusOK},
+		{name: "trusted address from second subnet", path: "/update/", realIP: "10.1.2.3", code: http.StatusOK},
+		{name: "untrusted address", path: "/updates/", realIP: "192.168.2.15", code: http.StatusForbidden},
+		{name: "untrusted address to sub path", path: "/update/gauge/test/1", realIP: "172.16.0.1", code: http.StatusForbidden},
+		{name: "without header", path: "/update/", code: http.StatusForbidden},
+		{name: "invalid header", path: "/update/", realIP: "not ip", code: http.StatusForbidden},
+		{name: "not protected path", path: "/value/gauge/test", realIP: "172.16.0.1", code: http.StatusOK},
+	}
+
+	handler := TrustedSubnet(subnets, "update", "updates")
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.realIP != "" {
+				req.Header.Set(RealIPHeader, tt.realIP)
+			}
+			resp := httptest.NewRecorder()
+
+			handler(testHandler()).ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.code, resp.Code)
+		})
+	}
+}
+
+func Test_TrustedSubnet_Empty(t *testing.T) {
+	handler := TrustedSubnet(nil, "update", "updates")
+
+	req := httptest.NewRequest(http.MethodPost, "/updates/", nil)
+	resp := httptest.NewRecorder()
+
+	handler(testHandler()).ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
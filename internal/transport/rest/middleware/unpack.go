@@ -3,15 +3,15 @@ package middleware
 import (
 	"bytes"
 	"compress/gzip"
-	"crypto/hmac"
-	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
 
+	"github.com/vilasle/metrics/internal/encrypt"
+	"github.com/vilasle/metrics/internal/keyring"
 	"github.com/vilasle/metrics/internal/logger"
 )
 
@@ -34,9 +34,18 @@ func (c UnpackerChain) Unpack(b []byte, req *http.Request) (result []byte, err e
 	return result, err
 }
 
+// CheckHashSum checks hash sum of body by the only key, see CheckHashSumKeyring
 func CheckHashSum(key []byte) UnpackFunc {
+	return CheckHashSumKeyring(keyring.FromSecret(key))
+}
+
+// CheckHashSumKeyring checks hash sum of body from header HashSHA256 by key from header keyring.IDHeader.
+// Request without key id is checked by all keys which are not expired.
+// If request has headers keyring.TimestampHeader and keyring.NonceHeader, they are signed with body, see RejectReplays
+// If keyring is nil or request does not have hash sum, checking is skipped
+func CheckHashSumKeyring(keys *keyring.Keyring) UnpackFunc {
 	return func(b []byte, req *http.Request) ([]byte, error) {
-		if len(key) == 0 {
+		if keys == nil {
 			return b, nil
 		}
 
@@ -51,17 +60,15 @@ func CheckHashSum(key []byte) UnpackFunc {
 			return b, err
 		}
 
-		logger.Debug("request hash sum", "sum", reqHash)
+		id := req.Header.Get(keyring.IDHeader)
+		logger.Debug("request hash sum", "sum", reqHash, "key id", id)
 
-		hashSum, err := getHashSumWithKey(b, key)
+		content := keyring.SignedContent(b, req.Header.Get(keyring.TimestampHeader), req.Header.Get(keyring.NonceHeader))
+		match, err := keys.Verify(id, content, reqHash)
 		if err != nil {
-			return b, err
+			return b, errors.Join(ErrInvalidHashSum, err)
 		}
 
-		logger.Debug("generated hash", "hash", hashSum)
-
-		match := hmac.Equal(reqHash, hashSum)
-
 		if match {
 			return b, nil
 		}
@@ -70,17 +77,19 @@ func CheckHashSum(key []byte) UnpackFunc {
 	}
 }
 
-func getHashSumWithKey(b []byte, key []byte) ([]byte, error) {
-	h := hmac.New(sha256.New, key)
-
-	if _, err := h.Write(b); err != nil {
-		return []byte{}, err
+// DecryptContent decrypts body of requests to passed paths by the only key, see DecryptContentKeySet
+func DecryptContent(key *rsa.PrivateKey, path ...string) UnpackFunc {
+	var keys *encrypt.KeySet
+	if key != nil {
+		keys, _ = encrypt.NewKeySet(key)
 	}
-
-	return h.Sum(nil), nil
+	return DecryptContentKeySet(keys, path...)
 }
 
-func DecryptContent(key *rsa.PrivateKey, path ...string) UnpackFunc {
+// DecryptContentKeySet decrypts body of requests to passed paths.
+// Envelope mode is chosen by header encrypt.ModeHeader, requests without the header are decrypted by RSA-OAEP as is.
+// Key is chosen by header encrypt.KeyIDHeader, requests without the header are decrypted by each key of set
+func DecryptContentKeySet(keys *encrypt.KeySet, path ...string) UnpackFunc {
 	encryptedPath := make(map[string]struct{}, len(path))
 	for i := range path {
 		rs := path[i]
@@ -97,7 +106,7 @@ func DecryptContent(key *rsa.PrivateKey, path ...string) UnpackFunc {
 	}
 
 	return func(b []byte, req *http.Request) ([]byte, error) {
-		if key == nil {
+		if keys == nil {
 			return b, nil
 		}
 
@@ -114,7 +123,7 @@ func DecryptContent(key *rsa.PrivateKey, path ...string) UnpackFunc {
 			return b, nil
 		}
 
-		return rsa.DecryptOAEP(sha256.New(), rand.Reader, key, b, []byte{})
+		return keys.Decrypt(req.Header.Get(encrypt.KeyIDHeader), req.Header.Get(encrypt.ModeHeader), b)
 	}
 }
 
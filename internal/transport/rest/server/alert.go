@@ -0,0 +1,40 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/vilasle/metrics/internal/alert"
+	"github.com/vilasle/metrics/internal/service"
+)
+
+func showAlerts(svc service.AlertService, r *http.Request) Response {
+	state := alert.State(r.URL.Query().Get("state"))
+	switch state {
+	case "", alert.StatePending, alert.StateFiring, alert.StateResolved:
+	default:
+		return newTextResponse(emptyBody(), ErrInvalidQueryParameter)
+	}
+
+	alerts, err := svc.Alerts(r.Context())
+	if err != nil {
+		return newTextResponse(emptyBody(), err)
+	}
+
+	if state != "" {
+		alerts = alertsInState(alerts, state)
+	}
+
+	content, err := json.Marshal(alerts)
+	return newJSONResponse(content, err)
+}
+
+func alertsInState(alerts []alert.Alert, state alert.State) []alert.Alert {
+	rs := make([]alert.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if a.State == state {
+			rs = append(rs, a)
+		}
+	}
+	return rs
+}
@@ -0,0 +1,63 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/alert"
+)
+
+func TestDisplayAlerts(t *testing.T) {
+	alerts := []alert.Alert{
+		{Rule: "heap", State: alert.StateFiring},
+		{Rule: "poll", State: alert.StatePending},
+	}
+
+	testCases := []struct {
+		name       string
+		query      string
+		alerts     []alert.Alert
+		err        error
+		statusCode int
+		want       []alert.Alert
+	}{
+		{name: "all alerts", alerts: alerts, statusCode: http.StatusOK, want: alerts},
+		{name: "firing alerts", query: "?state=firing", alerts: alerts, statusCode: http.StatusOK, want: alerts[:1]},
+		{name: "resolved alerts", query: "?state=resolved", alerts: alerts, statusCode: http.StatusOK, want: []alert.Alert{}},
+		{name: "unknown state", query: "?state=unknown", statusCode: http.StatusBadRequest},
+		{name: "service failed", alerts: nil, err: errors.New("failed"), statusCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := NewMockAlertService(ctrl)
+			if tt.alerts != nil || tt.err != nil {
+				svc.EXPECT().Alerts(gomock.Any()).Return(tt.alerts, tt.err)
+			}
+
+			req, err := http.NewRequest(http.MethodGet, "/alerts"+tt.query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			DisplayAlerts(svc).ServeHTTP(rr, req)
+
+			require.Equal(t, tt.statusCode, rr.Code)
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+
+			var got []alert.Alert
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -9,3 +9,5 @@ var ErrEmptyRequestBody = errors.New("empty request body")
 var ErrEmptyRequiredFields = errors.New("empty required fields")
 var ErrInvalidKeyType = errors.New("invalid hash key type")
 var ErrInvalidHashSum = errors.New("invalid hash sum")
+var ErrInvalidQueryParameter = errors.New("invalid query parameter")
+var ErrInvalidRemoteWriteRequest = errors.New("invalid remote write request")
@@ -18,11 +18,25 @@ func (fn HandlerWithResponse) ServeHTTP(w http.ResponseWriter, r *http.Request)
 // Accept POST requests.
 // Can accept Content-Type [text/plain, application/json]
 // if request Content-Type is text/plain, then url must be in format /<type>/<name>/<value>. Response body will by empty
+// labels can be passed by query parameters like ?label=host=a&label=region=b
 // if request Content-Type is application/json
 // for gauge metric body must be in format :
 // {"type": "gauge", "id" : "metric_id", "value": "metric_value"}
 // for counter metric body must be in format :
 // {"type": "gauge", "id" : "metric_id", "delta": metric_value}
+// for histogram metric body must be in format :
+// {"type": "histogram", "id" : "metric_id", "bounds": [0.1, 0.5], "counts": [3, 2, 1], "sum": 1.45}
+// where bounds are upper bounds of buckets, counts has one element more for bucket up to +Inf,
+// observations of histogram are merged with stored ones of the same series
+// for summary metric body must be in format :
+// {"type": "summary", "id" : "metric_id", "sketch": {"positive": {"12": 3}, "negative": {"4": 1}, "zero": 2}, "sum": 1.45}
+// where keys of buckets are their indexes, or {"type": "summary", "id" : "metric_id", "value": 1.5} for one observation,
+// text value of summary is one observation too, sketches are merged with stored ones of the same series
+// labels are passed by optional field "labels": {"host": "a"}
+// time of observation is passed by optional field "timestamp": "2024-01-01T00:00:00Z", metric without it is observed now,
+// samples which are older than max sample age are dropped, late samples of gauge do not replace newer value
+// instance ID of agent is passed by header X-Agent-ID and is remembered as source of metric
+// metric which is sent again with the same header Idempotency-Key is acknowledged but is not applied twice
 func UpdateMetric(svc service.MetricService) HandlerWithResponse {
 	return func(w http.ResponseWriter, r *http.Request) Response {
 		return updateMetric(svc, r)
@@ -40,6 +54,8 @@ func UpdateMetric(svc service.MetricService) HandlerWithResponse {
 //	{"type": "counter", "id" : "metric_id", "delta": metric_value}
 //
 // ]
+// instance ID of agent is passed by header X-Agent-ID and is remembered as source of metrics
+// batch which is sent again with the same header Idempotency-Key is acknowledged but is not applied twice
 func BatchUpdate(svc service.MetricService) HandlerWithResponse {
 	return func(w http.ResponseWriter, r *http.Request) Response {
 		return updateMetrics(svc, r)
@@ -59,6 +75,7 @@ func DisplayAllMetrics(svc service.MetricService) HandlerWithResponse {
 // Accept POST requests for json body and GET requests for empty body
 // Can accept Content-Type [text/plain, application/json]
 // if request Content-Type is text/plain, then url must be in format /<type>/<name>/. Response body will content value of metric
+// series of metric are filtered by labels from query parameters like ?label=host=a, json body passes filter by field "labels"
 // if request Content-Type is application/json body must be in format : {"type": "gauge", "id" : "metric_id"}
 // Response body will content json string with value of metric like this:
 //
@@ -70,6 +87,101 @@ func DisplayMetric(svc service.MetricService) HandlerWithResponse {
 	}
 }
 
+// DisplayHistory is handler for displaying values of metric for period.
+// Accept GET requests, url must be in format /history/<type>/<name>.
+// Query parameters:
+//
+//	from - beginning of period in RFC3339 format or unix time in seconds, by default an hour before to
+//	to - end of period in RFC3339 format or unix time in seconds, by default current time
+//	step - duration of step like 1m or count of seconds, values are returned as is if it is empty
+//	agg - aggregation of values on each step: avg, min, max, last for gauge and sum, rate for counter
+//	label - label of series in format name=value, can be passed several times
+//
+// Response body will content json array like this:
+//
+//	[{"time": "2024-01-01T00:00:00Z", "value": 1.5}, {"time": "2024-01-01T00:01:00Z", "value": 2}]
+func DisplayHistory(svc service.MetricService) HandlerWithResponse {
+	return func(w http.ResponseWriter, r *http.Request) Response {
+		return showHistory(svc, r)
+	}
+}
+
+// ExposeMetrics is handler for scraping of metrics by Prometheus.
+// Accept GET requests.
+// Return all gauges, summed counters, histograms and summaries in Prometheus text exposition format like this:
+//
+//	# TYPE Alloc gauge
+//	Alloc 1.123
+//	# TYPE PollCount counter
+//	PollCount 5
+//
+// Histograms are exposed by series _bucket with cumulative counts, _sum and _count.
+// Summaries are exposed by series with label quantile for p50, p90 and p99, _sum and _count.
+// Symbols of name which are not allowed by Prometheus are replaced by '_'
+func ExposeMetrics(svc service.MetricService) HandlerWithResponse {
+	return func(w http.ResponseWriter, r *http.Request) Response {
+		return exposeMetrics(svc, r)
+	}
+}
+
+// RemoteWrite is handler for receiving of samples by Prometheus remote write protocol.
+// Accept POST requests with snappy compressed protobuf body.
+// Label __name__ is the name of metric, other labels of series are stored as labels of metric.
+// Series are counters if metadata defines them as counters, histograms or summaries parts,
+// series without metadata are counters if name ends with _total, _count, _sum or _bucket, others are gauges.
+// Prometheus counters are cumulative, that's why only increments of them are saved.
+// The first sample of series is the baseline, only increments after it are saved; series without samples
+// during an hour are forgotten. Times of samples are kept as timestamps of metrics
+func RemoteWrite(svc service.MetricService) HandlerWithResponse {
+	rcv := newRemoteWriteReceiver(svc)
+	return func(w http.ResponseWriter, r *http.Request) Response {
+		return rcv.receive(r)
+	}
+}
+
+// DisplaySource is handler for displaying which agent reported metric last time.
+// Accept GET requests, url must be in format /source/<type>/<name>,
+// series of metric is chosen by labels from query parameters like ?label=host=a.
+// Response body will content json like {"agent": "host/uuid", "time": "2024-01-01T00:00:00Z"},
+// agent is empty if metric was reported without header X-Agent-ID
+func DisplaySource(svc service.MetricService) HandlerWithResponse {
+	return func(w http.ResponseWriter, r *http.Request) Response {
+		return showSource(svc, r)
+	}
+}
+
+// DisplayAgents is handler for displaying agents which reported metrics.
+// Accept GET requests.
+// Response body will content json array like [{"id": "host/uuid", "last_seen": "2024-01-01T00:00:00Z", "metrics": 10}].
+// If query parameter inactive is passed (duration like 5m or count of seconds),
+// only agents which did not report metrics for this period are returned
+func DisplayAgents(svc service.MetricService) HandlerWithResponse {
+	return func(w http.ResponseWriter, r *http.Request) Response {
+		return showAgents(svc, r)
+	}
+}
+
+// DisplayAlerts is handler for displaying alerts of alerting rules.
+// Accept GET requests.
+// Response body will content json array like [{"rule": "heap", "state": "firing", "summary": "HeapAlloc > 1e+09 for 5m0s",
+// "value": 1.2e9, "active_since": "2024-01-01T00:00:00Z", ...}] ordered by name of rule.
+// If query parameter state is passed (pending, firing or resolved), only alerts in this state are returned
+func DisplayAlerts(svc service.AlertService) HandlerWithResponse {
+	return func(w http.ResponseWriter, r *http.Request) Response {
+		return showAlerts(svc, r)
+	}
+}
+
+// ReloadKeys is handler for reloading private keys of server without restart.
+// Accept POST requests.
+// Response body will content json like {"keys": ["<key id>", ...]} with ids of loaded keys,
+// if keys can not be loaded, previous keys are kept and response has status 500
+func ReloadKeys(keys KeyReloader) HandlerWithResponse {
+	return func(w http.ResponseWriter, r *http.Request) Response {
+		return reloadKeys(keys)
+	}
+}
+
 // Ping is handler for checking service health.
 // Accept GET requests.
 // Return 200 OK if service is healthy.
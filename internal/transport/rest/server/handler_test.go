@@ -848,3 +848,43 @@ func BenchmarkDisplayMetricAsJSON(b *testing.B) {
 		handler.ServeHTTP(rr, reqC)
 	})
 }
+
+func TestDisplayMetricWithLabels(t *testing.T) {
+	svc := server.NewMetricService(memory.NewMetricRepository())
+	require.NoError(t, svc.Save(context.TODO(),
+		metric.WithLabels(metric.NewGaugeMetric("cpu", 0.5), metric.Labels{"host": "a"}),
+		metric.WithLabels(metric.NewGaugeMetric("cpu", 0.75), metric.Labels{"host": "b"}),
+	))
+
+	testCases := []struct {
+		name       string
+		query      string
+		statusCode int
+		want       string
+	}{
+		{name: "series of host a", query: "?label=host=a", statusCode: http.StatusOK, want: "0.5"},
+		{name: "series of host b", query: "?label=host%3Db", statusCode: http.StatusOK, want: "0.75"},
+		{name: "not existed series", query: "?label=host=c", statusCode: http.StatusNotFound},
+		{name: "wrong label", query: "?label=host", statusCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("type", metric.TypeGauge)
+			ctx.URLParams.Add("name", "cpu")
+
+			req, err := http.NewRequest(http.MethodGet, "/value/{type}/{name}"+tt.query, nil)
+			require.NoError(t, err)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+
+			rr := httptest.NewRecorder()
+			DisplayMetric(svc).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.statusCode, rr.Code)
+			if tt.want != "" {
+				assert.Equal(t, tt.want, rr.Body.String())
+			}
+		})
+	}
+}
@@ -2,10 +2,16 @@ package rest
 
 import (
 	"context"
+	"net/url"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/vilasle/metrics/internal/metric"
 )
 
+// labelQueryParameter is the query parameter which passes label of metric in format name=value
+const labelQueryParameter = "label"
+
 func filled(v ...string) bool {
 	for _, v := range v {
 		if v == "" {
@@ -30,3 +36,34 @@ func getRawDataFromContext(ctx context.Context) rawData {
 		Value: chi.URLParamFromCtx(ctx, "value"),
 	}
 }
+
+// getLabelsFromQuery returns labels from query parameters like ?label=host=a&label=region=b
+func getLabelsFromQuery(values url.Values) (metric.Labels, error) {
+	pairs := values[labelQueryParameter]
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	labels := make(metric.Labels, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, ErrInvalidQueryParameter
+		}
+		labels[name] = value
+	}
+	return labels, nil
+}
+
+// labelsQuery returns query string for passing labels to handlers
+func labelsQuery(labels metric.Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	values := url.Values{}
+	for _, name := range labels.Names() {
+		values.Add(labelQueryParameter, name+"="+labels[name])
+	}
+	return "?" + values.Encode()
+}
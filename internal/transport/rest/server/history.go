@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/vilasle/metrics/internal/service"
+)
+
+// defaultHistoryPeriod is the period of history which is returned when parameter from is not passed
+const defaultHistoryPeriod = time.Hour
+
+func showHistory(svc service.MetricService, r *http.Request) Response {
+	raw := getRawDataFromContext(r.Context())
+	if notFilled(raw.Name, raw.Type) {
+		return newTextResponse(emptyBody(), ErrEmptyRequiredFields)
+	}
+
+	query, err := parseHistoryQuery(r.URL.Query(), time.Now())
+	if err != nil {
+		return newTextResponse(emptyBody(), err)
+	}
+	query.Type, query.Name = raw.Type, raw.Name
+
+	points, err := svc.History(r.Context(), query)
+	if err != nil {
+		return newTextResponse(emptyBody(), err)
+	}
+
+	content, err := json.Marshal(points)
+	return newJSONResponse(content, err)
+}
+
+func parseHistoryQuery(values url.Values, now time.Time) (service.HistoryQuery, error) {
+	var (
+		query service.HistoryQuery
+		err   error
+	)
+
+	if query.To, err = parseTime(values.Get("to"), now); err != nil {
+		return query, err
+	}
+	if query.From, err = parseTime(values.Get("from"), query.To.Add(-defaultHistoryPeriod)); err != nil {
+		return query, err
+	}
+	if query.Step, err = parseStep(values.Get("step")); err != nil {
+		return query, err
+	}
+	query.Aggregation = values.Get("agg")
+	if query.Labels, err = getLabelsFromQuery(values); err != nil {
+		return query, err
+	}
+
+	return query, nil
+}
+
+// parseTime accepts time in RFC3339 format or unix time in seconds
+func parseTime(v string, defVal time.Time) (time.Time, error) {
+	if v == "" {
+		return defVal, nil
+	}
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return t, ErrInvalidQueryParameter
+	}
+	return t, nil
+}
+
+// parseStep accepts duration in format of time.ParseDuration or count of seconds
+func parseStep(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Duration(sec) * time.Second, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return d, ErrInvalidQueryParameter
+	}
+	return d, nil
+}
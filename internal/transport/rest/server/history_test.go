@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/service"
+)
+
+func TestDisplayHistory(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	type mockArgs struct {
+		query  service.HistoryQuery
+		result []metric.Point
+		err    error
+	}
+
+	testCases := []struct {
+		name       string
+		params     map[string]string
+		query      string
+		mockArgs   *mockArgs
+		statusCode int
+		body       string
+	}{
+		{
+			name:   "gauge with step",
+			params: map[string]string{"type": "gauge", "name": "gauge1"},
+			query:  "?from=2024-01-01T00:00:00Z&to=1704070800&step=1m&agg=avg",
+			mockArgs: &mockArgs{
+				query: service.HistoryQuery{
+					Type: "gauge", Name: "gauge1",
+					From: from, To: time.Unix(to.Unix(), 0),
+					Step: time.Minute, Aggregation: "avg",
+				},
+				result: []metric.Point{{Time: from, Value: 1.5}},
+			},
+			statusCode: http.StatusOK,
+			body:       `[{"time":"2024-01-01T00:00:00Z","value":1.5}]`,
+		},
+		{
+			name:   "history is not stored",
+			params: map[string]string{"type": "gauge", "name": "gauge1"},
+			query:  "?from=2024-01-01T00:00:00Z&to=2024-01-01T01:00:00Z",
+			mockArgs: &mockArgs{
+				query:  service.HistoryQuery{Type: "gauge", Name: "gauge1", From: from, To: to},
+				result: nil,
+				err:    service.ErrHistoryIsNotStored,
+			},
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "invalid step",
+			params:     map[string]string{"type": "gauge", "name": "gauge1"},
+			query:      "?step=abc",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid time",
+			params:     map[string]string{"type": "counter", "name": "counter1"},
+			query:      "?from=yesterday",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "empty name",
+			params:     map[string]string{"type": "counter"},
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/history/{type}/{name}"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := chi.NewRouteContext()
+			for k, v := range tt.params {
+				ctx.URLParams.Add(k, v)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			svc := NewMockMetricService(ctrl)
+			if tt.mockArgs != nil {
+				svc.EXPECT().History(req.Context(), tt.mockArgs.query).Return(tt.mockArgs.result, tt.mockArgs.err)
+			}
+
+			rr := httptest.NewRecorder()
+			DisplayHistory(svc).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.statusCode, rr.Code)
+			if tt.body != "" {
+				assert.JSONEq(t, tt.body, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/vilasle/metrics/internal/service"
+)
+
+// idempotencyKeyHeader is the header which contains idempotency key of batch,
+// batch which is sent again with the same key is acknowledged but is not applied twice
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// withIdempotencyKey returns context of request which carries idempotency key from header
+func withIdempotencyKey(r *http.Request) *http.Request {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" {
+		return r
+	}
+	return r.WithContext(service.WithIdempotencyKey(r.Context(), key))
+}
@@ -0,0 +1,44 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository/memory"
+	"github.com/vilasle/metrics/internal/service/server"
+)
+
+func TestBatchUpdateIdempotent(t *testing.T) {
+	svc := server.NewMetricService(memory.NewMetricRepository())
+
+	send := func(key string) {
+		t.Helper()
+		body := `[{"id":"PollCount","type":"counter","delta":5}]`
+		req, err := http.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(agentIDHeader, "host-a/1")
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+
+		rr := httptest.NewRecorder()
+		BatchUpdate(svc).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	send("batch-1")
+	send("batch-1")
+	send("batch-2")
+	send("")
+
+	m, err := svc.Get(context.Background(), metric.TypeCounter, "PollCount", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "15", m.Value())
+}
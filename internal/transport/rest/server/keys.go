@@ -0,0 +1,25 @@
+package rest
+
+import (
+	"encoding/json"
+
+	"github.com/vilasle/metrics/internal/logger"
+)
+
+// KeyReloader is the set of keys which can be reloaded without restart of server, e.g. *encrypt.KeySet
+type KeyReloader interface {
+	Reload() error
+	IDs() []string
+}
+
+func reloadKeys(keys KeyReloader) Response {
+	if err := keys.Reload(); err != nil {
+		logger.Errorw("can not reload keys", "error", err)
+		return newTextResponse(emptyBody(), err)
+	}
+
+	content, err := json.Marshal(struct {
+		Keys []string `json:"keys"`
+	}{Keys: keys.IDs()})
+	return newJSONResponse(content, err)
+}
@@ -0,0 +1,51 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeKeys struct {
+	ids []string
+	err error
+}
+
+func (k *fakeKeys) Reload() error {
+	return k.err
+}
+
+func (k *fakeKeys) IDs() []string {
+	return k.ids
+}
+
+func TestReloadKeys(t *testing.T) {
+	testCases := []struct {
+		name       string
+		keys       *fakeKeys
+		statusCode int
+		want       string
+	}{
+		{name: "reloaded", keys: &fakeKeys{ids: []string{"a", "b"}}, statusCode: http.StatusOK, want: `{"keys":["a","b"]}`},
+		{name: "reloading failed", keys: &fakeKeys{err: errors.New("failed")}, statusCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/admin/keys/reload", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			ReloadKeys(tt.keys).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.statusCode, rr.Code)
+			if tt.want != "" {
+				assert.JSONEq(t, tt.want, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package rest
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vilasle/metrics/internal/logger"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/service"
+)
+
+// prometheusContentType is the content type of Prometheus text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type prometheusResponse struct {
+	sp simpleResponse
+}
+
+func newPrometheusResponse(content []byte, err error) Response {
+	return prometheusResponse{sp: simpleResponse{content: content, err: err}}
+}
+
+func (r prometheusResponse) write(w http.ResponseWriter) {
+	w.Header().Add("Content-Type", prometheusContentType)
+	r.sp.write(w)
+}
+
+func exposeMetrics(svc service.MetricService, r *http.Request) Response {
+	metrics, err := svc.All(r.Context())
+	if err != nil {
+		return newTextResponse(emptyBody(), err)
+	}
+	return newPrometheusResponse(generatePrometheusExposition(metrics), nil)
+}
+
+// generatePrometheusExposition returns metrics in Prometheus text format sorted by name and labels.
+// If several metrics have the same name after sanitizing only series of the first one are exposed
+func generatePrometheusExposition(metrics []metric.Metric) []byte {
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Name() != metrics[j].Name() {
+			return metrics[i].Name() < metrics[j].Name()
+		}
+		if metrics[i].Type() != metrics[j].Type() {
+			return metrics[i].Type() < metrics[j].Type()
+		}
+		return metrics[i].Labels().String() < metrics[j].Labels().String()
+	})
+
+	type family struct {
+		name, kind string
+	}
+
+	buf := &bytes.Buffer{}
+	exposed := make(map[string]family, len(metrics))
+	for _, m := range metrics {
+		var value string
+		switch m.Type() {
+		case metric.TypeGauge:
+			value = formatPrometheusFloat(m.Float64())
+		case metric.TypeCounter:
+			value = strconv.FormatInt(m.Int64(), 10)
+		case metric.TypeHistogram:
+			if _, ok := m.(metric.Histogram); !ok {
+				continue
+			}
+		case metric.TypeSummary:
+			if _, ok := m.(metric.Summary); !ok {
+				continue
+			}
+		default:
+			continue
+		}
+
+		name := sanitizeMetricName(m.Name())
+		if f, ok := exposed[name]; !ok {
+			exposed[name] = family{name: m.Name(), kind: m.Type()}
+			buf.WriteString("# TYPE " + name + " " + m.Type() + "\n")
+		} else if f.name != m.Name() || f.kind != m.Type() {
+			logger.Warn("metric with the same name is already exposed", "name", m.Name(), "exposedName", name)
+			continue
+		}
+
+		if h, ok := m.(metric.Histogram); ok {
+			writePrometheusHistogram(buf, name, h)
+			continue
+		}
+		if s, ok := m.(metric.Summary); ok {
+			writePrometheusSummary(buf, name, s)
+			continue
+		}
+		buf.WriteString(name + formatPrometheusLabels(m.Labels()) + " " + value + "\n")
+	}
+	return buf.Bytes()
+}
+
+// writePrometheusHistogram writes series _bucket with cumulative counts, _sum and _count of histogram
+func writePrometheusHistogram(buf *bytes.Buffer, name string, h metric.Histogram) {
+	data := h.Data()
+	labels := h.Labels().Clone()
+	if labels == nil {
+		labels = make(metric.Labels, 1)
+	}
+
+	var cumulative int64
+	for i, c := range data.Counts {
+		cumulative += c
+		if i < len(data.Bounds) {
+			labels["le"] = formatPrometheusFloat(data.Bounds[i])
+		} else {
+			labels["le"] = "+Inf"
+		}
+		buf.WriteString(name + "_bucket" + formatPrometheusLabels(labels) + " " + strconv.FormatInt(cumulative, 10) + "\n")
+	}
+
+	series := formatPrometheusLabels(h.Labels())
+	buf.WriteString(name + "_sum" + series + " " + formatPrometheusFloat(data.Sum) + "\n")
+	buf.WriteString(name + "_count" + series + " " + strconv.FormatInt(cumulative, 10) + "\n")
+}
+
+// writePrometheusSummary writes series with label quantile for each of metric.DefaultQuantiles, _sum and _count of summary
+func writePrometheusSummary(buf *bytes.Buffer, name string, s metric.Summary) {
+	labels := s.Labels().Clone()
+	if labels == nil {
+		labels = make(metric.Labels, 1)
+	}
+
+	for _, q := range metric.DefaultQuantiles {
+		labels["quantile"] = formatPrometheusFloat(q)
+		buf.WriteString(name + formatPrometheusLabels(labels) + " " + formatPrometheusFloat(s.Quantile(q)) + "\n")
+	}
+
+	series := formatPrometheusLabels(s.Labels())
+	buf.WriteString(name + "_sum" + series + " " + formatPrometheusFloat(s.Float64()) + "\n")
+	buf.WriteString(name + "_count" + series + " " + strconv.FormatInt(s.Int64(), 10) + "\n")
+}
+
+// formatPrometheusLabels returns labels like {host="a",region="b"}, names of labels are sanitized
+func formatPrometheusLabels(labels metric.Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	sb := strings.Builder{}
+	sb.WriteByte('{')
+	for i, name := range labels.Names() {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(sanitizeLabelName(name))
+		sb.WriteString(`="`)
+		sb.WriteString(labelValueReplacer.Replace(labels[name]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// sanitizeLabelName replaces symbols which are not allowed by Prometheus in names of labels to '_',
+// name must match [a-zA-Z_][a-zA-Z0-9_]*
+func sanitizeLabelName(name string) string {
+	return strings.ReplaceAll(sanitizeMetricName(name), ":", "_")
+}
+
+// sanitizeMetricName replaces symbols which are not allowed by Prometheus to '_',
+// name must match [a-zA-Z_:][a-zA-Z0-9_:]*
+func sanitizeMetricName(name string) string {
+	if name == "" {
+		return "_"
+	}
+
+	sb := strings.Builder{}
+	sb.Grow(len(name) + 1)
+	if name[0] >= '0' && name[0] <= '9' {
+		sb.WriteByte('_')
+	}
+	for _, c := range name {
+		if c == '_' || c == ':' ||
+			(c >= 'a' && c <= 'z') ||
+			(c >= 'A' && c <= 'Z') ||
+			(c >= '0' && c <= '9') {
+			sb.WriteRune(c)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
+
+func formatPrometheusFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
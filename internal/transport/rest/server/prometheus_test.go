@@ -0,0 +1,158 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository/memory"
+	"github.com/vilasle/metrics/internal/service/server"
+)
+
+func TestExposeMetrics(t *testing.T) {
+	svc := server.NewMetricService(memory.NewMetricRepository())
+	require.NoError(t, svc.Save(context.Background(),
+		metric.NewGaugeMetric("Alloc", 1.5),
+		metric.NewGaugeMetric("cpu.utilization-1", 0.25),
+		metric.NewCounterMetric("PollCount", 2),
+		metric.NewCounterMetric("PollCount", 3),
+	))
+
+	req, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	ExposeMetrics(svc).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, prometheusContentType, rr.Header().Get("Content-Type"))
+
+	want := "# TYPE Alloc gauge\nAlloc 1.5\n" +
+		"# TYPE PollCount counter\nPollCount 5\n" +
+		"# TYPE cpu_utilization_1 gauge\ncpu_utilization_1 0.25\n"
+	assert.Equal(t, want, rr.Body.String())
+}
+
+func TestExposeMetrics_StorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := NewMockMetricService(ctrl)
+
+	req, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	require.NoError(t, err)
+
+	svc.EXPECT().All(req.Context()).Return(nil, fmt.Errorf("storage error"))
+
+	rr := httptest.NewRecorder()
+	ExposeMetrics(svc).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+}
+
+func Test_sanitizeMetricName(t *testing.T) {
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{name: "Alloc", want: "Alloc"},
+		{name: "http:requests_total", want: "http:requests_total"},
+		{name: "cpu.utilization-1", want: "cpu_utilization_1"},
+		{name: "1metric", want: "_1metric"},
+		{name: "метрика", want: "_______"},
+		{name: "", want: "_"},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sanitizeMetricName(tt.name))
+		})
+	}
+}
+
+func Test_formatPrometheusFloat(t *testing.T) {
+	assert.Equal(t, "1.123", formatPrometheusFloat(1.123))
+	assert.Equal(t, "1e+21", formatPrometheusFloat(1e21))
+	assert.Equal(t, "NaN", formatPrometheusFloat(math.NaN()))
+	assert.Equal(t, "+Inf", formatPrometheusFloat(math.Inf(1)))
+	assert.Equal(t, "-Inf", formatPrometheusFloat(math.Inf(-1)))
+}
+
+func TestExposeMetrics_Labels(t *testing.T) {
+	svc := server.NewMetricService(memory.NewMetricRepository())
+	require.NoError(t, svc.Save(context.Background(),
+		metric.WithLabels(metric.NewGaugeMetric("cpu", 0.5), metric.Labels{"host": "b"}),
+		metric.WithLabels(metric.NewGaugeMetric("cpu", 0.25), metric.Labels{"host": "a", "core.id": `"1"`}),
+		metric.NewGaugeMetric("cpu", 1),
+	))
+
+	req, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	ExposeMetrics(svc).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	want := "# TYPE cpu gauge\ncpu 1\n" +
+		"cpu{core_id=\"\\\"1\\\"\",host=\"a\"} 0.25\n" +
+		"cpu{host=\"b\"} 0.5\n"
+	assert.Equal(t, want, rr.Body.String())
+}
+
+func TestExposeMetrics_Histogram(t *testing.T) {
+	h, err := metric.NewHistogramMetricFromData("latency", metric.HistogramData{
+		Bounds: []float64{0.1, 0.5},
+		Counts: []int64{3, 2, 1},
+		Sum:    1.45,
+	})
+	require.NoError(t, err)
+
+	svc := server.NewMetricService(memory.NewMetricRepository())
+	require.NoError(t, svc.Save(context.Background(), metric.WithLabels(h, metric.Labels{"host": "a"})))
+
+	req, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	ExposeMetrics(svc).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	want := "# TYPE latency histogram\n" +
+		"latency_bucket{host=\"a\",le=\"0.1\"} 3\n" +
+		"latency_bucket{host=\"a\",le=\"0.5\"} 5\n" +
+		"latency_bucket{host=\"a\",le=\"+Inf\"} 6\n" +
+		"latency_sum{host=\"a\"} 1.45\n" +
+		"latency_count{host=\"a\"} 6\n"
+	assert.Equal(t, want, rr.Body.String())
+}
+
+func TestExposeMetrics_Summary(t *testing.T) {
+	svc := server.NewMetricService(memory.NewMetricRepository())
+	require.NoError(t, svc.Save(context.Background(), metric.NewSummaryMetric("PauseNs", 1, 1, 1, 1, 1, 100, 100, 100, 100, 100)))
+
+	req, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	ExposeMetrics(svc).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	one := formatPrometheusFloat(metric.NewSummaryMetric("", 1).Quantile(0.5))
+	hundred := formatPrometheusFloat(metric.NewSummaryMetric("", 100).Quantile(0.5))
+	want := "# TYPE PauseNs summary\n" +
+		"PauseNs{quantile=\"0.5\"} " + one + "\n" +
+		"PauseNs{quantile=\"0.9\"} " + hundred + "\n" +
+		"PauseNs{quantile=\"0.99\"} " + hundred + "\n" +
+		"PauseNs_sum 505\n" +
+		"PauseNs_count 10\n"
+	assert.Equal(t, want, rr.Body.String())
+}
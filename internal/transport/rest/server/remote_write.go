@@ -0,0 +1,253 @@
+package rest
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/vilasle/metrics/internal/logger"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/service"
+	"github.com/vilasle/metrics/internal/transport/prompb"
+)
+
+const metricNameLabel = "__name__"
+
+// counterTTL is the period after which state of counter series without samples is forgotten,
+// the next sample of such series is the new baseline
+const counterTTL = time.Hour
+
+// counterState is the state of cumulative counter series from Prometheus
+type counterState struct {
+	// known is false for series without samples, its first sample is the baseline
+	known bool
+	// last is the last received value
+	last float64
+	// saved is the part of the last value which is already saved as delta of counter
+	saved float64
+	// updated is the time when the last sample was received
+	updated time.Time
+}
+
+type remoteWriteReceiver struct {
+	svc      service.MetricService
+	mx       *sync.Mutex
+	counters map[string]counterState
+	purged   time.Time
+	now      func() time.Time
+}
+
+func newRemoteWriteReceiver(svc service.MetricService) *remoteWriteReceiver {
+	return &remoteWriteReceiver{
+		svc:      svc,
+		mx:       &sync.Mutex{},
+		counters: make(map[string]counterState),
+		purged:   time.Now(),
+		now:      time.Now,
+	}
+}
+
+func (rcv *remoteWriteReceiver) receive(r *http.Request) Response {
+	content, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return newTextResponse(emptyBody(), ErrReadingRequestBody)
+	}
+	if len(content) == 0 {
+		return newTextResponse(emptyBody(), ErrEmptyRequestBody)
+	}
+
+	req, err := decodeWriteRequest(content)
+	if err != nil {
+		logger.Debugw("can not decode remote write request", "error", err)
+		return newTextResponse(emptyBody(), ErrInvalidRemoteWriteRequest)
+	}
+
+	rcv.mx.Lock()
+	defer rcv.mx.Unlock()
+
+	rcv.purge()
+
+	metrics, states := rcv.convert(req)
+	if len(metrics) == 0 {
+		// baselines of new series are kept though there are no increments
+		rcv.keep(states)
+		return newTextResponse(emptyBody(), nil)
+	}
+
+	if err := rcv.svc.Save(r.Context(), metrics...); err != nil {
+		return newTextResponse(emptyBody(), err)
+	}
+
+	// state of counters is changed only after saving, that's why retry of request does not lose deltas
+	rcv.keep(states)
+	return newTextResponse(emptyBody(), nil)
+}
+
+// keep replaces states of counter series. The caller must hold the lock
+func (rcv *remoteWriteReceiver) keep(states map[string]counterState) {
+	for key, state := range states {
+		rcv.counters[key] = state
+	}
+}
+
+// purge forgets series which have not got samples during counterTTL, series are checked not often than once per counterTTL.
+// The caller must hold the lock
+func (rcv *remoteWriteReceiver) purge() {
+	now := rcv.now()
+	if now.Sub(rcv.purged) < counterTTL {
+		return
+	}
+	rcv.purged = now
+
+	for key, state := range rcv.counters {
+		if now.Sub(state.updated) >= counterTTL {
+			delete(rcv.counters, key)
+		}
+	}
+}
+
+func decodeWriteRequest(content []byte) (*prompb.WriteRequest, error) {
+	b, err := snappy.Decode(nil, content)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &prompb.WriteRequest{}
+	if err := proto.Unmarshal(b, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// convert returns metrics from samples of request and new states of counter series
+func (rcv *remoteWriteReceiver) convert(req *prompb.WriteRequest) ([]metric.Metric, map[string]counterState) {
+	types := make(map[string]prompb.MetricMetadata_MetricType, len(req.GetMetadata()))
+	for _, md := range req.GetMetadata() {
+		types[md.GetMetricFamilyName()] = md.GetType()
+	}
+
+	metrics := make([]metric.Metric, 0, len(req.GetTimeseries()))
+	states := make(map[string]counterState)
+	now := rcv.now()
+
+	for _, ts := range req.GetTimeseries() {
+		name, labels := seriesLabels(ts.GetLabels())
+		if name == "" {
+			continue
+		}
+
+		if !isCounterSeries(name, types) {
+			for _, s := range ts.GetSamples() {
+				if !math.IsNaN(s.GetValue()) {
+					m := metric.WithLabels(metric.NewGaugeMetric(name, s.GetValue()), labels)
+					metrics = append(metrics, withSampleTime(m, s.GetTimestamp()))
+				}
+			}
+			continue
+		}
+
+		key := seriesKey(ts.GetLabels())
+		state, ok := states[key]
+		if !ok {
+			state = rcv.counters[key]
+		}
+		for _, s := range ts.GetSamples() {
+			var delta int64
+			if delta, state = nextCounterState(state, s.GetValue()); delta > 0 {
+				m := metric.WithLabels(metric.NewCounterMetric(name, delta), labels)
+				metrics = append(metrics, withSampleTime(m, s.GetTimestamp()))
+			}
+		}
+		state.updated = now
+		states[key] = state
+	}
+	return metrics, states
+}
+
+// withSampleTime sets time of sample in unix milliseconds to metric, sample without time is stamped by service
+func withSampleTime(m metric.Metric, msec int64) metric.Metric {
+	if msec != 0 {
+		m.SetTimestamp(time.UnixMilli(msec))
+	}
+	return m
+}
+
+// nextCounterState returns the whole part of increment of cumulative counter.
+// The first value of series is the baseline without increment, because it is unknown when counter was started.
+// Decreasing of value is handled as reset of counter, so value after reset is increment itself
+func nextCounterState(state counterState, value float64) (int64, counterState) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, state
+	}
+
+	if !state.known {
+		state.known, state.last, state.saved = true, value, value
+		return 0, state
+	}
+
+	if value < state.last {
+		state.saved = 0
+	}
+	state.last = value
+
+	delta := int64(value - state.saved)
+	state.saved += float64(delta)
+
+	return delta, state
+}
+
+// isCounterSeries defines type of series by metadata of metric family.
+// Series without metadata are counters if name has one of suffixes _total, _count, _sum, _bucket
+func isCounterSeries(name string, types map[string]prompb.MetricMetadata_MetricType) bool {
+	if t, ok := types[name]; ok {
+		return t == prompb.MetricMetadata_COUNTER
+	}
+
+	for _, suffix := range []string{"_total", "_count", "_sum", "_bucket"} {
+		family, ok := strings.CutSuffix(name, suffix)
+		if !ok {
+			continue
+		}
+
+		t, ok := types[family]
+		if !ok {
+			return true
+		}
+		return t == prompb.MetricMetadata_COUNTER ||
+			t == prompb.MetricMetadata_HISTOGRAM ||
+			t == prompb.MetricMetadata_SUMMARY
+	}
+	return false
+}
+
+// seriesLabels returns name of metric from label __name__ and other labels of series
+func seriesLabels(labels []*prompb.Label) (string, metric.Labels) {
+	var name string
+	rs := make(metric.Labels, len(labels))
+	for _, l := range labels {
+		if l.GetName() == metricNameLabel {
+			name = l.GetValue()
+			continue
+		}
+		rs[l.GetName()] = l.GetValue()
+	}
+	return name, rs
+}
+
+// seriesKey returns identifier of series which does not depend on order of labels
+func seriesKey(labels []*prompb.Label) string {
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, l.GetName()+"\xff"+l.GetValue())
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\xfe")
+}
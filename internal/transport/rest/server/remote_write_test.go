@@ -0,0 +1,183 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository/memory"
+	"github.com/vilasle/metrics/internal/service/server"
+	"github.com/vilasle/metrics/internal/transport/prompb"
+)
+
+func newSeries(value float64, labels ...string) *prompb.TimeSeries {
+	ts := &prompb.TimeSeries{Samples: []*prompb.Sample{{Value: value}}}
+	for i := 0; i+1 < len(labels); i += 2 {
+		ts.Labels = append(ts.Labels, &prompb.Label{Name: labels[i], Value: labels[i+1]})
+	}
+	return ts
+}
+
+func remoteWriteRequest(t *testing.T, req *prompb.WriteRequest) *http.Request {
+	b, err := proto.Marshal(req)
+	require.NoError(t, err)
+
+	r, err := http.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(snappy.Encode(nil, b)))
+	require.NoError(t, err)
+	r.Header.Set("Content-Encoding", "snappy")
+	r.Header.Set("Content-Type", "application/x-protobuf")
+	return r
+}
+
+func TestRemoteWrite(t *testing.T) {
+	ctx := context.Background()
+	svc := server.NewMetricService(memory.NewMetricRepository())
+	handler := RemoteWrite(svc)
+
+	send := func(req *prompb.WriteRequest) int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, remoteWriteRequest(t, req))
+		return rr.Code
+	}
+
+	metadata := []*prompb.MetricMetadata{
+		{Type: prompb.MetricMetadata_GAUGE, MetricFamilyName: "temperature"},
+		{Type: prompb.MetricMetadata_COUNTER, MetricFamilyName: "requests"},
+	}
+
+	require.Equal(t, http.StatusOK, send(&prompb.WriteRequest{
+		Metadata: metadata,
+		Timeseries: []*prompb.TimeSeries{
+			newSeries(21.5, "__name__", "temperature", "job", "app"),
+			newSeries(10, "__name__", "requests", "code", "200"),
+			newSeries(5, "__name__", "requests", "code", "500"),
+			newSeries(7, "__name__", "http_requests_total"),
+			newSeries(1, "job", "app"),
+		},
+	}))
+
+	require.Equal(t, http.StatusOK, send(&prompb.WriteRequest{
+		Metadata: metadata,
+		Timeseries: []*prompb.TimeSeries{
+			newSeries(22, "__name__", "temperature", "job", "app"),
+			// increment of series
+			newSeries(15, "__name__", "requests", "code", "200"),
+			// reset of series
+			newSeries(2, "__name__", "requests", "code", "500"),
+			newSeries(math.NaN(), "__name__", "http_requests_total"),
+		},
+	}))
+
+	require.Equal(t, http.StatusOK, send(&prompb.WriteRequest{
+		Timeseries: []*prompb.TimeSeries{
+			newSeries(9, "__name__", "http_requests_total"),
+		},
+	}))
+
+	gauge, err := svc.Get(ctx, metric.TypeGauge, "temperature", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "22", gauge.Value())
+
+	counter, err := svc.Get(ctx, metric.TypeCounter, "requests", nil)
+	require.NoError(t, err)
+	// first samples of series are baselines without increments
+	assert.Equal(t, int64(5+2), counter.Int64())
+
+	counter, err = svc.Get(ctx, metric.TypeCounter, "http_requests_total", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), counter.Int64())
+
+	// labels of series are kept
+	counter, err = svc.Get(ctx, metric.TypeCounter, "requests", metric.Labels{"code": "500"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), counter.Int64())
+
+	gauge, err = svc.Get(ctx, metric.TypeGauge, "temperature", metric.Labels{"job": "app"})
+	require.NoError(t, err)
+	assert.Equal(t, metric.Labels{"job": "app"}, gauge.Labels())
+}
+
+func TestRemoteWrite_InvalidRequest(t *testing.T) {
+	handler := RemoteWrite(server.NewMetricService(memory.NewMetricRepository()))
+
+	for name, body := range map[string][]byte{
+		"empty body":     {},
+		"not snappy":     []byte("not snappy content"),
+		"not prometheus": snappy.Encode(nil, []byte{0xff, 0xff, 0xff}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+	}
+}
+
+func Test_nextCounterState(t *testing.T) {
+	state := counterState{}
+
+	// baseline
+	delta, state := nextCounterState(state, 1.5)
+	assert.Equal(t, int64(0), delta)
+
+	// fractional parts are accumulated
+	delta, state = nextCounterState(state, 2.1)
+	assert.Equal(t, int64(0), delta)
+
+	delta, state = nextCounterState(state, 2.9)
+	assert.Equal(t, int64(1), delta)
+
+	// reset
+	delta, _ = nextCounterState(state, 1)
+	assert.Equal(t, int64(1), delta)
+}
+
+func Test_isCounterSeries(t *testing.T) {
+	types := map[string]prompb.MetricMetadata_MetricType{
+		"latency":     prompb.MetricMetadata_HISTOGRAM,
+		"rpc":         prompb.MetricMetadata_SUMMARY,
+		"queue_count": prompb.MetricMetadata_GAUGE,
+		"inflight":    prompb.MetricMetadata_GAUGEHISTOGRAM,
+	}
+
+	assert.True(t, isCounterSeries("latency_bucket", types))
+	assert.True(t, isCounterSeries("latency_sum", types))
+	assert.True(t, isCounterSeries("rpc_count", types))
+	assert.False(t, isCounterSeries("rpc", types))
+	assert.False(t, isCounterSeries("queue_count", types))
+	assert.False(t, isCounterSeries("inflight_bucket", types))
+	assert.True(t, isCounterSeries("errors_total", types))
+	assert.False(t, isCounterSeries("memory_bytes", types))
+}
+
+func Test_remoteWriteReceiver_purge(t *testing.T) {
+	now := time.Now()
+	rcv := newRemoteWriteReceiver(server.NewMetricService(memory.NewMetricRepository()))
+	rcv.now, rcv.purged = func() time.Time { return now }, now
+	rcv.counters["stale"] = counterState{known: true, updated: now.Add(-counterTTL)}
+	rcv.counters["fresh"] = counterState{known: true, updated: now}
+
+	// series are not checked before counterTTL since the last check
+	rcv.purge()
+	assert.Len(t, rcv.counters, 2)
+
+	now = now.Add(counterTTL)
+	rcv.counters["fresh"] = counterState{known: true, updated: now.Add(-time.Minute)}
+	rcv.purge()
+	assert.Equal(t, []string{"fresh"}, slices.Collect(maps.Keys(rcv.counters)))
+}
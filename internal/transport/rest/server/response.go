@@ -86,6 +86,13 @@ func errorBadRequest(err error) bool {
 		metric.ErrEmptyValue,
 		metric.ErrUnknownMetricType,
 		ErrInvalidHashSum,
+		ErrInvalidQueryParameter,
+		ErrInvalidRemoteWriteRequest,
+		service.ErrInvalidPeriod,
+		service.ErrUnknownAggregation,
+		metric.ErrInvalidHistogram,
+		metric.ErrHistogramBoundsMismatch,
+		metric.ErrInvalidSummary,
 	)
 }
 func errorNotFound(err error) bool {
@@ -96,6 +103,7 @@ func errorNotFound(err error) bool {
 		ErrForbiddenResource,
 		ErrEmptyRequiredFields,
 		metric.ErrEmptyName,
+		service.ErrHistoryIsNotStored,
 	)
 }
 
@@ -2,7 +2,13 @@ package rest
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"io/fs"
+	"net"
 	"net/http"
+	"os"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -10,6 +16,9 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 )
 
+// UnixSocketPrefix is the prefix of address which means that server listens on unix socket, e.g. unix:/run/metrics.sock
+const UnixSocketPrefix = "unix:"
+
 // HTTPServer is the structure that holds and wraps the http server
 type HTTPServer struct {
 	srv     *http.Server
@@ -18,7 +27,7 @@ type HTTPServer struct {
 }
 
 // NewHTTPServer create new instance of HTTPServer
-// addr is the address to listen on
+// addr is the address to listen on, it may be path of unix socket with prefix unix:
 // middlewareOptions are the middleware to use
 func NewHTTPServer(addr string, middlewareOptions ...func(http.Handler) http.Handler) *HTTPServer {
 	mux := chi.NewRouter()
@@ -28,8 +37,6 @@ func NewHTTPServer(addr string, middlewareOptions ...func(http.Handler) http.Han
 		mux.Use(m)
 	}
 
-	mux.Mount("/debug", middleware.Profiler())
-
 	srv := &HTTPServer{
 		srv: &http.Server{
 			Addr:         addr,
@@ -57,6 +64,16 @@ func (s *HTTPServer) Register(path string, handler http.Handler, methods ...stri
 	}
 }
 
+// MountProfiler - serve pprof on /debug, it must be mounted only on servers which are not public
+func (s *HTTPServer) MountProfiler() {
+	s.mux.Mount("/debug", middleware.Profiler())
+}
+
+// UseTLS - serve HTTPS with config, config must provide certificate of server
+func (s *HTTPServer) UseTLS(config *tls.Config) {
+	s.srv.TLSConfig = config
+}
+
 // Start - start the server
 func (s *HTTPServer) Start() error {
 	s.srv.Handler = s.mux
@@ -64,7 +81,16 @@ func (s *HTTPServer) Start() error {
 
 	defer s.running.Swap(false)
 
-	err := s.srv.ListenAndServe()
+	ln, err := listen(s.srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	if s.srv.TLSConfig != nil {
+		err = s.srv.ServeTLS(ln, "", "")
+	} else {
+		err = s.srv.Serve(ln)
+	}
 
 	if err != nil && err == http.ErrServerClosed {
 		err = nil
@@ -92,3 +118,30 @@ func (s *HTTPServer) Stop(ctx context.Context) error {
 func (s *HTTPServer) ForceStop() error {
 	return s.srv.Close()
 }
+
+// listen listens on tcp address or on unix socket, unix socket is accessible only by owner of process
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, UnixSocketPrefix)
+	if !ok {
+		if addr == "" {
+			addr = ":http"
+		}
+		return net.Listen("tcp", addr)
+	}
+
+	// socket file is left if previous process was killed
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
@@ -2,12 +2,18 @@ package rest
 
 import (
 	"context"
+	"io/fs"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -151,3 +157,52 @@ func TestHttpServer_IsRunning(t *testing.T) {
 		})
 	}
 }
+
+func TestHttpServer_Profiler(t *testing.T) {
+	s := NewHTTPServer(":8080")
+
+	rr := httptest.NewRecorder()
+	s.mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code, "profiler must not be mounted by default")
+
+	s.MountProfiler()
+
+	rr = httptest.NewRecorder()
+	s.mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestHttpServer_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin.sock")
+	// stale socket of previous process
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+
+	s := NewHTTPServer(UnixSocketPrefix + path)
+	s.Register("/test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	go func() {
+		if err := s.Start(); err != nil {
+			t.Errorf("HttpServer.Start() error = %v", err)
+		}
+	}()
+	require.Eventually(t, func() bool {
+		info, err := os.Stat(path)
+		return err == nil && info.Mode()&fs.ModeSocket != 0
+	}, time.Second, 10*time.Millisecond)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0o600), info.Mode().Perm())
+
+	client := http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://admin/test")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NoError(t, s.Stop(context.Background()))
+}
@@ -1,7 +1,7 @@
 // Code generated by MockGen. DO NOT EDIT.
 // Source: internal/service/service.go
 
-// Package service is a generated GoMock package.
+// Package rest is a generated GoMock package.
 package rest
 
 import (
@@ -9,7 +9,9 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	alert "github.com/vilasle/metrics/internal/alert"
 	metric "github.com/vilasle/metrics/internal/metric"
+	service "github.com/vilasle/metrics/internal/service"
 )
 
 // MockMetricService is a mock of MetricService interface.
@@ -35,6 +37,21 @@ func (m *MockMetricService) EXPECT() *MockMetricServiceMockRecorder {
 	return m.recorder
 }
 
+// Agents mocks base method.
+func (m *MockMetricService) Agents(arg0 context.Context) ([]service.AgentInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Agents", arg0)
+	ret0, _ := ret[0].([]service.AgentInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Agents indicates an expected call of Agents.
+func (mr *MockMetricServiceMockRecorder) Agents(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Agents", reflect.TypeOf((*MockMetricService)(nil).Agents), arg0)
+}
+
 // All mocks base method.
 func (m *MockMetricService) All(arg0 context.Context) ([]metric.Metric, error) {
 	m.ctrl.T.Helper()
@@ -63,18 +80,33 @@ func (mr *MockMetricServiceMockRecorder) Close() *gomock.Call {
 }
 
 // Get mocks base method.
-func (m *MockMetricService) Get(ctx context.Context, metricType, name string) (metric.Metric, error) {
+func (m *MockMetricService) Get(ctx context.Context, metricType, name string, filter metric.Labels) (metric.Metric, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", ctx, metricType, name)
+	ret := m.ctrl.Call(m, "Get", ctx, metricType, name, filter)
 	ret0, _ := ret[0].(metric.Metric)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockMetricServiceMockRecorder) Get(ctx, metricType, name interface{}) *gomock.Call {
+func (mr *MockMetricServiceMockRecorder) Get(ctx, metricType, name, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockMetricService)(nil).Get), ctx, metricType, name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockMetricService)(nil).Get), ctx, metricType, name, filter)
+}
+
+// History mocks base method.
+func (m *MockMetricService) History(ctx context.Context, query service.HistoryQuery) ([]metric.Point, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", ctx, query)
+	ret0, _ := ret[0].([]metric.Point)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// History indicates an expected call of History.
+func (mr *MockMetricServiceMockRecorder) History(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockMetricService)(nil).History), ctx, query)
 }
 
 // Ping mocks base method.
@@ -110,6 +142,21 @@ func (mr *MockMetricServiceMockRecorder) Save(arg0 interface{}, arg1 ...interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockMetricService)(nil).Save), varargs...)
 }
 
+// Source mocks base method.
+func (m *MockMetricService) Source(ctx context.Context, metricType, name string, labels metric.Labels) (service.Source, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Source", ctx, metricType, name, labels)
+	ret0, _ := ret[0].(service.Source)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Source indicates an expected call of Source.
+func (mr *MockMetricServiceMockRecorder) Source(ctx, metricType, name, labels interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Source", reflect.TypeOf((*MockMetricService)(nil).Source), ctx, metricType, name, labels)
+}
+
 // Stats mocks base method.
 func (m *MockMetricService) Stats(arg0 context.Context) ([]metric.Metric, error) {
 	m.ctrl.T.Helper()
@@ -125,6 +172,44 @@ func (mr *MockMetricServiceMockRecorder) Stats(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockMetricService)(nil).Stats), arg0)
 }
 
+// MockAlertService is a mock of AlertService interface.
+type MockAlertService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAlertServiceMockRecorder
+}
+
+// MockAlertServiceMockRecorder is the mock recorder for MockAlertService.
+type MockAlertServiceMockRecorder struct {
+	mock *MockAlertService
+}
+
+// NewMockAlertService creates a new mock instance.
+func NewMockAlertService(ctrl *gomock.Controller) *MockAlertService {
+	mock := &MockAlertService{ctrl: ctrl}
+	mock.recorder = &MockAlertServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAlertService) EXPECT() *MockAlertServiceMockRecorder {
+	return m.recorder
+}
+
+// Alerts mocks base method.
+func (m *MockAlertService) Alerts(arg0 context.Context) ([]alert.Alert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Alerts", arg0)
+	ret0, _ := ret[0].([]alert.Alert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Alerts indicates an expected call of Alerts.
+func (mr *MockAlertServiceMockRecorder) Alerts(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Alerts", reflect.TypeOf((*MockAlertService)(nil).Alerts), arg0)
+}
+
 // MockCollector is a mock of Collector interface.
 type MockCollector struct {
 	ctrl     *gomock.Controller
@@ -174,6 +259,34 @@ func (mr *MockCollectorMockRecorder) Collect() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Collect", reflect.TypeOf((*MockCollector)(nil).Collect))
 }
 
+// GetCounterValue mocks base method.
+func (m *MockCollector) GetCounterValue(arg0 string) metric.Metric {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCounterValue", arg0)
+	ret0, _ := ret[0].(metric.Metric)
+	return ret0
+}
+
+// GetCounterValue indicates an expected call of GetCounterValue.
+func (mr *MockCollectorMockRecorder) GetCounterValue(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCounterValue", reflect.TypeOf((*MockCollector)(nil).GetCounterValue), arg0)
+}
+
+// GetGaugeValue mocks base method.
+func (m *MockCollector) GetGaugeValue(arg0 string) metric.Metric {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGaugeValue", arg0)
+	ret0, _ := ret[0].(metric.Metric)
+	return ret0
+}
+
+// GetGaugeValue indicates an expected call of GetGaugeValue.
+func (mr *MockCollectorMockRecorder) GetGaugeValue(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGaugeValue", reflect.TypeOf((*MockCollector)(nil).GetGaugeValue), arg0)
+}
+
 // ResetCounter mocks base method.
 func (m *MockCollector) ResetCounter(arg0 string) {
 	m.ctrl.T.Helper()
@@ -186,6 +299,30 @@ func (mr *MockCollectorMockRecorder) ResetCounter(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetCounter", reflect.TypeOf((*MockCollector)(nil).ResetCounter), arg0)
 }
 
+// ResetSummary mocks base method.
+func (m *MockCollector) ResetSummary(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ResetSummary", arg0)
+}
+
+// ResetSummary indicates an expected call of ResetSummary.
+func (mr *MockCollectorMockRecorder) ResetSummary(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetSummary", reflect.TypeOf((*MockCollector)(nil).ResetSummary), arg0)
+}
+
+// SetValue mocks base method.
+func (m *MockCollector) SetValue(arg0 metric.Metric) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetValue", arg0)
+}
+
+// SetValue indicates an expected call of SetValue.
+func (mr *MockCollectorMockRecorder) SetValue(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetValue", reflect.TypeOf((*MockCollector)(nil).SetValue), arg0)
+}
+
 // MockSender is a mock of Sender interface.
 type MockSender struct {
 	ctrl     *gomock.Controller
@@ -209,11 +346,23 @@ func (m *MockSender) EXPECT() *MockSenderMockRecorder {
 	return m.recorder
 }
 
+// Close mocks base method.
+func (m *MockSender) Close() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close")
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockSenderMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockSender)(nil).Close))
+}
+
 // Send mocks base method.
-func (m *MockSender) Send(arg0 ...metric.Metric) error {
+func (m *MockSender) Send(arg0 context.Context, arg1 ...metric.Metric) error {
 	m.ctrl.T.Helper()
-	varargs := []interface{}{}
-	for _, a := range arg0 {
+	varargs := []interface{}{arg0}
+	for _, a := range arg1 {
 		varargs = append(varargs, a)
 	}
 	ret := m.ctrl.Call(m, "Send", varargs...)
@@ -222,7 +371,8 @@ func (m *MockSender) Send(arg0 ...metric.Metric) error {
 }
 
 // Send indicates an expected call of Send.
-func (mr *MockSenderMockRecorder) Send(arg0 ...interface{}) *gomock.Call {
+func (mr *MockSenderMockRecorder) Send(arg0 interface{}, arg1 ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockSender)(nil).Send), arg0...)
+	varargs := append([]interface{}{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockSender)(nil).Send), varargs...)
 }
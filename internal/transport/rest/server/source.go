@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/vilasle/metrics/internal/service"
+)
+
+// agentIDHeader is the header which contains instance ID of agent which sent metrics
+const agentIDHeader = "X-Agent-ID"
+
+// withAgent returns context of request which carries instance ID of agent from header
+func withAgent(r *http.Request) *http.Request {
+	id := r.Header.Get(agentIDHeader)
+	if id == "" {
+		return r
+	}
+	return r.WithContext(service.WithAgent(r.Context(), id))
+}
+
+func showSource(svc service.MetricService, r *http.Request) Response {
+	raw := getRawDataFromContext(r.Context())
+	if notFilled(raw.Name, raw.Type) {
+		return newTextResponse(emptyBody(), ErrEmptyRequiredFields)
+	}
+
+	labels, err := getLabelsFromQuery(r.URL.Query())
+	if err != nil {
+		return newTextResponse(emptyBody(), err)
+	}
+
+	src, err := svc.Source(r.Context(), raw.Type, raw.Name, labels)
+	if err != nil {
+		return newTextResponse(emptyBody(), err)
+	}
+
+	content, err := json.Marshal(src)
+	return newJSONResponse(content, err)
+}
+
+func showAgents(svc service.MetricService, r *http.Request) Response {
+	inactive, err := parseStep(r.URL.Query().Get("inactive"))
+	if err != nil {
+		return newTextResponse(emptyBody(), err)
+	}
+
+	agents, err := svc.Agents(r.Context())
+	if err != nil {
+		return newTextResponse(emptyBody(), err)
+	}
+
+	if inactive > 0 {
+		agents = inactiveAgents(agents, time.Now().Add(-inactive))
+	}
+
+	content, err := json.Marshal(agents)
+	return newJSONResponse(content, err)
+}
+
+// inactiveAgents returns agents which did not report metrics since moment
+func inactiveAgents(agents []service.AgentInfo, since time.Time) []service.AgentInfo {
+	rs := make([]service.AgentInfo, 0, len(agents))
+	for _, a := range agents {
+		if a.LastSeen.Before(since) {
+			rs = append(rs, a)
+		}
+	}
+	return rs
+}
@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vilasle/metrics/internal/metric"
+	"github.com/vilasle/metrics/internal/repository/memory"
+	"github.com/vilasle/metrics/internal/service"
+	"github.com/vilasle/metrics/internal/service/server"
+)
+
+func TestDisplaySource(t *testing.T) {
+	svc := server.NewMetricService(memory.NewMetricRepository())
+
+	body := `[{"id":"cpu","type":"gauge","value":1,"labels":{"core":"1"}},{"id":"PollCount","type":"counter","delta":1}]`
+	req, err := http.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(agentIDHeader, "host-a/1")
+
+	rr := httptest.NewRecorder()
+	BatchUpdate(svc).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	testCases := []struct {
+		name       string
+		path       map[string]string
+		query      string
+		statusCode int
+		agent      string
+	}{
+		{name: "gauge with labels", path: map[string]string{"type": "gauge", "name": "cpu"}, query: "?label=core=1", statusCode: http.StatusOK, agent: "host-a/1"},
+		{name: "counter", path: map[string]string{"type": "counter", "name": "PollCount"}, statusCode: http.StatusOK, agent: "host-a/1"},
+		{name: "other series", path: map[string]string{"type": "gauge", "name": "cpu"}, statusCode: http.StatusNotFound},
+		{name: "empty name", path: map[string]string{"type": "gauge"}, statusCode: http.StatusNotFound},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := chi.NewRouteContext()
+			for k, v := range tt.path {
+				ctx.URLParams.Add(k, v)
+			}
+
+			req, err := http.NewRequest(http.MethodGet, "/source/{type}/{name}"+tt.query, nil)
+			require.NoError(t, err)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+
+			rr := httptest.NewRecorder()
+			DisplaySource(svc).ServeHTTP(rr, req)
+
+			require.Equal(t, tt.statusCode, rr.Code)
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+
+			var src service.Source
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &src))
+			assert.Equal(t, tt.agent, src.Agent)
+			assert.WithinDuration(t, time.Now(), src.Time, time.Minute)
+		})
+	}
+}
+
+func TestDisplayAgents(t *testing.T) {
+	svc := server.NewMetricService(memory.NewMetricRepository())
+
+	for _, agent := range []string{"host-a/1", "host-b/2"} {
+		ctx := service.WithAgent(context.Background(), agent)
+		require.NoError(t, svc.Save(ctx, metric.NewGaugeMetric(agent, 1)))
+	}
+
+	testCases := []struct {
+		name       string
+		query      string
+		statusCode int
+		want       []string
+	}{
+		{name: "all agents", statusCode: http.StatusOK, want: []string{"host-a/1", "host-b/2"}},
+		{name: "inactive agents", query: "?inactive=1h", statusCode: http.StatusOK, want: []string{}},
+		{name: "invalid period", query: "?inactive=hour", statusCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/agents"+tt.query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			DisplayAgents(svc).ServeHTTP(rr, req)
+
+			require.Equal(t, tt.statusCode, rr.Code)
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+
+			var agents []service.AgentInfo
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &agents))
+
+			got := make([]string, 0, len(agents))
+			for _, a := range agents {
+				got = append(got, a.ID)
+				assert.Equal(t, 1, a.Metrics)
+			}
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func Test_inactiveAgents(t *testing.T) {
+	now := time.Now()
+	agents := []service.AgentInfo{
+		{ID: "old", LastSeen: now.Add(-time.Hour)},
+		{ID: "new", LastSeen: now},
+	}
+
+	got := inactiveAgents(agents, now.Add(-time.Minute))
+	assert.Equal(t, []service.AgentInfo{agents[0]}, got)
+}
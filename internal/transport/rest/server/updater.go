@@ -21,6 +21,7 @@ auto-tests use filled Content-Type header only for iter1
 that's why handle any Content-Type as text/plain with exception of application/json
 */
 func updateMetric(svc service.MetricService, r *http.Request) Response {
+	r = withIdempotencyKey(withAgent(r))
 	switch r.Header.Get("Content-Type") {
 	case "application/json":
 		return handleUpdateAsTextJSON(svc, r)
@@ -33,7 +34,13 @@ func handleUpdateAsTextPlain(svc service.MetricService, r *http.Request) Respons
 	raw := getRawDataFromContext(r.Context())
 	logger.Debugw("raw data from url", "raw", raw, "url", r.URL.String())
 
+	labels, err := getLabelsFromQuery(r.URL.Query())
+	if err != nil {
+		return newTextResponse(emptyBody(), err)
+	}
+
 	if m, err := metric.ParseMetric(raw.Name, raw.Value, raw.Type); err == nil {
+		m.SetLabels(labels)
 		err := svc.Save(r.Context(), m)
 		return newTextResponse(emptyBody(), err)
 	} else {
@@ -66,6 +73,7 @@ func handleUpdateAsTextJSON(svc service.MetricService, r *http.Request) Response
 }
 
 func updateMetrics(svc service.MetricService, r *http.Request) Response {
+	r = withIdempotencyKey(withAgent(r))
 	switch r.Header.Get("Content-Type") {
 	case "application/json":
 		return handleUpdateMetricsAsBatch(svc, r)
@@ -42,8 +42,8 @@ func generateViewOfAllMetrics(metrics []metric.Metric) ([]byte, error) {
 			Name string
 			Link string
 		}{
-			Name: m.Name(),
-			Link: fmt.Sprintf("/value/%s/%s", m.Type(), m.Name()),
+			Name: metric.SeriesID(m.Name(), m.Labels()),
+			Link: fmt.Sprintf("/value/%s/%s%s", m.Type(), m.Name(), labelsQuery(m.Labels())),
 		})
 	}
 
@@ -81,7 +81,12 @@ func handleDisplayMetricAsTextPlain(svc service.MetricService, r *http.Request)
 		return newTextResponse(emptyBody(), ErrEmptyRequiredFields)
 	}
 
-	metric, err := svc.Get(r.Context(), raw.Type, raw.Name)
+	labels, err := getLabelsFromQuery(r.URL.Query())
+	if err != nil {
+		return newTextResponse(emptyBody(), err)
+	}
+
+	metric, err := svc.Get(r.Context(), raw.Type, raw.Name, labels)
 	if err != nil {
 		return newTextResponse(emptyBody(), err)
 	}
@@ -104,7 +109,7 @@ func handleDisplayMetricAsTextJSON(svc service.MetricService, r *http.Request) R
 		return newTextResponse(emptyBody(), err)
 	}
 
-	metric, err := svc.Get(r.Context(), m.Type(), m.Name())
+	metric, err := svc.Get(r.Context(), m.Type(), m.Name(), m.Labels())
 	if err != nil {
 		return newTextResponse(emptyBody(), err)
 	}